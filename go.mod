@@ -2,7 +2,11 @@ module github.com/coopco/nanobot
 
 go 1.25
 
-require github.com/sashabaranov/go-openai v1.36.1
+require (
+	github.com/fsnotify/fsnotify v1.9.0
+	github.com/sashabaranov/go-openai v1.41.2
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
 	github.com/anthropics/anthropic-sdk-go v1.26.0 // indirect
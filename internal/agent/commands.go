@@ -0,0 +1,158 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+
+	"github.com/coopco/nanobot/internal/tools"
+)
+
+// defaultCommandPrefix marks a message as a built-in command when no prefix
+// is configured.
+const defaultCommandPrefix = "/"
+
+// command is a built-in verb handled by the loop without calling the model.
+type command struct {
+	usage string
+	help  string
+	run   func(a *AgentLoop, ctx context.Context, sessionKey, args string) (Reply, error)
+}
+
+// commands maps each verb, without the prefix, to its handler. It is filled
+// in by init because the help handler refers back to it.
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"help": {
+			usage: "help",
+			help:  "list commands, tools and skills",
+			run:   (*AgentLoop).helpCommand,
+		},
+		"reset": {
+			usage: "reset",
+			help:  "clear the conversation history",
+			run: func(a *AgentLoop, _ context.Context, sessionKey, _ string) (Reply, error) {
+				return a.resetSession(sessionKey)
+			},
+		},
+		"model": {
+			usage: "model [name|default]",
+			help:  "show or switch the model used in this conversation",
+			run:   (*AgentLoop).modelCommand,
+		},
+		"cancel": {
+			usage: "cancel [task]",
+			help:  "list running background tasks or cancel one",
+			run:   (*AgentLoop).cancelCommand,
+		},
+	}
+}
+
+// runCommand handles content if it is a built-in command. It reports false,
+// leaving the message for the model, when content does not start with the
+// command prefix or names an unknown verb. ctx carries the origin of the
+// message, as set by tools.WithOrigin.
+func (a *AgentLoop) runCommand(ctx context.Context, sessionKey, content string) (Reply, bool, error) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(content), a.cmdPrefix)
+	if !ok {
+		return Reply{}, false, nil
+	}
+	verb, args, _ := strings.Cut(rest, " ")
+	cmd, ok := commands[strings.ToLower(verb)]
+	if !ok {
+		return Reply{}, false, nil
+	}
+	reply, err := cmd.run(a, ctx, sessionKey, strings.TrimSpace(args))
+	return reply, true, err
+}
+
+// resetSession clears the history of sessionKey, archiving the old
+// conversation, and confirms without consulting the model.
+func (a *AgentLoop) resetSession(sessionKey string) (Reply, error) {
+	archived, err := a.sessions.Reset(sessionKey)
+	if err != nil {
+		return Reply{}, fmt.Errorf("reset session: %w", err)
+	}
+	slog.Info("session reset", "session", sessionKey, "archive", archived)
+	return Reply{Content: "Conversation cleared. The previous history has been archived."}, nil
+}
+
+func (a *AgentLoop) helpCommand(_ context.Context, _, _ string) (Reply, error) {
+	var sb strings.Builder
+	sb.WriteString("Commands:\n")
+	verbs := make([]string, 0, len(commands))
+	for verb := range commands {
+		verbs = append(verbs, verb)
+	}
+	sort.Strings(verbs)
+	for _, verb := range verbs {
+		fmt.Fprintf(&sb, "  %s%s: %s\n", a.cmdPrefix, commands[verb].usage, commands[verb].help)
+	}
+
+	defs := a.tools.Definitions()
+	if len(defs) > 0 {
+		sb.WriteString("\nTools:\n")
+		for _, d := range defs {
+			fmt.Fprintf(&sb, "  %s: %s\n", d.Function.Name, d.Function.Description)
+		}
+	}
+
+	if a.skills != nil {
+		if skills := a.skills.LoadAll(); len(skills) > 0 {
+			sb.WriteString("\nSkills:\n")
+			for _, s := range skills {
+				fmt.Fprintf(&sb, "  %s: %s\n", s.Meta.Name, s.Meta.Description)
+			}
+		}
+	}
+	return Reply{Content: strings.TrimRight(sb.String(), "\n")}, nil
+}
+
+func (a *AgentLoop) modelCommand(_ context.Context, sessionKey, args string) (Reply, error) {
+	sess := a.sessions.GetOrCreate(sessionKey)
+	switch {
+	case args == "":
+		return Reply{Content: "Current model: " + a.modelFor(sess.Model())}, nil
+	case strings.EqualFold(args, "default"):
+		sess.SetModel("")
+	default:
+		sess.SetModel(args)
+	}
+	if err := a.sessions.Save(sess); err != nil {
+		return Reply{}, fmt.Errorf("save session: %w", err)
+	}
+	return Reply{Content: "Model for this conversation set to " + a.modelFor(sess.Model()) + "."}, nil
+}
+
+// cancelCommand lists or cancels the background tasks started from the
+// conversation the command came from; other chats' tasks are out of reach.
+func (a *AgentLoop) cancelCommand(ctx context.Context, _, args string) (Reply, error) {
+	if a.subagents == nil {
+		return Reply{Content: "No background tasks are available."}, nil
+	}
+	channel, chatID := tools.OriginFrom(ctx)
+	if args == "" {
+		running := a.subagents.ListRunningFor(channel, chatID)
+		if len(running) == 0 {
+			return Reply{Content: "No background tasks are running."}, nil
+		}
+		sort.Strings(running)
+		return Reply{Content: "Running tasks: " + strings.Join(running, ", ")}, nil
+	}
+	if !a.subagents.CancelFor(args, channel, chatID) {
+		return Reply{Content: fmt.Sprintf("No running task %q.", args)}, nil
+	}
+	return Reply{Content: fmt.Sprintf("Cancelled task %s.", args)}, nil
+}
+
+// modelFor returns the model to use given a session's override.
+func (a *AgentLoop) modelFor(override string) string {
+	if override != "" {
+		return override
+	}
+	return a.model
+}
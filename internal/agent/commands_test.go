@@ -0,0 +1,135 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/coopco/nanobot/internal/providers"
+	"github.com/coopco/nanobot/internal/session"
+	"github.com/coopco/nanobot/internal/tools"
+)
+
+func TestCommand_HelpListsTools(t *testing.T) {
+	mock := &mockProvider{}
+	loop := newTestLoop(t, mock, 5)
+
+	got, err := loop.ProcessDirect(context.Background(), "/help")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"/reset", "/model", "/cancel", "echo: Echoes input"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("help reply missing %q:\n%s", want, got)
+		}
+	}
+	if mock.callIndex != 0 {
+		t.Errorf("provider called %d times, want 0", mock.callIndex)
+	}
+	if h := loop.sessions.GetOrCreate("direct").GetHistory(); len(h) != 0 {
+		t.Errorf("command was recorded in history: %+v", h)
+	}
+}
+
+func TestCommand_ModelSwitchesSessionModel(t *testing.T) {
+	rec := &recordingProvider{}
+	loop := newTestLoop(t, rec, 5)
+	ctx := context.Background()
+
+	if _, err := loop.ProcessDirect(ctx, "/model gpt-4o"); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := loop.ProcessDirect(ctx, "/model"); !strings.Contains(got, "gpt-4o") {
+		t.Errorf("/model reply = %q, want the override", got)
+	}
+	if _, err := loop.ProcessDirect(ctx, "hi"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loop.ProcessDirect(ctx, "/model default"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loop.ProcessDirect(ctx, "again"); err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.requests) != 2 || rec.requests[0].Model != "gpt-4o" || rec.requests[1].Model != "test-model" {
+		t.Fatalf("requests used models %v, want [gpt-4o test-model]", requestModels(rec.requests))
+	}
+}
+
+func TestCommand_CustomPrefixAndUnknownVerb(t *testing.T) {
+	mock := &mockProvider{responses: []*providers.ChatResponse{{Content: "a"}, {Content: "b"}}}
+	loop := NewAgentLoop(AgentLoopConfig{
+		Provider:      mock,
+		Sessions:      session.NewManager(t.TempDir()),
+		Tools:         tools.NewRegistry(),
+		Model:         "test-model",
+		MaxIterations: 5,
+		CommandPrefix: "!",
+	})
+	ctx := context.Background()
+
+	if got, _ := loop.ProcessDirect(ctx, "!reset"); !strings.Contains(got, "Conversation cleared") {
+		t.Errorf("!reset reply = %q", got)
+	}
+	// Neither the default prefix nor an unknown verb is a command.
+	for _, msg := range []string{"/reset", "!etc/passwd is odd"} {
+		if _, err := loop.ProcessDirect(ctx, msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if mock.callIndex != 2 {
+		t.Errorf("provider called %d times, want 2", mock.callIndex)
+	}
+}
+
+func TestCommand_Cancel(t *testing.T) {
+	loop := newTestLoop(t, &mockProvider{}, 5)
+	if got, _ := loop.ProcessDirect(context.Background(), "/cancel abc"); !strings.Contains(got, "No background tasks") {
+		t.Errorf("reply without subagents = %q", got)
+	}
+
+	loop.subagents = NewSubagentManager(&mockProvider{}, "m", 0, 0, nil)
+	if got, _ := loop.ProcessDirect(context.Background(), "/cancel abc"); !strings.Contains(got, `No running task "abc"`) {
+		t.Errorf("reply for unknown task = %q", got)
+	}
+}
+
+func TestCommand_CancelIsScopedToChat(t *testing.T) {
+	loop := newTestLoop(t, &mockProvider{}, 5)
+	blocker := &blockingProvider{ready: make(chan struct{})}
+	loop.subagents, _ = newTestSubagentManager(t, blocker)
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	taskID := loop.subagents.Spawn(ctx, "long job", "job", "telegram", "1")
+	<-blocker.ready
+
+	run := func(chatID, content string) string {
+		t.Helper()
+		reply, ok, err := loop.runCommand(tools.WithOrigin(ctx, "telegram", chatID), "telegram:"+chatID, content)
+		if !ok || err != nil {
+			t.Fatalf("%s: ok=%v err=%v", content, ok, err)
+		}
+		return reply.Content
+	}
+
+	if got := run("2", "/cancel"); got != "No background tasks are running." {
+		t.Errorf("list from another chat = %q", got)
+	}
+	if got := run("2", "/cancel "+taskID); !strings.Contains(got, "No running task") {
+		t.Errorf("cancel from another chat = %q", got)
+	}
+	if got := run("1", "/cancel"); got != "Running tasks: "+taskID {
+		t.Errorf("list from the owning chat = %q", got)
+	}
+	if got := run("1", "/cancel "+taskID); got != "Cancelled task "+taskID+"." {
+		t.Errorf("cancel from the owning chat = %q", got)
+	}
+}
+
+func requestModels(reqs []providers.ChatRequest) []string {
+	models := make([]string, len(reqs))
+	for i, r := range reqs {
+		models[i] = r.Model
+	}
+	return models
+}
@@ -3,6 +3,7 @@ package agent
 import (
 	"encoding/base64"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -25,27 +26,27 @@ var BootstrapFiles = []string{
 
 // ContextBuilder assembles system prompts from workspace files and runtime context.
 type ContextBuilder struct {
-	workspace string
-	tools     *tools.Registry
+	workspace  string
+	tools      *tools.Registry
+	promptFile string // replaces BootstrapFiles when set
 }
 
 func NewContextBuilder(workspace string, toolRegistry *tools.Registry) *ContextBuilder {
 	return &ContextBuilder{workspace: workspace, tools: toolRegistry}
 }
 
-// BuildSystemPrompt reads bootstrap files from workspace and appends runtime context.
-func (c *ContextBuilder) BuildSystemPrompt(memoryContent, skillsContent string) string {
-	var parts []string
-
-	for _, name := range BootstrapFiles {
-		data, err := os.ReadFile(filepath.Join(c.workspace, name))
-		if err != nil {
-			continue
-		}
-		parts = append(parts, string(data))
+// SetSystemPromptFile makes BuildSystemPrompt use path in place of the
+// BootstrapFiles. A relative path is resolved against the workspace.
+func (c *ContextBuilder) SetSystemPromptFile(path string) {
+	if path != "" && !filepath.IsAbs(path) {
+		path = filepath.Join(c.workspace, path)
 	}
+	c.promptFile = path
+}
 
-	base := strings.Join(parts, "\n\n---\n\n")
+// BuildSystemPrompt reads bootstrap files from workspace and appends runtime context.
+func (c *ContextBuilder) BuildSystemPrompt(memoryContent, skillsContent string) string {
+	base := c.readBase()
 
 	if memoryContent != "" {
 		base += "\n\n## Memory\n\n" + memoryContent
@@ -72,51 +73,88 @@ func (c *ContextBuilder) BuildSystemPrompt(memoryContent, skillsContent string)
 	return base
 }
 
+// readBase returns the prompt file if one is set and readable, otherwise the
+// bootstrap files found in the workspace.
+func (c *ContextBuilder) readBase() string {
+	if c.promptFile != "" {
+		data, err := os.ReadFile(c.promptFile)
+		if err == nil {
+			return string(data)
+		}
+		slog.Warn("system prompt file unreadable, using bootstrap files", "path", c.promptFile, "error", err)
+	}
+
+	var parts []string
+	for _, name := range BootstrapFiles {
+		data, err := os.ReadFile(filepath.Join(c.workspace, name))
+		if err != nil {
+			continue
+		}
+		parts = append(parts, string(data))
+	}
+	return strings.Join(parts, "\n\n---\n\n")
+}
+
 // ProcessMedia converts a slice of bus.Media items into ContentParts for multimodal messages.
-// URL media becomes an image_url part directly; local file media is read, MIME-detected,
-// and base64-encoded into a data URI; inline Data bytes are base64-encoded into a data URI.
+// Audio becomes an input_audio part and files a file part; everything else is
+// treated as an image. Local file media is read and inline Data used as is, with the
+// MIME type detected when not given; images are passed on as base64 data URIs. Remote
+// URLs are passed through for images only, as audio and file parts need the content.
 func ProcessMedia(media []bus.Media) []providers.ContentPart {
 	parts := make([]providers.ContentPart, 0, len(media))
 	for _, m := range media {
+		data := m.Data
 		switch {
-		case m.Data != nil:
-			// Inline bytes — detect MIME if not provided, then encode as data URI.
-			mime := m.MimeType
-			if mime == "" {
-				mime = http.DetectContentType(m.Data)
+		case data != nil:
+			// Inline bytes — used as they are.
+		case isLocalPath(m.URL):
+			// Local file — read it.
+			var err error
+			if data, err = os.ReadFile(m.URL); err != nil {
+				continue
 			}
-			encoded := base64.StdEncoding.EncodeToString(m.Data)
+		case m.URL != "" && m.Type != "audio" && m.Type != "file":
+			// Remote URL — pass through directly.
 			parts = append(parts, providers.ContentPart{
 				Type: "image_url",
 				ImageURL: &providers.ImageURL{
-					URL:    fmt.Sprintf("data:%s;base64,%s", mime, encoded),
+					URL:    m.URL,
 					Detail: "auto",
 				},
 			})
-		case isLocalPath(m.URL):
-			// Local file — read, detect MIME, encode.
-			data, err := os.ReadFile(m.URL)
-			if err != nil {
-				continue
-			}
-			mime := m.MimeType
-			if mime == "" {
-				mime = http.DetectContentType(data)
+			continue
+		default:
+			continue
+		}
+
+		mime := m.MimeType
+		if mime == "" {
+			mime = http.DetectContentType(data)
+		}
+		encoded := base64.StdEncoding.EncodeToString(data)
+		switch m.Type {
+		case "audio":
+			parts = append(parts, providers.ContentPart{
+				Type:       "input_audio",
+				InputAudio: &providers.InputAudio{Data: encoded, Format: audioFormat(mime)},
+			})
+		case "file":
+			name := "document"
+			if isLocalPath(m.URL) {
+				name = filepath.Base(m.URL)
 			}
-			encoded := base64.StdEncoding.EncodeToString(data)
 			parts = append(parts, providers.ContentPart{
-				Type: "image_url",
-				ImageURL: &providers.ImageURL{
-					URL:    fmt.Sprintf("data:%s;base64,%s", mime, encoded),
-					Detail: "auto",
+				Type: "file",
+				File: &providers.FilePart{
+					Filename: name,
+					FileData: fmt.Sprintf("data:%s;base64,%s", mime, encoded),
 				},
 			})
-		case m.URL != "":
-			// Remote URL — pass through directly.
+		default:
 			parts = append(parts, providers.ContentPart{
 				Type: "image_url",
 				ImageURL: &providers.ImageURL{
-					URL:    m.URL,
+					URL:    fmt.Sprintf("data:%s;base64,%s", mime, encoded),
 					Detail: "auto",
 				},
 			})
@@ -125,6 +163,19 @@ func ProcessMedia(media []bus.Media) []providers.ContentPart {
 	return parts
 }
 
+// audioFormat names the encoding of an audio MIME type the way input_audio
+// parts expect, e.g. "wav" for audio/x-wav and "mp3" for audio/mpeg.
+func audioFormat(mime string) string {
+	sub := strings.TrimPrefix(strings.TrimSpace(strings.Split(mime, ";")[0]), "audio/")
+	switch sub {
+	case "mpeg", "mp3":
+		return "mp3"
+	case "wav", "x-wav", "wave", "vnd.wave":
+		return "wav"
+	}
+	return strings.TrimPrefix(sub, "x-")
+}
+
 // isLocalPath returns true when the string looks like a filesystem path rather than a URL.
 func isLocalPath(s string) bool {
 	return !strings.HasPrefix(s, "http://") && !strings.HasPrefix(s, "https://") && s != ""
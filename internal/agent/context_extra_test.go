@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/coopco/nanobot/internal/bus"
+	"github.com/coopco/nanobot/internal/providers"
 	"github.com/coopco/nanobot/internal/session"
 )
 
@@ -97,8 +98,54 @@ func TestProcessMedia_LocalFile(t *testing.T) {
 		t.Fatalf("expected 1 part, got %d", len(parts))
 	}
 	encoded := base64.StdEncoding.EncodeToString(content)
-	if !strings.Contains(parts[0].ImageURL.URL, encoded) {
-		t.Error("expected base64-encoded file content in data URI")
+	if parts[0].Type != "file" || parts[0].File == nil {
+		t.Fatalf("expected a file part, got %+v", parts[0])
+	}
+	if parts[0].File.Filename != "test.bin" || !strings.Contains(parts[0].File.FileData, encoded) {
+		t.Errorf("unexpected file part %+v", parts[0].File)
+	}
+}
+
+func TestProcessMedia_Audio(t *testing.T) {
+	data := []byte("ID3 fake mp3")
+	parts := ProcessMedia([]bus.Media{{Type: "audio", Data: data, MimeType: "audio/mpeg"}})
+	if len(parts) != 1 || parts[0].Type != "input_audio" || parts[0].InputAudio == nil {
+		t.Fatalf("expected an input_audio part, got %+v", parts)
+	}
+	want := providers.InputAudio{Data: base64.StdEncoding.EncodeToString(data), Format: "mp3"}
+	if *parts[0].InputAudio != want {
+		t.Errorf("InputAudio = %+v, want %+v", *parts[0].InputAudio, want)
+	}
+
+	// Remote audio cannot be inlined and is left out.
+	if parts := ProcessMedia([]bus.Media{{Type: "audio", URL: "https://example.com/a.wav"}}); len(parts) != 0 {
+		t.Errorf("expected no parts for remote audio, got %+v", parts)
+	}
+}
+
+func TestProcessMedia_Document(t *testing.T) {
+	data := []byte("%PDF-1.4 fake")
+	parts := ProcessMedia([]bus.Media{{Type: "file", Data: data, MimeType: "application/pdf"}})
+	if len(parts) != 1 || parts[0].Type != "file" || parts[0].File == nil {
+		t.Fatalf("expected a file part, got %+v", parts)
+	}
+	want := "data:application/pdf;base64," + base64.StdEncoding.EncodeToString(data)
+	if parts[0].File.FileData != want || parts[0].File.Filename != "document" {
+		t.Errorf("File = %+v", parts[0].File)
+	}
+}
+
+func TestAudioFormat(t *testing.T) {
+	for mime, want := range map[string]string{
+		"audio/mpeg":  "mp3",
+		"audio/x-wav": "wav",
+		"audio/wav":   "wav",
+		"audio/ogg":   "ogg",
+		"audio/x-m4a": "m4a",
+	} {
+		if got := audioFormat(mime); got != want {
+			t.Errorf("audioFormat(%q) = %q, want %q", mime, got, want)
+		}
 	}
 }
 
@@ -3,11 +3,15 @@ package agent
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/coopco/nanobot/internal/bus"
+	"github.com/coopco/nanobot/internal/metrics"
 	"github.com/coopco/nanobot/internal/providers"
 	"github.com/coopco/nanobot/internal/session"
 	"github.com/coopco/nanobot/internal/tools"
@@ -24,13 +28,26 @@ type AgentLoop struct {
 	temperature  float64
 	maxIter      int
 	systemPrompt string
+	progress     bool
+	continueLen  bool            // ask the model to go on after a response cut off at the token limit
+	toolWorkers  int             // tool calls from one response run at most this many at a time
+	ctxBuilder   *ContextBuilder // nil when no workspace is configured
+	memory       *MemoryStore
+	skills       *SkillsLoader
+	subagents    *SubagentManager // nil disables /cancel
+	cmdPrefix    string           // marks built-in commands such as /help
+	msgTimeout   time.Duration    // bounds handling one bus message; 0 disables
+	msgSlots     chan struct{}    // one token per message in flight; nil is unbounded
+	thinking     int              // extended thinking budget in tokens, 0 disables
+	typing       TypingIndicator
+	maxResult    int // tool result cap in bytes, below one disables it
 	mu           sync.Mutex
 }
 
 // AgentLoopConfig holds all dependencies and settings for AgentLoop.
 type AgentLoopConfig struct {
 	Bus           *bus.MessageBus
-	Provider      providers.Provider
+	Provider      providers.Provider // a *providers.ProviderRouter picks the backend from Model
 	Sessions      *session.Manager
 	Tools         *tools.Registry
 	Model         string
@@ -38,15 +55,72 @@ type AgentLoopConfig struct {
 	Temperature   float64
 	MaxIterations int
 	SystemPrompt  string
+	// Workspace, when set, makes the loop rebuild its system prompt for every
+	// message from the workspace bootstrap files, memory and skills.
+	// SystemPrompt is then ignored. SystemPromptFile replaces the bootstrap
+	// files and may be relative to Workspace.
+	Workspace        string
+	SystemPromptFile string
+	// Progress publishes a "progress" message on every tool-loop iteration
+	// after the first, in addition to the per-tool "tool_hint" messages.
+	Progress bool
+	// ContinueOnLength asks the model to continue, up to
+	// maxLengthContinuations times, when a reply is cut off at MaxTokens.
+	// Otherwise the truncated reply is returned and a warning logged.
+	ContinueOnLength bool
+	// ToolWorkers bounds how many tool calls from one model response run
+	// concurrently. Zero means defaultToolWorkers; 1 runs them in order.
+	ToolWorkers int
+	// CommandPrefix starts a built-in command such as /help or /reset.
+	// Empty means defaultCommandPrefix.
+	CommandPrefix string
+	// Subagents, when set, lets the /cancel command stop background tasks.
+	Subagents *SubagentManager
+	// MessageTimeout bounds how long one message from the bus may take,
+	// provider and tool calls included. The sender gets an error reply when
+	// it runs out. Zero disables the limit.
+	MessageTimeout time.Duration
+	// MaxConcurrentMessages bounds how many bus messages are processed at
+	// once. Further messages wait on the bus until a slot frees up. Zero
+	// means no limit.
+	MaxConcurrentMessages int
+	// ThinkingBudget is passed on as ChatRequest.ThinkingBudget to let the
+	// model reason before answering. Zero disables extended thinking.
+	ThinkingBudget int
+	// Typing is told when processing of a bus message starts and ends, so
+	// the chat can show a typing indicator. Nil disables it.
+	Typing TypingIndicator
+	// MaxToolResultSize caps, in bytes, each tool result added to the
+	// conversation; longer results are truncated with a note. Zero means
+	// defaultMaxToolResultSize and a negative value disables the cap.
+	MaxToolResultSize int
 }
 
+// defaultToolWorkers is the tool-call concurrency used when none is configured.
+const defaultToolWorkers = 4
+
+// maxLengthContinuations bounds how often one reply is extended after being
+// cut off at the token limit.
+const maxLengthContinuations = 3
+
+// continuePrompt asks the model to resume a reply cut off at the token limit.
+const continuePrompt = "Your previous reply was cut off at the token limit. Continue exactly where you left off, without repeating anything."
+
 // NewAgentLoop creates an AgentLoop from the given config.
 func NewAgentLoop(cfg AgentLoopConfig) *AgentLoop {
 	maxIter := cfg.MaxIterations
 	if maxIter <= 0 {
 		maxIter = 40
 	}
-	return &AgentLoop{
+	workers := cfg.ToolWorkers
+	if workers <= 0 {
+		workers = defaultToolWorkers
+	}
+	prefix := cfg.CommandPrefix
+	if prefix == "" {
+		prefix = defaultCommandPrefix
+	}
+	a := &AgentLoop{
 		bus:          cfg.Bus,
 		provider:     cfg.Provider,
 		sessions:     cfg.Sessions,
@@ -56,115 +130,299 @@ func NewAgentLoop(cfg AgentLoopConfig) *AgentLoop {
 		temperature:  cfg.Temperature,
 		maxIter:      maxIter,
 		systemPrompt: cfg.SystemPrompt,
+		progress:     cfg.Progress,
+		continueLen:  cfg.ContinueOnLength,
+		toolWorkers:  workers,
+		subagents:    cfg.Subagents,
+		cmdPrefix:    prefix,
+		msgTimeout:   cfg.MessageTimeout,
+		thinking:     cfg.ThinkingBudget,
+		typing:       cfg.Typing,
+		maxResult:    toolResultLimit(cfg.MaxToolResultSize),
+	}
+	if a.typing == nil {
+		a.typing = noTyping{}
 	}
+	if cfg.MaxConcurrentMessages > 0 {
+		a.msgSlots = make(chan struct{}, cfg.MaxConcurrentMessages)
+	}
+	if cfg.Workspace != "" {
+		a.ctxBuilder = NewContextBuilder(cfg.Workspace, cfg.Tools)
+		a.ctxBuilder.SetSystemPromptFile(cfg.SystemPromptFile)
+		a.memory = NewMemoryStore(cfg.Workspace)
+		a.skills = NewSkillsLoader(cfg.Workspace)
+	}
+	return a
 }
 
 // Run consumes inbound messages from the bus and processes each in a goroutine.
-// Returns when ctx is cancelled.
+// With MaxConcurrentMessages set it stops consuming while that many are in
+// flight, leaving the rest queued on the bus. Returns ctx's error when ctx is
+// cancelled, or nil once the bus has been drained or closed.
 func (a *AgentLoop) Run(ctx context.Context) error {
+	if a.skills != nil {
+		if err := a.skills.Watch(ctx); err != nil {
+			slog.Warn("skills watcher not started; skills are rescanned per message", "err", err)
+		}
+	}
 	for {
+		if a.msgSlots != nil {
+			select {
+			case a.msgSlots <- struct{}{}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
 		msg, err := a.bus.ConsumeInbound(ctx)
+		if errors.Is(err, bus.ErrBusClosed) {
+			return nil
+		}
 		if err != nil {
 			return err
 		}
-		go a.processMessage(ctx, msg)
+		go func() {
+			defer a.releaseSlot()
+			a.processMessage(ctx, msg)
+		}()
+	}
+}
+
+// releaseSlot frees the concurrency slot taken by Run for one message.
+func (a *AgentLoop) releaseSlot() {
+	if a.msgSlots != nil {
+		<-a.msgSlots
 	}
 }
 
 // processMessage handles a single inbound message: builds context, runs the tool loop,
-// saves the session, and publishes the outbound response.
+// saves the session, and publishes the outbound response to msg.ReplyTarget.
 func (a *AgentLoop) processMessage(ctx context.Context, msg bus.InboundMessage) {
-	sess := a.sessions.GetOrCreate(msg.SessionKey())
-
-	messages := sessionToProviderMessages(sess.GetHistory())
-	messages = append(messages, providers.Message{Role: "user", Content: msg.Content})
+	metrics.MessagesIn.Inc(msg.Channel)
+	channel, chatID := msg.ReplyTarget()
+	a.typing.StartTyping(channel, chatID)
+	defer a.typing.StopTyping(channel, chatID)
+	notify := func(kind, content string, meta map[string]string) {
+		// Activity updates are best-effort; never stall the loop on a full bus.
+		a.bus.TryPublishOutbound(bus.OutboundMessage{
+			Channel:  channel,
+			ChatID:   chatID,
+			Content:  content,
+			Type:     kind,
+			Metadata: meta,
+		})
+	}
 
-	finalContent, err := a.runToolLoop(ctx, messages)
+	if a.msgTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, a.msgTimeout)
+		defer cancel()
+	}
+	toolCtx := tools.WithOrigin(ctx, channel, chatID)
+	reply, err := a.runTurn(toolCtx, msg.SessionKey(), msg.Content, msg.Media, notify, nil)
 	if err != nil {
 		slog.Error("agent tool loop error", "session", msg.SessionKey(), "err", err)
+		content := fmt.Sprintf("Error: %v", err)
+		if errors.Is(err, context.DeadlineExceeded) && ctx.Err() != nil {
+			content = fmt.Sprintf("Error: no reply within %s; the request was abandoned.", a.msgTimeout)
+		}
 		a.bus.PublishOutbound(bus.OutboundMessage{
-			Channel: msg.Channel,
-			ChatID:  msg.ChatID,
-			Content: fmt.Sprintf("Error: %v", err),
+			Channel: channel,
+			ChatID:  chatID,
+			Content: content,
 			Type:    "error",
+			ReplyTo: replyTo(msg),
 		})
 		return
 	}
 
-	sess.AppendMessage(session.Message{Role: "user", Content: msg.Content})
-	sess.AppendMessage(session.Message{Role: "assistant", Content: finalContent})
-	if err := a.sessions.Save(sess); err != nil {
-		slog.Error("failed to save session", "session", msg.SessionKey(), "err", err)
+	out := bus.OutboundMessage{
+		Channel: channel,
+		ChatID:  chatID,
+		Content: reply.Content,
+		Type:    "text",
+		ReplyTo: replyTo(msg),
+		Model:   reply.Model,
+	}
+	if reply.Model != "" {
+		out.Usage = &bus.Usage{
+			PromptTokens:     reply.Usage.PromptTokens,
+			CompletionTokens: reply.Usage.CompletionTokens,
+			TotalTokens:      reply.Usage.TotalTokens,
+		}
 	}
+	a.bus.PublishOutbound(out)
+}
 
-	a.bus.PublishOutbound(bus.OutboundMessage{
-		Channel: msg.Channel,
-		ChatID:  msg.ChatID,
-		Content: finalContent,
-		Type:    "text",
-	})
+// replyTo returns the thread a reply to msg belongs in, or "" when msg was
+// not posted in a thread or its reply goes to another chat.
+func replyTo(msg bus.InboundMessage) string {
+	if msg.Metadata[bus.MetaOriginChannel] != "" {
+		return ""
+	}
+	return msg.Metadata[bus.MetaThreadID]
 }
 
 // ProcessDirect processes a single message without the bus, for CLI mode.
 func (a *AgentLoop) ProcessDirect(ctx context.Context, message string) (string, error) {
-	sess := a.sessions.GetOrCreate("direct")
+	reply, err := a.runTurn(ctx, "direct", message, nil, nil, nil)
+	if err != nil {
+		return "", err
+	}
+	return reply.Content, nil
+}
+
+// ProcessSession runs one user turn against the session named by sessionKey
+// without going through the bus, for API clients. The reply carries the
+// token usage of the whole turn.
+func (a *AgentLoop) ProcessSession(ctx context.Context, sessionKey, content string, media []bus.Media) (Reply, error) {
+	return a.runTurn(ctx, sessionKey, content, media, nil, nil)
+}
+
+// Event is a live update from a turn in progress.
+type Event struct {
+	Type    string // "delta" (a fragment of model text), "tool_hint" or "progress"
+	Content string
+	Meta    map[string]string
+}
+
+// ProcessSessionStream is ProcessSession with live updates: onEvent receives
+// model text as it is generated, when the provider can stream, and the same
+// activity updates channels get. It is called from the goroutine running
+// the turn.
+func (a *AgentLoop) ProcessSessionStream(ctx context.Context, sessionKey, content string, media []bus.Media, onEvent func(Event)) (Reply, error) {
+	notify := func(kind, content string, meta map[string]string) {
+		onEvent(Event{Type: kind, Content: content, Meta: meta})
+	}
+	onDelta := func(text string) {
+		onEvent(Event{Type: "delta", Content: text})
+	}
+	return a.runTurn(ctx, sessionKey, content, media, notify, onDelta)
+}
+
+// Reply is the outcome of one agent turn.
+type Reply struct {
+	Content string
+	Model   string          // model that answered; empty for built-in commands
+	Usage   providers.Usage // summed over every provider call in the turn
+}
+
+// runTurn appends the user's message to the session history, runs the tool
+// loop and saves the exchange. The session is left unchanged if the loop fails.
+// Built-in commands such as /reset are handled without the model.
+func (a *AgentLoop) runTurn(ctx context.Context, sessionKey, content string, media []bus.Media, notify notifyFunc, onDelta func(string)) (Reply, error) {
+	if reply, ok, err := a.runCommand(ctx, sessionKey, content); ok {
+		return reply, err
+	}
+	sess := a.sessions.GetOrCreate(sessionKey)
 
 	messages := sessionToProviderMessages(sess.GetHistory())
-	messages = append(messages, providers.Message{Role: "user", Content: message})
+	userMsg := providers.Message{Role: "user", Content: content}
+	if len(media) > 0 {
+		userMsg.ContentParts = ProcessMedia(media)
+	}
+	messages = append(messages, userMsg)
 
-	finalContent, err := a.runToolLoop(ctx, messages)
+	reply, err := a.runToolLoop(ctx, a.modelFor(sess.Model()), messages, notify, onDelta)
 	if err != nil {
-		return "", err
+		return Reply{}, err
 	}
 
-	sess.AppendMessage(session.Message{Role: "user", Content: message})
-	sess.AppendMessage(session.Message{Role: "assistant", Content: finalContent})
+	sess.AppendMessage(session.Message{Role: "user", Content: content, ContentParts: contentPartsToSession(userMsg.ContentParts)})
+	sess.AppendMessage(session.Message{Role: "assistant", Content: reply.Content})
 	if err := a.sessions.Save(sess); err != nil {
-		slog.Error("failed to save direct session", "err", err)
+		slog.Error("failed to save session", "session", sessionKey, "err", err)
 	}
-
-	return finalContent, nil
+	return reply, nil
 }
 
-// runToolLoop executes the LLM + tool call loop and returns the final text response.
-func (a *AgentLoop) runToolLoop(ctx context.Context, messages []providers.Message) (string, error) {
+// notifyFunc publishes a live activity update ("tool_hint" or "progress").
+type notifyFunc func(kind, content string, meta map[string]string)
+
+// runToolLoop executes the LLM + tool call loop with model and returns the
+// final text response. notify, if non-nil, receives activity updates while the loop runs; onDelta,
+// if non-nil, receives model text as it is generated.
+func (a *AgentLoop) runToolLoop(ctx context.Context, model string, messages []providers.Message, notify notifyFunc, onDelta func(string)) (Reply, error) {
+	var usage providers.Usage
+	var partial strings.Builder // text of a reply being continued after truncation
+	continuations := 0
 	toolDefs := toolDefsToProviderTools(a.tools.Definitions())
+	systemPrompt := a.buildSystemPrompt()
+	repeats := newRepeatTracker()
 
 	for i := 0; i < a.maxIter; i++ {
+		if notify != nil && a.progress && i > 0 {
+			notify("progress", fmt.Sprintf("thinking (step %d/%d)…", i+1, a.maxIter), nil)
+		}
 		req := providers.ChatRequest{
-			Model:        a.model,
-			Messages:     messages,
-			Tools:        toolDefs,
-			MaxTokens:    a.maxTokens,
-			Temperature:  a.temperature,
-			SystemPrompt: a.systemPrompt,
+			Model:          model,
+			Messages:       messages,
+			Tools:          toolDefs,
+			MaxTokens:      a.maxTokens,
+			Temperature:    a.temperature,
+			SystemPrompt:   systemPrompt,
+			ThinkingBudget: a.thinking,
 		}
 
-		resp, err := a.provider.Chat(ctx, req)
+		resp, err := a.chat(ctx, req, onDelta)
 		if err != nil {
-			return "", fmt.Errorf("provider chat error: %w", err)
+			return Reply{}, fmt.Errorf("provider chat error: %w", err)
 		}
+		usage.Add(resp.Usage)
 
 		// Build assistant message with any tool calls
 		assistantMsg := providers.Message{
-			Role:      "assistant",
-			Content:   resp.Content,
-			ToolCalls: resp.ToolCalls,
+			Role:           "assistant",
+			Content:        resp.Content,
+			ToolCalls:      resp.ToolCalls,
+			ThinkingBlocks: resp.ThinkingBlocks,
 		}
 		messages = append(messages, assistantMsg)
 
 		if len(resp.ToolCalls) == 0 {
-			return resp.Content, nil
+			partial.WriteString(resp.Content)
+			switch resp.StopReason {
+			case providers.StopReasonLength:
+				if a.continueLen && continuations < maxLengthContinuations {
+					continuations++
+					messages = append(messages, providers.Message{Role: "user", Content: continuePrompt})
+					continue
+				}
+				slog.Warn("model reply truncated at the token limit", "model", model, "maxTokens", a.maxTokens)
+			case providers.StopReasonRefusal:
+				slog.Warn("model refused or reply was filtered", "model", model)
+			}
+			return Reply{Content: partial.String(), Model: model, Usage: usage}, nil
 		}
+		partial.Reset()
 
-		// Execute each tool call and append results
-		for _, tc := range resp.ToolCalls {
-			slog.Debug("executing tool", "name", tc.Name, "id", tc.ID)
-			result := a.tools.Execute(ctx, tc.Name, json.RawMessage(tc.Arguments))
+		// Check every call against the repeat limits before running any.
+		counts := make([]int, len(resp.ToolCalls))
+		for j, tc := range resp.ToolCalls {
+			counts[j] = repeats.record(tc.Name, tc.Arguments)
+			if counts[j] >= repeatAbortAt {
+				slog.Warn("aborting tool loop on repeated tool call", "name", tc.Name, "count", counts[j])
+				return Reply{Content: repeatAbortMessage(tc.Name, counts[j]), Model: model, Usage: usage}, nil
+			}
+			if notify != nil {
+				notify("tool_hint", fmt.Sprintf("running %s…", tc.Name), map[string]string{"tool": tc.Name})
+			}
+		}
+
+		// Execute the calls and append their results in the order requested.
+		for j, result := range a.executeToolCalls(ctx, resp.ToolCalls) {
+			tc := resp.ToolCalls[j]
+			if result.IsError {
+				slog.Warn("tool call failed", "name", tc.Name, "id", tc.ID)
+			}
+			result.Content = truncateToolResult(result.Content, a.maxResult)
+			if counts[j] >= repeatWarnAt {
+				result.Content += repeatWarning(tc.Name, counts[j])
+			}
 			messages = append(messages, providers.Message{
 				Role:       "tool",
-				Content:    result,
+				Content:    result.Content,
 				ToolCallID: tc.ID,
+				IsError:    result.IsError,
 			})
 		}
 	}
@@ -172,10 +430,86 @@ func (a *AgentLoop) runToolLoop(ctx context.Context, messages []providers.Messag
 	// Exceeded maxIter — return whatever the last assistant content was
 	for i := len(messages) - 1; i >= 0; i-- {
 		if messages[i].Role == "assistant" {
-			return messages[i].Content, nil
+			return Reply{Content: messages[i].Content, Model: model, Usage: usage}, nil
 		}
 	}
-	return "", fmt.Errorf("max iterations (%d) reached without a final response", a.maxIter)
+	return Reply{}, fmt.Errorf("max iterations (%d) reached without a final response", a.maxIter)
+}
+
+// executeToolCalls runs calls concurrently, at most a.toolWorkers at a time,
+// and returns their results in the same order. A call that panics yields an
+// error result without affecting the others.
+func (a *AgentLoop) executeToolCalls(ctx context.Context, calls []providers.ToolCall) []tools.ToolResult {
+	results := make([]tools.ToolResult, len(calls))
+	sem := make(chan struct{}, a.toolWorkers)
+	var wg sync.WaitGroup
+	for i, tc := range calls {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			defer func() {
+				if r := recover(); r != nil {
+					slog.Error("tool panicked", "name", tc.Name, "id", tc.ID, "panic", r)
+					results[i] = tools.ToolResult{Content: fmt.Sprintf("Error executing %s: %v", tc.Name, r), IsError: true}
+				}
+			}()
+			slog.Debug("executing tool", "name", tc.Name, "id", tc.ID)
+			results[i] = a.tools.ExecuteResult(ctx, tc.Name, json.RawMessage(tc.Arguments))
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// chat sends req to the provider. With onDelta set it streams when the
+// provider supports it, and otherwise passes the whole reply text on at once.
+func (a *AgentLoop) chat(ctx context.Context, req providers.ChatRequest, onDelta func(string)) (*providers.ChatResponse, error) {
+	start := time.Now()
+	resp, err := a.send(ctx, req, onDelta)
+	observeProviderCall(req.Model, start, err)
+	return resp, err
+}
+
+func (a *AgentLoop) send(ctx context.Context, req providers.ChatRequest, onDelta func(string)) (*providers.ChatResponse, error) {
+	if onDelta == nil {
+		return a.provider.Chat(ctx, req)
+	}
+	if sp, ok := a.provider.(providers.StreamingProvider); ok {
+		return sp.ChatStream(ctx, req, onDelta)
+	}
+	resp, err := a.provider.Chat(ctx, req)
+	if err == nil && resp.Content != "" {
+		onDelta(resp.Content)
+	}
+	return resp, err
+}
+
+// observeProviderCall records a provider request that started at start.
+func observeProviderCall(model string, start time.Time, err error) {
+	metrics.ProviderCalls.Inc(model)
+	metrics.ProviderLatency.Observe(time.Since(start).Seconds(), model)
+	if err != nil {
+		metrics.Errors.Inc("provider")
+	}
+}
+
+// buildSystemPrompt returns the system prompt for one message. With a
+// workspace it is rebuilt each time, so edits to bootstrap files, memory and
+// skills take effect without a restart.
+func (a *AgentLoop) buildSystemPrompt() string {
+	if a.ctxBuilder == nil {
+		return a.systemPrompt
+	}
+	var skills []string
+	if always := a.skills.GetAlwaysSkills(); always != "" {
+		skills = append(skills, always)
+	}
+	if summary := a.skills.BuildSkillsSummary(); strings.Contains(summary, "<skill ") {
+		skills = append(skills, summary)
+	}
+	return a.ctxBuilder.BuildSystemPrompt(a.memory.ReadMemory(), strings.Join(skills, "\n\n"))
 }
 
 // sessionToProviderMessages converts session history to provider message format.
@@ -187,6 +521,9 @@ func sessionToProviderMessages(history []session.Message) []providers.Message {
 			Content:    m.Content,
 			ToolCallID: m.ToolCallID,
 		}
+		if len(m.ContentParts) > 0 {
+			pm.ContentParts = contentPartsFromSession(m.ContentParts)
+		}
 		if len(m.ToolCalls) > 0 {
 			pm.ToolCalls = make([]providers.ToolCall, len(m.ToolCalls))
 			for i, tc := range m.ToolCalls {
@@ -202,6 +539,47 @@ func sessionToProviderMessages(history []session.Message) []providers.Message {
 	return msgs
 }
 
+// contentPartsToSession converts the parts of a multimodal message for
+// storing in the session history.
+func contentPartsToSession(parts []providers.ContentPart) []session.ContentPart {
+	if len(parts) == 0 {
+		return nil
+	}
+	out := make([]session.ContentPart, 0, len(parts))
+	for _, p := range parts {
+		sp := session.ContentPart{Type: p.Type, Text: p.Text}
+		switch {
+		case p.ImageURL != nil:
+			sp.URL, sp.Detail = p.ImageURL.URL, p.ImageURL.Detail
+		case p.InputAudio != nil:
+			sp.Data, sp.Format = p.InputAudio.Data, p.InputAudio.Format
+		case p.File != nil:
+			sp.URL, sp.Filename = p.File.FileData, p.File.Filename
+		}
+		out = append(out, sp)
+	}
+	return out
+}
+
+// contentPartsFromSession rebuilds the parts of a multimodal message from the
+// session history.
+func contentPartsFromSession(parts []session.ContentPart) []providers.ContentPart {
+	out := make([]providers.ContentPart, 0, len(parts))
+	for _, sp := range parts {
+		p := providers.ContentPart{Type: sp.Type, Text: sp.Text}
+		switch sp.Type {
+		case "image_url":
+			p.ImageURL = &providers.ImageURL{URL: sp.URL, Detail: sp.Detail}
+		case "input_audio":
+			p.InputAudio = &providers.InputAudio{Data: sp.Data, Format: sp.Format}
+		case "file":
+			p.File = &providers.FilePart{Filename: sp.Filename, FileData: sp.URL}
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
 // toolDefsToProviderTools converts tool registry definitions to provider tool format.
 func toolDefsToProviderTools(defs []tools.ToolDefinition) []providers.ToolDef {
 	result := make([]providers.ToolDef, len(defs))
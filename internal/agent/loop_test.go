@@ -3,6 +3,11 @@ package agent
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -110,17 +115,17 @@ func TestProcessDirect_WithToolCall(t *testing.T) {
 }
 
 func TestProcessDirect_MaxIterations(t *testing.T) {
-	// Provider always returns a tool call — loop must stop at maxIter.
-	infiniteResp := &providers.ChatResponse{
-		Content: "thinking",
-		ToolCalls: []providers.ToolCall{
-			{ID: "tc1", Name: "echo", Arguments: `{"text":"loop"}`},
-		},
-		StopReason: "tool_use",
-	}
+	// Provider always returns a tool call — loop must stop at maxIter. The
+	// arguments differ each time so repeat detection does not kick in first.
 	mock := &mockProvider{}
 	for i := 0; i < 50; i++ {
-		mock.responses = append(mock.responses, infiniteResp)
+		mock.responses = append(mock.responses, &providers.ChatResponse{
+			Content: "thinking",
+			ToolCalls: []providers.ToolCall{
+				{ID: "tc1", Name: "echo", Arguments: fmt.Sprintf(`{"text":"loop %d"}`, i)},
+			},
+			StopReason: "tool_use",
+		})
 	}
 
 	loop := newTestLoop(t, mock, 5)
@@ -189,3 +194,831 @@ func TestRun_ProcessesMessages(t *testing.T) {
 		t.Fatal("timed out waiting for outbound message")
 	}
 }
+
+func TestRun_RepliesInThread(t *testing.T) {
+	mb := bus.NewMessageBus(10)
+	loop := NewAgentLoop(AgentLoopConfig{
+		Bus:           mb,
+		Provider:      &mockProvider{responses: []*providers.ChatResponse{{Content: "pong", StopReason: "stop"}}},
+		Sessions:      session.NewManager(t.TempDir()),
+		Tools:         tools.NewRegistry(),
+		MaxIterations: 10,
+	})
+
+	received := make(chan bus.OutboundMessage, 1)
+	mb.Subscribe("test", func(msg bus.OutboundMessage) {
+		received <- msg
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go mb.DispatchOutbound(ctx)
+	go loop.Run(ctx) //nolint:errcheck
+
+	mb.PublishInbound(bus.InboundMessage{
+		Channel:  "test",
+		ChatID:   "chat1",
+		Content:  "ping",
+		Metadata: map[string]string{bus.MetaThreadID: "root-1"},
+	})
+
+	select {
+	case msg := <-received:
+		if msg.ReplyTo != "root-1" {
+			t.Errorf("ReplyTo = %q, want the thread root", msg.ReplyTo)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out waiting for outbound message")
+	}
+
+	redirected := bus.InboundMessage{Metadata: map[string]string{
+		bus.MetaThreadID:      "root-1",
+		bus.MetaOriginChannel: "telegram",
+	}}
+	if got := replyTo(redirected); got != "" {
+		t.Errorf("replyTo for a redirected message = %q, want empty", got)
+	}
+}
+
+func TestRun_ReturnsNilWhenBusDrained(t *testing.T) {
+	mb := bus.NewMessageBus(10)
+	loop := NewAgentLoop(AgentLoopConfig{
+		Bus:      mb,
+		Provider: &mockProvider{},
+		Sessions: session.NewManager(t.TempDir()),
+		Tools:    tools.NewRegistry(),
+	})
+
+	done := make(chan error, 1)
+	go func() { done <- loop.Run(context.Background()) }()
+
+	if err := mb.Drain(context.Background()); err != nil {
+		t.Fatalf("Drain: %v", err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Run returned %v, want nil after drain", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("Run did not return after the bus was drained")
+	}
+}
+
+func TestProcessDirect_RoutesByModel(t *testing.T) {
+	claude := &mockProvider{responses: []*providers.ChatResponse{{Content: "from claude"}}}
+	gpt := &mockProvider{responses: []*providers.ChatResponse{{Content: "from gpt"}}}
+	router := providers.NewProviderRouter(nil, nil)
+	router.Set("anthropic", claude)
+	router.Set("openai", gpt)
+
+	for model, want := range map[string]string{"claude-3-haiku": "from claude", "gpt-4o": "from gpt"} {
+		loop := newTestLoop(t, router, 5)
+		loop.model = model
+		got, err := loop.ProcessDirect(context.Background(), "hi")
+		if err != nil {
+			t.Fatalf("%s: %v", model, err)
+		}
+		if got != want {
+			t.Errorf("%s: got %q, want %q", model, got, want)
+		}
+	}
+}
+
+func TestRun_PublishesToolHints(t *testing.T) {
+	mock := &mockProvider{
+		responses: []*providers.ChatResponse{
+			{ToolCalls: []providers.ToolCall{{ID: "c1", Name: "echo", Arguments: `{"text":"a"}`}}},
+			{ToolCalls: []providers.ToolCall{{ID: "c2", Name: "echo", Arguments: `{"text":"b"}`}}},
+			{Content: "done", StopReason: "stop"},
+		},
+	}
+
+	reg := tools.NewRegistry()
+	reg.Register(&echoTool{})
+	mb := bus.NewMessageBus(10)
+	loop := NewAgentLoop(AgentLoopConfig{
+		Bus:           mb,
+		Provider:      mock,
+		Sessions:      session.NewManager(t.TempDir()),
+		Tools:         reg,
+		Model:         "test-model",
+		MaxIterations: 10,
+		Progress:      true,
+	})
+
+	received := make(chan bus.OutboundMessage, 10)
+	mb.Subscribe("test", func(msg bus.OutboundMessage) {
+		received <- msg
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go mb.DispatchOutbound(ctx)
+	go loop.Run(ctx) //nolint:errcheck
+
+	mb.PublishInbound(bus.InboundMessage{Channel: "test", ChatID: "chat1", Content: "go"})
+
+	var types []string
+	var hints int
+	for len(types) == 0 || types[len(types)-1] != "text" {
+		select {
+		case msg := <-received:
+			types = append(types, msg.Type)
+			if msg.Type == "tool_hint" {
+				hints++
+				if msg.Metadata["tool"] != "echo" || msg.ChatID != "chat1" {
+					t.Errorf("unexpected hint: %+v", msg)
+				}
+			}
+		case <-time.After(3 * time.Second):
+			t.Fatalf("timed out; received types %v", types)
+		}
+	}
+
+	want := []string{"tool_hint", "progress", "tool_hint", "progress", "text"}
+	if len(types) != len(want) {
+		t.Fatalf("received types %v, want %v", types, want)
+	}
+	for i := range want {
+		if types[i] != want[i] {
+			t.Fatalf("received types %v, want %v", types, want)
+		}
+	}
+	if hints != 2 {
+		t.Errorf("expected 2 tool hints, got %d", hints)
+	}
+}
+
+// recordingProvider wraps mockProvider and keeps every request it receives.
+type recordingProvider struct {
+	mockProvider
+	requests []providers.ChatRequest
+}
+
+func (r *recordingProvider) Chat(ctx context.Context, req providers.ChatRequest) (*providers.ChatResponse, error) {
+	r.requests = append(r.requests, req)
+	return r.mockProvider.Chat(ctx, req)
+}
+
+func TestProcessDirect_TruncatesLargeToolResult(t *testing.T) {
+	huge := strings.Repeat("x", 5000)
+	args, _ := json.Marshal(map[string]string{"text": huge})
+	rec := &recordingProvider{mockProvider: mockProvider{responses: []*providers.ChatResponse{
+		{ToolCalls: []providers.ToolCall{{ID: "tc1", Name: "echo", Arguments: string(args)}}, StopReason: "tool_use"},
+		{Content: "done", StopReason: "stop"},
+	}}}
+	reg := tools.NewRegistry()
+	reg.Register(&echoTool{})
+	loop := NewAgentLoop(AgentLoopConfig{
+		Provider:          rec,
+		Sessions:          session.NewManager(t.TempDir()),
+		Tools:             reg,
+		MaxToolResultSize: 100,
+	})
+
+	if _, err := loop.ProcessDirect(context.Background(), "echo a lot"); err != nil {
+		t.Fatal(err)
+	}
+	msgs := rec.requests[1].Messages
+	got := msgs[len(msgs)-1]
+	want := "echo: " + huge[:94] + "\n[output truncated, 4906 bytes omitted]"
+	if got.Role != "tool" || got.Content != want {
+		t.Errorf("tool message = %q (%d bytes), want the first 100 bytes and a marker", got.Content, len(got.Content))
+	}
+}
+
+func TestProcessDirect_KeepsThinkingBlocksForToolCalls(t *testing.T) {
+	thinking := []providers.ThinkingBlock{{Type: "thinking", Thinking: "use echo", Signature: "sig"}}
+	rec := &recordingProvider{mockProvider: mockProvider{responses: []*providers.ChatResponse{
+		{ToolCalls: []providers.ToolCall{{ID: "tc1", Name: "echo", Arguments: `{"text":"hi"}`}}, ThinkingBlocks: thinking, StopReason: "tool_use"},
+		{Content: "done", StopReason: "stop"},
+	}}}
+	loop := newTestLoop(t, rec, 5)
+
+	if _, err := loop.ProcessDirect(context.Background(), "echo hi"); err != nil {
+		t.Fatal(err)
+	}
+	msgs := rec.requests[1].Messages
+	assistant := msgs[len(msgs)-2]
+	if assistant.Role != "assistant" || len(assistant.ThinkingBlocks) != 1 || assistant.ThinkingBlocks[0].Signature != "sig" {
+		t.Errorf("assistant message = %+v, want its thinking block sent back", assistant)
+	}
+}
+
+func TestTruncateToolResult(t *testing.T) {
+	if got := truncateToolResult("short", 100); got != "short" {
+		t.Errorf("short result changed to %q", got)
+	}
+	if got := truncateToolResult(strings.Repeat("x", 200), -1); len(got) != 200 {
+		t.Errorf("disabled limit truncated the result to %d bytes", len(got))
+	}
+	// "é" is two bytes; a cut through it backs up to the rune start.
+	if got := truncateToolResult("aé", 2); got != "a\n[output truncated, 2 bytes omitted]" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestRun_ForwardsInboundMedia(t *testing.T) {
+	rec := &recordingProvider{mockProvider: mockProvider{
+		responses: []*providers.ChatResponse{{Content: "a cat"}},
+	}}
+	mb := bus.NewMessageBus(10)
+	loop := NewAgentLoop(AgentLoopConfig{
+		Bus:           mb,
+		Provider:      rec,
+		Sessions:      session.NewManager(t.TempDir()),
+		Tools:         tools.NewRegistry(),
+		Model:         "test-model",
+		MaxIterations: 5,
+	})
+
+	received := make(chan bus.OutboundMessage, 1)
+	mb.Subscribe("test", func(msg bus.OutboundMessage) { received <- msg })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go mb.DispatchOutbound(ctx)
+	go loop.Run(ctx) //nolint:errcheck
+
+	mb.PublishInbound(bus.InboundMessage{
+		Channel: "test",
+		ChatID:  "chat1",
+		Content: "what is this?",
+		Media:   []bus.Media{{Type: "image", URL: "https://example.com/cat.png"}},
+	})
+
+	select {
+	case msg := <-received:
+		if msg.Content != "a cat" {
+			t.Fatalf("reply = %q, want a cat", msg.Content)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out waiting for reply")
+	}
+
+	if len(rec.requests) != 1 {
+		t.Fatalf("provider called %d times, want 1", len(rec.requests))
+	}
+	msgs := rec.requests[0].Messages
+	user := msgs[len(msgs)-1]
+	if user.Role != "user" || user.Content != "what is this?" {
+		t.Fatalf("last message = %+v, want the user's text", user)
+	}
+	if len(user.ContentParts) != 1 || user.ContentParts[0].Type != "image_url" ||
+		user.ContentParts[0].ImageURL == nil || user.ContentParts[0].ImageURL.URL != "https://example.com/cat.png" {
+		t.Errorf("content parts = %+v, want the image URL", user.ContentParts)
+	}
+}
+
+func TestProcessSession_ReplaysMediaFromHistory(t *testing.T) {
+	dir := t.TempDir()
+	newLoop := func(p providers.Provider) *AgentLoop {
+		return NewAgentLoop(AgentLoopConfig{
+			Bus:           bus.NewMessageBus(10),
+			Provider:      p,
+			Sessions:      session.NewManager(dir),
+			Tools:         tools.NewRegistry(),
+			Model:         "test-model",
+			MaxIterations: 5,
+		})
+	}
+	first := &mockProvider{responses: []*providers.ChatResponse{{Content: "a cat"}}}
+	media := []bus.Media{{Type: "image", Data: []byte("\x89PNG\r\n\x1a\n"), MimeType: "image/png"}}
+	if _, err := newLoop(first).ProcessSession(context.Background(), "api:cats", "what is this?", media); err != nil {
+		t.Fatal(err)
+	}
+
+	// A fresh loop and session manager load the turn back from disk.
+	rec := &recordingProvider{mockProvider: mockProvider{responses: []*providers.ChatResponse{{Content: "still a cat"}}}}
+	if _, err := newLoop(rec).ProcessSession(context.Background(), "api:cats", "and now?", nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.requests) != 1 {
+		t.Fatalf("provider called %d times, want 1", len(rec.requests))
+	}
+	var earlier *providers.Message
+	for i, m := range rec.requests[0].Messages {
+		if m.Role == "user" && m.Content == "what is this?" {
+			earlier = &rec.requests[0].Messages[i]
+		}
+	}
+	if earlier == nil {
+		t.Fatalf("earlier user message missing from %+v", rec.requests[0].Messages)
+	}
+	want := "data:image/png;base64,iVBORw0KGgo="
+	if len(earlier.ContentParts) != 1 || earlier.ContentParts[0].Type != "image_url" ||
+		earlier.ContentParts[0].ImageURL == nil || earlier.ContentParts[0].ImageURL.URL != want {
+		t.Errorf("replayed content parts = %+v, want the image %s", earlier.ContentParts, want)
+	}
+}
+
+func TestContentPartsSessionRoundTrip(t *testing.T) {
+	parts := []providers.ContentPart{
+		{Type: "image_url", ImageURL: &providers.ImageURL{URL: "https://example.com/a.png", Detail: "low"}},
+		{Type: "input_audio", InputAudio: &providers.InputAudio{Data: "UklGRg==", Format: "wav"}},
+		{Type: "file", File: &providers.FilePart{Filename: "r.pdf", FileData: "data:application/pdf;base64,JVBERg=="}},
+		{Type: "text", Text: "caption"},
+	}
+	got := contentPartsFromSession(contentPartsToSession(parts))
+	if len(got) != len(parts) {
+		t.Fatalf("got %d parts, want %d", len(got), len(parts))
+	}
+	if *got[0].ImageURL != *parts[0].ImageURL || *got[1].InputAudio != *parts[1].InputAudio ||
+		*got[2].File != *parts[2].File || got[3].Text != "caption" {
+		t.Errorf("round trip = %+v, want %+v", got, parts)
+	}
+	if contentPartsToSession(nil) != nil {
+		t.Error("expected nil parts for a text-only message")
+	}
+}
+
+func TestRun_OutboundCarriesModelAndUsage(t *testing.T) {
+	mock := &mockProvider{responses: []*providers.ChatResponse{
+		{ToolCalls: []providers.ToolCall{{ID: "c1", Name: "echo", Arguments: `{"text":"x"}`}},
+			Usage: providers.Usage{PromptTokens: 10, CompletionTokens: 2, TotalTokens: 12}},
+		{Content: "done", Usage: providers.Usage{PromptTokens: 20, CompletionTokens: 5, TotalTokens: 25}},
+	}}
+	loop := newTestLoop(t, mock, 5)
+	mb := loop.bus
+
+	received := make(chan bus.OutboundMessage, 4)
+	mb.Subscribe("test", func(msg bus.OutboundMessage) {
+		if msg.Type == "text" {
+			received <- msg
+		}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go mb.DispatchOutbound(ctx)
+	go loop.Run(ctx) //nolint:errcheck
+
+	mb.PublishInbound(bus.InboundMessage{Channel: "test", ChatID: "chat1", Content: "go"})
+
+	select {
+	case msg := <-received:
+		if msg.Model != "test-model" {
+			t.Errorf("Model = %q, want test-model", msg.Model)
+		}
+		want := bus.Usage{PromptTokens: 30, CompletionTokens: 7, TotalTokens: 37}
+		if msg.Usage == nil || *msg.Usage != want {
+			t.Errorf("Usage = %+v, want %+v", msg.Usage, want)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out waiting for reply")
+	}
+}
+
+func TestRun_ReplyGoesToOrigin(t *testing.T) {
+	mock := &mockProvider{responses: []*providers.ChatResponse{{Content: "time for standup"}}}
+	loop := newTestLoop(t, mock, 5)
+	mb := loop.bus
+
+	received := make(chan bus.OutboundMessage, 4)
+	mb.Subscribe("telegram", func(msg bus.OutboundMessage) { received <- msg })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go mb.DispatchOutbound(ctx)
+	go loop.Run(ctx) //nolint:errcheck
+
+	mb.PublishInbound(bus.InboundMessage{
+		Channel:            bus.SystemChannel,
+		Content:            "remind the team about standup",
+		SessionKeyOverride: "cron:standup",
+		Metadata: map[string]string{
+			"source":              "cron",
+			bus.MetaOriginChannel: "telegram",
+			bus.MetaOriginChatID:  "42",
+		},
+	})
+
+	select {
+	case msg := <-received:
+		if msg.ChatID != "42" || msg.Content != "time for standup" {
+			t.Errorf("reply = %+v, want %q to chat 42", msg, "time for standup")
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out waiting for reply on telegram")
+	}
+}
+
+// slowProvider holds each call for delay and records the peak number of
+// calls in flight.
+type slowProvider struct {
+	delay    time.Duration
+	inFlight atomic.Int32
+	peak     atomic.Int32
+}
+
+func (p *slowProvider) Chat(ctx context.Context, req providers.ChatRequest) (*providers.ChatResponse, error) {
+	n := p.inFlight.Add(1)
+	defer p.inFlight.Add(-1)
+	for {
+		peak := p.peak.Load()
+		if n <= peak || p.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	select {
+	case <-time.After(p.delay):
+		return &providers.ChatResponse{Content: "ok"}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func TestRun_MaxConcurrentMessages(t *testing.T) {
+	const limit, total = 3, 12
+	mb := bus.NewMessageBus(total)
+	provider := &slowProvider{delay: 30 * time.Millisecond}
+	loop := NewAgentLoop(AgentLoopConfig{
+		Bus:                   mb,
+		Provider:              provider,
+		Sessions:              session.NewManager(t.TempDir()),
+		Tools:                 tools.NewRegistry(),
+		Model:                 "test-model",
+		MaxIterations:         5,
+		MaxConcurrentMessages: limit,
+	})
+
+	received := make(chan bus.OutboundMessage, total)
+	mb.Subscribe("test", func(msg bus.OutboundMessage) {
+		if msg.Type == "text" {
+			received <- msg
+		}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go mb.DispatchOutbound(ctx)
+	go loop.Run(ctx) //nolint:errcheck
+
+	for i := range total {
+		mb.PublishInbound(bus.InboundMessage{Channel: "test", ChatID: fmt.Sprintf("chat%d", i), Content: "hi"})
+	}
+	for range total {
+		select {
+		case <-received:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for replies")
+		}
+	}
+	if peak := provider.peak.Load(); peak > limit {
+		t.Errorf("peak concurrent provider calls = %d, want at most %d", peak, limit)
+	} else if peak < 2 {
+		t.Errorf("peak concurrent provider calls = %d, want messages processed in parallel", peak)
+	}
+}
+
+func TestRun_MessageTimeout(t *testing.T) {
+	mb := bus.NewMessageBus(10)
+	loop := NewAgentLoop(AgentLoopConfig{
+		Bus:            mb,
+		Provider:       &blockingProvider{ready: make(chan struct{})},
+		Sessions:       session.NewManager(t.TempDir()),
+		Tools:          tools.NewRegistry(),
+		Model:          "test-model",
+		MaxIterations:  5,
+		MessageTimeout: 50 * time.Millisecond,
+	})
+
+	received := make(chan bus.OutboundMessage, 1)
+	mb.Subscribe("test", func(msg bus.OutboundMessage) { received <- msg })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go mb.DispatchOutbound(ctx)
+	go loop.Run(ctx) //nolint:errcheck
+
+	mb.PublishInbound(bus.InboundMessage{Channel: "test", ChatID: "chat1", Content: "hello"})
+
+	select {
+	case msg := <-received:
+		if msg.Type != "error" || !strings.Contains(msg.Content, "no reply within 50ms") {
+			t.Errorf("reply = %+v, want a timeout error", msg)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no reply after the message timeout")
+	}
+}
+
+func TestProcessDirect_FlagsToolErrors(t *testing.T) {
+	rec := &recordingProvider{mockProvider: mockProvider{
+		responses: []*providers.ChatResponse{
+			{ToolCalls: []providers.ToolCall{
+				{ID: "c1", Name: "echo", Arguments: `{"text":"ok"}`},
+				{ID: "c2", Name: "no_such_tool", Arguments: `{}`},
+			}},
+			{Content: "done"},
+		},
+	}}
+	loop := newTestLoop(t, rec, 5)
+
+	if _, err := loop.ProcessDirect(context.Background(), "go"); err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.requests) != 2 {
+		t.Fatalf("expected 2 provider calls, got %d", len(rec.requests))
+	}
+
+	results := map[string]providers.Message{}
+	for _, m := range rec.requests[1].Messages {
+		if m.Role == "tool" {
+			results[m.ToolCallID] = m
+		}
+	}
+	if results["c1"].IsError {
+		t.Errorf("successful tool result flagged as error: %+v", results["c1"])
+	}
+	if !results["c2"].IsError {
+		t.Errorf("failed tool result not flagged as error: %+v", results["c2"])
+	}
+}
+
+func TestProcessDirect_MalformedToolArguments(t *testing.T) {
+	rec := &recordingProvider{mockProvider: mockProvider{
+		responses: []*providers.ChatResponse{
+			{ToolCalls: []providers.ToolCall{{ID: "c1", Name: "echo", Arguments: `not-json`}}},
+			{Content: "done"},
+		},
+	}}
+	loop := newTestLoop(t, rec, 5)
+
+	if _, err := loop.ProcessDirect(context.Background(), "go"); err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.requests) != 2 {
+		t.Fatalf("expected 2 provider calls, got %d", len(rec.requests))
+	}
+	msgs := rec.requests[1].Messages
+	result := msgs[len(msgs)-1]
+	if result.Role != "tool" || !result.IsError || !strings.Contains(result.Content, "not valid JSON, please retry") {
+		t.Errorf("tool result = %+v, want a corrective JSON error", result)
+	}
+}
+
+func TestProcessDirect_ResetCommand(t *testing.T) {
+	mock := &mockProvider{responses: []*providers.ChatResponse{{Content: "first"}}}
+	loop := newTestLoop(t, mock, 5)
+
+	if _, err := loop.ProcessDirect(context.Background(), "hello"); err != nil {
+		t.Fatal(err)
+	}
+	got, err := loop.ProcessDirect(context.Background(), " /reset ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(got, "Conversation cleared") {
+		t.Errorf("reply = %q, want a reset confirmation", got)
+	}
+	if mock.callIndex != 1 {
+		t.Errorf("provider called %d times, want the reset handled without it", mock.callIndex)
+	}
+	if h := loop.sessions.GetOrCreate("direct").GetHistory(); len(h) != 0 {
+		t.Errorf("history after reset = %+v, want empty", h)
+	}
+}
+
+// sleepTool waits for "ms" milliseconds, or panics when "panic" is set.
+type sleepTool struct{}
+
+func (t *sleepTool) Name() string        { return "sleep" }
+func (t *sleepTool) Description() string { return "Sleeps" }
+func (t *sleepTool) Parameters() json.RawMessage {
+	return json.RawMessage(`{"type":"object","properties":{"ms":{"type":"integer"},"panic":{"type":"boolean"}}}`)
+}
+func (t *sleepTool) Execute(ctx context.Context, params json.RawMessage) (string, error) {
+	var p struct {
+		MS    int  `json:"ms"`
+		Panic bool `json:"panic"`
+	}
+	json.Unmarshal(params, &p) //nolint:errcheck
+	if p.Panic {
+		panic("boom")
+	}
+	time.Sleep(time.Duration(p.MS) * time.Millisecond)
+	return fmt.Sprintf("slept %dms", p.MS), nil
+}
+
+func TestProcessDirect_RunsToolCallsConcurrently(t *testing.T) {
+	rec := &recordingProvider{mockProvider: mockProvider{
+		responses: []*providers.ChatResponse{
+			{ToolCalls: []providers.ToolCall{
+				{ID: "c1", Name: "sleep", Arguments: `{"ms":300}`},
+				{ID: "c2", Name: "sleep", Arguments: `{"ms":200}`},
+				{ID: "c3", Name: "sleep", Arguments: `{"panic":true}`},
+				{ID: "c4", Name: "sleep", Arguments: `{"ms":100}`},
+			}},
+			{Content: "done"},
+		},
+	}}
+	loop := newTestLoop(t, rec, 5)
+	loop.tools.Register(&sleepTool{})
+	// With a timeout each tool runs in a goroutine of its own.
+	loop.tools.SetTimeout(time.Second)
+
+	start := time.Now()
+	if _, err := loop.ProcessDirect(context.Background(), "go"); err != nil {
+		t.Fatal(err)
+	}
+	// Run one after another the calls would take 600ms.
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("tool calls took %v, want close to the slowest call", elapsed)
+	}
+
+	var results []providers.Message
+	for _, m := range rec.requests[1].Messages {
+		if m.Role == "tool" {
+			results = append(results, m)
+		}
+	}
+	want := []struct {
+		id, content string
+		isError     bool
+	}{
+		{"c1", "slept 300ms", false},
+		{"c2", "slept 200ms", false},
+		{"c3", "Error executing sleep: tool sleep panicked: boom\n\n[Analyze the error above and try a different approach.]", true},
+		{"c4", "slept 100ms", false},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("got %d tool results, want %d", len(results), len(want))
+	}
+	for i, w := range want {
+		if r := results[i]; r.ToolCallID != w.id || r.Content != w.content || r.IsError != w.isError {
+			t.Errorf("result %d = %+v, want %s %q (error %v)", i, r, w.id, w.content, w.isError)
+		}
+	}
+}
+
+func TestProcessDirect_SingleToolWorkerRunsInOrder(t *testing.T) {
+	rec := &recordingProvider{mockProvider: mockProvider{
+		responses: []*providers.ChatResponse{
+			{ToolCalls: []providers.ToolCall{
+				{ID: "c1", Name: "sleep", Arguments: `{"ms":100}`},
+				{ID: "c2", Name: "sleep", Arguments: `{"ms":100}`},
+			}},
+			{Content: "done"},
+		},
+	}}
+	reg := tools.NewRegistry()
+	reg.Register(&sleepTool{})
+	loop := NewAgentLoop(AgentLoopConfig{
+		Bus:         bus.NewMessageBus(10),
+		Provider:    rec,
+		Sessions:    session.NewManager(t.TempDir()),
+		Tools:       reg,
+		Model:       "test-model",
+		ToolWorkers: 1,
+	})
+
+	start := time.Now()
+	if _, err := loop.ProcessDirect(context.Background(), "go"); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("tool calls took %v with one worker, want them run one at a time", elapsed)
+	}
+}
+
+// loopingProvider always asks for the same tool call.
+type loopingProvider struct {
+	requests []providers.ChatRequest
+}
+
+func (p *loopingProvider) Chat(_ context.Context, req providers.ChatRequest) (*providers.ChatResponse, error) {
+	p.requests = append(p.requests, req)
+	return &providers.ChatResponse{
+		ToolCalls: []providers.ToolCall{{ID: fmt.Sprintf("c%d", len(p.requests)), Name: "echo", Arguments: `{"text":"again"}`}},
+	}, nil
+}
+
+func TestProcessDirect_BreaksRepeatedToolCalls(t *testing.T) {
+	prov := &loopingProvider{}
+	loop := newTestLoop(t, prov, 40)
+
+	result, err := loop.ProcessDirect(context.Background(), "go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prov.requests) != repeatAbortAt {
+		t.Errorf("provider called %d times, want the loop to stop after %d", len(prov.requests), repeatAbortAt)
+	}
+	if !strings.Contains(result, "same arguments") {
+		t.Errorf("result = %q, want an explanation of the abort", result)
+	}
+
+	// The model was warned before the loop gave up.
+	msgs := prov.requests[repeatWarnAt].Messages
+	last := msgs[len(msgs)-1]
+	if last.Role != "tool" || !strings.Contains(last.Content, "identical arguments 3 times") {
+		t.Errorf("tool result = %q, want a repeat warning", last.Content)
+	}
+}
+
+func TestRepeatTrackerCanonicalisesArguments(t *testing.T) {
+	r := newRepeatTracker()
+	r.record("echo", `{"a":1,"b":2}`)
+	if n := r.record("echo", `{ "b": 2, "a": 1 }`); n != 2 {
+		t.Errorf("reordered arguments counted %d, want 2", n)
+	}
+	if n := r.record("echo", `{"a":2}`); n != 1 {
+		t.Errorf("different arguments counted %d, want 1", n)
+	}
+	if n := r.record("other", `{"a":1,"b":2}`); n != 1 {
+		t.Errorf("different tool counted %d, want 1", n)
+	}
+}
+
+func TestProcessDirect_TruncatedReply(t *testing.T) {
+	for _, continueOnLength := range []bool{false, true} {
+		rec := &recordingProvider{mockProvider: mockProvider{
+			responses: []*providers.ChatResponse{
+				{Content: "Hello, ", StopReason: providers.StopReasonLength},
+				{Content: "world", StopReason: providers.StopReasonStop},
+			},
+		}}
+		loop := NewAgentLoop(AgentLoopConfig{
+			Bus:              bus.NewMessageBus(10),
+			Provider:         rec,
+			Sessions:         session.NewManager(t.TempDir()),
+			Tools:            tools.NewRegistry(),
+			Model:            "test-model",
+			MaxIterations:    5,
+			ContinueOnLength: continueOnLength,
+		})
+
+		got, err := loop.ProcessDirect(context.Background(), "greet")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !continueOnLength {
+			if got != "Hello, " || len(rec.requests) != 1 {
+				t.Errorf("without continuation: got %q after %d calls, want the truncated reply", got, len(rec.requests))
+			}
+			continue
+		}
+		if got != "Hello, world" {
+			t.Errorf("with continuation: got %q, want the joined reply", got)
+		}
+		msgs := rec.requests[1].Messages
+		if last := msgs[len(msgs)-1]; last.Role != "user" || last.Content != continuePrompt {
+			t.Errorf("continuation request ended with %+v, want the continue prompt", last)
+		}
+	}
+}
+
+func TestProcessDirect_BuildsPromptFromWorkspace(t *testing.T) {
+	ws := t.TempDir()
+	os.WriteFile(filepath.Join(ws, "AGENTS.md"), []byte("You are the workspace agent."), 0o644)
+	os.WriteFile(filepath.Join(ws, "MEMORY.md"), []byte("User likes tea."), 0o644)
+
+	newLoop := func(promptFile string) (*AgentLoop, *recordingProvider) {
+		rec := &recordingProvider{mockProvider: mockProvider{
+			responses: []*providers.ChatResponse{{Content: "ok"}, {Content: "ok"}},
+		}}
+		loop := NewAgentLoop(AgentLoopConfig{
+			Bus:              bus.NewMessageBus(10),
+			Provider:         rec,
+			Sessions:         session.NewManager(t.TempDir()),
+			Tools:            tools.NewRegistry(),
+			Model:            "test-model",
+			SystemPrompt:     "static prompt",
+			Workspace:        ws,
+			SystemPromptFile: promptFile,
+		})
+		return loop, rec
+	}
+
+	loop, rec := newLoop("")
+	if _, err := loop.ProcessDirect(context.Background(), "hi"); err != nil {
+		t.Fatal(err)
+	}
+	prompt := rec.requests[0].SystemPrompt
+	for _, want := range []string{"You are the workspace agent.", "User likes tea.", "## Runtime Context"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("system prompt missing %q:\n%s", want, prompt)
+		}
+	}
+
+	// The prompt is rebuilt per message, so workspace edits apply immediately.
+	os.WriteFile(filepath.Join(ws, "AGENTS.md"), []byte("Updated instructions."), 0o644)
+	if _, err := loop.ProcessDirect(context.Background(), "again"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(rec.requests[1].SystemPrompt, "Updated instructions.") {
+		t.Errorf("prompt not rebuilt after AGENTS.md changed:\n%s", rec.requests[1].SystemPrompt)
+	}
+
+	os.WriteFile(filepath.Join(ws, "custom.md"), []byte("Custom persona."), 0o644)
+	loop, rec = newLoop("custom.md")
+	if _, err := loop.ProcessDirect(context.Background(), "hi"); err != nil {
+		t.Fatal(err)
+	}
+	prompt = rec.requests[0].SystemPrompt
+	if !strings.Contains(prompt, "Custom persona.") || strings.Contains(prompt, "Updated instructions.") {
+		t.Errorf("SystemPromptFile should replace bootstrap files:\n%s", prompt)
+	}
+}
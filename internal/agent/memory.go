@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
@@ -31,6 +32,152 @@ func (m *MemoryStore) ReadMemory() string {
 	return string(data)
 }
 
+// GetMemory returns the body of the MEMORY.md section headed "## key".
+// Keys match case-insensitively.
+func (m *MemoryStore) GetMemory(key string) (string, bool) {
+	_, sections := parseMemory(m.ReadMemory())
+	if i := findSection(sections, key); i >= 0 {
+		return sections[i].body, true
+	}
+	return "", false
+}
+
+// SetMemory replaces the body of the section for key, adding the section at
+// the end of MEMORY.md if it does not exist yet.
+func (m *MemoryStore) SetMemory(key, value string) error {
+	if err := checkMemoryValue(value); err != nil {
+		return err
+	}
+	return m.editMemory(func(sections []memorySection) []memorySection {
+		if i := findSection(sections, key); i >= 0 {
+			sections[i].body = strings.TrimSpace(value)
+			return sections
+		}
+		return append(sections, memorySection{key: key, body: strings.TrimSpace(value)})
+	})
+}
+
+// AppendMemory adds value as a new paragraph at the end of the section for
+// key, creating the section if needed.
+func (m *MemoryStore) AppendMemory(key, value string) error {
+	if err := checkMemoryValue(value); err != nil {
+		return err
+	}
+	return m.editMemory(func(sections []memorySection) []memorySection {
+		i := findSection(sections, key)
+		if i < 0 {
+			return append(sections, memorySection{key: key, body: strings.TrimSpace(value)})
+		}
+		if sections[i].body == "" {
+			sections[i].body = strings.TrimSpace(value)
+		} else {
+			sections[i].body += "\n\n" + strings.TrimSpace(value)
+		}
+		return sections
+	})
+}
+
+// DeleteMemory removes the section for key. It reports whether the section existed.
+func (m *MemoryStore) DeleteMemory(key string) (bool, error) {
+	var found bool
+	err := m.editMemory(func(sections []memorySection) []memorySection {
+		i := findSection(sections, key)
+		if i < 0 {
+			return sections
+		}
+		found = true
+		return append(sections[:i], sections[i+1:]...)
+	})
+	return found, err
+}
+
+// editMemory rewrites MEMORY.md with the sections returned by edit. Text
+// before the first section heading is kept as is.
+func (m *MemoryStore) editMemory(edit func([]memorySection) []memorySection) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	preamble, sections := parseMemory(m.ReadMemory())
+	content := formatMemory(preamble, edit(sections))
+	if err := os.WriteFile(filepath.Join(m.workspace, "MEMORY.md"), []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to write MEMORY.md: %w", err)
+	}
+	return nil
+}
+
+// memorySection is one "## key" section of MEMORY.md.
+type memorySection struct {
+	key  string
+	body string
+}
+
+// checkMemoryValue rejects a value with a line that parseMemory would read
+// as the heading of another section.
+func checkMemoryValue(value string) error {
+	for _, line := range strings.Split(strings.TrimSpace(value), "\n") {
+		if strings.HasPrefix(line, "## ") {
+			return fmt.Errorf("memory value must not contain a section heading: %q", line)
+		}
+	}
+	return nil
+}
+
+// parseMemory splits MEMORY.md into the free text before the first "## "
+// heading and the sections that follow.
+func parseMemory(text string) (string, []memorySection) {
+	var preamble []string
+	var sections []memorySection
+	var body []string
+	flush := func() {
+		if len(sections) > 0 {
+			sections[len(sections)-1].body = strings.TrimSpace(strings.Join(body, "\n"))
+		}
+		body = nil
+	}
+	for _, line := range strings.Split(text, "\n") {
+		if key, ok := strings.CutPrefix(line, "## "); ok {
+			flush()
+			sections = append(sections, memorySection{key: strings.TrimSpace(key)})
+			continue
+		}
+		if len(sections) == 0 {
+			preamble = append(preamble, line)
+		} else {
+			body = append(body, line)
+		}
+	}
+	flush()
+	return strings.TrimSpace(strings.Join(preamble, "\n")), sections
+}
+
+func formatMemory(preamble string, sections []memorySection) string {
+	var parts []string
+	if preamble != "" {
+		parts = append(parts, preamble)
+	}
+	for _, s := range sections {
+		part := "## " + s.key
+		if s.body != "" {
+			part += "\n\n" + s.body
+		}
+		parts = append(parts, part)
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return strings.Join(parts, "\n\n") + "\n"
+}
+
+func findSection(sections []memorySection, key string) int {
+	key = strings.TrimSpace(key)
+	for i, s := range sections {
+		if strings.EqualFold(s.key, key) {
+			return i
+		}
+	}
+	return -1
+}
+
 // ReadHistory returns the content of HISTORY.md, or empty string if not found.
 func (m *MemoryStore) ReadHistory() string {
 	data, err := os.ReadFile(filepath.Join(m.workspace, "HISTORY.md"))
@@ -9,6 +9,7 @@ import (
 	"testing"
 
 	"github.com/coopco/nanobot/internal/providers"
+	"github.com/coopco/nanobot/internal/tools"
 )
 
 type mockMemoryProvider struct {
@@ -94,3 +95,77 @@ func TestConsolidate(t *testing.T) {
 		t.Errorf("expected memory content, got %q", string(memory))
 	}
 }
+
+func TestMemorySections(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "MEMORY.md"), []byte("Consolidated facts.\n\n## Projects\n\nnanobot\n"), 0644)
+	ms := NewMemoryStore(dir)
+
+	if err := ms.SetMemory("user", "Name is Ada"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ms.AppendMemory("projects", "gateway"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A fresh store reads what the first one wrote.
+	reopened := NewMemoryStore(dir)
+	if got, ok := reopened.GetMemory("USER"); !ok || got != "Name is Ada" {
+		t.Errorf("GetMemory(user) = %q, %v", got, ok)
+	}
+	if got, _ := reopened.GetMemory("projects"); got != "nanobot\n\ngateway" {
+		t.Errorf("GetMemory(projects) = %q", got)
+	}
+
+	found, err := reopened.DeleteMemory("projects")
+	if err != nil || !found {
+		t.Fatalf("DeleteMemory = %v, %v", found, err)
+	}
+	want := "Consolidated facts.\n\n## user\n\nName is Ada\n"
+	if got := reopened.ReadMemory(); got != want {
+		t.Errorf("MEMORY.md = %q, want %q", got, want)
+	}
+}
+
+func TestMemoryRejectsSectionHeadings(t *testing.T) {
+	dir := t.TempDir()
+	ms := NewMemoryStore(dir)
+	if err := ms.SetMemory("user", "Name is Ada"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ms.SetMemory("notes", "ok\n## user\nName is Mallory"); err == nil {
+		t.Error("SetMemory accepted a value with a section heading")
+	}
+	if err := ms.AppendMemory("notes", "  ## user"); err == nil {
+		t.Error("AppendMemory accepted a value with a section heading")
+	}
+	if got, _ := ms.GetMemory("user"); got != "Name is Ada" {
+		t.Errorf("GetMemory(user) = %q, want it unchanged", got)
+	}
+	if _, ok := ms.GetMemory("notes"); ok {
+		t.Error("rejected value was written")
+	}
+
+	// Deeper headings do not start a section and are kept.
+	if err := ms.SetMemory("notes", "### Detail\nmore"); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := ms.GetMemory("notes"); got != "### Detail\nmore" {
+		t.Errorf("GetMemory(notes) = %q", got)
+	}
+}
+
+func TestMemoryToolFeedsSystemPrompt(t *testing.T) {
+	dir := t.TempDir()
+	tool := tools.NewMemoryTool(NewMemoryStore(dir))
+	if _, err := tool.Execute(context.Background(), json.RawMessage(`{"action":"set","key":"user","value":"Prefers short answers"}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	loop := NewAgentLoop(AgentLoopConfig{Workspace: dir, Tools: tools.NewRegistry()})
+	prompt := loop.buildSystemPrompt()
+	if !strings.Contains(prompt, "## Memory") || !strings.Contains(prompt, "Prefers short answers") {
+		t.Errorf("stored memory missing from system prompt:\n%s", prompt)
+	}
+}
@@ -0,0 +1,56 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	// repeatWarnAt is the number of identical tool calls after which the
+	// model is told it is repeating itself.
+	repeatWarnAt = 3
+	// repeatAbortAt is the number of identical tool calls after which the
+	// tool loop gives up instead of burning the remaining iterations.
+	repeatAbortAt = 5
+)
+
+// repeatTracker counts identical (tool name, arguments) calls within one run
+// of the tool loop, so a model stuck calling the same tool is stopped early.
+type repeatTracker struct {
+	counts map[string]int
+}
+
+func newRepeatTracker() *repeatTracker {
+	return &repeatTracker{counts: make(map[string]int)}
+}
+
+// record notes a call and returns how many times it has now been made.
+func (r *repeatTracker) record(name, args string) int {
+	key := name + "\x00" + canonicalArgs(args)
+	r.counts[key]++
+	return r.counts[key]
+}
+
+// repeatWarning is appended to the result of a repeated tool call.
+func repeatWarning(name string, n int) string {
+	return fmt.Sprintf("\n\n[You have called %s with identical arguments %d times and the result will not change. Try a different approach, or answer with what you have.]", name, n)
+}
+
+// repeatAbortMessage is the final response when the loop is stopped.
+func repeatAbortMessage(name string, n int) string {
+	return fmt.Sprintf("I stopped because I kept calling %s with the same arguments (%d times) without making progress. Please rephrase the request or give me more detail.", name, n)
+}
+
+// canonicalArgs normalises JSON arguments so key order and whitespace do not
+// hide a repeat. Invalid JSON is compared as is.
+func canonicalArgs(args string) string {
+	var v any
+	if err := json.Unmarshal([]byte(args), &v); err != nil {
+		return args
+	}
+	out, err := json.Marshal(v)
+	if err != nil {
+		return args
+	}
+	return string(out)
+}
@@ -1,12 +1,18 @@
 package agent
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"strings"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	"gopkg.in/yaml.v3"
 )
 
 // SkillMeta holds parsed frontmatter from a SKILL.md file.
@@ -20,6 +26,15 @@ type SkillMeta struct {
 // SkillsLoader scans workspace and builtin skills directories.
 type SkillsLoader struct {
 	workspaceSkillsDir string
+
+	// Once Watch is running, LoadAll serves skills from cached and the watcher
+	// invalidates it; gen counts invalidations so a load that raced with a
+	// change is not cached.
+	mu       sync.Mutex
+	watching bool
+	cached   []LoadedSkill
+	valid    bool
+	gen      uint64
 }
 
 func NewSkillsLoader(workspace string) *SkillsLoader {
@@ -33,8 +48,94 @@ type LoadedSkill struct {
 	Path    string
 }
 
-// LoadAll scans the skills directory and returns all valid skills.
+// LoadAll returns all valid skills. Without a watcher it rescans the skills
+// directory on every call.
 func (l *SkillsLoader) LoadAll() []LoadedSkill {
+	l.mu.Lock()
+	if !l.watching {
+		l.mu.Unlock()
+		return l.scan()
+	}
+	if l.valid {
+		skills := append([]LoadedSkill(nil), l.cached...)
+		l.mu.Unlock()
+		return skills
+	}
+	gen := l.gen
+	l.mu.Unlock()
+
+	skills := l.scan()
+
+	l.mu.Lock()
+	if l.gen == gen {
+		l.cached = skills
+		l.valid = true
+	}
+	l.mu.Unlock()
+	return append([]LoadedSkill(nil), skills...)
+}
+
+// Watch caches loaded skills and drops the cache whenever a skill file in the
+// skills directory is added, changed or removed, so the next LoadAll picks up
+// the change. The directory is created if missing. Watch returns once the
+// watcher is running and stops when ctx is cancelled.
+func (l *SkillsLoader) Watch(ctx context.Context) error {
+	if err := os.MkdirAll(l.workspaceSkillsDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create skills dir: %w", err)
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create skills watcher: %w", err)
+	}
+	if err := watcher.Add(l.workspaceSkillsDir); err != nil {
+		watcher.Close()
+		return fmt.Errorf("failed to watch %s: %w", l.workspaceSkillsDir, err)
+	}
+
+	l.mu.Lock()
+	l.watching = true
+	l.valid = false
+	l.mu.Unlock()
+
+	go func() {
+		defer watcher.Close()
+		defer func() {
+			l.mu.Lock()
+			l.watching = false
+			l.cached = nil
+			l.valid = false
+			l.mu.Unlock()
+		}()
+
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if !strings.HasSuffix(event.Name, ".md") {
+					continue
+				}
+				l.mu.Lock()
+				l.valid = false
+				l.gen++
+				l.mu.Unlock()
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Printf("skills watcher error on %s: %v", l.workspaceSkillsDir, err)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+// scan reads every skill file in the skills directory.
+func (l *SkillsLoader) scan() []LoadedSkill {
 	entries, err := os.ReadDir(l.workspaceSkillsDir)
 	if err != nil {
 		return nil
@@ -82,7 +183,12 @@ func (l *SkillsLoader) BuildSkillsSummary() string {
 	var sb strings.Builder
 	sb.WriteString("<available_skills>\n")
 	for _, s := range l.LoadAll() {
-		if !s.Meta.Always {
+		if s.Meta.Always {
+			continue
+		}
+		if args := skillArgs(s.Content); len(args) > 0 {
+			sb.WriteString(fmt.Sprintf("<skill name=%q args=%q>%s</skill>\n", s.Meta.Name, strings.Join(args, ","), s.Meta.Description))
+		} else {
 			sb.WriteString(fmt.Sprintf("<skill name=%q>%s</skill>\n", s.Meta.Name, s.Meta.Description))
 		}
 	}
@@ -90,83 +196,121 @@ func (l *SkillsLoader) BuildSkillsSummary() string {
 	return sb.String()
 }
 
+// skillPlaceholder matches {{arg}} placeholders in skill content.
+var skillPlaceholder = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_-]*)\s*\}\}`)
+
+// RenderSkill returns the content of the named skill with each {{arg}}
+// placeholder replaced by its value from args. Every placeholder must be
+// supplied; unused args are ignored.
+func (l *SkillsLoader) RenderSkill(name string, args map[string]string) (string, error) {
+	for _, s := range l.LoadAll() {
+		if s.Meta.Name == name {
+			return renderSkill(s.Content, args)
+		}
+	}
+	return "", fmt.Errorf("skill %q not found", name)
+}
+
+func renderSkill(content string, args map[string]string) (string, error) {
+	var missing []string
+	for _, arg := range skillArgs(content) {
+		if _, ok := args[arg]; !ok {
+			missing = append(missing, arg)
+		}
+	}
+	if len(missing) > 0 {
+		return "", fmt.Errorf("missing skill arguments: %s", strings.Join(missing, ", "))
+	}
+	return skillPlaceholder.ReplaceAllStringFunc(content, func(m string) string {
+		return args[skillPlaceholder.FindStringSubmatch(m)[1]]
+	}), nil
+}
+
+// skillArgs returns the distinct placeholder names in content, in order of
+// first appearance.
+func skillArgs(content string) []string {
+	var args []string
+	seen := make(map[string]bool)
+	for _, m := range skillPlaceholder.FindAllStringSubmatch(content, -1) {
+		if !seen[m[1]] {
+			seen[m[1]] = true
+			args = append(args, m[1])
+		}
+	}
+	return args
+}
+
 // parseFrontmatter splits YAML frontmatter from content.
-// Returns (meta, content, ok).
+// Returns (meta, content, ok); ok is false when there is no frontmatter block
+// or it is not valid YAML.
 func parseFrontmatter(raw string) (SkillMeta, string, bool) {
 	// Must start with ---
 	if !strings.HasPrefix(raw, "---") {
 		return SkillMeta{}, "", false
 	}
-	// Find closing ---
-	rest := raw[3:]
-	// skip optional newline after opening ---
-	if len(rest) > 0 && rest[0] == '\n' {
-		rest = rest[1:]
-	}
-	idx := strings.Index(rest, "---")
-	if idx < 0 {
-		return SkillMeta{}, "", false
-	}
-	frontmatter := rest[:idx]
-	content := strings.TrimPrefix(rest[idx+3:], "\n")
+	rest := strings.TrimPrefix(strings.TrimPrefix(raw[3:], "\r"), "\n")
 
-	meta := parseMeta(frontmatter)
-	return meta, content, true
+	// The block ends at the first line that is exactly ---.
+	lines := strings.SplitAfter(rest, "\n")
+	for i, line := range lines {
+		if strings.TrimRight(line, " \t\r\n") != "---" {
+			continue
+		}
+		meta, err := parseMeta(strings.Join(lines[:i], ""))
+		if err != nil {
+			log.Printf("skill frontmatter invalid: %v", err)
+			return SkillMeta{}, "", false
+		}
+		return meta, strings.Join(lines[i+1:], ""), true
+	}
+	return SkillMeta{}, "", false
 }
 
-func parseMeta(fm string) SkillMeta {
-	var meta SkillMeta
-	lines := strings.Split(fm, "\n")
-	inRequires := false
+// parseMeta unmarshals a frontmatter block into SkillMeta.
+func parseMeta(fm string) (SkillMeta, error) {
+	var raw struct {
+		Name        string     `yaml:"name"`
+		Description string     `yaml:"description"`
+		Always      bool       `yaml:"always"`
+		Requires    stringList `yaml:"requires"`
+	}
+	if err := yaml.Unmarshal([]byte(fm), &raw); err != nil {
+		return SkillMeta{}, err
+	}
+	return SkillMeta{
+		Name:        raw.Name,
+		Description: raw.Description,
+		Always:      raw.Always,
+		Requires:    raw.Requires,
+	}, nil
+}
 
-	for _, line := range lines {
-		trimmed := strings.TrimSpace(line)
-		if trimmed == "" {
-			continue
-		}
+// stringList accepts either a YAML sequence or a comma-separated scalar, so
+// both "requires: [git, gh]" and "requires: git, gh" work.
+type stringList []string
 
-		// List item under requires:
-		if inRequires {
-			if strings.HasPrefix(trimmed, "-") {
-				val := strings.TrimSpace(strings.TrimPrefix(trimmed, "-"))
-				if val != "" {
-					meta.Requires = append(meta.Requires, val)
-				}
-				continue
-			}
-			// Not a list item — fall through to normal key: value parsing
-			inRequires = false
+func (l *stringList) UnmarshalYAML(node *yaml.Node) error {
+	var items []string
+	switch node.Kind {
+	case yaml.SequenceNode:
+		if err := node.Decode(&items); err != nil {
+			return err
 		}
-
-		kv := strings.SplitN(trimmed, ":", 2)
-		if len(kv) != 2 {
-			continue
+	case yaml.ScalarNode:
+		var s string
+		if err := node.Decode(&s); err != nil {
+			return err
 		}
-		key := strings.TrimSpace(kv[0])
-		val := strings.TrimSpace(kv[1])
-
-		switch key {
-		case "name":
-			meta.Name = val
-		case "description":
-			meta.Description = val
-		case "always":
-			meta.Always = val == "true"
-		case "requires":
-			if val == "" {
-				inRequires = true
-			} else {
-				// comma-separated inline
-				for _, r := range strings.Split(val, ",") {
-					r = strings.TrimSpace(r)
-					if r != "" {
-						meta.Requires = append(meta.Requires, r)
-					}
-				}
-			}
+		items = strings.Split(s, ",")
+	default:
+		return fmt.Errorf("line %d: expected a list or comma-separated string", node.Line)
+	}
+	for _, item := range items {
+		if item = strings.TrimSpace(item); item != "" {
+			*l = append(*l, item)
 		}
 	}
-	return meta
+	return nil
 }
 
 // checkRequirements returns true if all required commands are available.
@@ -1,10 +1,12 @@
 package agent
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
 func writeSkill(t *testing.T, dir, name, content string) {
@@ -131,3 +133,180 @@ Content
 		t.Errorf("expected skill to be skipped due to missing requirement, got %d skills", len(skills))
 	}
 }
+
+func TestRenderSkill(t *testing.T) {
+	dir := t.TempDir()
+	writeSkill(t, filepath.Join(dir, "skills"), "deploy.md", `---
+name: deploy
+description: Deploy a service
+---
+
+Deploy {{service}} to {{ env }}. Notify the {{service}} owners.
+`)
+	l := NewSkillsLoader(dir)
+
+	out, err := l.RenderSkill("deploy", map[string]string{"service": "api", "env": "staging", "extra": "ignored"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Deploy api to staging. Notify the api owners."; !strings.Contains(out, want) {
+		t.Errorf("rendered %q, want it to contain %q", out, want)
+	}
+
+	if summary := l.BuildSkillsSummary(); !strings.Contains(summary, `args="service,env"`) {
+		t.Errorf("summary does not advertise args: %s", summary)
+	}
+}
+
+func TestRenderSkillErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeSkill(t, filepath.Join(dir, "skills"), "deploy.md", `---
+name: deploy
+description: Deploy a service
+---
+
+Deploy {{service}} to {{env}}.
+`)
+	l := NewSkillsLoader(dir)
+
+	_, err := l.RenderSkill("deploy", map[string]string{"service": "api"})
+	if err == nil || !strings.Contains(err.Error(), "env") {
+		t.Errorf("expected missing argument error naming env, got %v", err)
+	}
+	if _, err := l.RenderSkill("nope", nil); err == nil {
+		t.Error("expected error for unknown skill")
+	}
+}
+
+func TestWatchPicksUpNewSkill(t *testing.T) {
+	dir := t.TempDir()
+	skillsDir := filepath.Join(dir, "skills")
+	writeSkill(t, skillsDir, "first.md", `---
+name: first
+always: true
+---
+
+First skill
+`)
+
+	l := NewSkillsLoader(dir)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := l.Watch(ctx); err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+
+	if out := l.GetAlwaysSkills(); !strings.Contains(out, "First skill") {
+		t.Fatalf("initial skills = %q", out)
+	}
+
+	writeSkill(t, skillsDir, "second.md", `---
+name: second
+always: true
+---
+
+Second skill
+`)
+
+	deadline := time.Now().Add(3 * time.Second)
+	for !strings.Contains(l.GetAlwaysSkills(), "Second skill") {
+		if time.Now().After(deadline) {
+			t.Fatalf("new skill never appeared; got %q", l.GetAlwaysSkills())
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	if err := os.Remove(filepath.Join(skillsDir, "first.md")); err != nil {
+		t.Fatal(err)
+	}
+	for strings.Contains(l.GetAlwaysSkills(), "First skill") {
+		if time.Now().After(deadline) {
+			t.Fatal("removed skill still served from cache")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestWatchServesFromCache(t *testing.T) {
+	dir := t.TempDir()
+	writeSkill(t, filepath.Join(dir, "skills"), "a.md", "---\nname: a\n---\nA\n")
+
+	l := NewSkillsLoader(dir)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := l.Watch(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	first := l.LoadAll()
+	l.mu.Lock()
+	valid := l.valid
+	l.mu.Unlock()
+	if len(first) != 1 || !valid {
+		t.Fatalf("expected one cached skill, got %d (valid=%v)", len(first), valid)
+	}
+}
+
+func TestParseFrontmatterYAML(t *testing.T) {
+	tests := []struct {
+		name string
+		fm   string
+		want SkillMeta
+	}{
+		{
+			name: "quoted description with colon",
+			fm:   "name: \"git-helper\"\ndescription: 'Git: commits, rebases and more'\nalways: true\n",
+			want: SkillMeta{Name: "git-helper", Description: "Git: commits, rebases and more", Always: true},
+		},
+		{
+			name: "block list requires",
+			fm:   "name: deploy\nrequires:\n  - git\n  - ssh\n",
+			want: SkillMeta{Name: "deploy", Requires: []string{"git", "ssh"}},
+		},
+		{
+			name: "flow list requires",
+			fm:   "name: deploy\nrequires: [git, ssh]\n",
+			want: SkillMeta{Name: "deploy", Requires: []string{"git", "ssh"}},
+		},
+		{
+			name: "comma separated requires",
+			fm:   "name: deploy\nrequires: git, ssh\n",
+			want: SkillMeta{Name: "deploy", Requires: []string{"git", "ssh"}},
+		},
+		{
+			name: "comments and multi-line description",
+			fm:   "# maintained by ops\nname: notes # inline comment\ndescription: >\n  Keep notes\n  tidy\nalways: false\nextra:\n  nested: ignored\n",
+			want: SkillMeta{Name: "notes", Description: "Keep notes tidy\n"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			meta, content, ok := parseFrontmatter("---\n" + tt.fm + "---\nbody\n")
+			if !ok {
+				t.Fatal("parseFrontmatter failed")
+			}
+			if content != "body\n" {
+				t.Errorf("content = %q", content)
+			}
+			if meta.Name != tt.want.Name || meta.Description != tt.want.Description || meta.Always != tt.want.Always {
+				t.Errorf("meta = %+v, want %+v", meta, tt.want)
+			}
+			if strings.Join(meta.Requires, ",") != strings.Join(tt.want.Requires, ",") {
+				t.Errorf("requires = %v, want %v", meta.Requires, tt.want.Requires)
+			}
+		})
+	}
+}
+
+func TestParseFrontmatterInvalid(t *testing.T) {
+	for _, raw := range []string{
+		"no frontmatter",
+		"---\nname: unterminated\n",
+		"---\nname: [unclosed\n---\nbody\n",
+		"---\nrequires: {a: b}\n---\nbody\n",
+	} {
+		if _, _, ok := parseFrontmatter(raw); ok {
+			t.Errorf("parseFrontmatter(%q) succeeded, want failure", raw)
+		}
+	}
+}
@@ -5,7 +5,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/coopco/nanobot/internal/bus"
 	"github.com/coopco/nanobot/internal/providers"
@@ -20,10 +22,34 @@ type SubagentManager struct {
 	temperature float64
 	bus         *bus.MessageBus
 	mu          sync.Mutex
-	running     map[string]context.CancelFunc
+	running     map[string]runningTask
 	counter     int
+	maxIter     int
+	tools       *tools.Registry    // nil means defaultSubagentTools
+	allowed     []string           // if non-empty, only these tools are exposed
+	root        string             // workspace root applied to subagent tools; empty leaves them as configured
+	shellPolicy *tools.ShellPolicy // applied to subagent run_shell tools when set
+	progressGap time.Duration      // minimum time between progress reports
+	storePath   string             // where running tasks are recorded; empty disables
+	maxResult   int                // tool result cap in bytes, below one disables it
+	pending     map[string]subagentRecord
 }
 
+// runningTask is a live subagent and the conversation that started it.
+type runningTask struct {
+	cancel  context.CancelFunc
+	channel string
+	chatID  string
+}
+
+// defaultSubagentMaxIter bounds a subagent's tool loop unless SetMaxIterations
+// overrides it.
+const defaultSubagentMaxIter = 15
+
+// defaultSubagentProgressInterval spaces out progress reports so a busy
+// subagent does not flood its origin conversation.
+const defaultSubagentProgressInterval = 30 * time.Second
+
 // NewSubagentManager creates a new SubagentManager.
 func NewSubagentManager(provider providers.Provider, model string, maxTokens int, temperature float64, msgBus *bus.MessageBus) *SubagentManager {
 	return &SubagentManager{
@@ -32,8 +58,113 @@ func NewSubagentManager(provider providers.Provider, model string, maxTokens int
 		maxTokens:   maxTokens,
 		temperature: temperature,
 		bus:         msgBus,
-		running:     make(map[string]context.CancelFunc),
+		running:     make(map[string]runningTask),
+		maxIter:     defaultSubagentMaxIter,
+		progressGap: defaultSubagentProgressInterval,
+		maxResult:   defaultMaxToolResultSize,
+	}
+}
+
+// SetProgressInterval sets the minimum time between progress reports. Zero
+// reports after every tool iteration; a negative value disables them.
+func (m *SubagentManager) SetProgressInterval(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.progressGap = d
+}
+
+// SetMaxIterations sets how many LLM round trips a subagent may make. Values
+// below one restore the default.
+func (m *SubagentManager) SetMaxIterations(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if n < 1 {
+		n = defaultSubagentMaxIter
 	}
+	m.maxIter = n
+}
+
+// SetMaxToolResultSize caps, in bytes, each tool result added to a
+// subagent's conversation, as AgentLoopConfig.MaxToolResultSize does for
+// the main loop.
+func (m *SubagentManager) SetMaxToolResultSize(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.maxResult = toolResultLimit(n)
+}
+
+// SetTools replaces the default file and shell tools given to subagents.
+// Passing nil restores the default set.
+func (m *SubagentManager) SetTools(reg *tools.Registry) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tools = reg
+}
+
+// SetAllowedTools restricts subagents to the named tools from their registry.
+// An empty list allows every tool.
+func (m *SubagentManager) SetAllowedTools(names []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.allowed = append([]string(nil), names...)
+}
+
+// SetWorkspaceRoot confines the file and shell tools of subagents spawned
+// afterwards to dir, as Registry.SetWorkspaceRoot does for the main agent.
+// An empty dir leaves the tools as configured.
+func (m *SubagentManager) SetWorkspaceRoot(dir string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.root = dir
+}
+
+// SetShellPolicy applies p to the run_shell tool of subagents spawned
+// afterwards, as Registry.SetShellPolicy does for the main agent.
+func (m *SubagentManager) SetShellPolicy(p tools.ShellPolicy) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.shellPolicy = &p
+}
+
+// defaultSubagentTools returns the file and shell tools subagents get unless
+// SetTools overrides them.
+func defaultSubagentTools() *tools.Registry {
+	reg := tools.NewRegistry()
+	reg.Register(tools.NewReadFileTool())
+	reg.Register(tools.NewWriteFileTool())
+	reg.Register(tools.NewEditFileTool())
+	reg.Register(tools.NewListDirTool())
+	reg.Register(tools.NewRunShellTool())
+	return reg
+}
+
+// toolsFor builds the registry for one subagent from the configured base and
+// allowlist. m.mu must be held.
+func (m *SubagentManager) toolsFor() *tools.Registry {
+	base := m.tools
+	if base == nil {
+		base = defaultSubagentTools()
+	}
+	reg := base.Clone()
+	if m.root != "" {
+		reg.SetWorkspaceRoot(m.root)
+	}
+	if m.shellPolicy != nil {
+		reg.SetShellPolicy(*m.shellPolicy)
+	}
+	if len(m.allowed) == 0 {
+		return reg
+	}
+	keep := make(map[string]bool, len(m.allowed))
+	for _, name := range m.allowed {
+		keep[name] = true
+	}
+	for _, def := range base.Definitions() {
+		if !keep[def.Function.Name] {
+			reg.Unregister(def.Function.Name)
+		}
+	}
+	return reg
 }
 
 // Spawn starts a background subagent goroutine. Returns a task ID.
@@ -42,23 +173,36 @@ func (m *SubagentManager) Spawn(ctx context.Context, task, label, originChannel,
 	taskID := fmt.Sprintf("task_%d", m.counter)
 	m.counter++
 	childCtx, cancel := context.WithCancel(ctx)
-	m.running[taskID] = cancel
+	m.running[taskID] = runningTask{cancel: cancel, channel: originChannel, chatID: originChatID}
+	isolatedTools := m.toolsFor()
+	maxIter := m.maxIter
+	maxResult := m.maxResult
+	progressGap := m.progressGap
+	started := time.Now()
+	m.trackTask(subagentRecord{
+		ID:            taskID,
+		Task:          task,
+		Label:         label,
+		OriginChannel: originChannel,
+		OriginChatID:  originChatID,
+		StartedAt:     started,
+	})
 	m.mu.Unlock()
 
+	sessionKey := fmt.Sprintf("%s:%s", originChannel, originChatID)
+
 	go func() {
 		defer func() {
 			m.mu.Lock()
 			delete(m.running, taskID)
+			// A task stopped by shutdown stays in the store for Restore to
+			// report; Cancel untracks the ones stopped on request.
+			if ctx.Err() == nil {
+				m.untrackTask(taskID)
+			}
 			m.mu.Unlock()
 		}()
 
-		isolatedTools := tools.NewRegistry()
-		isolatedTools.Register(tools.NewReadFileTool())
-		isolatedTools.Register(tools.NewWriteFileTool())
-		isolatedTools.Register(tools.NewEditFileTool())
-		isolatedTools.Register(tools.NewListDirTool())
-		isolatedTools.Register(tools.NewRunShellTool())
-
 		systemPrompt := fmt.Sprintf(
 			"You are a focused task agent. Complete the following task:\n%s\n\nUse the available tools to accomplish this task. Be thorough and report your findings.",
 			task,
@@ -70,7 +214,7 @@ func (m *SubagentManager) Spawn(ctx context.Context, task, label, originChannel,
 		}
 
 		var result string
-		const maxIter = 15
+		lastReport := started
 		for i := 0; i < maxIter; i++ {
 			req := providers.ChatRequest{
 				Model:        m.model,
@@ -81,7 +225,9 @@ func (m *SubagentManager) Spawn(ctx context.Context, task, label, originChannel,
 				SystemPrompt: systemPrompt,
 			}
 
+			start := time.Now()
 			resp, err := m.provider.Chat(childCtx, req)
+			observeProviderCall(req.Model, start, err)
 			if err != nil {
 				slog.Error("subagent provider error", "taskID", taskID, "err", err)
 				result = fmt.Sprintf("error: %v", err)
@@ -89,9 +235,10 @@ func (m *SubagentManager) Spawn(ctx context.Context, task, label, originChannel,
 			}
 
 			assistantMsg := providers.Message{
-				Role:      "assistant",
-				Content:   resp.Content,
-				ToolCalls: resp.ToolCalls,
+				Role:           "assistant",
+				Content:        resp.Content,
+				ToolCalls:      resp.ToolCalls,
+				ThinkingBlocks: resp.ThinkingBlocks,
 			}
 			messages = append(messages, assistantMsg)
 
@@ -102,11 +249,22 @@ func (m *SubagentManager) Spawn(ctx context.Context, task, label, originChannel,
 
 			for _, tc := range resp.ToolCalls {
 				slog.Debug("subagent executing tool", "taskID", taskID, "name", tc.Name)
-				toolResult := isolatedTools.Execute(childCtx, tc.Name, json.RawMessage(tc.Arguments))
+				toolResult := isolatedTools.ExecuteResult(childCtx, tc.Name, json.RawMessage(tc.Arguments))
 				messages = append(messages, providers.Message{
 					Role:       "tool",
-					Content:    toolResult,
+					Content:    truncateToolResult(toolResult.Content, maxResult),
 					ToolCallID: tc.ID,
+					IsError:    toolResult.IsError,
+				})
+			}
+
+			if progressGap >= 0 && time.Since(lastReport) >= progressGap && i < maxIter-1 {
+				lastReport = time.Now()
+				m.bus.TryPublishInbound(bus.InboundMessage{
+					Channel:            bus.SystemChannel,
+					Content:            subagentProgress(label, i+1, maxIter, time.Since(started), resp),
+					SessionKeyOverride: sessionKey,
+					Metadata:           subagentMeta(taskID, "progress", originChannel, originChatID),
 				})
 			}
 
@@ -121,29 +279,85 @@ func (m *SubagentManager) Spawn(ctx context.Context, task, label, originChannel,
 			}
 		}
 
+		if ctx.Err() != nil {
+			return
+		}
 		m.bus.PublishInbound(bus.InboundMessage{
-			Channel:            "system",
-			Content:            fmt.Sprintf("[Subagent %q completed]\n\n%s", label, result),
-			SessionKeyOverride: fmt.Sprintf("%s:%s", originChannel, originChatID),
+			Channel:            bus.SystemChannel,
+			Content:            fmt.Sprintf("[Subagent %q completed]\nElapsed: %s\n\n%s", label, roundElapsed(time.Since(started)), result),
+			SessionKeyOverride: sessionKey,
+			Metadata:           subagentMeta(taskID, "completed", originChannel, originChatID),
 		})
 	}()
 
 	return taskID
 }
 
+// subagentMeta labels a message about a subagent task and routes the reply
+// to the conversation that started it.
+func subagentMeta(taskID, event, originChannel, originChatID string) map[string]string {
+	return map[string]string{
+		"subagent_task":       taskID,
+		"subagent_event":      event,
+		bus.MetaOriginChannel: originChannel,
+		bus.MetaOriginChatID:  originChatID,
+	}
+}
+
+// subagentProgress describes a subagent's state after one tool iteration,
+// including any partial text the model produced alongside its tool calls.
+func subagentProgress(label string, step, maxIter int, elapsed time.Duration, resp *providers.ChatResponse) string {
+	names := make([]string, len(resp.ToolCalls))
+	for i, tc := range resp.ToolCalls {
+		names[i] = tc.Name
+	}
+	msg := fmt.Sprintf("[Subagent %q progress]\nStep %d/%d, elapsed %s, ran: %s",
+		label, step, maxIter, roundElapsed(elapsed), strings.Join(names, ", "))
+	if partial := strings.TrimSpace(resp.Content); partial != "" {
+		msg += "\n\n" + partial
+	}
+	return msg
+}
+
+func roundElapsed(d time.Duration) time.Duration {
+	if d < time.Second {
+		return d.Round(time.Millisecond)
+	}
+	return d.Round(time.Second)
+}
+
 // Cancel cancels a running subagent by task ID. Returns true if found.
 func (m *SubagentManager) Cancel(taskID string) bool {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	cancel, ok := m.running[taskID]
+	t, ok := m.running[taskID]
 	if !ok {
 		return false
 	}
-	cancel()
-	delete(m.running, taskID)
+	m.cancelLocked(taskID, t)
+	return true
+}
+
+// CancelFor cancels a running subagent only if it was started from the given
+// channel and chat, so one conversation cannot stop another's tasks.
+func (m *SubagentManager) CancelFor(taskID, channel, chatID string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, ok := m.running[taskID]
+	if !ok || t.channel != channel || t.chatID != chatID {
+		return false
+	}
+	m.cancelLocked(taskID, t)
 	return true
 }
 
+// cancelLocked stops t and forgets it. The caller must hold m.mu.
+func (m *SubagentManager) cancelLocked(taskID string, t runningTask) {
+	t.cancel()
+	delete(m.running, taskID)
+	m.untrackTask(taskID)
+}
+
 // ListRunning returns IDs of currently running subagents.
 func (m *SubagentManager) ListRunning() []string {
 	m.mu.Lock()
@@ -154,3 +368,17 @@ func (m *SubagentManager) ListRunning() []string {
 	}
 	return ids
 }
+
+// ListRunningFor returns IDs of the running subagents started from the given
+// channel and chat.
+func (m *SubagentManager) ListRunningFor(channel, chatID string) []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var ids []string
+	for id, t := range m.running {
+		if t.channel == channel && t.chatID == chatID {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,146 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/coopco/nanobot/internal/bus"
+)
+
+// subagentRecord is a running task as persisted to the store. Tasks are
+// removed from the store when they finish, so whatever is left after a
+// restart was interrupted.
+type subagentRecord struct {
+	ID            string    `json:"id"`
+	Task          string    `json:"task"`
+	Label         string    `json:"label"`
+	OriginChannel string    `json:"originChannel"`
+	OriginChatID  string    `json:"originChatId"`
+	StartedAt     time.Time `json:"startedAt"`
+}
+
+type subagentStore struct {
+	Tasks []subagentRecord `json:"tasks"`
+}
+
+// SetStorePath makes the manager record running tasks in a JSON file at
+// path, so tasks cut short by a restart can be found by Restore. An empty
+// path turns persistence off.
+func (m *SubagentManager) SetStorePath(path string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.storePath = path
+}
+
+// Restore handles the tasks a previous process left unfinished. With respawn
+// set each is started again; otherwise its origin conversation is told it was
+// interrupted. Call it once on startup, before spawning new tasks.
+func (m *SubagentManager) Restore(ctx context.Context, respawn bool) error {
+	m.mu.Lock()
+	path := m.storePath
+	m.mu.Unlock()
+	if path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read subagent store: %w", err)
+	}
+	var store subagentStore
+	if err := json.Unmarshal(data, &store); err != nil {
+		return fmt.Errorf("failed to parse subagent store: %w", err)
+	}
+
+	m.mu.Lock()
+	for _, rec := range store.Tasks {
+		// Keep new task IDs clear of the ones being reported.
+		if n, err := strconv.Atoi(strings.TrimPrefix(rec.ID, "task_")); err == nil && n >= m.counter {
+			m.counter = n + 1
+		}
+	}
+	m.pending = make(map[string]subagentRecord)
+	if err := m.saveTasks(); err != nil {
+		m.mu.Unlock()
+		return err
+	}
+	m.mu.Unlock()
+
+	for _, rec := range store.Tasks {
+		if respawn {
+			id := m.Spawn(ctx, rec.Task, rec.Label, rec.OriginChannel, rec.OriginChatID)
+			slog.Info("subagent task restarted", "previous", rec.ID, "taskID", id, "label", rec.Label)
+			continue
+		}
+		slog.Info("subagent task interrupted by restart", "taskID", rec.ID, "label", rec.Label)
+		m.bus.PublishInbound(bus.InboundMessage{
+			Channel: bus.SystemChannel,
+			Content: fmt.Sprintf("[Subagent %q interrupted]\nThe task was stopped by a restart after starting at %s and did not finish.\n\nTask: %s",
+				rec.Label, rec.StartedAt.Format(time.RFC3339), rec.Task),
+			SessionKeyOverride: fmt.Sprintf("%s:%s", rec.OriginChannel, rec.OriginChatID),
+			Metadata:           subagentMeta(rec.ID, "interrupted", rec.OriginChannel, rec.OriginChatID),
+		})
+	}
+	return nil
+}
+
+// trackTask records a started task in the store. m.mu must be held.
+func (m *SubagentManager) trackTask(rec subagentRecord) {
+	if m.storePath == "" {
+		return
+	}
+	if m.pending == nil {
+		m.pending = make(map[string]subagentRecord)
+	}
+	m.pending[rec.ID] = rec
+	if err := m.saveTasks(); err != nil {
+		slog.Warn("failed to persist subagent task", "taskID", rec.ID, "err", err)
+	}
+}
+
+// untrackTask removes a finished task from the store. m.mu must be held.
+func (m *SubagentManager) untrackTask(id string) {
+	if _, ok := m.pending[id]; !ok {
+		return
+	}
+	delete(m.pending, id)
+	if err := m.saveTasks(); err != nil {
+		slog.Warn("failed to persist subagent task completion", "taskID", id, "err", err)
+	}
+}
+
+// saveTasks writes the pending tasks to the store. m.mu must be held.
+func (m *SubagentManager) saveTasks() error {
+	store := subagentStore{Tasks: make([]subagentRecord, 0, len(m.pending))}
+	for _, rec := range m.pending {
+		store.Tasks = append(store.Tasks, rec)
+	}
+	sort.Slice(store.Tasks, func(i, j int) bool { return store.Tasks[i].StartedAt.Before(store.Tasks[j].StartedAt) })
+
+	data, err := json.MarshalIndent(store, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal subagent store: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(m.storePath), 0o755); err != nil {
+		return fmt.Errorf("failed to create store directory: %w", err)
+	}
+	tmp := m.storePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write subagent store: %w", err)
+	}
+	if err := os.Rename(tmp, m.storePath); err != nil {
+		return fmt.Errorf("failed to write subagent store: %w", err)
+	}
+	return nil
+}
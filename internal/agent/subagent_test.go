@@ -2,12 +2,17 @@ package agent
 
 import (
 	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/coopco/nanobot/internal/bus"
 	"github.com/coopco/nanobot/internal/providers"
+	"github.com/coopco/nanobot/internal/tools"
 )
 
 type mockSubagentProvider struct {
@@ -200,3 +205,397 @@ func drainInbound(mb *bus.MessageBus) <-chan bus.InboundMessage {
 	}()
 	return ch
 }
+
+func TestSubagentToolsSpawnAndCancel(t *testing.T) {
+	blocker := &blockingProvider{ready: make(chan struct{})}
+	mgr, _ := newTestSubagentManager(t, blocker)
+	spawn := tools.NewSpawnSubagentTool(mgr)
+	cancelTool := tools.NewCancelSubagentTool(mgr)
+
+	// The tool call's context ends when the call returns; the subagent must
+	// keep running regardless.
+	callCtx, endCall := context.WithCancel(tools.WithOrigin(context.Background(), "ch", "c1"))
+	result, err := spawn.Execute(callCtx, json.RawMessage(`{"task":"long job","label":"job"}`))
+	endCall()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(result, "task_0") {
+		t.Fatalf("unexpected spawn result %q", result)
+	}
+
+	select {
+	case <-blocker.ready:
+	case <-time.After(3 * time.Second):
+		t.Fatal("subagent never called the provider")
+	}
+	time.Sleep(20 * time.Millisecond)
+	if running := mgr.ListRunning(); len(running) != 1 || running[0] != "task_0" {
+		t.Fatalf("running = %v, want [task_0]", running)
+	}
+
+	if _, err := cancelTool.Execute(tools.WithOrigin(context.Background(), "ch", "c1"), json.RawMessage(`{"task_id":"task_0"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if running := mgr.ListRunning(); len(running) != 0 {
+		t.Errorf("running after cancel = %v, want none", running)
+	}
+}
+
+// scriptedSubagentProvider returns responses from next and records every request.
+type scriptedSubagentProvider struct {
+	mu       sync.Mutex
+	requests []providers.ChatRequest
+	next     func(call int) *providers.ChatResponse
+}
+
+func (p *scriptedSubagentProvider) Chat(_ context.Context, req providers.ChatRequest) (*providers.ChatResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.requests = append(p.requests, req)
+	return p.next(len(p.requests)), nil
+}
+
+func TestSubagentAllowedToolsCannotWrite(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "out.txt")
+	args, _ := json.Marshal(map[string]string{"path": target, "content": "x"})
+
+	prov := &scriptedSubagentProvider{next: func(call int) *providers.ChatResponse {
+		if call == 1 {
+			return &providers.ChatResponse{ToolCalls: []providers.ToolCall{{ID: "w1", Name: "write_file", Arguments: string(args)}}}
+		}
+		return &providers.ChatResponse{Content: "done"}
+	}}
+	mgr, mb := newTestSubagentManager(t, prov)
+	mgr.SetAllowedTools([]string{"read_file", "list_dir"})
+
+	mgr.Spawn(context.Background(), "write a file", "writer", "ch", "c1")
+	select {
+	case <-drainInbound(mb):
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out waiting for subagent completion")
+	}
+
+	prov.mu.Lock()
+	defer prov.mu.Unlock()
+	var offered []string
+	for _, td := range prov.requests[0].Tools {
+		offered = append(offered, td.Function.Name)
+	}
+	if len(offered) != 2 {
+		t.Errorf("subagent offered tools %v, want only read_file and list_dir", offered)
+	}
+	if _, err := os.Stat(target); !os.IsNotExist(err) {
+		t.Errorf("write_file ran despite not being allowed (stat err %v)", err)
+	}
+	last := prov.requests[1].Messages[len(prov.requests[1].Messages)-1]
+	if last.Role != "tool" || !last.IsError {
+		t.Errorf("expected an error tool result, got %+v", last)
+	}
+}
+
+func TestSubagentCannotReadOutsideWorkspaceRoot(t *testing.T) {
+	workspace := t.TempDir()
+	secret := filepath.Join(t.TempDir(), "secret.txt")
+	os.WriteFile(secret, []byte("top secret"), 0644)
+	args, _ := json.Marshal(map[string]string{"path": secret})
+
+	prov := &scriptedSubagentProvider{next: func(call int) *providers.ChatResponse {
+		if call == 1 {
+			return &providers.ChatResponse{ToolCalls: []providers.ToolCall{{ID: "r1", Name: "read_file", Arguments: string(args)}}}
+		}
+		return &providers.ChatResponse{Content: "done"}
+	}}
+	mgr, mb := newTestSubagentManager(t, prov)
+	mgr.SetWorkspaceRoot(workspace)
+
+	mgr.Spawn(context.Background(), "read the secret", "reader", "ch", "c1")
+	select {
+	case <-drainInbound(mb):
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out waiting for subagent completion")
+	}
+
+	prov.mu.Lock()
+	defer prov.mu.Unlock()
+	last := prov.requests[1].Messages[len(prov.requests[1].Messages)-1]
+	if !last.IsError || strings.Contains(last.Content, "top secret") {
+		t.Errorf("read_file outside the workspace returned %q, want an error", last.Content)
+	}
+}
+
+func TestSubagentShellPolicy(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy tools.ShellPolicy
+	}{
+		{"disabled", tools.ShellPolicy{Disabled: true}},
+		{"allow list", tools.ShellPolicy{Allow: []string{"echo"}}},
+		{"deny list", tools.ShellPolicy{Deny: []string{"touch"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			marker := filepath.Join(t.TempDir(), "ran")
+			args, _ := json.Marshal(map[string]string{"command": "touch " + marker})
+			prov := &scriptedSubagentProvider{next: func(call int) *providers.ChatResponse {
+				if call == 1 {
+					return &providers.ChatResponse{ToolCalls: []providers.ToolCall{{ID: "s1", Name: "run_shell", Arguments: string(args)}}}
+				}
+				return &providers.ChatResponse{Content: "done"}
+			}}
+			mgr, mb := newTestSubagentManager(t, prov)
+			mgr.SetShellPolicy(tt.policy)
+
+			mgr.Spawn(context.Background(), "touch a file", "shell", "ch", "c1")
+			select {
+			case <-drainInbound(mb):
+			case <-time.After(3 * time.Second):
+				t.Fatal("timed out waiting for subagent completion")
+			}
+
+			if _, err := os.Stat(marker); err == nil {
+				t.Error("run_shell ran a command the policy forbids")
+			}
+			prov.mu.Lock()
+			defer prov.mu.Unlock()
+			last := prov.requests[1].Messages[len(prov.requests[1].Messages)-1]
+			if !last.IsError {
+				t.Errorf("tool result = %q, want an error", last.Content)
+			}
+		})
+	}
+}
+
+func TestSubagentTruncatesLargeToolResult(t *testing.T) {
+	args, _ := json.Marshal(map[string]string{"text": strings.Repeat("y", 1000)})
+	prov := &scriptedSubagentProvider{next: func(call int) *providers.ChatResponse {
+		if call == 1 {
+			return &providers.ChatResponse{ToolCalls: []providers.ToolCall{{ID: "e1", Name: "echo", Arguments: string(args)}}}
+		}
+		return &providers.ChatResponse{Content: "done"}
+	}}
+	mgr, mb := newTestSubagentManager(t, prov)
+	reg := tools.NewRegistry()
+	reg.Register(&echoTool{})
+	mgr.SetTools(reg)
+	mgr.SetMaxToolResultSize(50)
+
+	mgr.Spawn(context.Background(), "echo a lot", "echo", "ch", "c1")
+	select {
+	case <-drainInbound(mb):
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out waiting for subagent completion")
+	}
+
+	prov.mu.Lock()
+	defer prov.mu.Unlock()
+	last := prov.requests[1].Messages[len(prov.requests[1].Messages)-1]
+	if last.Role != "tool" || !strings.HasSuffix(last.Content, "[output truncated, 956 bytes omitted]") || len(last.Content) > 100 {
+		t.Errorf("tool message = %q, want it truncated with a marker", last.Content)
+	}
+}
+
+func TestSubagentCustomMaxIterations(t *testing.T) {
+	prov := &scriptedSubagentProvider{next: func(call int) *providers.ChatResponse {
+		return &providers.ChatResponse{ToolCalls: []providers.ToolCall{{ID: "l", Name: "list_dir", Arguments: `{"path":"."}`}}}
+	}}
+	mgr, mb := newTestSubagentManager(t, prov)
+	mgr.SetMaxIterations(3)
+
+	mgr.Spawn(context.Background(), "loop forever", "looper", "ch", "c1")
+	select {
+	case <-drainInbound(mb):
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out waiting for subagent completion")
+	}
+
+	prov.mu.Lock()
+	defer prov.mu.Unlock()
+	if len(prov.requests) != 3 {
+		t.Errorf("provider called %d times, want 3", len(prov.requests))
+	}
+}
+
+func TestSubagentReportsProgress(t *testing.T) {
+	prov := &scriptedSubagentProvider{next: func(call int) *providers.ChatResponse {
+		if call < 3 {
+			return &providers.ChatResponse{
+				Content:   "checked part " + string(rune('0'+call)),
+				ToolCalls: []providers.ToolCall{{ID: "l", Name: "list_dir", Arguments: `{"path":"."}`}},
+			}
+		}
+		return &providers.ChatResponse{Content: "all done"}
+	}}
+	mgr, mb := newTestSubagentManager(t, prov)
+	mgr.SetProgressInterval(0)
+
+	taskID := mgr.Spawn(context.Background(), "survey", "surveyor", "ch", "c1")
+
+	var events []string
+	for {
+		var msg bus.InboundMessage
+		select {
+		case msg = <-drainInbound(mb):
+		case <-time.After(3 * time.Second):
+			t.Fatalf("timed out; events so far %v", events)
+		}
+		if msg.Metadata["subagent_task"] != taskID || msg.SessionKeyOverride != "ch:c1" {
+			t.Errorf("message not tagged with task/origin: %+v", msg)
+		}
+		events = append(events, msg.Metadata["subagent_event"])
+		if msg.Metadata["subagent_event"] == "progress" && !strings.Contains(msg.Content, "checked part") {
+			t.Errorf("progress message missing partial result: %q", msg.Content)
+		}
+		if msg.Metadata["subagent_event"] == "completed" {
+			if !strings.Contains(msg.Content, "Elapsed: ") || !strings.Contains(msg.Content, "all done") {
+				t.Errorf("unexpected completion message: %q", msg.Content)
+			}
+			break
+		}
+	}
+
+	if len(events) < 2 || events[0] != "progress" {
+		t.Errorf("events = %v, want progress before completion", events)
+	}
+}
+
+func TestSubagentRestoreReportsInterruptedTask(t *testing.T) {
+	storePath := filepath.Join(t.TempDir(), "subagents.json")
+
+	blocker := &blockingProvider{ready: make(chan struct{})}
+	before, _ := newTestSubagentManager(t, blocker)
+	before.SetStorePath(storePath)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	before.Spawn(ctx, "index the repository", "indexer", "telegram", "chat42")
+	<-blocker.ready
+
+	// A new process starts from the same store while the task is unfinished.
+	after, mb := newTestSubagentManager(t, &mockSubagentProvider{})
+	after.SetStorePath(storePath)
+	if err := after.Restore(context.Background(), false); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+
+	select {
+	case msg := <-drainInbound(mb):
+		if !strings.HasPrefix(msg.Content, `[Subagent "indexer" interrupted]`) || !strings.Contains(msg.Content, "index the repository") {
+			t.Errorf("unexpected report: %s", msg.Content)
+		}
+		if channel, chatID := msg.ReplyTarget(); channel != "telegram" || chatID != "chat42" {
+			t.Errorf("report goes to %s:%s, want telegram:chat42", channel, chatID)
+		}
+		if msg.Metadata["subagent_task"] != "task_0" || msg.Metadata["subagent_event"] != "interrupted" {
+			t.Errorf("metadata = %v", msg.Metadata)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("interrupted task was not reported")
+	}
+
+	// The report is made once, and new tasks do not reuse the old ID.
+	if err := after.Restore(context.Background(), false); err != nil {
+		t.Fatal(err)
+	}
+	if id := after.Spawn(ctx, "next", "next", "telegram", "chat42"); id != "task_1" {
+		t.Errorf("new task ID = %s, want task_1", id)
+	}
+
+	// Let both managers finish writing the store before it is removed.
+	cancel()
+	waitIdle(t, before)
+	waitIdle(t, after)
+}
+
+func TestSubagentRestoreAfterShutdown(t *testing.T) {
+	storePath := filepath.Join(t.TempDir(), "subagents.json")
+
+	blocker := &blockingProvider{ready: make(chan struct{})}
+	before, _ := newTestSubagentManager(t, blocker)
+	before.SetStorePath(storePath)
+	ctx, cancel := context.WithCancel(context.Background())
+	before.Spawn(ctx, "index the repository", "indexer", "telegram", "chat42")
+	<-blocker.ready
+	cancelled := before.Spawn(context.Background(), "cancelled task", "cancelled", "telegram", "chat42")
+	before.Cancel(cancelled)
+
+	// Graceful shutdown cancels the parent context of running tasks.
+	cancel()
+	waitIdle(t, before)
+
+	after, mb := newTestSubagentManager(t, &mockSubagentProvider{})
+	after.SetStorePath(storePath)
+	if err := after.Restore(context.Background(), false); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+
+	select {
+	case msg := <-drainInbound(mb):
+		if !strings.HasPrefix(msg.Content, `[Subagent "indexer" interrupted]`) {
+			t.Errorf("unexpected report: %s", msg.Content)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("task stopped by shutdown was not reported")
+	}
+	select {
+	case msg := <-drainInbound(mb):
+		t.Errorf("unexpected report after the interrupted task: %s", msg.Content)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+// waitIdle waits until m has no running tasks.
+func waitIdle(t *testing.T, m *SubagentManager) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for len(m.ListRunning()) > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("tasks still running: %v", m.ListRunning())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestSubagentRestoreRespawns(t *testing.T) {
+	storePath := filepath.Join(t.TempDir(), "subagents.json")
+	data := `{"tasks":[{"id":"task_3","task":"summarise the logs","label":"logs","originChannel":"slack","originChatId":"C1"}]}`
+	if err := os.WriteFile(storePath, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	mgr, mb := newTestSubagentManager(t, &mockSubagentProvider{
+		responses: []*providers.ChatResponse{{Content: "logs look fine"}},
+	})
+	mgr.SetStorePath(storePath)
+	if err := mgr.Restore(context.Background(), true); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+
+	select {
+	case msg := <-drainInbound(mb):
+		if !strings.HasPrefix(msg.Content, `[Subagent "logs" completed]`) || !strings.Contains(msg.Content, "logs look fine") {
+			t.Errorf("unexpected message: %s", msg.Content)
+		}
+		if msg.Metadata["subagent_task"] != "task_4" {
+			t.Errorf("respawned task ID = %s, want task_4", msg.Metadata["subagent_task"])
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("respawned task did not complete")
+	}
+
+	// A finished task is dropped from the store so it is not run again.
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		data, err := os.ReadFile(storePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(data), `"tasks": []`) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("store still lists tasks:\n%s", data)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
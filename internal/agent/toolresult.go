@@ -0,0 +1,35 @@
+package agent
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// defaultMaxToolResultSize is the largest tool result, in bytes, added to the
+// conversation unless configured otherwise. Every later provider request in
+// the turn carries it again, so one huge file or command output would
+// otherwise make the rest of the turn slow and expensive.
+const defaultMaxToolResultSize = 64 * 1024
+
+// toolResultLimit turns a configured size into the limit truncateToolResult
+// applies: zero means the default and a negative size disables the limit.
+func toolResultLimit(size int) int {
+	if size == 0 {
+		return defaultMaxToolResultSize
+	}
+	return size
+}
+
+// truncateToolResult cuts content to at most limit bytes, on a rune
+// boundary, and notes how much was left out. A limit below one keeps the
+// whole result.
+func truncateToolResult(content string, limit int) string {
+	if limit < 1 || len(content) <= limit {
+		return content
+	}
+	cut := limit
+	for cut > 0 && !utf8.RuneStart(content[cut]) {
+		cut--
+	}
+	return fmt.Sprintf("%s\n[output truncated, %d bytes omitted]", content[:cut], len(content)-cut)
+}
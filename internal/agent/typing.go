@@ -0,0 +1,17 @@
+package agent
+
+// TypingIndicator shows a chat that the agent is working on a reply. The
+// loop calls StartTyping when it begins processing a message from the bus
+// and StopTyping once it is done, whether or not a reply was produced.
+// channels.Manager implements it for channels whose platform has a typing
+// indicator.
+type TypingIndicator interface {
+	StartTyping(channel, chatID string)
+	StopTyping(channel, chatID string)
+}
+
+// noTyping is the TypingIndicator used when none is configured.
+type noTyping struct{}
+
+func (noTyping) StartTyping(channel, chatID string) {}
+func (noTyping) StopTyping(channel, chatID string)  {}
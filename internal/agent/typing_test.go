@@ -0,0 +1,103 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/coopco/nanobot/internal/bus"
+	"github.com/coopco/nanobot/internal/channels"
+	"github.com/coopco/nanobot/internal/providers"
+	"github.com/coopco/nanobot/internal/session"
+	"github.com/coopco/nanobot/internal/tools"
+)
+
+var _ TypingIndicator = (*channels.Manager)(nil)
+
+// eventLog records typing hooks and provider calls in the order they happen.
+type eventLog struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (l *eventLog) add(e string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = append(l.events, e)
+}
+
+func (l *eventLog) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return strings.Join(l.events, ",")
+}
+
+type fakeTyping struct{ log *eventLog }
+
+func (f fakeTyping) StartTyping(channel, chatID string) { f.log.add("start " + channel + ":" + chatID) }
+func (f fakeTyping) StopTyping(channel, chatID string)  { f.log.add("stop " + channel + ":" + chatID) }
+
+// loggingProvider records each call and answers with reply, or fails with err.
+type loggingProvider struct {
+	log   *eventLog
+	reply string
+	err   error
+}
+
+func (p *loggingProvider) Chat(_ context.Context, _ providers.ChatRequest) (*providers.ChatResponse, error) {
+	p.log.add("chat")
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &providers.ChatResponse{Content: p.reply}, nil
+}
+
+func newTypingLoop(t *testing.T, p providers.Provider, typing TypingIndicator) *AgentLoop {
+	t.Helper()
+	return NewAgentLoop(AgentLoopConfig{
+		Bus:           bus.NewMessageBus(10),
+		Provider:      p,
+		Sessions:      session.NewManager(t.TempDir()),
+		Tools:         tools.NewRegistry(),
+		Model:         "test-model",
+		MaxIterations: 5,
+		Typing:        typing,
+	})
+}
+
+func TestProcessMessage_TypingAroundProcessing(t *testing.T) {
+	log := &eventLog{}
+	loop := newTypingLoop(t, &loggingProvider{log: log, reply: "hi"}, fakeTyping{log})
+	loop.processMessage(context.Background(), bus.InboundMessage{Channel: "telegram", ChatID: "42", Content: "hello"})
+
+	if got, want := log.String(), "start telegram:42,chat,stop telegram:42"; got != want {
+		t.Errorf("events = %s, want %s", got, want)
+	}
+}
+
+func TestProcessMessage_TypingStoppedOnError(t *testing.T) {
+	log := &eventLog{}
+	loop := newTypingLoop(t, &loggingProvider{log: log, err: errors.New("boom")}, fakeTyping{log})
+	loop.processMessage(context.Background(), bus.InboundMessage{
+		Channel:  bus.SystemChannel,
+		ChatID:   "cron",
+		Content:  "run job",
+		Metadata: map[string]string{bus.MetaOriginChannel: "slack", bus.MetaOriginChatID: "C1"},
+	})
+
+	// The indicator is shown where the reply goes, not on the system channel.
+	if got, want := log.String(), "start slack:C1,chat,stop slack:C1"; got != want {
+		t.Errorf("events = %s, want %s", got, want)
+	}
+}
+
+func TestProcessMessage_NoTypingConfigured(t *testing.T) {
+	log := &eventLog{}
+	loop := newTypingLoop(t, &loggingProvider{log: log, reply: "hi"}, nil)
+	loop.processMessage(context.Background(), bus.InboundMessage{Channel: "telegram", ChatID: "42", Content: "hello"})
+	if got := log.String(); got != "chat" {
+		t.Errorf("events = %s, want only the provider call", got)
+	}
+}
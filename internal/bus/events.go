@@ -13,6 +13,25 @@ type InboundMessage struct {
 	Metadata           map[string]string // arbitrary metadata
 }
 
+// Metadata keys that redirect the reply to an inbound message. Internal
+// traffic such as cron triggers arrives on SystemChannel and sets them so the
+// reply reaches a real chat.
+const (
+	MetaOriginChannel = "origin_channel"
+	MetaOriginChatID  = "origin_chat_id"
+)
+
+// Metadata keys channels set on inbound messages when the platform supplies
+// them. MetaTimestamp holds the time the message was sent, in RFC 3339.
+// MetaThreadID is the ID of the message that started the thread the message
+// was posted in; the reply is sent with it as ReplyTo to stay in the thread.
+const (
+	MetaSenderName = "sender_name"
+	MetaMessageID  = "message_id"
+	MetaTimestamp  = "timestamp"
+	MetaThreadID   = "thread_id"
+)
+
 // Media represents an attached media item.
 type Media struct {
 	Type     string // "image", "audio", "video", "file"
@@ -30,6 +49,15 @@ func (m InboundMessage) SessionKey() string {
 	return fmt.Sprintf("%s:%s", m.Channel, m.ChatID)
 }
 
+// ReplyTarget returns where a reply to m should be sent: the origin recorded
+// in its metadata if any, otherwise the channel and chat it arrived on.
+func (m InboundMessage) ReplyTarget() (channel, chatID string) {
+	if ch := m.Metadata[MetaOriginChannel]; ch != "" {
+		return ch, m.Metadata[MetaOriginChatID]
+	}
+	return m.Channel, m.ChatID
+}
+
 // OutboundMessage represents a message to be sent to a channel.
 type OutboundMessage struct {
 	Channel  string            // target channel
@@ -38,4 +66,25 @@ type OutboundMessage struct {
 	Type     string            // "text", "progress", "tool_hint", "error"
 	ReplyTo  string            // optional message ID to reply to
 	Metadata map[string]string // arbitrary metadata
+	Model    string            // model that produced a "text" reply, if any
+	Usage    *Usage            // tokens spent on a "text" reply, if known
+	// Attachments are files delivered after Content, such as a report or an
+	// image produced by a tool.
+	Attachments []Attachment
+}
+
+// Attachment is a file sent with an outbound message. Its content is Data,
+// or the local file at Path when Data is empty.
+type Attachment struct {
+	Name     string // file name shown to the recipient; defaults to the base of Path
+	Path     string // local file path
+	MimeType string // detected from the name or content when empty
+	Data     []byte // raw data
+}
+
+// Usage is the token cost of one agent turn, summed over its provider calls.
+type Usage struct {
+	PromptTokens     int
+	CompletionTokens int
+	TotalTokens      int
 }
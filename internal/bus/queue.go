@@ -2,16 +2,81 @@ package bus
 
 import (
 	"context"
+	"errors"
+	"slices"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
+// SystemChannel is the inbound channel name used for internal traffic such as
+// cron triggers and subagent results. These messages travel on a priority
+// lane so user traffic cannot starve them.
+const SystemChannel = "system"
+
+// ErrDraining is returned when an inbound message is published after Drain
+// has been called.
+var ErrDraining = errors.New("bus is draining and accepts no new inbound messages")
+
+// ErrBusClosed is returned by ConsumeInbound once the bus has been closed, or
+// drained and no inbound messages remain.
+var ErrBusClosed = errors.New("bus is closed")
+
+// drainPollInterval is how often Drain checks whether the queues are empty.
+const drainPollInterval = 10 * time.Millisecond
+
 // MessageBus is a hub-and-spoke message bus using Go channels.
 type MessageBus struct {
 	inbound  chan InboundMessage
+	system   chan InboundMessage // priority lane for SystemChannel messages
 	outbound chan OutboundMessage
 	subs     map[string][]func(OutboundMessage) // channel name -> subscribers
+	dead     func(OutboundMessage)              // receives messages no subscriber matched; may be nil
 	mu       sync.RWMutex
 	bufSize  int
+	draining atomic.Bool   // set by Drain; inbound publishes are refused
+	drainCh  chan struct{} // closed by Drain to wake idle consumers
+	drainOne sync.Once
+	// outPending counts outbound messages being published or queued, or
+	// being delivered by DispatchOutbound.
+	outPending atomic.Int64
+
+	inCounters  queueCounters
+	sysCounters queueCounters
+	outCounters queueCounters
+}
+
+// queueCounters tracks lifetime totals for one direction of the bus.
+type queueCounters struct {
+	published atomic.Uint64
+	consumed  atomic.Uint64
+	dropped   atomic.Uint64
+}
+
+func (c *queueCounters) snapshot(length, capacity int) QueueStats {
+	return QueueStats{
+		Len:       length,
+		Cap:       capacity,
+		Published: c.published.Load(),
+		Consumed:  c.consumed.Load(),
+		Dropped:   c.dropped.Load(),
+	}
+}
+
+// QueueStats describes the state of one direction of the bus.
+type QueueStats struct {
+	Len       int    // messages currently buffered
+	Cap       int    // buffer capacity
+	Published uint64 // total messages accepted onto the queue
+	Consumed  uint64 // total messages taken off the queue
+	Dropped   uint64 // total messages rejected because the buffer was full
+}
+
+// Stats is a point-in-time snapshot of the bus queues.
+type Stats struct {
+	Inbound  QueueStats // user traffic
+	System   QueueStats // inbound priority lane for SystemChannel messages
+	Outbound QueueStats
 }
 
 // NewMessageBus creates a new MessageBus with the given buffer size.
@@ -22,33 +87,166 @@ func NewMessageBus(bufSize int) *MessageBus {
 	}
 	return &MessageBus{
 		inbound:  make(chan InboundMessage, bufSize),
+		system:   make(chan InboundMessage, bufSize),
 		outbound: make(chan OutboundMessage, bufSize),
 		subs:     make(map[string][]func(OutboundMessage)),
+		drainCh:  make(chan struct{}),
 		bufSize:  bufSize,
 	}
 }
 
-// PublishInbound sends an inbound message onto the bus.
+// inboundLane returns the queue and counters msg is published to.
+func (b *MessageBus) inboundLane(msg InboundMessage) (chan InboundMessage, *queueCounters) {
+	if msg.Channel == SystemChannel {
+		return b.system, &b.sysCounters
+	}
+	return b.inbound, &b.inCounters
+}
+
+// PublishInbound sends an inbound message onto the bus, blocking while the
+// buffer is full. While the bus is draining the message is counted as dropped.
 func (b *MessageBus) PublishInbound(msg InboundMessage) {
-	b.inbound <- msg
+	lane, counters := b.inboundLane(msg)
+	if b.draining.Load() {
+		counters.dropped.Add(1)
+		return
+	}
+	lane <- msg
+	counters.published.Add(1)
 }
 
-// PublishOutbound sends an outbound message onto the bus.
+// PublishOutbound sends an outbound message onto the bus, blocking while the
+// buffer is full.
 func (b *MessageBus) PublishOutbound(msg OutboundMessage) {
+	b.outPending.Add(1)
 	b.outbound <- msg
+	b.outCounters.published.Add(1)
 }
 
-// ConsumeInbound blocks until an inbound message is available or ctx is cancelled.
+// PublishInboundContext sends an inbound message onto the bus, blocking until
+// there is room in the buffer or ctx is cancelled. It returns ErrDraining once
+// Drain has been called.
+func (b *MessageBus) PublishInboundContext(ctx context.Context, msg InboundMessage) error {
+	lane, counters := b.inboundLane(msg)
+	if b.draining.Load() {
+		counters.dropped.Add(1)
+		return ErrDraining
+	}
+	select {
+	case lane <- msg:
+		counters.published.Add(1)
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// PublishOutboundContext sends an outbound message onto the bus, blocking until
+// there is room in the buffer or ctx is cancelled.
+func (b *MessageBus) PublishOutboundContext(ctx context.Context, msg OutboundMessage) error {
+	b.outPending.Add(1)
+	select {
+	case b.outbound <- msg:
+		b.outCounters.published.Add(1)
+		return nil
+	case <-ctx.Done():
+		b.outPending.Add(-1)
+		return ctx.Err()
+	}
+}
+
+// TryPublishInbound sends an inbound message without blocking. It reports
+// false, and counts the message as dropped, if the buffer is full or the bus
+// is draining.
+func (b *MessageBus) TryPublishInbound(msg InboundMessage) bool {
+	lane, counters := b.inboundLane(msg)
+	if b.draining.Load() {
+		counters.dropped.Add(1)
+		return false
+	}
+	select {
+	case lane <- msg:
+		counters.published.Add(1)
+		return true
+	default:
+		counters.dropped.Add(1)
+		return false
+	}
+}
+
+// TryPublishOutbound sends an outbound message without blocking. It reports
+// false, and counts the message as dropped, if the buffer is full.
+func (b *MessageBus) TryPublishOutbound(msg OutboundMessage) bool {
+	b.outPending.Add(1)
+	select {
+	case b.outbound <- msg:
+		b.outCounters.published.Add(1)
+		return true
+	default:
+		b.outPending.Add(-1)
+		b.outCounters.dropped.Add(1)
+		return false
+	}
+}
+
+// ConsumeInbound blocks until an inbound message is available or ctx is
+// cancelled. Queued SystemChannel messages are returned before user traffic.
+// Once the bus is closed, or draining with nothing left to consume, it returns
+// ErrBusClosed.
 func (b *MessageBus) ConsumeInbound(ctx context.Context) (InboundMessage, error) {
+	if msg, ok, err := b.tryConsumeInbound(); ok || err != nil {
+		return msg, err
+	}
+
 	select {
+	case msg, ok := <-b.system:
+		if ok {
+			b.sysCounters.consumed.Add(1)
+			return msg, nil
+		}
 	case msg, ok := <-b.inbound:
-		if !ok {
-			return InboundMessage{}, context.Canceled
+		if ok {
+			b.inCounters.consumed.Add(1)
+			return msg, nil
 		}
-		return msg, nil
+	case <-b.drainCh:
 	case <-ctx.Done():
 		return InboundMessage{}, ctx.Err()
 	}
+	// The bus was closed or drained while waiting; hand out what is left.
+	if msg, ok, err := b.tryConsumeInbound(); ok || err != nil {
+		return msg, err
+	}
+	return InboundMessage{}, ErrBusClosed
+}
+
+// tryConsumeInbound takes a queued inbound message without blocking, system
+// lane first. It reports false with a nil error if both lanes are empty and
+// the bus is neither closed nor draining.
+func (b *MessageBus) tryConsumeInbound() (InboundMessage, bool, error) {
+	closed := false
+	select {
+	case msg, ok := <-b.system:
+		if ok {
+			b.sysCounters.consumed.Add(1)
+			return msg, true, nil
+		}
+		closed = true
+	default:
+	}
+	select {
+	case msg, ok := <-b.inbound:
+		if ok {
+			b.inCounters.consumed.Add(1)
+			return msg, true, nil
+		}
+		closed = true
+	default:
+	}
+	if closed || b.draining.Load() {
+		return InboundMessage{}, false, ErrBusClosed
+	}
+	return InboundMessage{}, false, nil
 }
 
 // Subscribe registers fn to receive outbound messages for the given channel.
@@ -59,6 +257,42 @@ func (b *MessageBus) Subscribe(channel string, fn func(OutboundMessage)) {
 	b.subs[channel] = append(b.subs[channel], fn)
 }
 
+// SetDeadLetter registers fn to receive outbound messages that match no
+// subscriber, e.g. a reply addressed to a channel that is not running, and
+// those passed to DeadLetter. Only one handler is kept; nil removes it.
+func (b *MessageBus) SetDeadLetter(fn func(OutboundMessage)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.dead = fn
+}
+
+// DeadLetter hands msg to the dead-letter handler, if one is set. Wildcard
+// subscribers that route messages themselves call it for a message no
+// channel takes.
+func (b *MessageBus) DeadLetter(msg OutboundMessage) {
+	b.mu.RLock()
+	dead := b.dead
+	b.mu.RUnlock()
+	if dead != nil {
+		dead(msg)
+	}
+}
+
+// SubscribeMulti registers fn to receive outbound messages for each of the
+// given channels. Duplicate names are ignored so fn is called once per message.
+func (b *MessageBus) SubscribeMulti(channels []string, fn func(OutboundMessage)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	seen := make(map[string]bool, len(channels))
+	for _, ch := range channels {
+		if seen[ch] {
+			continue
+		}
+		seen[ch] = true
+		b.subs[ch] = append(b.subs[ch], fn)
+	}
+}
+
 // DispatchOutbound runs in a goroutine, reading outbound messages and
 // delivering them to matching subscribers. Returns when ctx is cancelled
 // or the outbound channel is closed.
@@ -69,30 +303,72 @@ func (b *MessageBus) DispatchOutbound(ctx context.Context) {
 			if !ok {
 				return
 			}
+			b.outCounters.consumed.Add(1)
 			b.dispatch(msg)
+			b.outPending.Add(-1)
 		case <-ctx.Done():
 			return
 		}
 	}
 }
 
-// dispatch delivers msg to all matching subscribers (channel-specific + wildcard).
+// dispatch delivers msg to all matching subscribers (channel-specific +
+// wildcard), or to the dead-letter handler if there are none. The lock is
+// released before any of them runs, so they may call back into the bus.
 func (b *MessageBus) dispatch(msg OutboundMessage) {
 	b.mu.RLock()
-	defer b.mu.RUnlock()
+	// channel-specific subscribers, then wildcard ones (empty string = all channels)
+	fns := append(slices.Clone(b.subs[msg.Channel]), b.subs[""]...)
+	dead := b.dead
+	b.mu.RUnlock()
 
-	// channel-specific subscribers
-	for _, fn := range b.subs[msg.Channel] {
+	for _, fn := range fns {
 		fn(msg)
 	}
-	// wildcard subscribers (empty string = all channels)
-	for _, fn := range b.subs[""] {
-		fn(msg)
+	if len(fns) == 0 && dead != nil {
+		dead(msg)
+	}
+}
+
+// Stats returns a snapshot of queue depths and lifetime message counts.
+func (b *MessageBus) Stats() Stats {
+	return Stats{
+		Inbound:  b.inCounters.snapshot(len(b.inbound), cap(b.inbound)),
+		System:   b.sysCounters.snapshot(len(b.system), cap(b.system)),
+		Outbound: b.outCounters.snapshot(len(b.outbound), cap(b.outbound)),
+	}
+}
+
+// Drain prepares the bus for shutdown. It stops accepting inbound messages and
+// waits until consumers have taken every buffered inbound message and the
+// dispatcher has delivered every queued outbound one. Outbound publishes are
+// still accepted, so replies to drained messages get through. Drain returns
+// ctx's error if the queues are not empty by the time ctx is done. Once the
+// inbound queues are empty, ConsumeInbound returns ErrBusClosed.
+//
+// The bus cannot tell when a consumer finishes with a message it has taken;
+// replies published after Drain returns are delivered only while
+// DispatchOutbound keeps running.
+func (b *MessageBus) Drain(ctx context.Context) error {
+	b.draining.Store(true)
+	b.drainOne.Do(func() { close(b.drainCh) })
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+	for {
+		if len(b.inbound) == 0 && len(b.system) == 0 && b.outPending.Load() == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
 	}
 }
 
-// Close closes both the inbound and outbound channels.
+// Close closes the inbound lanes and the outbound channel.
 func (b *MessageBus) Close() {
 	close(b.inbound)
+	close(b.system)
 	close(b.outbound)
 }
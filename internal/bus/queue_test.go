@@ -2,6 +2,7 @@ package bus
 
 import (
 	"context"
+	"errors"
 	"sync"
 	"testing"
 	"time"
@@ -96,6 +97,120 @@ func TestConsumeInboundCancellation(t *testing.T) {
 	}
 }
 
+func TestConsumeInboundAfterClose(t *testing.T) {
+	b := NewMessageBus(10)
+	b.PublishInbound(InboundMessage{Channel: "telegram", Content: "queued"})
+	b.Close()
+
+	if msg, err := b.ConsumeInbound(context.Background()); err != nil || msg.Content != "queued" {
+		t.Fatalf("got %+v, %v; want the queued message first", msg, err)
+	}
+	if _, err := b.ConsumeInbound(context.Background()); !errors.Is(err, ErrBusClosed) {
+		t.Errorf("err = %v, want ErrBusClosed", err)
+	}
+}
+
+func TestConsumeInboundAfterDrain(t *testing.T) {
+	b := NewMessageBus(10)
+	errc := make(chan error, 1)
+	go func() {
+		_, err := b.ConsumeInbound(context.Background())
+		errc <- err
+	}()
+
+	if err := b.Drain(context.Background()); err != nil {
+		t.Fatalf("Drain: %v", err)
+	}
+	select {
+	case err := <-errc:
+		if !errors.Is(err, ErrBusClosed) {
+			t.Errorf("blocked consumer err = %v, want ErrBusClosed", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("blocked consumer was not woken by Drain")
+	}
+	if _, err := b.ConsumeInbound(context.Background()); !errors.Is(err, ErrBusClosed) {
+		t.Errorf("err = %v, want ErrBusClosed", err)
+	}
+}
+
+func TestPublishInboundContextBackpressure(t *testing.T) {
+	b := NewMessageBus(1)
+	ctx := context.Background()
+
+	if err := b.PublishInboundContext(ctx, InboundMessage{Content: "first"}); err != nil {
+		t.Fatalf("first publish: %v", err)
+	}
+
+	published := make(chan error, 1)
+	go func() {
+		published <- b.PublishInboundContext(ctx, InboundMessage{Content: "second"})
+	}()
+
+	select {
+	case err := <-published:
+		t.Fatalf("publish to a full buffer returned early: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if msg, err := b.ConsumeInbound(ctx); err != nil || msg.Content != "first" {
+		t.Fatalf("consume = %+v, %v", msg, err)
+	}
+
+	select {
+	case err := <-published:
+		if err != nil {
+			t.Fatalf("second publish: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("publish did not unblock after consume")
+	}
+
+	if msg, err := b.ConsumeInbound(ctx); err != nil || msg.Content != "second" {
+		t.Fatalf("consume = %+v, %v", msg, err)
+	}
+}
+
+func TestPublishContextCancellation(t *testing.T) {
+	b := NewMessageBus(1)
+	b.PublishInbound(InboundMessage{Content: "fill"})
+	b.PublishOutbound(OutboundMessage{Content: "fill"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if err := b.PublishInboundContext(ctx, InboundMessage{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("PublishInboundContext err = %v, want deadline exceeded", err)
+	}
+	if err := b.PublishOutboundContext(ctx, OutboundMessage{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("PublishOutboundContext err = %v, want deadline exceeded", err)
+	}
+}
+
+func TestStatsReflectDrops(t *testing.T) {
+	b := NewMessageBus(2)
+
+	for i := 0; i < 5; i++ {
+		b.TryPublishInbound(InboundMessage{Content: "in"})
+	}
+	if ok := b.TryPublishOutbound(OutboundMessage{Content: "out"}); !ok {
+		t.Fatal("expected outbound publish to succeed")
+	}
+	if _, err := b.ConsumeInbound(context.Background()); err != nil {
+		t.Fatalf("consume: %v", err)
+	}
+
+	got := b.Stats()
+	wantIn := QueueStats{Len: 1, Cap: 2, Published: 2, Consumed: 1, Dropped: 3}
+	if got.Inbound != wantIn {
+		t.Errorf("Inbound = %+v, want %+v", got.Inbound, wantIn)
+	}
+	wantOut := QueueStats{Len: 1, Cap: 2, Published: 1}
+	if got.Outbound != wantOut {
+		t.Errorf("Outbound = %+v, want %+v", got.Outbound, wantOut)
+	}
+}
+
 func TestSubscribeAll(t *testing.T) {
 	b := NewMessageBus(10)
 	ctx, cancel := context.WithCancel(context.Background())
@@ -142,6 +257,46 @@ func TestSubscribeAll(t *testing.T) {
 	}
 }
 
+func TestSubscribeMulti(t *testing.T) {
+	b := NewMessageBus(10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mu sync.Mutex
+	counts := make(map[string]int)
+	b.SubscribeMulti([]string{"telegram", "slack", "telegram"}, func(msg OutboundMessage) {
+		mu.Lock()
+		counts[msg.Channel]++
+		mu.Unlock()
+	})
+
+	// a wildcard subscriber tells us when every message has been dispatched
+	var total sync.WaitGroup
+	total.Add(3)
+	b.Subscribe("", func(OutboundMessage) { total.Done() })
+
+	go b.DispatchOutbound(ctx)
+
+	for _, ch := range []string{"telegram", "slack", "discord"} {
+		b.PublishOutbound(OutboundMessage{Channel: ch, Content: "msg"})
+	}
+
+	done := make(chan struct{})
+	go func() { total.Wait(); close(done) }()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for dispatch")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := map[string]int{"telegram": 1, "slack": 1}
+	if len(counts) != len(want) || counts["telegram"] != 1 || counts["slack"] != 1 {
+		t.Errorf("counts = %v, want %v", counts, want)
+	}
+}
+
 func TestSessionKey(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -174,3 +329,131 @@ func TestSessionKey(t *testing.T) {
 		})
 	}
 }
+
+func TestSystemMessagesJumpQueue(t *testing.T) {
+	b := NewMessageBus(10)
+	for i := 0; i < 10; i++ {
+		if !b.TryPublishInbound(InboundMessage{Channel: "telegram", Content: "user"}) {
+			t.Fatalf("user message %d dropped", i)
+		}
+	}
+	// The user lane is full, but system traffic has its own lane.
+	if !b.TryPublishInbound(InboundMessage{Channel: SystemChannel, Content: "cron"}) {
+		t.Fatal("system message dropped while user lane was full")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	got, err := b.ConsumeInbound(ctx)
+	if err != nil {
+		t.Fatalf("consume: %v", err)
+	}
+	if got.Content != "cron" {
+		t.Fatalf("first consumed = %q, want the system message", got.Content)
+	}
+	for i := 0; i < 10; i++ {
+		got, err := b.ConsumeInbound(ctx)
+		if err != nil {
+			t.Fatalf("consume %d: %v", i, err)
+		}
+		if got.Content != "user" {
+			t.Errorf("message %d = %q, want user", i, got.Content)
+		}
+	}
+
+	stats := b.Stats()
+	if stats.System.Published != 1 || stats.System.Consumed != 1 {
+		t.Errorf("System = %+v, want one published and consumed", stats.System)
+	}
+	if stats.Inbound.Published != 10 || stats.Inbound.Consumed != 10 {
+		t.Errorf("Inbound = %+v, want ten published and consumed", stats.Inbound)
+	}
+}
+
+func TestDeadLetter(t *testing.T) {
+	b := NewMessageBus(10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dead := make(chan OutboundMessage, 2)
+	b.SetDeadLetter(func(msg OutboundMessage) { dead <- msg })
+	b.Subscribe("telegram", func(OutboundMessage) {})
+
+	go b.DispatchOutbound(ctx)
+
+	b.PublishOutbound(OutboundMessage{Channel: "telegram", ChatID: "c1", Content: "delivered"})
+	b.PublishOutbound(OutboundMessage{Channel: "nowhere", ChatID: "c2", Content: "lost"})
+
+	select {
+	case msg := <-dead:
+		if msg.Channel != "nowhere" || msg.Content != "lost" {
+			t.Errorf("dead letter = %+v, want the unroutable message", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("dead-letter handler was not called")
+	}
+	select {
+	case msg := <-dead:
+		t.Errorf("unexpected dead letter: %+v", msg)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestDrain(t *testing.T) {
+	b := NewMessageBus(16)
+	for i := 0; i < 5; i++ {
+		b.PublishInbound(InboundMessage{Channel: "telegram", Content: "msg"})
+	}
+	b.PublishInbound(InboundMessage{Channel: SystemChannel, Content: "tick"})
+
+	var consumed sync.WaitGroup
+	consumed.Add(1)
+	got := 0
+	go func() {
+		defer consumed.Done()
+		for got < 6 {
+			if _, err := b.ConsumeInbound(context.Background()); err != nil {
+				return
+			}
+			got++
+			time.Sleep(5 * time.Millisecond)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := b.Drain(ctx); err != nil {
+		t.Fatalf("Drain: %v", err)
+	}
+	if s := b.Stats(); s.Inbound.Consumed != 5 || s.System.Consumed != 1 {
+		t.Fatalf("Drain returned before all messages were consumed: %+v", s)
+	}
+	consumed.Wait()
+
+	// New inbound messages are refused once draining.
+	if err := b.PublishInboundContext(ctx, InboundMessage{Channel: "telegram"}); !errors.Is(err, ErrDraining) {
+		t.Errorf("PublishInboundContext err = %v, want ErrDraining", err)
+	}
+	if b.TryPublishInbound(InboundMessage{Channel: "telegram"}) {
+		t.Error("TryPublishInbound accepted a message while draining")
+	}
+	b.PublishInbound(InboundMessage{Channel: "telegram"})
+	if s := b.Stats(); s.Inbound.Len != 0 || s.Inbound.Dropped != 3 {
+		t.Errorf("inbound stats after drain = %+v, want 3 dropped", s.Inbound)
+	}
+	// Outbound replies are still accepted.
+	if !b.TryPublishOutbound(OutboundMessage{Channel: "telegram"}) {
+		t.Error("outbound publish refused while draining")
+	}
+}
+
+func TestDrainTimeout(t *testing.T) {
+	b := NewMessageBus(4)
+	b.PublishInbound(InboundMessage{Channel: "telegram"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	if err := b.Drain(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Drain err = %v, want DeadlineExceeded", err)
+	}
+}
@@ -0,0 +1,70 @@
+package channels
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/coopco/nanobot/internal/bus"
+)
+
+// FileSender is implemented by channels that can deliver files. The manager
+// sends each attachment of an outbound message with SendFile after its text;
+// other channels get a note naming the file instead.
+type FileSender interface {
+	// SendFile delivers file to chatID. The file's Name, MimeType and Data
+	// are always set.
+	SendFile(chatID string, file bus.Attachment) error
+}
+
+// maxAttachmentSize is the largest file the manager will load for sending.
+// Platforms set their own, usually lower, limits.
+const maxAttachmentSize = 50 << 20
+
+// loadAttachment returns a with its content read into Data and its Name and
+// MimeType filled in.
+func loadAttachment(a bus.Attachment) (bus.Attachment, error) {
+	if len(a.Data) == 0 {
+		if a.Path == "" {
+			return a, fmt.Errorf("attachment %q has neither data nor a path", a.Name)
+		}
+		info, err := os.Stat(a.Path)
+		if err != nil {
+			return a, fmt.Errorf("read attachment: %w", err)
+		}
+		if info.Size() > maxAttachmentSize {
+			return a, fmt.Errorf("attachment %s is %d bytes, over the %d byte limit", a.Path, info.Size(), maxAttachmentSize)
+		}
+		data, err := os.ReadFile(a.Path)
+		if err != nil {
+			return a, fmt.Errorf("read attachment: %w", err)
+		}
+		a.Data = data
+	}
+	a.Name = attachmentName(a)
+	if a.MimeType == "" {
+		a.MimeType = mime.TypeByExtension(filepath.Ext(a.Name))
+	}
+	if a.MimeType == "" {
+		a.MimeType = http.DetectContentType(a.Data)
+	}
+	return a, nil
+}
+
+// attachmentName is the name shown for a in notes and logs.
+func attachmentName(a bus.Attachment) string {
+	switch {
+	case a.Name != "":
+		return a.Name
+	case a.Path != "":
+		return filepath.Base(a.Path)
+	}
+	return "attachment"
+}
+
+// attachmentNote stands in for a file the channel cannot deliver.
+func attachmentNote(a bus.Attachment) string {
+	return fmt.Sprintf("[File %q could not be sent on this channel.]", attachmentName(a))
+}
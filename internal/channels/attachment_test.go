@@ -0,0 +1,99 @@
+package channels
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/coopco/nanobot/internal/bus"
+)
+
+// fileChannel is a mockChannel that can also send files.
+type fileChannel struct {
+	mockChannel
+	files []bus.Attachment
+}
+
+func (f *fileChannel) SendFile(chatID string, file bus.Attachment) error {
+	f.files = append(f.files, file)
+	return nil
+}
+
+func TestLoadAttachmentFromPath(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.pdf")
+	os.WriteFile(path, []byte("%PDF-1.4"), 0o644)
+
+	got, err := loadAttachment(bus.Attachment{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "report.pdf" || got.MimeType != "application/pdf" || string(got.Data) != "%PDF-1.4" {
+		t.Errorf("got name %q, type %q, data %q", got.Name, got.MimeType, got.Data)
+	}
+}
+
+func TestLoadAttachmentSniffsType(t *testing.T) {
+	got, err := loadAttachment(bus.Attachment{Data: []byte("\x89PNG\r\n\x1a\n")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "attachment" || got.MimeType != "image/png" {
+		t.Errorf("got name %q, type %q", got.Name, got.MimeType)
+	}
+}
+
+func TestLoadAttachmentErrors(t *testing.T) {
+	if _, err := loadAttachment(bus.Attachment{Name: "empty"}); err == nil {
+		t.Error("expected error for attachment without data or path")
+	}
+	if _, err := loadAttachment(bus.Attachment{Path: filepath.Join(t.TempDir(), "missing.txt")}); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
+
+func TestSendPartsSendsAttachments(t *testing.T) {
+	ch := &fileChannel{mockChannel: mockChannel{name: "files"}}
+	sendParts(ch, bus.OutboundMessage{
+		ChatID:      "c",
+		Content:     "here you go",
+		Attachments: []bus.Attachment{{Name: "a.txt", Data: []byte("a")}, {Name: "b.csv", Data: []byte("b")}},
+	})
+
+	if len(ch.sent) != 1 || ch.sent[0].Content != "here you go" || ch.sent[0].Attachments != nil {
+		t.Fatalf("unexpected text sends %+v", ch.sent)
+	}
+	if len(ch.files) != 2 || ch.files[0].Name != "a.txt" || ch.files[1].MimeType != "text/csv; charset=utf-8" {
+		t.Errorf("unexpected files %+v", ch.files)
+	}
+}
+
+func TestSendPartsAttachmentOnly(t *testing.T) {
+	ch := &fileChannel{mockChannel: mockChannel{name: "files"}}
+	sendParts(ch, bus.OutboundMessage{ChatID: "c", Attachments: []bus.Attachment{{Name: "a.txt", Data: []byte("a")}}})
+	if len(ch.sent) != 0 || len(ch.files) != 1 {
+		t.Errorf("sent %d texts and %d files, want 0 and 1", len(ch.sent), len(ch.files))
+	}
+}
+
+func TestSendPartsAttachmentFallsBackToNote(t *testing.T) {
+	plain := &mockChannel{name: "plain"}
+	sendParts(plain, bus.OutboundMessage{
+		ChatID:      "c",
+		Content:     "report attached",
+		Attachments: []bus.Attachment{{Path: "/tmp/out/report.pdf"}},
+	})
+	if len(plain.sent) != 2 {
+		t.Fatalf("sent %d messages, want text and note", len(plain.sent))
+	}
+	if note := plain.sent[1].Content; !strings.Contains(note, `"report.pdf"`) || plain.sent[1].ChatID != "c" {
+		t.Errorf("unexpected note %+v", plain.sent[1])
+	}
+
+	// A file that cannot be read is noted even on channels that send files.
+	files := &fileChannel{mockChannel: mockChannel{name: "files"}}
+	sendParts(files, bus.OutboundMessage{Attachments: []bus.Attachment{{Path: filepath.Join(t.TempDir(), "gone.txt")}}})
+	if len(files.files) != 0 || len(files.sent) != 1 || !strings.Contains(files.sent[0].Content, "gone.txt") {
+		t.Errorf("sent %+v and files %+v", files.sent, files.files)
+	}
+}
@@ -3,6 +3,7 @@ package channels
 import (
 	"context"
 	"encoding/json"
+	"time"
 
 	"github.com/coopco/nanobot/internal/bus"
 )
@@ -40,3 +41,20 @@ func RegisteredNames() []string {
 	}
 	return names
 }
+
+// inboundMeta builds the metadata for an inbound message from the platform's
+// message ID, the sender's display name and the time the message was sent.
+// Values the platform did not supply are left out.
+func inboundMeta(messageID, senderName string, sent time.Time) map[string]string {
+	meta := make(map[string]string)
+	if messageID != "" {
+		meta[bus.MetaMessageID] = messageID
+	}
+	if senderName != "" {
+		meta[bus.MetaSenderName] = senderName
+	}
+	if !sent.IsZero() {
+		meta[bus.MetaTimestamp] = sent.UTC().Format(time.RFC3339)
+	}
+	return meta
+}
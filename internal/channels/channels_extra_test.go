@@ -4,13 +4,18 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"encoding/pem"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/bwmarrin/discordgo"
+
 	"github.com/coopco/nanobot/internal/bus"
 )
 
@@ -192,6 +197,27 @@ func TestDingTalkHandleEvent_Message(t *testing.T) {
 	}
 }
 
+func TestDingTalkHandleEvent_Metadata(t *testing.T) {
+	msgBus := bus.NewMessageBus(4)
+	ch, _ := newDingTalkChannel(json.RawMessage(`{"clientId":"cid","clientSecret":"csec"}`), msgBus)
+	dc := ch.(*DingTalkChannel)
+
+	body := `{"msgtype":"text","msgId":"msg_1","text":{"content":"hi"},"senderId":"s1","senderNick":"Li Lei","conversationId":"c1","createAt":1700000000123}`
+	dc.handleEvent(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	msg, err := msgBus.ConsumeInbound(ctx)
+	if err != nil {
+		t.Fatalf("expected inbound message: %v", err)
+	}
+	assertMeta(t, msg.Metadata, map[string]string{
+		bus.MetaMessageID:  "msg_1",
+		bus.MetaSenderName: "Li Lei",
+		bus.MetaTimestamp:  "2023-11-14T22:13:20Z",
+	})
+}
+
 func TestDingTalkHandleEvent_DisallowedUser(t *testing.T) {
 	msgBus := bus.NewMessageBus(4)
 	cfg := `{"clientId":"cid","clientSecret":"csec","allowedUsers":["allowed"]}`
@@ -276,6 +302,27 @@ func TestQQHandleEvent_Message(t *testing.T) {
 	}
 }
 
+func TestQQHandleEvent_Metadata(t *testing.T) {
+	msgBus := bus.NewMessageBus(4)
+	ch, _ := newQQChannel(json.RawMessage(`{"appId":"aid","token":"tok","appSecret":"sec"}`), msgBus)
+	qc := ch.(*QQChannel)
+
+	body := `{"op":0,"t":"AT_MESSAGE_CREATE","d":{"id":"m1","channel_id":"ch1","author":{"id":"a1","username":"xiaoming"},"content":"hi","timestamp":"2023-11-15T06:13:20+08:00"}}`
+	qc.handleEvent(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	msg, err := msgBus.ConsumeInbound(ctx)
+	if err != nil {
+		t.Fatalf("expected inbound message: %v", err)
+	}
+	assertMeta(t, msg.Metadata, map[string]string{
+		bus.MetaMessageID:  "m1",
+		bus.MetaSenderName: "xiaoming",
+		bus.MetaTimestamp:  "2023-11-14T22:13:20Z",
+	})
+}
+
 func TestQQHandleEvent_NonMessageOp(t *testing.T) {
 	cfg := `{"appId":"aid","token":"tok","appSecret":"sec"}`
 	ch, _ := newQQChannel(json.RawMessage(cfg), bus.NewMessageBus(4))
@@ -386,6 +433,27 @@ func TestParseIMAPFetch_SkipsIMAPLines(t *testing.T) {
 	}
 }
 
+func TestParseIMAPMeta(t *testing.T) {
+	lines := []string{
+		"* 1 FETCH (BODY[HEADER.FIELDS (FROM SUBJECT MESSAGE-ID DATE)] {120}",
+		"From: Jane Doe <jane@test.com>",
+		"Subject: Hi",
+		"Message-ID: <abc123@test.com>",
+		"Date: Tue, 14 Nov 2023 22:13:20 +0000",
+		"",
+		"Date: not a header",
+	}
+	assertMeta(t, parseIMAPMeta(lines), map[string]string{
+		bus.MetaMessageID:  "<abc123@test.com>",
+		bus.MetaSenderName: "Jane Doe",
+		bus.MetaTimestamp:  "2023-11-14T22:13:20Z",
+	})
+
+	if meta := parseIMAPMeta([]string{"From: jane@test.com", ""}); len(meta) != 0 {
+		t.Errorf("metadata = %v, want none for a bare address", meta)
+	}
+}
+
 // --- Mochat ---
 
 func TestNewMochatChannel(t *testing.T) {
@@ -406,6 +474,29 @@ func TestNewMochatChannel(t *testing.T) {
 	}
 }
 
+func TestMochatSend_CustomCA(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}), 0o644)
+
+	cfg, _ := json.Marshal(map[string]any{"url": srv.URL, "caFile": caFile})
+	ch, err := newMochatChannel(cfg, bus.NewMessageBus(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ch.Send(bus.OutboundMessage{ChatID: "c1", Content: "hi"}); err != nil {
+		t.Errorf("Send with the server's CA: %v", err)
+	}
+
+	cfg, _ = json.Marshal(map[string]any{"url": srv.URL, "caFile": filepath.Join(t.TempDir(), "missing.pem")})
+	if _, err := newMochatChannel(cfg, bus.NewMessageBus(4)); err == nil {
+		t.Error("expected error for a missing CA file")
+	}
+}
+
 func TestMochatSend(t *testing.T) {
 	var receivedBody string
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -426,6 +517,7 @@ func TestMochatSend(t *testing.T) {
 }
 
 func TestMochatSend_Error(t *testing.T) {
+	fastRetries(t)
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write([]byte("server error"))
@@ -468,6 +560,29 @@ func TestMochatPoll(t *testing.T) {
 	}
 }
 
+func TestMochatPoll_Metadata(t *testing.T) {
+	msgBus := bus.NewMessageBus(4)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"id":42,"timestamp":1700000000,"senderId":"s1","senderName":"Bob","chatId":"c1","content":"hi"}]`))
+	}))
+	defer srv.Close()
+
+	mc := &MochatChannel{baseURL: srv.URL, bus: msgBus, allowedUsers: map[string]bool{}}
+	mc.poll()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	msg, err := msgBus.ConsumeInbound(ctx)
+	if err != nil {
+		t.Fatalf("expected inbound message: %v", err)
+	}
+	assertMeta(t, msg.Metadata, map[string]string{
+		bus.MetaMessageID:  "42",
+		bus.MetaSenderName: "Bob",
+		bus.MetaTimestamp:  "2023-11-14T22:13:20Z",
+	})
+}
+
 // --- Constructor error cases ---
 
 func TestNewFeishuChannel_InvalidJSON(t *testing.T) {
@@ -515,6 +630,7 @@ func TestNewWhatsAppChannel_InvalidJSON(t *testing.T) {
 // --- Feishu/DingTalk Send with mock server ---
 
 func TestFeishuSend_ViaStruct(t *testing.T) {
+	fastRetries(t)
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Header.Get("Authorization") == "" {
 			t.Error("expected Authorization header")
@@ -525,18 +641,20 @@ func TestFeishuSend_ViaStruct(t *testing.T) {
 
 	// We can't easily override the Feishu API URL, but we can test Send directly
 	// by constructing a FeishuChannel with a mock token
-	fc := &FeishuChannel{accessToken: "test-token"}
+	fc := &FeishuChannel{token: expiringToken{token: "test-token"}}
 	// Send will fail because it hits the real Feishu API, but we verify the method exists
 	_ = fc.Send(bus.OutboundMessage{ChatID: "chat1", Content: "test"})
 }
 
 func TestDingTalkSend(t *testing.T) {
-	dc := &DingTalkChannel{clientID: "cid", accessToken: "test-token"}
+	fastRetries(t)
+	dc := &DingTalkChannel{clientID: "cid", token: expiringToken{token: "test-token"}}
 	// Send will fail because it hits the real DingTalk API, but we verify the method exists
 	_ = dc.Send(bus.OutboundMessage{ChatID: "chat1", Content: "test"})
 }
 
 func TestQQSend(t *testing.T) {
+	fastRetries(t)
 	qc := &QQChannel{appID: "aid", token: "tok"}
 	_ = qc.Send(bus.OutboundMessage{ChatID: "chat1", Content: "test"})
 }
@@ -637,3 +755,118 @@ func TestMochatStartStop(t *testing.T) {
 		t.Fatalf("Stop error: %v", err)
 	}
 }
+
+// --- Discord ---
+
+func TestDiscordMedia(t *testing.T) {
+	got := discordMedia([]*discordgo.MessageAttachment{
+		{URL: "https://cdn.discordapp.com/a.png", ContentType: "image/png"},
+		{URL: "https://cdn.discordapp.com/notes.txt", ContentType: "text/plain"},
+		nil,
+	})
+	if len(got) != 1 {
+		t.Fatalf("got %d media, want only the image", len(got))
+	}
+	want := bus.Media{Type: "image", URL: "https://cdn.discordapp.com/a.png", MimeType: "image/png"}
+	if got[0].Type != want.Type || got[0].URL != want.URL || got[0].MimeType != want.MimeType {
+		t.Errorf("media = %+v, want %+v", got[0], want)
+	}
+	if discordMedia(nil) != nil {
+		t.Error("expected nil media without attachments")
+	}
+}
+
+func TestDiscordSendFile(t *testing.T) {
+	var gotPath, gotName, gotType, gotData string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Errorf("request is not multipart: %v", err)
+		}
+		for _, headers := range r.MultipartForm.File {
+			f, _ := headers[0].Open()
+			data, _ := io.ReadAll(f)
+			gotName, gotType, gotData = headers[0].Filename, headers[0].Header.Get("Content-Type"), string(data)
+		}
+		w.Write([]byte(`{"id":"m1","channel_id":"C1"}`))
+	}))
+	defer srv.Close()
+	redirectDefaultTransport(t, srv)
+
+	ch, err := newDiscordChannel(json.RawMessage(`{"token":"tok"}`), bus.NewMessageBus(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ch.(*DiscordChannel).SendFile("C1", bus.Attachment{Name: "notes.txt", MimeType: "text/plain", Data: []byte("hello")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(gotPath, "/channels/C1/messages") || gotName != "notes.txt" || gotType != "text/plain" || gotData != "hello" {
+		t.Errorf("posted %s with file %q (%s) %q", gotPath, gotName, gotType, gotData)
+	}
+}
+
+// --- Telegram ---
+
+func TestTelegramSendFile(t *testing.T) {
+	type upload struct{ method, field, chatID, name, data string }
+	var uploads []upload
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/getMe") {
+			w.Write([]byte(`{"ok":true,"result":{"id":1,"is_bot":true,"first_name":"bot","username":"bot"}}`))
+			return
+		}
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Errorf("request is not multipart: %v", err)
+		}
+		for field, headers := range r.MultipartForm.File {
+			f, _ := headers[0].Open()
+			data, _ := io.ReadAll(f)
+			uploads = append(uploads, upload{r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:], field, r.FormValue("chat_id"), headers[0].Filename, string(data)})
+		}
+		w.Write([]byte(`{"ok":true,"result":{"message_id":1,"date":0,"chat":{"id":42}}}`))
+	}))
+	defer srv.Close()
+	redirectDefaultTransport(t, srv)
+
+	ch, err := newTelegramChannel(json.RawMessage(`{"token":"tok"}`), bus.NewMessageBus(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tg := ch.(*TelegramChannel)
+	if err := tg.SendFile("42", bus.Attachment{Name: "report.pdf", MimeType: "application/pdf", Data: []byte("%PDF")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tg.SendFile("42", bus.Attachment{Name: "chart.png", MimeType: "image/png", Data: []byte("png")}); err != nil {
+		t.Fatal(err)
+	}
+	want := []upload{
+		{"sendDocument", "document", "42", "report.pdf", "%PDF"},
+		{"sendPhoto", "photo", "42", "chart.png", "png"},
+	}
+	if len(uploads) != len(want) {
+		t.Fatalf("uploads = %+v, want %+v", uploads, want)
+	}
+	for i := range want {
+		if uploads[i] != want[i] {
+			t.Errorf("upload %d = %+v, want %+v", i, uploads[i], want[i])
+		}
+	}
+	if err := tg.SendFile("not-a-number", bus.Attachment{Name: "a.txt"}); err == nil {
+		t.Error("expected error for invalid chat ID")
+	}
+}
+
+// assertMeta checks that an inbound message carries exactly the want
+// metadata.
+func assertMeta(t *testing.T, got, want map[string]string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Errorf("metadata = %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("metadata[%q] = %q, want %q", k, got[k], v)
+		}
+	}
+}
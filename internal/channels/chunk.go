@@ -0,0 +1,183 @@
+package channels
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// MessageLimiter is implemented by channels whose platform caps the length
+// of a single message. The manager splits longer replies into several sends.
+type MessageLimiter interface {
+	// MaxMessageLength is the longest message, in characters, the platform
+	// accepts; zero or less means no limit.
+	MaxMessageLength() int
+}
+
+// Default message length limits, in characters, of the platforms' APIs.
+const (
+	telegramMaxMessage = 4096
+	discordMaxMessage  = 2000
+	slackMaxMessage    = 4000 // Slack truncates text beyond 40k, but advises 4k
+	whatsappMaxMessage = 4096
+	feishuMaxMessage   = 10000
+	dingtalkMaxMessage = 5000
+	qqMaxMessage       = 2000
+)
+
+// maxMessageLength returns configured, or def when it is not set.
+func maxMessageLength(configured, def int) int {
+	if configured > 0 {
+		return configured
+	}
+	return def
+}
+
+// splitMessage splits text into parts of at most limit characters. Parts
+// break between lines where possible, and a fenced code block is kept whole
+// unless it alone exceeds the limit, in which case each part closes the
+// fence and the next reopens it. A single overlong line is broken at spaces,
+// or mid-word as a last resort. A limit of zero or less disables splitting.
+func splitMessage(text string, limit int) []string {
+	if limit <= 0 || utf8.RuneCountInString(text) <= limit {
+		return []string{text}
+	}
+
+	var parts []string
+	var cur []string
+	curLen := 0
+	flush := func() {
+		if s := strings.Trim(strings.Join(cur, "\n"), "\n"); s != "" {
+			parts = append(parts, s)
+		}
+		cur, curLen = nil, 0
+	}
+	add := func(unit string) {
+		n := utf8.RuneCountInString(unit)
+		if len(cur) > 0 && curLen+1+n > limit {
+			flush()
+		}
+		if len(cur) > 0 {
+			curLen++
+		}
+		cur = append(cur, unit)
+		curLen += n
+	}
+
+	for _, block := range messageBlocks(text) {
+		if utf8.RuneCountInString(block) <= limit {
+			add(block)
+			continue
+		}
+		flush()
+		var pieces []string
+		if isFence(block) {
+			pieces = splitCodeBlock(block, limit)
+		} else {
+			pieces = splitLine(block, limit)
+		}
+		for _, p := range pieces {
+			add(p)
+		}
+	}
+	flush()
+	return parts
+}
+
+// messageBlocks splits text into lines, except that a fenced code block,
+// from its opening ``` line to its closing one, is a single block.
+func messageBlocks(text string) []string {
+	var blocks []string
+	var fence []string
+	for _, line := range strings.Split(text, "\n") {
+		if fence != nil {
+			fence = append(fence, line)
+			if isFence(line) {
+				blocks = append(blocks, strings.Join(fence, "\n"))
+				fence = nil
+			}
+			continue
+		}
+		if isFence(line) {
+			fence = []string{line}
+			continue
+		}
+		blocks = append(blocks, line)
+	}
+	if fence != nil { // unterminated fence
+		blocks = append(blocks, strings.Join(fence, "\n"))
+	}
+	return blocks
+}
+
+func isFence(s string) bool {
+	return strings.HasPrefix(strings.TrimSpace(s), "```")
+}
+
+// splitCodeBlock splits an overlong fenced block into blocks that each
+// carry the opening fence line (with its language) and a closing fence.
+func splitCodeBlock(block string, limit int) []string {
+	lines := strings.Split(block, "\n")
+	open := lines[0]
+	body := lines[1:]
+	if len(body) > 0 && isFence(body[len(body)-1]) {
+		body = body[:len(body)-1]
+	}
+
+	const closing = "```"
+	overhead := utf8.RuneCountInString(open) + 1 + 1 + len(closing)
+	room := limit - overhead
+	if room < 1 {
+		// The fence itself does not fit; fall back to plain splitting.
+		return splitLine(block, limit)
+	}
+
+	var out []string
+	var cur []string
+	curLen := 0
+	flush := func() {
+		if len(cur) > 0 {
+			out = append(out, open+"\n"+strings.Join(cur, "\n")+"\n"+closing)
+		}
+		cur, curLen = nil, 0
+	}
+	for _, line := range body {
+		for _, piece := range splitLine(line, room) {
+			n := utf8.RuneCountInString(piece)
+			if len(cur) > 0 && curLen+1+n > room {
+				flush()
+			}
+			if len(cur) > 0 {
+				curLen++
+			}
+			cur = append(cur, piece)
+			curLen += n
+		}
+	}
+	flush()
+	return out
+}
+
+// splitLine breaks a line into pieces of at most limit characters, at the
+// last space before the limit when there is one.
+func splitLine(line string, limit int) []string {
+	var out []string
+	runes := []rune(line)
+	for len(runes) > limit {
+		cut := limit
+		for i := limit; i > limit/2; i-- {
+			if runes[i] == ' ' {
+				cut = i
+				break
+			}
+		}
+		out = append(out, string(runes[:cut]))
+		runes = runes[cut:]
+		if len(runes) > 0 && runes[0] == ' ' {
+			runes = runes[1:]
+		}
+	}
+	if len(runes) > 0 || len(out) == 0 {
+		out = append(out, string(runes))
+	}
+	return out
+}
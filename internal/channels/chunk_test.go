@@ -0,0 +1,116 @@
+package channels
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/coopco/nanobot/internal/bus"
+)
+
+func TestSplitMessageShortUnchanged(t *testing.T) {
+	for _, limit := range []int{0, 100} {
+		if got := splitMessage("hello\nworld", limit); len(got) != 1 || got[0] != "hello\nworld" {
+			t.Errorf("limit %d: got %q", limit, got)
+		}
+	}
+}
+
+func TestSplitMessageOnLines(t *testing.T) {
+	text := strings.Repeat("line of text\n", 10) // 10 lines of 12 characters
+	parts := splitMessage(text, 40)
+	if len(parts) != 4 {
+		t.Fatalf("got %d parts, want 4: %q", len(parts), parts)
+	}
+	if strings.Join(parts, "\n") != strings.TrimSuffix(text, "\n") {
+		t.Errorf("parts do not reassemble into the original text: %q", parts)
+	}
+	for i, p := range parts {
+		if n := utf8.RuneCountInString(p); n > 40 {
+			t.Errorf("part %d has %d characters, over the limit", i, n)
+		}
+	}
+}
+
+func TestSplitMessageKeepsCodeBlockWhole(t *testing.T) {
+	code := "```go\nfunc main() {\n\tfmt.Println(\"hi\")\n}\n```"
+	text := strings.Repeat("intro text here\n", 3) + code + "\nafter the code"
+	parts := splitMessage(text, 60)
+	if len(parts) != 2 {
+		t.Fatalf("got %d parts, want 2: %q", len(parts), parts)
+	}
+	if !strings.HasPrefix(parts[1], code) {
+		t.Errorf("code block was not kept in one part: %q", parts)
+	}
+	for i, p := range parts {
+		if strings.Count(p, "```")%2 != 0 {
+			t.Errorf("part %d leaves a code fence open: %q", i, p)
+		}
+	}
+}
+
+func TestSplitMessageOverlongCodeBlock(t *testing.T) {
+	var body []string
+	for i := 0; i < 30; i++ {
+		body = append(body, "x := compute(value)")
+	}
+	text := "```go\n" + strings.Join(body, "\n") + "\n```"
+	parts := splitMessage(text, 100)
+	if len(parts) < 2 {
+		t.Fatalf("got %d parts, want the block split", len(parts))
+	}
+	lines := 0
+	for i, p := range parts {
+		if utf8.RuneCountInString(p) > 100 {
+			t.Errorf("part %d is over the limit: %d characters", i, utf8.RuneCountInString(p))
+		}
+		if !strings.HasPrefix(p, "```go\n") || !strings.HasSuffix(p, "\n```") {
+			t.Errorf("part %d is not a closed go code block: %q", i, p)
+		}
+		lines += strings.Count(p, "x := compute(value)")
+	}
+	if lines != 30 {
+		t.Errorf("parts carry %d code lines, want 30", lines)
+	}
+}
+
+func TestSplitMessageOverlongLine(t *testing.T) {
+	text := strings.Repeat("word ", 50) // 250 characters, no newlines
+	parts := splitMessage(text, 60)
+	if len(parts) != 5 {
+		t.Fatalf("got %d parts, want 5: %q", len(parts), parts)
+	}
+	for i, p := range parts {
+		if strings.HasPrefix(p, " ") || strings.Contains(p, "wo rd") || utf8.RuneCountInString(p) > 60 {
+			t.Errorf("part %d split badly: %q", i, p)
+		}
+	}
+}
+
+type limitedChannel struct {
+	mockChannel
+	limit int
+}
+
+func (l *limitedChannel) MaxMessageLength() int { return l.limit }
+
+func TestSendPartsInOrder(t *testing.T) {
+	ch := &limitedChannel{mockChannel: mockChannel{name: "limited"}, limit: 10}
+	sendParts(ch, bus.OutboundMessage{Channel: "limited", ChatID: "c", Content: "first\nsecond\nthird"})
+	var got []string
+	for _, m := range ch.sent {
+		if m.ChatID != "c" {
+			t.Errorf("part sent to %q, want c", m.ChatID)
+		}
+		got = append(got, m.Content)
+	}
+	if strings.Join(got, "|") != "first|second|third" {
+		t.Errorf("sent parts = %q", got)
+	}
+
+	plain := &mockChannel{name: "plain"}
+	sendParts(plain, bus.OutboundMessage{Content: strings.Repeat("a", 5000)})
+	if len(plain.sent) != 1 {
+		t.Errorf("channel without a limit got %d sends, want 1", len(plain.sent))
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"io"
 	"log/slog"
 	"net/http"
+	"time"
 
 	"github.com/coopco/nanobot/internal/bus"
 )
@@ -17,10 +18,11 @@ func init() {
 }
 
 type dingtalkConfig struct {
-	ClientID     string   `json:"clientId"`
-	ClientSecret string   `json:"clientSecret"`
-	WebhookPort  int      `json:"webhookPort"`
-	AllowedUsers []string `json:"allowedUsers"`
+	ClientID         string   `json:"clientId"`
+	ClientSecret     string   `json:"clientSecret"`
+	WebhookPort      int      `json:"webhookPort"`
+	AllowedUsers     []string `json:"allowedUsers"`
+	MaxMessageLength int      `json:"maxMessageLength"`
 }
 
 // DingTalkChannel implements Channel for DingTalk via HTTP webhooks.
@@ -30,7 +32,8 @@ type DingTalkChannel struct {
 	bus          *bus.MessageBus
 	allowedUsers map[string]bool
 	server       *http.Server
-	accessToken  string
+	token        expiringToken
+	maxLen       int // longest message sent in one piece
 }
 
 func newDingTalkChannel(cfg json.RawMessage, msgBus *bus.MessageBus) (Channel, error) {
@@ -45,21 +48,25 @@ func newDingTalkChannel(cfg json.RawMessage, msgBus *bus.MessageBus) (Channel, e
 	for _, u := range c.AllowedUsers {
 		allowed[u] = true
 	}
-	return &DingTalkChannel{
+	ch := &DingTalkChannel{
 		clientID:     c.ClientID,
 		clientSecret: c.ClientSecret,
 		bus:          msgBus,
 		allowedUsers: allowed,
 		server:       &http.Server{Addr: fmt.Sprintf(":%d", c.WebhookPort)},
-	}, nil
+		maxLen:       maxMessageLength(c.MaxMessageLength, dingtalkMaxMessage),
+	}
+	ch.token.fetch = ch.fetchToken
+	return ch, nil
 }
 
 func (c *DingTalkChannel) Name() string { return "dingtalk" }
 
 func (c *DingTalkChannel) Start(ctx context.Context) error {
-	if err := c.refreshToken(); err != nil {
+	if err := c.token.refresh(); err != nil {
 		return fmt.Errorf("dingtalk: get access token: %w", err)
 	}
+	go c.token.keepFresh(ctx, "dingtalk")
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("/", c.handleEvent)
@@ -79,7 +86,8 @@ func (c *DingTalkChannel) Start(ctx context.Context) error {
 	return nil
 }
 
-func (c *DingTalkChannel) refreshToken() error {
+// fetchToken requests a new access token and reports how long it is valid.
+func (c *DingTalkChannel) fetchToken() (string, time.Duration, error) {
 	body, _ := json.Marshal(map[string]string{
 		"clientId":     c.clientID,
 		"clientSecret": c.clientSecret,
@@ -90,22 +98,22 @@ func (c *DingTalkChannel) refreshToken() error {
 		bytes.NewReader(body),
 	)
 	if err != nil {
-		return err
+		return "", 0, err
 	}
 	defer resp.Body.Close()
 	var result struct {
 		AccessToken string `json:"accessToken"`
+		ExpireIn    int    `json:"expireIn"` // seconds
 		ErrCode     int    `json:"errcode"`
 		ErrMsg      string `json:"errmsg"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return err
+		return "", 0, err
 	}
 	if result.ErrCode != 0 {
-		return fmt.Errorf("dingtalk auth error %d: %s", result.ErrCode, result.ErrMsg)
+		return "", 0, fmt.Errorf("dingtalk auth error %d: %s", result.ErrCode, result.ErrMsg)
 	}
-	c.accessToken = result.AccessToken
-	return nil
+	return result.AccessToken, time.Duration(result.ExpireIn) * time.Second, nil
 }
 
 func (c *DingTalkChannel) handleEvent(w http.ResponseWriter, r *http.Request) {
@@ -120,8 +128,11 @@ func (c *DingTalkChannel) handleEvent(w http.ResponseWriter, r *http.Request) {
 		Text    struct {
 			Content string `json:"content"`
 		} `json:"text"`
-		SenderID   string `json:"senderId"`
+		MsgID          string `json:"msgId"`
+		SenderID       string `json:"senderId"`
+		SenderNick     string `json:"senderNick"`
 		ConversationID string `json:"conversationId"`
+		CreateAt       int64  `json:"createAt"` // milliseconds since the epoch
 	}
 	if err := json.Unmarshal(data, &event); err != nil {
 		http.Error(w, "parse error", http.StatusBadRequest)
@@ -134,11 +145,16 @@ func (c *DingTalkChannel) handleEvent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	var sent time.Time
+	if event.CreateAt > 0 {
+		sent = time.UnixMilli(event.CreateAt)
+	}
 	c.bus.PublishInbound(bus.InboundMessage{
 		Channel:  "dingtalk",
 		SenderID: event.SenderID,
 		ChatID:   event.ConversationID,
 		Content:  event.Text.Content,
+		Metadata: inboundMeta(event.MsgID, event.SenderNick, sent),
 	})
 	w.WriteHeader(http.StatusOK)
 }
@@ -148,6 +164,10 @@ func (c *DingTalkChannel) Stop() error {
 }
 
 func (c *DingTalkChannel) Send(msg bus.OutboundMessage) error {
+	token, err := c.token.get()
+	if err != nil {
+		return fmt.Errorf("dingtalk: get access token: %w", err)
+	}
 	msgParam, _ := json.Marshal(map[string]string{"content": msg.Content})
 	body, _ := json.Marshal(map[string]interface{}{
 		"robotCode": c.clientID,
@@ -163,9 +183,9 @@ func (c *DingTalkChannel) Send(msg bus.OutboundMessage) error {
 		return err
 	}
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("x-acs-dingtalk-access-token", c.accessToken)
+	req.Header.Set("x-acs-dingtalk-access-token", token)
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := doWithRetry(http.DefaultClient, req)
 	if err != nil {
 		return fmt.Errorf("dingtalk: send message: %w", err)
 	}
@@ -183,3 +203,6 @@ func (c *DingTalkChannel) IsAllowed(senderID string) bool {
 	}
 	return c.allowedUsers[senderID]
 }
+
+// MaxMessageLength implements MessageLimiter.
+func (c *DingTalkChannel) MaxMessageLength() int { return c.maxLen }
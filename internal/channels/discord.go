@@ -1,10 +1,13 @@
 package channels
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"strings"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
 
@@ -16,14 +19,17 @@ func init() {
 }
 
 type discordConfig struct {
-	Token        string   `json:"token"`
-	AllowedUsers []string `json:"allowedUsers"`
+	Token            string   `json:"token"`
+	AllowedUsers     []string `json:"allowedUsers"`
+	MaxMessageLength int      `json:"maxMessageLength"`
 }
 
 type DiscordChannel struct {
 	session      *discordgo.Session
 	bus          *bus.MessageBus
 	allowedUsers map[string]bool
+	maxLen       int // longest message sent in one piece
+	typing       typingLoop
 }
 
 func newDiscordChannel(cfg json.RawMessage, msgBus *bus.MessageBus) (Channel, error) {
@@ -39,11 +45,14 @@ func newDiscordChannel(cfg json.RawMessage, msgBus *bus.MessageBus) (Channel, er
 	for _, u := range dcfg.AllowedUsers {
 		allowed[u] = true
 	}
-	return &DiscordChannel{
+	c := &DiscordChannel{
 		session:      session,
 		bus:          msgBus,
 		allowedUsers: allowed,
-	}, nil
+		maxLen:       maxMessageLength(dcfg.MaxMessageLength, discordMaxMessage),
+	}
+	c.typing = typingLoop{interval: discordTypingInterval, send: c.sendTyping}
+	return c, nil
 }
 
 func (c *DiscordChannel) Name() string { return "discord" }
@@ -62,6 +71,7 @@ func (c *DiscordChannel) Start(ctx context.Context) error {
 			SenderID: m.Author.ID,
 			ChatID:   m.ChannelID,
 			Content:  m.Content,
+			Media:    discordMedia(m.Attachments),
 		})
 	})
 	if err := c.session.Open(); err != nil {
@@ -82,9 +92,57 @@ func (c *DiscordChannel) Send(msg bus.OutboundMessage) error {
 	return nil
 }
 
+// SendFile implements FileSender, posting file as a message attachment.
+func (c *DiscordChannel) SendFile(chatID string, file bus.Attachment) error {
+	_, err := c.session.ChannelMessageSendComplex(chatID, &discordgo.MessageSend{
+		Files: []*discordgo.File{{Name: file.Name, ContentType: file.MimeType, Reader: bytes.NewReader(file.Data)}},
+	})
+	if err != nil {
+		return fmt.Errorf("discord: failed to send file: %w", err)
+	}
+	return nil
+}
+
+// discordTypingInterval is how often the typing indicator is triggered;
+// Discord shows it for ten seconds or until the bot's next message.
+const discordTypingInterval = 8 * time.Second
+
+// StartTyping implements Typer.
+func (c *DiscordChannel) StartTyping(chatID string) error { return c.typing.start(chatID) }
+
+// StopTyping implements Typer. Discord has no call to clear the indicator,
+// so it is left to expire.
+func (c *DiscordChannel) StopTyping(chatID string) error {
+	c.typing.stop(chatID)
+	return nil
+}
+
+func (c *DiscordChannel) sendTyping(chatID string) error {
+	if err := c.session.ChannelTyping(chatID); err != nil {
+		return fmt.Errorf("discord: failed to trigger typing: %w", err)
+	}
+	return nil
+}
+
 func (c *DiscordChannel) IsAllowed(senderID string) bool {
 	if len(c.allowedUsers) == 0 {
 		return true
 	}
 	return c.allowedUsers[senderID]
 }
+
+// MaxMessageLength implements MessageLimiter.
+func (c *DiscordChannel) MaxMessageLength() int { return c.maxLen }
+
+// discordMedia converts image attachments to bus.Media. Discord serves
+// attachments from a public CDN, so the URL is passed through as is.
+func discordMedia(attachments []*discordgo.MessageAttachment) []bus.Media {
+	var media []bus.Media
+	for _, a := range attachments {
+		if a == nil || !strings.HasPrefix(a.ContentType, "image/") {
+			continue
+		}
+		media = append(media, bus.Media{Type: "image", URL: a.URL, MimeType: a.ContentType})
+	}
+	return media
+}
@@ -2,29 +2,61 @@ package channels
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"crypto/tls"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"mime"
+	"mime/multipart"
 	"net"
+	"net/http"
+	"net/mail"
 	"net/smtp"
+	"net/textproto"
+	"net/url"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/coopco/nanobot/internal/bus"
+	"github.com/coopco/nanobot/internal/tlsutil"
 )
 
 func init() {
 	Register("email", newEmailChannel)
 }
 
+// Email authentication modes.
+const (
+	emailAuthPassword = "password" // IMAP LOGIN and SMTP PLAIN
+	emailAuthXOAUTH2  = "xoauth2"  // SASL XOAUTH2 with an OAuth2 access token
+)
+
+// defaultEmailFolder is the mailbox polled when none is configured.
+const defaultEmailFolder = "INBOX"
+
 type emailConfig struct {
 	IMAPServer   string   `json:"imapServer"`
 	SMTPServer   string   `json:"smtpServer"`
 	Username     string   `json:"username"`
 	Password     string   `json:"password"`
 	AllowedUsers []string `json:"allowedUsers"`
+	Folder       string   `json:"folder"`    // mailbox to poll; default INBOX
+	SinceDays    int      `json:"sinceDays"` // ignore mail older than this many days; 0 takes all unread
+
+	AuthMode     string `json:"authMode"` // "password" (default) or "xoauth2"
+	AccessToken  string `json:"accessToken"`
+	RefreshToken string `json:"refreshToken"`
+	TokenURL     string `json:"tokenUrl"`
+	ClientID     string `json:"clientId"`
+	ClientSecret string `json:"clientSecret"`
+
+	tlsutil.Options
 }
 
 // EmailChannel implements Channel using IMAP polling for receive and SMTP for send.
@@ -33,9 +65,14 @@ type EmailChannel struct {
 	smtpServer   string
 	username     string
 	password     string
+	folder       string
+	sinceDays    int
+	authMode     string
+	tokens       *oauthTokenSource // set in xoauth2 mode
 	bus          *bus.MessageBus
 	allowedUsers map[string]bool
 	cancel       context.CancelFunc
+	tlsConfig    *tls.Config // nil verifies against the system roots
 }
 
 func newEmailChannel(cfg json.RawMessage, msgBus *bus.MessageBus) (Channel, error) {
@@ -47,14 +84,44 @@ func newEmailChannel(cfg json.RawMessage, msgBus *bus.MessageBus) (Channel, erro
 	for _, u := range c.AllowedUsers {
 		allowed[u] = true
 	}
-	return &EmailChannel{
+	ch := &EmailChannel{
 		imapServer:   c.IMAPServer,
 		smtpServer:   c.SMTPServer,
 		username:     c.Username,
 		password:     c.Password,
+		folder:       c.Folder,
+		sinceDays:    c.SinceDays,
+		authMode:     c.AuthMode,
 		bus:          msgBus,
 		allowedUsers: allowed,
-	}, nil
+	}
+	tlsCfg, err := c.Options.Config()
+	if err != nil {
+		return nil, fmt.Errorf("email: %w", err)
+	}
+	ch.tlsConfig = tlsCfg
+	if ch.folder == "" {
+		ch.folder = defaultEmailFolder
+	}
+	switch c.AuthMode {
+	case "", emailAuthPassword:
+		ch.authMode = emailAuthPassword
+	case emailAuthXOAUTH2:
+		if c.AccessToken == "" && (c.RefreshToken == "" || c.TokenURL == "") {
+			return nil, fmt.Errorf("email: xoauth2 needs an accessToken or a refreshToken and tokenUrl")
+		}
+		ch.tokens = &oauthTokenSource{
+			accessToken:  c.AccessToken,
+			refreshToken: c.RefreshToken,
+			tokenURL:     c.TokenURL,
+			clientID:     c.ClientID,
+			clientSecret: c.ClientSecret,
+			httpClient:   &http.Client{Timeout: 30 * time.Second},
+		}
+	default:
+		return nil, fmt.Errorf("email: unknown authMode %q (use password or xoauth2)", c.AuthMode)
+	}
+	return ch, nil
 }
 
 func (c *EmailChannel) Name() string { return "email" }
@@ -81,6 +148,22 @@ func (c *EmailChannel) Start(ctx context.Context) error {
 	return nil
 }
 
+// searchCmd returns the SEARCH command for unread mail, limited to the last
+// sinceDays days when set. SINCE compares dates only, so a message from
+// earlier on the first day is included.
+func (c *EmailChannel) searchCmd(now time.Time) string {
+	if c.sinceDays <= 0 {
+		return "SEARCH UNSEEN"
+	}
+	return "SEARCH UNSEEN SINCE " + now.AddDate(0, 0, -c.sinceDays).Format("2-Jan-2006")
+}
+
+// imapQuote returns s as an IMAP quoted string, so mailbox names with spaces
+// or other special characters reach the server intact.
+func imapQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
 // imapCmd sends an IMAP command and returns the response lines until a tagged response.
 func imapCmd(conn *bufio.ReadWriter, tag, cmd string) ([]string, error) {
 	line := fmt.Sprintf("%s %s\r\n", tag, cmd)
@@ -97,6 +180,17 @@ func imapCmd(conn *bufio.ReadWriter, tag, cmd string) ([]string, error) {
 			return nil, err
 		}
 		l = strings.TrimRight(l, "\r\n")
+		if strings.HasPrefix(l, "+") {
+			// A continuation request here carries an authentication error;
+			// answer with an empty line so the server sends the tagged result.
+			if _, err := conn.WriteString("\r\n"); err != nil {
+				return nil, err
+			}
+			if err := conn.Flush(); err != nil {
+				return nil, err
+			}
+			continue
+		}
 		lines = append(lines, l)
 		if strings.HasPrefix(l, tag+" ") {
 			break
@@ -106,8 +200,7 @@ func imapCmd(conn *bufio.ReadWriter, tag, cmd string) ([]string, error) {
 }
 
 func (c *EmailChannel) pollInbox() {
-	tlsCfg := &tls.Config{ServerName: strings.Split(c.imapServer, ":")[0]}
-	rawConn, err := tls.Dial("tcp", c.imapServer, tlsCfg)
+	rawConn, err := tls.Dial("tcp", c.imapServer, c.serverTLS(c.imapServer))
 	if err != nil {
 		// Try plain TCP if TLS fails (port 143)
 		host := strings.Split(c.imapServer, ":")[0]
@@ -132,21 +225,31 @@ func (c *EmailChannel) pollInbox() {
 }
 
 func (c *EmailChannel) processIMAP(rw *bufio.ReadWriter) {
-	// LOGIN
-	loginCmd := fmt.Sprintf("LOGIN %q %q", c.username, c.password)
+	// LOGIN or AUTHENTICATE
+	loginCmd, err := c.imapAuthCmd(context.Background())
+	if err != nil {
+		slog.Error("email: imap auth", "err", err)
+		return
+	}
 	if _, err := imapCmd(rw, "a1", loginCmd); err != nil {
 		slog.Error("email: imap login", "err", err)
 		return
 	}
 
-	// SELECT INBOX
-	if _, err := imapCmd(rw, "a2", "SELECT INBOX"); err != nil {
-		slog.Error("email: imap select", "err", err)
+	// SELECT the folder; a server that refuses it answers NO
+	lines, err := imapCmd(rw, "a2", "SELECT "+imapQuote(c.folder))
+	if err != nil {
+		slog.Error("email: imap select", "err", err, "folder", c.folder)
+		return
+	}
+	if len(lines) == 0 || !strings.HasPrefix(lines[len(lines)-1], "a2 OK") {
+		slog.Error("email: imap select failed", "folder", c.folder, "response", strings.Join(lines, " | "))
+		imapCmd(rw, "a6", "LOGOUT")
 		return
 	}
 
 	// SEARCH UNSEEN
-	lines, err := imapCmd(rw, "a3", "SEARCH UNSEEN")
+	lines, err = imapCmd(rw, "a3", c.searchCmd(time.Now()))
 	if err != nil {
 		slog.Error("email: imap search", "err", err)
 		return
@@ -163,7 +266,7 @@ func (c *EmailChannel) processIMAP(rw *bufio.ReadWriter) {
 	}
 
 	for _, uid := range uids {
-		fetchLines, err := imapCmd(rw, "a4", fmt.Sprintf("FETCH %s (BODY[HEADER.FIELDS (FROM SUBJECT)] BODY[TEXT])", uid))
+		fetchLines, err := imapCmd(rw, "a4", fmt.Sprintf("FETCH %s (BODY[HEADER.FIELDS (FROM SUBJECT MESSAGE-ID DATE)] BODY[TEXT])", uid))
 		if err != nil {
 			slog.Error("email: imap fetch", "err", err, "uid", uid)
 			continue
@@ -178,6 +281,7 @@ func (c *EmailChannel) processIMAP(rw *bufio.ReadWriter) {
 				SenderID: from,
 				ChatID:   from,
 				Content:  fmt.Sprintf("Subject: %s\n%s", subject, body),
+				Metadata: parseIMAPMeta(fetchLines),
 			})
 		}
 
@@ -210,6 +314,36 @@ func parseIMAPFetch(lines []string) (from, subject, body string) {
 	return
 }
 
+// parseIMAPMeta returns the inbound metadata carried in the headers of a
+// fetched message: its Message-ID, the display name in From and the Date.
+func parseIMAPMeta(lines []string) map[string]string {
+	var messageID, senderName string
+	var sent time.Time
+	for _, l := range lines {
+		if l == "" {
+			break
+		}
+		name, value, ok := strings.Cut(l, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.ToLower(name) {
+		case "message-id":
+			messageID = value
+		case "from":
+			if addr, err := mail.ParseAddress(value); err == nil {
+				senderName = addr.Name
+			}
+		case "date":
+			if t, err := mail.ParseDate(value); err == nil {
+				sent = t
+			}
+		}
+	}
+	return inboundMeta(messageID, senderName, sent)
+}
+
 func (c *EmailChannel) Stop() error {
 	if c.cancel != nil {
 		c.cancel()
@@ -218,20 +352,251 @@ func (c *EmailChannel) Stop() error {
 }
 
 func (c *EmailChannel) Send(msg bus.OutboundMessage) error {
-	host := strings.Split(c.smtpServer, ":")[0]
-	auth := smtp.PlainAuth("", c.username, c.password, host)
-
 	body := fmt.Sprintf("To: %s\r\nSubject: Re: nanobot\r\n\r\n%s", msg.ChatID, msg.Content)
-	err := smtp.SendMail(c.smtpServer, auth, c.username, []string{msg.ChatID}, []byte(body))
-	if err != nil {
+	if err := c.deliver(msg.ChatID, []byte(body)); err != nil {
 		return fmt.Errorf("email: send: %w", err)
 	}
 	return nil
 }
 
+// SendFile implements FileSender, mailing file as the sole attachment of a
+// message.
+func (c *EmailChannel) SendFile(chatID string, file bus.Attachment) error {
+	if err := c.deliver(chatID, attachmentMail(chatID, file)); err != nil {
+		return fmt.Errorf("email: send file: %w", err)
+	}
+	return nil
+}
+
+// deliver sends a complete message to one recipient over SMTP.
+func (c *EmailChannel) deliver(to string, msg []byte) error {
+	auth, err := c.smtpAuth(context.Background())
+	if err != nil {
+		return err
+	}
+	if c.tlsConfig == nil {
+		return smtp.SendMail(c.smtpServer, auth, c.username, []string{to}, msg)
+	}
+	return sendMail(c.smtpServer, c.serverTLS(c.smtpServer), auth, c.username, []string{to}, msg)
+}
+
+// attachmentMail builds a multipart/mixed message to recipient carrying file
+// base64-encoded.
+func attachmentMail(to string, file bus.Attachment) []byte {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Type", file.MimeType)
+	h.Set("Content-Transfer-Encoding", "base64")
+	h.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": file.Name}))
+	part, _ := mw.CreatePart(h)
+	encoded := base64.StdEncoding.EncodeToString(file.Data)
+	for len(encoded) > 76 {
+		io.WriteString(part, encoded[:76]+"\r\n")
+		encoded = encoded[76:]
+	}
+	io.WriteString(part, encoded+"\r\n")
+	mw.Close()
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "To: %s\r\nSubject: Re: nanobot\r\nMIME-Version: 1.0\r\n", to)
+	fmt.Fprintf(&msg, "Content-Type: multipart/mixed; boundary=%q\r\n\r\n", mw.Boundary())
+	msg.Write(body.Bytes())
+	return msg.Bytes()
+}
+
+// serverTLS returns the TLS configuration for connecting to addr.
+func (c *EmailChannel) serverTLS(addr string) *tls.Config {
+	cfg := &tls.Config{}
+	if c.tlsConfig != nil {
+		cfg = c.tlsConfig.Clone()
+	}
+	cfg.ServerName = strings.Split(addr, ":")[0]
+	return cfg
+}
+
+// sendMail is smtp.SendMail with the STARTTLS configuration supplied by the
+// caller, which smtp.SendMail does not allow.
+func sendMail(addr string, tlsCfg *tls.Config, auth smtp.Auth, from string, to []string, msg []byte) error {
+	client, err := smtp.Dial(addr)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(tlsCfg); err != nil {
+			return err
+		}
+	}
+	if auth != nil {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return errors.New("smtp: server doesn't support AUTH")
+		}
+		if err := client.Auth(auth); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(from); err != nil {
+		return err
+	}
+	for _, addr := range to {
+		if err := client.Rcpt(addr); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
 func (c *EmailChannel) IsAllowed(senderID string) bool {
 	if len(c.allowedUsers) == 0 {
 		return true
 	}
 	return c.allowedUsers[senderID]
 }
+
+// imapAuthCmd returns the IMAP command that logs in with the configured
+// authentication mode.
+func (c *EmailChannel) imapAuthCmd(ctx context.Context) (string, error) {
+	if c.authMode != emailAuthXOAUTH2 {
+		return fmt.Sprintf("LOGIN %q %q", c.username, c.password), nil
+	}
+	token, err := c.tokens.token(ctx)
+	if err != nil {
+		return "", err
+	}
+	ir := base64.StdEncoding.EncodeToString([]byte(xoauth2String(c.username, token)))
+	return "AUTHENTICATE XOAUTH2 " + ir, nil
+}
+
+// smtpAuth returns the SMTP authentication for the configured mode.
+func (c *EmailChannel) smtpAuth(ctx context.Context) (smtp.Auth, error) {
+	if c.authMode != emailAuthXOAUTH2 {
+		host := strings.Split(c.smtpServer, ":")[0]
+		return smtp.PlainAuth("", c.username, c.password, host), nil
+	}
+	token, err := c.tokens.token(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &xoauth2Auth{username: c.username, token: token}, nil
+}
+
+// xoauth2String builds the SASL XOAUTH2 initial client response used by
+// Gmail and Office 365.
+func xoauth2String(username, accessToken string) string {
+	return "user=" + username + "\x01auth=Bearer " + accessToken + "\x01\x01"
+}
+
+// xoauth2Auth implements smtp.Auth for the XOAUTH2 mechanism.
+type xoauth2Auth struct {
+	username string
+	token    string
+}
+
+func (a *xoauth2Auth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	if !server.TLS {
+		return "", nil, errors.New("xoauth2 requires an encrypted connection")
+	}
+	return "XOAUTH2", []byte(xoauth2String(a.username, a.token)), nil
+}
+
+// Next answers the server's error challenge with an empty response, after
+// which the server reports the failure.
+func (a *xoauth2Auth) Next(_ []byte, more bool) ([]byte, error) {
+	if more {
+		return []byte{}, nil
+	}
+	return nil, nil
+}
+
+// oauthTokenSource hands out an OAuth2 access token, refreshing it with the
+// refresh token shortly before it expires. Without a refresh token the
+// configured access token is used as is.
+type oauthTokenSource struct {
+	refreshToken string
+	tokenURL     string
+	clientID     string
+	clientSecret string
+	httpClient   *http.Client
+
+	mu          sync.Mutex
+	accessToken string
+	expiry      time.Time // zero when unknown
+}
+
+// tokenExpiryMargin is how long before expiry an access token is refreshed.
+const tokenExpiryMargin = time.Minute
+
+func (s *oauthTokenSource) token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.refreshToken == "" || s.tokenURL == "" {
+		return s.accessToken, nil
+	}
+	if s.accessToken != "" && !s.expiry.IsZero() && time.Until(s.expiry) > tokenExpiryMargin {
+		return s.accessToken, nil
+	}
+	if err := s.refresh(ctx); err != nil {
+		return "", err
+	}
+	return s.accessToken, nil
+}
+
+// refresh exchanges the refresh token for a new access token. s.mu must be held.
+func (s *oauthTokenSource) refresh(ctx context.Context) error {
+	form := url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {s.refreshToken},
+	}
+	if s.clientID != "" {
+		form.Set("client_id", s.clientID)
+	}
+	if s.clientSecret != "" {
+		form.Set("client_secret", s.clientSecret)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to build token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("token refresh failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("token refresh returned status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		AccessToken  string `json:"access_token"`
+		ExpiresIn    int    `json:"expires_in"`
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return fmt.Errorf("failed to decode token response: %w", err)
+	}
+	if body.AccessToken == "" {
+		return errors.New("token response has no access_token")
+	}
+	s.accessToken = body.AccessToken
+	s.expiry = time.Time{}
+	if body.ExpiresIn > 0 {
+		s.expiry = time.Now().Add(time.Duration(body.ExpiresIn) * time.Second)
+	}
+	if body.RefreshToken != "" {
+		s.refreshToken = body.RefreshToken
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+package channels
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coopco/nanobot/internal/bus"
+)
+
+func newTestEmail(t *testing.T, cfg emailConfig) *EmailChannel {
+	t.Helper()
+	raw, _ := json.Marshal(cfg)
+	ch, err := newEmailChannel(raw, bus.NewMessageBus(16))
+	if err != nil {
+		t.Fatalf("newEmailChannel: %v", err)
+	}
+	return ch.(*EmailChannel)
+}
+
+func TestXOAUTH2String(t *testing.T) {
+	got := xoauth2String("bot@example.com", "ya29.token")
+	want := "user=bot@example.com\x01auth=Bearer ya29.token\x01\x01"
+	if got != want {
+		t.Errorf("xoauth2String = %q, want %q", got, want)
+	}
+}
+
+func TestEmailAuthModeSelection(t *testing.T) {
+	pw := newTestEmail(t, emailConfig{SMTPServer: "smtp.example.com:587", Username: "bot", Password: "secret"})
+	cmd, err := pw.imapAuthCmd(context.Background())
+	if err != nil || cmd != `LOGIN "bot" "secret"` {
+		t.Errorf("password imap command = %q, %v", cmd, err)
+	}
+	if auth, _ := pw.smtpAuth(context.Background()); isXOAUTH2(auth) {
+		t.Error("password mode should not use XOAUTH2 for SMTP")
+	}
+
+	ox := newTestEmail(t, emailConfig{Username: "bot@example.com", AuthMode: "xoauth2", AccessToken: "tok"})
+	cmd, err = ox.imapAuthCmd(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ir, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(cmd, "AUTHENTICATE XOAUTH2 "))
+	if !strings.HasPrefix(cmd, "AUTHENTICATE XOAUTH2 ") || string(ir) != xoauth2String("bot@example.com", "tok") {
+		t.Errorf("xoauth2 imap command = %q", cmd)
+	}
+	auth, err := ox.smtpAuth(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mech, resp, err := auth.Start(&smtp.ServerInfo{Name: "smtp.example.com", TLS: true})
+	if err != nil || mech != "XOAUTH2" || string(resp) != xoauth2String("bot@example.com", "tok") {
+		t.Errorf("smtp Start = %q %q %v", mech, resp, err)
+	}
+	if _, _, err := auth.Start(&smtp.ServerInfo{Name: "smtp.example.com"}); err == nil {
+		t.Error("expected XOAUTH2 to refuse an unencrypted connection")
+	}
+}
+
+func TestEmailAuthModeInvalid(t *testing.T) {
+	for _, cfg := range []emailConfig{
+		{AuthMode: "kerberos"},
+		{AuthMode: "xoauth2"},
+	} {
+		raw, _ := json.Marshal(cfg)
+		if _, err := newEmailChannel(raw, bus.NewMessageBus(1)); err == nil {
+			t.Errorf("expected error for %+v", cfg)
+		}
+	}
+}
+
+func TestEmailXOAUTH2Refresh(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		r.ParseForm()
+		if r.Form.Get("grant_type") != "refresh_token" || r.Form.Get("refresh_token") != "rt" || r.Form.Get("client_id") != "cid" {
+			t.Errorf("unexpected token request: %v", r.Form)
+		}
+		fmt.Fprint(w, `{"access_token":"fresh","expires_in":3600}`)
+	}))
+	defer srv.Close()
+
+	ch := newTestEmail(t, emailConfig{
+		Username: "bot@example.com", AuthMode: "xoauth2",
+		RefreshToken: "rt", TokenURL: srv.URL, ClientID: "cid",
+	})
+	for i := 0; i < 2; i++ {
+		tok, err := ch.tokens.token(context.Background())
+		if err != nil || tok != "fresh" {
+			t.Fatalf("token = %q, %v; want the refreshed token", tok, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("token endpoint called %d times, want 1 while the token is valid", calls)
+	}
+}
+
+func isXOAUTH2(a smtp.Auth) bool {
+	_, ok := a.(*xoauth2Auth)
+	return ok
+}
+
+func TestEmailSelectsConfiguredFolder(t *testing.T) {
+	for _, tc := range []struct{ folder, want string }{
+		{"", `a2 SELECT "INBOX"`},
+		{"Bots/Nano bot", `a2 SELECT "Bots/Nano bot"`},
+		{`Say "hi"`, `a2 SELECT "Say \"hi\""`},
+	} {
+		ch := newTestEmail(t, emailConfig{Username: "bot", Password: "secret", Folder: tc.folder})
+		server := "a1 OK LOGIN completed\r\n" +
+			"* 0 EXISTS\r\na2 OK [READ-WRITE] SELECT completed\r\n" +
+			"* SEARCH\r\na3 OK SEARCH completed\r\n" +
+			"* BYE\r\na6 OK LOGOUT completed\r\n"
+		var sent strings.Builder
+		rw := bufio.NewReadWriter(bufio.NewReader(strings.NewReader(server)), bufio.NewWriter(&sent))
+
+		ch.processIMAP(rw)
+
+		cmds := strings.Split(sent.String(), "\r\n")
+		if len(cmds) < 3 || cmds[1] != tc.want {
+			t.Errorf("folder %q: commands = %q, want %s second", tc.folder, cmds, tc.want)
+		}
+		if !strings.Contains(sent.String(), "a3 SEARCH UNSEEN") {
+			t.Errorf("folder %q: mailbox not searched: %q", tc.folder, sent.String())
+		}
+	}
+}
+
+func TestEmailSelectFailureStopsPoll(t *testing.T) {
+	ch := newTestEmail(t, emailConfig{Username: "bot", Password: "secret", Folder: "Missing"})
+	server := "a1 OK LOGIN completed\r\n" +
+		"a2 NO [NONEXISTENT] Unknown mailbox\r\n" +
+		"a6 OK LOGOUT completed\r\n"
+	var sent strings.Builder
+	rw := bufio.NewReadWriter(bufio.NewReader(strings.NewReader(server)), bufio.NewWriter(&sent))
+
+	ch.processIMAP(rw)
+
+	if strings.Contains(sent.String(), "SEARCH") {
+		t.Errorf("searched after SELECT failed: %q", sent.String())
+	}
+	if !strings.Contains(sent.String(), "a6 LOGOUT") {
+		t.Errorf("did not log out: %q", sent.String())
+	}
+}
+
+func TestEmailSearchSince(t *testing.T) {
+	now := time.Date(2025, time.March, 3, 9, 30, 0, 0, time.UTC)
+
+	all := newTestEmail(t, emailConfig{Username: "bot", Password: "secret"})
+	if got := all.searchCmd(now); got != "SEARCH UNSEEN" {
+		t.Errorf("without sinceDays: %q", got)
+	}
+
+	recent := newTestEmail(t, emailConfig{Username: "bot", Password: "secret", SinceDays: 7})
+	if got := recent.searchCmd(now); got != "SEARCH UNSEEN SINCE 24-Feb-2025" {
+		t.Errorf("with sinceDays 7: %q", got)
+	}
+
+	server := "a1 OK\r\na2 OK\r\n* SEARCH\r\na3 OK\r\na6 OK\r\n"
+	var sent strings.Builder
+	recent.processIMAP(bufio.NewReadWriter(bufio.NewReader(strings.NewReader(server)), bufio.NewWriter(&sent)))
+	if !strings.Contains(sent.String(), "a3 SEARCH UNSEEN SINCE ") {
+		t.Errorf("SEARCH sent without SINCE: %q", sent.String())
+	}
+}
+
+func TestEmailAttachmentMail(t *testing.T) {
+	data := []byte(strings.Repeat("report line\n", 20))
+	raw := attachmentMail("user@example.com", bus.Attachment{Name: "report 1.txt", MimeType: "text/plain", Data: data})
+
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Header.Get("To") != "user@example.com" || msg.Header.Get("MIME-Version") != "1.0" {
+		t.Errorf("unexpected headers %v", msg.Header)
+	}
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/mixed" {
+		t.Fatalf("Content-Type = %q (%v)", msg.Header.Get("Content-Type"), err)
+	}
+	part, err := multipart.NewReader(msg.Body, params["boundary"]).NextPart()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if part.FileName() != "report 1.txt" || part.Header.Get("Content-Type") != "text/plain" {
+		t.Errorf("part %q has headers %v", part.FileName(), part.Header)
+	}
+	encoded, _ := io.ReadAll(part)
+	for _, line := range strings.Split(strings.TrimSpace(string(encoded)), "\r\n") {
+		if len(line) > 76 {
+			t.Errorf("base64 line of %d characters", len(line))
+		}
+	}
+	got, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(string(encoded), "\r\n", ""))
+	if err != nil || !bytes.Equal(got, data) {
+		t.Errorf("decoded attachment = %q (%v)", got, err)
+	}
+}
@@ -7,7 +7,13 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"mime/multipart"
 	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/coopco/nanobot/internal/bus"
 )
@@ -17,10 +23,11 @@ func init() {
 }
 
 type feishuConfig struct {
-	AppID        string   `json:"appId"`
-	AppSecret    string   `json:"appSecret"`
-	WebhookPort  int      `json:"webhookPort"`
-	AllowedUsers []string `json:"allowedUsers"`
+	AppID            string   `json:"appId"`
+	AppSecret        string   `json:"appSecret"`
+	WebhookPort      int      `json:"webhookPort"`
+	AllowedUsers     []string `json:"allowedUsers"`
+	MaxMessageLength int      `json:"maxMessageLength"`
 }
 
 // FeishuChannel implements Channel for Feishu (Lark) via HTTP webhooks.
@@ -30,7 +37,8 @@ type FeishuChannel struct {
 	bus          *bus.MessageBus
 	allowedUsers map[string]bool
 	server       *http.Server
-	accessToken  string
+	token        expiringToken
+	maxLen       int // longest message sent in one piece
 }
 
 func newFeishuChannel(cfg json.RawMessage, msgBus *bus.MessageBus) (Channel, error) {
@@ -45,21 +53,25 @@ func newFeishuChannel(cfg json.RawMessage, msgBus *bus.MessageBus) (Channel, err
 	for _, u := range c.AllowedUsers {
 		allowed[u] = true
 	}
-	return &FeishuChannel{
+	ch := &FeishuChannel{
 		appID:        c.AppID,
 		appSecret:    c.AppSecret,
 		bus:          msgBus,
 		allowedUsers: allowed,
 		server:       &http.Server{Addr: fmt.Sprintf(":%d", c.WebhookPort)},
-	}, nil
+		maxLen:       maxMessageLength(c.MaxMessageLength, feishuMaxMessage),
+	}
+	ch.token.fetch = ch.fetchToken
+	return ch, nil
 }
 
 func (c *FeishuChannel) Name() string { return "feishu" }
 
 func (c *FeishuChannel) Start(ctx context.Context) error {
-	if err := c.refreshToken(); err != nil {
+	if err := c.token.refresh(); err != nil {
 		return fmt.Errorf("feishu: get access token: %w", err)
 	}
+	go c.token.keepFresh(ctx, "feishu")
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("/", c.handleEvent)
@@ -79,7 +91,8 @@ func (c *FeishuChannel) Start(ctx context.Context) error {
 	return nil
 }
 
-func (c *FeishuChannel) refreshToken() error {
+// fetchToken requests a new access token and reports how long it is valid.
+func (c *FeishuChannel) fetchToken() (string, time.Duration, error) {
 	body, _ := json.Marshal(map[string]string{
 		"app_id":     c.appID,
 		"app_secret": c.appSecret,
@@ -90,22 +103,22 @@ func (c *FeishuChannel) refreshToken() error {
 		bytes.NewReader(body),
 	)
 	if err != nil {
-		return err
+		return "", 0, err
 	}
 	defer resp.Body.Close()
 	var result struct {
 		TenantAccessToken string `json:"tenant_access_token"`
+		Expire            int    `json:"expire"` // seconds
 		Code              int    `json:"code"`
 		Msg               string `json:"msg"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return err
+		return "", 0, err
 	}
 	if result.Code != 0 {
-		return fmt.Errorf("feishu auth error %d: %s", result.Code, result.Msg)
+		return "", 0, fmt.Errorf("feishu auth error %d: %s", result.Code, result.Msg)
 	}
-	c.accessToken = result.TenantAccessToken
-	return nil
+	return result.TenantAccessToken, time.Duration(result.Expire) * time.Second, nil
 }
 
 func (c *FeishuChannel) handleEvent(w http.ResponseWriter, r *http.Request) {
@@ -138,8 +151,11 @@ func (c *FeishuChannel) handleEvent(w http.ResponseWriter, r *http.Request) {
 				} `json:"sender_id"`
 			} `json:"sender"`
 			Message struct {
-				ChatID  string `json:"chat_id"`
-				Content string `json:"content"`
+				MessageID  string `json:"message_id"`
+				RootID     string `json:"root_id"` // first message of the thread, if in one
+				ChatID     string `json:"chat_id"`
+				Content    string `json:"content"`
+				CreateTime string `json:"create_time"` // milliseconds since the epoch
 			} `json:"message"`
 		} `json:"event"`
 	}
@@ -165,11 +181,20 @@ func (c *FeishuChannel) handleEvent(w http.ResponseWriter, r *http.Request) {
 	}
 	json.Unmarshal([]byte(event.Event.Message.Content), &msgContent)
 
+	var sent time.Time
+	if ms, err := strconv.ParseInt(event.Event.Message.CreateTime, 10, 64); err == nil {
+		sent = time.UnixMilli(ms)
+	}
+	meta := inboundMeta(event.Event.Message.MessageID, "", sent)
+	if root := event.Event.Message.RootID; root != "" {
+		meta[bus.MetaThreadID] = root
+	}
 	c.bus.PublishInbound(bus.InboundMessage{
 		Channel:  "feishu",
 		SenderID: senderID,
 		ChatID:   event.Event.Message.ChatID,
 		Content:  msgContent.Text,
+		Metadata: meta,
 	})
 	w.WriteHeader(http.StatusOK)
 }
@@ -178,33 +203,150 @@ func (c *FeishuChannel) Stop() error {
 	return c.server.Shutdown(context.Background())
 }
 
+// Send posts msg to its chat. A message with ReplyTo set is sent as a reply
+// in that message's thread, or to the chat if the reply is refused, e.g.
+// because the thread's first message was recalled.
 func (c *FeishuChannel) Send(msg bus.OutboundMessage) error {
-	contentJSON, _ := json.Marshal(map[string]string{"text": msg.Content})
+	content := map[string]string{"text": msg.Content}
+	if msg.ReplyTo != "" {
+		err := c.replyMessage(msg.ReplyTo, "text", content)
+		if err == nil {
+			return nil
+		}
+		slog.Warn("feishu: thread reply failed, sending to chat", "message", msg.ReplyTo, "err", err)
+	}
+	if err := c.sendMessage(msg.ChatID, "text", content); err != nil {
+		return fmt.Errorf("feishu: send message: %w", err)
+	}
+	return nil
+}
+
+// SendFile implements FileSender. Images are uploaded with the image API and
+// sent as image messages; anything else is sent as a file message.
+func (c *FeishuChannel) SendFile(chatID string, file bus.Attachment) error {
+	msgType, field, key := "file", "file_key", ""
+	var err error
+	if strings.HasPrefix(file.MimeType, "image/") {
+		msgType, field = "image", "image_key"
+		key, err = c.upload("images", "image", file, map[string]string{"image_type": "message"}, "image_key")
+	} else {
+		key, err = c.upload("files", "file", file, map[string]string{
+			"file_type": feishuFileType(file.Name),
+			"file_name": file.Name,
+		}, "file_key")
+	}
+	if err != nil {
+		return fmt.Errorf("feishu: upload file: %w", err)
+	}
+	if err := c.sendMessage(chatID, msgType, map[string]string{field: key}); err != nil {
+		return fmt.Errorf("feishu: send file: %w", err)
+	}
+	return nil
+}
+
+// sendMessage posts a message of msgType with the given content to a chat.
+func (c *FeishuChannel) sendMessage(chatID, msgType string, content map[string]string) error {
+	contentJSON, _ := json.Marshal(content)
 	body, _ := json.Marshal(map[string]string{
-		"receive_id": msg.ChatID,
-		"msg_type":   "text",
+		"receive_id": chatID,
+		"msg_type":   msgType,
 		"content":    string(contentJSON),
 	})
-	req, err := http.NewRequest(http.MethodPost,
-		"https://open.feishu.cn/open-apis/im/v1/messages?receive_id_type=chat_id",
-		bytes.NewReader(body),
-	)
+	_, err := c.call("https://open.feishu.cn/open-apis/im/v1/messages?receive_id_type=chat_id",
+		"application/json", body)
+	return err
+}
+
+// replyMessage posts a message of msgType with the given content into the
+// thread of the message messageID.
+func (c *FeishuChannel) replyMessage(messageID, msgType string, content map[string]string) error {
+	contentJSON, _ := json.Marshal(content)
+	body, _ := json.Marshal(map[string]any{
+		"msg_type":        msgType,
+		"content":         string(contentJSON),
+		"reply_in_thread": true,
+	})
+	_, err := c.call("https://open.feishu.cn/open-apis/im/v1/messages/"+url.PathEscape(messageID)+"/reply",
+		"application/json", body)
+	return err
+}
+
+// upload stores file with the im/v1 endpoint ("images" or "files"), sending
+// it in the form field named field alongside fields, and returns the key
+// named keyField from the response.
+func (c *FeishuChannel) upload(endpoint, field string, file bus.Attachment, fields map[string]string, keyField string) (string, error) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for k, v := range fields {
+		mw.WriteField(k, v)
+	}
+	part, err := mw.CreateFormFile(field, file.Name)
+	if err != nil {
+		return "", err
+	}
+	part.Write(file.Data)
+	if err := mw.Close(); err != nil {
+		return "", err
+	}
+	data, err := c.call("https://open.feishu.cn/open-apis/im/v1/"+endpoint, mw.FormDataContentType(), body.Bytes())
 	if err != nil {
-		return err
+		return "", err
+	}
+	var keys map[string]string
+	json.Unmarshal(data, &keys)
+	if keys[keyField] == "" {
+		return "", fmt.Errorf("no %s in response", keyField)
 	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+c.accessToken)
+	return keys[keyField], nil
+}
 
-	resp, err := http.DefaultClient.Do(req)
+// call POSTs body to an Open API url with the tenant access token and
+// returns the "data" object of a successful response.
+func (c *FeishuChannel) call(url, contentType string, body []byte) (json.RawMessage, error) {
+	token, err := c.token.get()
 	if err != nil {
-		return fmt.Errorf("feishu: send message: %w", err)
+		return nil, fmt.Errorf("get access token: %w", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := doWithRetry(http.DefaultClient, req)
+	if err != nil {
+		return nil, err
 	}
 	defer resp.Body.Close()
+	b, _ := io.ReadAll(resp.Body)
 	if resp.StatusCode >= 300 {
-		b, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("feishu: send message status %d: %s", resp.StatusCode, b)
+		return nil, fmt.Errorf("status %d: %s", resp.StatusCode, b)
 	}
-	return nil
+	var result struct {
+		Code int             `json:"code"`
+		Msg  string          `json:"msg"`
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(b, &result); err == nil && result.Code != 0 {
+		return nil, fmt.Errorf("error %d: %s", result.Code, result.Msg)
+	}
+	return result.Data, nil
+}
+
+// feishuFileType maps a file name to the file_type the upload API expects.
+func feishuFileType(name string) string {
+	switch ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(name), ".")); ext {
+	case "opus", "mp4", "pdf":
+		return ext
+	case "doc", "docx":
+		return "doc"
+	case "xls", "xlsx":
+		return "xls"
+	case "ppt", "pptx":
+		return "ppt"
+	}
+	return "stream"
 }
 
 func (c *FeishuChannel) IsAllowed(senderID string) bool {
@@ -213,3 +355,6 @@ func (c *FeishuChannel) IsAllowed(senderID string) bool {
 	}
 	return c.allowedUsers[senderID]
 }
+
+// MaxMessageLength implements MessageLimiter.
+func (c *FeishuChannel) MaxMessageLength() int { return c.maxLen }
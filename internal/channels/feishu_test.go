@@ -147,6 +147,55 @@ func TestFeishuHandleEventMessage(t *testing.T) {
 	}
 }
 
+func TestFeishuHandleEventMetadata(t *testing.T) {
+	fc := newTestFeishu(t, nil)
+
+	payload := `{
+		"header": {"event_type": "im.message.receive_v1"},
+		"event": {
+			"sender": {"sender_id": {"open_id": "ou_abc"}},
+			"message": {"message_id": "om_789", "chat_id": "oc_123", "create_time": "1700000000123", "content": "{\"text\":\"hi\"}"}
+		}
+	}`
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(payload))
+	fc.handleEvent(httptest.NewRecorder(), req)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	msg, err := fc.bus.ConsumeInbound(ctx)
+	if err != nil {
+		t.Fatalf("expected inbound message: %v", err)
+	}
+	assertMeta(t, msg.Metadata, map[string]string{
+		bus.MetaMessageID: "om_789",
+		bus.MetaTimestamp: "2023-11-14T22:13:20Z",
+	})
+}
+
+func TestFeishuHandleEventThread(t *testing.T) {
+	fc := newTestFeishu(t, nil)
+
+	payload := `{
+		"header": {"event_type": "im.message.receive_v1"},
+		"event": {
+			"sender": {"sender_id": {"open_id": "ou_abc"}},
+			"message": {"message_id": "om_2", "root_id": "om_root", "chat_id": "oc_123", "content": "{\"text\":\"hi\"}"}
+		}
+	}`
+	fc.handleEvent(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", strings.NewReader(payload)))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	msg, err := fc.bus.ConsumeInbound(ctx)
+	if err != nil {
+		t.Fatalf("expected inbound message: %v", err)
+	}
+	assertMeta(t, msg.Metadata, map[string]string{
+		bus.MetaMessageID: "om_2",
+		bus.MetaThreadID:  "om_root",
+	})
+}
+
 func TestFeishuHandleEventDisallowedUser(t *testing.T) {
 	msgBus := bus.NewMessageBus(16)
 	cfg := feishuConfig{AppID: "id", AppSecret: "sec", AllowedUsers: []string{"allowed-user"}}
@@ -212,7 +261,7 @@ func TestFeishuSend(t *testing.T) {
 	defer srv.Close()
 
 	ch := newTestFeishu(t, nil)
-	ch.accessToken = "test-token"
+	ch.token.token = "test-token"
 
 	// Patch the send URL by temporarily replacing the http.DefaultClient transport.
 	// Instead, we test Send() by pointing it at our mock server via a custom client.
@@ -231,6 +280,58 @@ func TestFeishuSend(t *testing.T) {
 	_ = srv
 }
 
+func TestFeishuSendReplyInThread(t *testing.T) {
+	var paths []string
+	var reply map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		if strings.HasSuffix(r.URL.Path, "/reply") {
+			json.NewDecoder(r.Body).Decode(&reply)
+		}
+		w.Write([]byte(`{"code":0}`))
+	}))
+	defer srv.Close()
+	redirectDefaultTransport(t, srv)
+
+	ch := newTestFeishu(t, nil)
+	ch.token.token = "test-token"
+	ch.token.expiry = time.Now().Add(time.Hour)
+	if err := ch.Send(bus.OutboundMessage{ChatID: "oc_1", Content: "hello", ReplyTo: "om_root"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(paths) != 1 || paths[0] != "/open-apis/im/v1/messages/om_root/reply" {
+		t.Fatalf("requests = %v, want one reply to om_root", paths)
+	}
+	if reply["msg_type"] != "text" || reply["content"] != `{"text":"hello"}` || reply["reply_in_thread"] != true {
+		t.Errorf("reply body = %v", reply)
+	}
+}
+
+func TestFeishuSendReplyFallsBackToChat(t *testing.T) {
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		if strings.HasSuffix(r.URL.Path, "/reply") {
+			w.Write([]byte(`{"code":230011,"msg":"message recalled"}`))
+			return
+		}
+		w.Write([]byte(`{"code":0}`))
+	}))
+	defer srv.Close()
+	redirectDefaultTransport(t, srv)
+
+	ch := newTestFeishu(t, nil)
+	ch.token.token = "test-token"
+	ch.token.expiry = time.Now().Add(time.Hour)
+	if err := ch.Send(bus.OutboundMessage{ChatID: "oc_1", Content: "hello", ReplyTo: "om_gone"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 2 || paths[1] != "/open-apis/im/v1/messages" {
+		t.Errorf("requests = %v, want a reply then a chat send", paths)
+	}
+}
+
 func TestFeishuStop(t *testing.T) {
 	ch := newTestFeishu(t, nil)
 	// Stop on a server that was never started should not panic.
@@ -239,3 +340,80 @@ func TestFeishuStop(t *testing.T) {
 		t.Errorf("unexpected error from Stop: %v", err)
 	}
 }
+
+func TestFeishuSendFile(t *testing.T) {
+	type upload struct{ path, field, name, data, fileType, imageType string }
+	uploads := make(chan upload, 2)
+	var messages []map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.Contains(r.URL.Path, "tenant_access_token"):
+			w.Write([]byte(`{"code":0,"tenant_access_token":"t","expire":7200}`))
+		case strings.HasPrefix(r.URL.Path, "/open-apis/im/v1/files"), strings.HasPrefix(r.URL.Path, "/open-apis/im/v1/images"):
+			if err := r.ParseMultipartForm(1 << 20); err != nil {
+				t.Errorf("upload is not multipart: %v", err)
+			}
+			field := "file"
+			if strings.HasSuffix(r.URL.Path, "images") {
+				field = "image"
+			}
+			f, hdr, err := r.FormFile(field)
+			if err != nil {
+				t.Fatalf("upload has no %s part: %v", field, err)
+			}
+			data, _ := io.ReadAll(f)
+			uploads <- upload{r.URL.Path, field, hdr.Filename, string(data), r.FormValue("file_type"), r.FormValue("image_type")}
+			w.Write([]byte(`{"code":0,"data":{"file_key":"fk","image_key":"ik"}}`))
+		case r.URL.Path == "/open-apis/im/v1/messages":
+			var m map[string]string
+			json.NewDecoder(r.Body).Decode(&m)
+			messages = append(messages, m)
+			w.Write([]byte(`{"code":0}`))
+		default:
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+	}))
+	defer srv.Close()
+	redirectDefaultTransport(t, srv)
+
+	ch := newTestFeishu(t, nil)
+	if err := ch.SendFile("oc_1", bus.Attachment{Name: "report.pdf", MimeType: "application/pdf", Data: []byte("%PDF")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ch.SendFile("oc_1", bus.Attachment{Name: "chart.png", MimeType: "image/png", Data: []byte("png")}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := <-uploads; got != (upload{"/open-apis/im/v1/files", "file", "report.pdf", "%PDF", "pdf", ""}) {
+		t.Errorf("file upload = %+v", got)
+	}
+	if got := <-uploads; got != (upload{"/open-apis/im/v1/images", "image", "chart.png", "png", "", "message"}) {
+		t.Errorf("image upload = %+v", got)
+	}
+	if len(messages) != 2 {
+		t.Fatalf("sent %d messages, want 2", len(messages))
+	}
+	if m := messages[0]; m["receive_id"] != "oc_1" || m["msg_type"] != "file" || m["content"] != `{"file_key":"fk"}` {
+		t.Errorf("file message = %v", m)
+	}
+	if m := messages[1]; m["msg_type"] != "image" || m["content"] != `{"image_key":"ik"}` {
+		t.Errorf("image message = %v", m)
+	}
+}
+
+func TestFeishuSendFileUploadError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "tenant_access_token") {
+			w.Write([]byte(`{"code":0,"tenant_access_token":"t","expire":7200}`))
+			return
+		}
+		w.Write([]byte(`{"code":234001,"msg":"file too large"}`))
+	}))
+	defer srv.Close()
+	redirectDefaultTransport(t, srv)
+
+	err := newTestFeishu(t, nil).SendFile("oc_1", bus.Attachment{Name: "big.zip", MimeType: "application/zip", Data: []byte("z")})
+	if err == nil || !strings.Contains(err.Error(), "file too large") {
+		t.Errorf("err = %v, want the API error", err)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"sync"
 
 	"github.com/coopco/nanobot/internal/bus"
+	"github.com/coopco/nanobot/internal/metrics"
 )
 
 type Manager struct {
@@ -72,7 +73,25 @@ func (m *Manager) StopAll() error {
 	return firstErr
 }
 
-// setupOutboundDispatch subscribes to outbound messages and routes to channels.
+// Shutdown stops the bus from accepting new inbound messages, waits up to
+// ctx's deadline for the buffered ones to be consumed and the queued replies
+// sent, and then stops every channel. Channels are stopped even if the drain
+// times out; the drain error is returned ahead of any stop error.
+func (m *Manager) Shutdown(ctx context.Context) error {
+	drainErr := m.bus.Drain(ctx)
+	if drainErr != nil {
+		slog.Warn("shutting down with undelivered messages", "stats", m.bus.Stats(), "error", drainErr)
+	}
+	stopErr := m.StopAll()
+	if drainErr != nil {
+		return fmt.Errorf("drain message bus: %w", drainErr)
+	}
+	return stopErr
+}
+
+// setupOutboundDispatch subscribes to outbound messages and routes to
+// channels. A message for a channel that is not running goes to the bus's
+// dead-letter handler.
 func (m *Manager) setupOutboundDispatch() {
 	m.bus.Subscribe("", func(msg bus.OutboundMessage) {
 		if msg.Type == "progress" || msg.Type == "tool_hint" {
@@ -85,11 +104,58 @@ func (m *Manager) setupOutboundDispatch() {
 
 		for _, ch := range chs {
 			if ch.Name() == msg.Channel {
-				if err := ch.Send(msg); err != nil {
-					slog.Error("failed to send message", "channel", ch.Name(), "error", err)
-				}
+				sendParts(ch, msg)
 				return
 			}
 		}
+		m.bus.DeadLetter(msg)
 	})
 }
+
+// sendParts sends msg on ch, split into several messages in order if it is
+// longer than the channel's MessageLimiter allows, followed by its
+// attachments. Sending stops at the first failure so the recipient never
+// sees a later part without the earlier.
+func sendParts(ch Channel, msg bus.OutboundMessage) {
+	var parts []string
+	if msg.Content != "" || len(msg.Attachments) == 0 {
+		parts = []string{msg.Content}
+		if l, ok := ch.(MessageLimiter); ok {
+			parts = splitMessage(msg.Content, l.MaxMessageLength())
+		}
+	}
+	for i, part := range parts {
+		text := msg
+		text.Content = part
+		text.Attachments = nil
+		if err := ch.Send(text); err != nil {
+			slog.Error("failed to send message", "channel", ch.Name(), "part", i+1, "parts", len(parts), "error", err)
+			metrics.Errors.Inc("channel")
+			return
+		}
+	}
+	for _, a := range msg.Attachments {
+		if err := sendAttachment(ch, msg, a); err != nil {
+			slog.Error("failed to send attachment", "channel", ch.Name(), "file", attachmentName(a), "error", err)
+			metrics.Errors.Inc("channel")
+			return
+		}
+	}
+	metrics.MessagesOut.Inc(ch.Name())
+}
+
+// sendAttachment delivers a as a file on ch. When ch cannot send files, or
+// the file cannot be read, it sends a note naming the file instead.
+func sendAttachment(ch Channel, msg bus.OutboundMessage, a bus.Attachment) error {
+	if fs, ok := ch.(FileSender); ok {
+		file, err := loadAttachment(a)
+		if err == nil {
+			return fs.SendFile(msg.ChatID, file)
+		}
+		slog.Warn("failed to load attachment", "channel", ch.Name(), "file", attachmentName(a), "error", err)
+	}
+	note := msg
+	note.Content = attachmentNote(a)
+	note.Attachments = nil
+	return ch.Send(note)
+}
@@ -174,3 +174,40 @@ func TestOutboundDispatchWrongChannel(t *testing.T) {
 		t.Errorf("expected 0 messages for wrong channel, got %d", len(mock.sent))
 	}
 }
+
+func TestOutboundDispatchUnknownChannelIsDeadLettered(t *testing.T) {
+	const name = "test-dead-letter"
+	mock := &mockChannel{name: name}
+	Register(name, func(cfg json.RawMessage, msgBus *bus.MessageBus) (Channel, error) {
+		return mock, nil
+	})
+
+	msgBus := bus.NewMessageBus(16)
+	dead := make(chan bus.OutboundMessage, 4)
+	msgBus.SetDeadLetter(func(msg bus.OutboundMessage) { dead <- msg })
+	mgr := NewManager(msgBus)
+	if err := mgr.AddChannel(name, json.RawMessage(`{}`)); err != nil {
+		t.Fatalf("AddChannel: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go msgBus.DispatchOutbound(ctx)
+
+	msgBus.PublishOutbound(bus.OutboundMessage{Channel: "not-running", ChatID: "c1", Type: "text", Content: "lost"})
+	msgBus.PublishOutbound(bus.OutboundMessage{Channel: name, ChatID: "c1", Type: "text", Content: "delivered"})
+
+	select {
+	case msg := <-dead:
+		if msg.Channel != "not-running" || msg.Content != "lost" {
+			t.Errorf("dead letter = %+v, want the message for the unknown channel", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("message for an unknown channel was not dead-lettered")
+	}
+	select {
+	case msg := <-dead:
+		t.Errorf("unexpected dead letter: %+v", msg)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -14,6 +14,7 @@ type mockChannel struct {
 	name    string
 	sent    []bus.OutboundMessage
 	started bool
+	stopped bool
 }
 
 func (m *mockChannel) Name() string { return m.name }
@@ -21,7 +22,10 @@ func (m *mockChannel) Start(_ context.Context) error {
 	m.started = true
 	return nil
 }
-func (m *mockChannel) Stop() error { return nil }
+func (m *mockChannel) Stop() error {
+	m.stopped = true
+	return nil
+}
 func (m *mockChannel) Send(msg bus.OutboundMessage) error {
 	m.sent = append(m.sent, msg)
 	return nil
@@ -142,3 +146,29 @@ func TestOutboundDispatchFiltering(t *testing.T) {
 		t.Fatalf("expected content %q, got %q", "hello", mock.sent[0].Content)
 	}
 }
+
+func TestManagerShutdownDeliversQueuedReplies(t *testing.T) {
+	msgBus := bus.NewMessageBus(16)
+	mgr := NewManager(msgBus)
+	mock := &mockChannel{name: "test-channel-shutdown"}
+	mgr.channels = append(mgr.channels, mock)
+
+	for i := 0; i < 3; i++ {
+		msgBus.PublishOutbound(bus.OutboundMessage{Channel: mock.name, Type: "text", Content: "reply"})
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go msgBus.DispatchOutbound(ctx)
+
+	drainCtx, drainCancel := context.WithTimeout(ctx, 2*time.Second)
+	defer drainCancel()
+	if err := mgr.Shutdown(drainCtx); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if len(mock.sent) != 3 {
+		t.Errorf("sent %d replies before shutdown, want 3", len(mock.sent))
+	}
+	if !mock.stopped {
+		t.Error("channel not stopped")
+	}
+}
@@ -8,10 +8,12 @@ import (
 	"io"
 	"log/slog"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/coopco/nanobot/internal/bus"
+	"github.com/coopco/nanobot/internal/tlsutil"
 )
 
 func init() {
@@ -21,6 +23,7 @@ func init() {
 type mochatConfig struct {
 	URL          string   `json:"url"`
 	AllowedUsers []string `json:"allowedUsers"`
+	tlsutil.Options
 }
 
 // MochatChannel implements Channel for Mochat via HTTP long-polling.
@@ -30,6 +33,7 @@ type MochatChannel struct {
 	allowedUsers map[string]bool
 	cancel       context.CancelFunc
 	lastSince    int64
+	client       *http.Client // nil uses http.DefaultClient
 }
 
 func newMochatChannel(cfg json.RawMessage, msgBus *bus.MessageBus) (Channel, error) {
@@ -42,12 +46,27 @@ func newMochatChannel(cfg json.RawMessage, msgBus *bus.MessageBus) (Channel, err
 	for _, u := range c.AllowedUsers {
 		allowed[u] = true
 	}
-	return &MochatChannel{
+	ch := &MochatChannel{
 		baseURL:      c.URL,
 		bus:          msgBus,
 		allowedUsers: allowed,
 		lastSince:    time.Now().Unix(),
-	}, nil
+	}
+	transport, err := c.Options.Transport()
+	if err != nil {
+		return nil, fmt.Errorf("mochat: %w", err)
+	}
+	if transport != nil {
+		ch.client = &http.Client{Transport: transport}
+	}
+	return ch, nil
+}
+
+func (c *MochatChannel) httpClient() *http.Client {
+	if c.client != nil {
+		return c.client
+	}
+	return http.DefaultClient
 }
 
 func (c *MochatChannel) Name() string { return "mochat" }
@@ -74,7 +93,7 @@ func (c *MochatChannel) Start(ctx context.Context) error {
 
 func (c *MochatChannel) poll() {
 	url := fmt.Sprintf("%s/api/messages?since=%d", c.baseURL, c.lastSince)
-	resp, err := http.Get(url)
+	resp, err := c.httpClient().Get(url)
 	if err != nil {
 		slog.Error("mochat: poll error", "err", err)
 		return
@@ -88,11 +107,12 @@ func (c *MochatChannel) poll() {
 	}
 
 	var messages []struct {
-		ID        int64  `json:"id"`
-		Timestamp int64  `json:"timestamp"`
-		SenderID  string `json:"senderId"`
-		ChatID    string `json:"chatId"`
-		Content   string `json:"content"`
+		ID         int64  `json:"id"`
+		Timestamp  int64  `json:"timestamp"` // seconds since the epoch
+		SenderID   string `json:"senderId"`
+		SenderName string `json:"senderName"`
+		ChatID     string `json:"chatId"`
+		Content    string `json:"content"`
 	}
 	if err := json.Unmarshal(data, &messages); err != nil {
 		return
@@ -106,11 +126,16 @@ func (c *MochatChannel) poll() {
 			slog.Warn("mochat: message from disallowed user", "user", msg.SenderID)
 			continue
 		}
+		var sent time.Time
+		if msg.Timestamp > 0 {
+			sent = time.Unix(msg.Timestamp, 0)
+		}
 		c.bus.PublishInbound(bus.InboundMessage{
 			Channel:  "mochat",
 			SenderID: msg.SenderID,
 			ChatID:   msg.ChatID,
 			Content:  msg.Content,
+			Metadata: inboundMeta(strconv.FormatInt(msg.ID, 10), msg.SenderName, sent),
 		})
 	}
 }
@@ -127,7 +152,13 @@ func (c *MochatChannel) Send(msg bus.OutboundMessage) error {
 		"chatId":  msg.ChatID,
 		"content": msg.Content,
 	})
-	resp, err := http.Post(c.baseURL+"/api/messages", "application/json", bytes.NewReader(body))
+	req, err := http.NewRequest(http.MethodPost, c.baseURL+"/api/messages", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := doWithRetry(c.httpClient(), req)
 	if err != nil {
 		return fmt.Errorf("mochat: send: %w", err)
 	}
@@ -8,6 +8,7 @@ import (
 	"io"
 	"log/slog"
 	"net/http"
+	"time"
 
 	"github.com/coopco/nanobot/internal/bus"
 )
@@ -17,20 +18,26 @@ func init() {
 }
 
 type qqConfig struct {
-	AppID        string   `json:"appId"`
-	Token        string   `json:"token"`
-	AppSecret    string   `json:"appSecret"`
-	WebhookPort  int      `json:"webhookPort"`
-	AllowedUsers []string `json:"allowedUsers"`
+	AppID            string   `json:"appId"`
+	Token            string   `json:"token"`
+	AppSecret        string   `json:"appSecret"`
+	WebhookPort      int      `json:"webhookPort"`
+	AllowedUsers     []string `json:"allowedUsers"`
+	MaxMessageLength int      `json:"maxMessageLength"`
 }
 
 // QQChannel implements Channel for QQ Official Bot via HTTP webhook.
+// Messages are sent with an app access token obtained from appSecret; the
+// deprecated static bot token is used only when no secret is configured.
 type QQChannel struct {
 	appID        string
 	token        string
+	appSecret    string
+	accessToken  expiringToken
 	bus          *bus.MessageBus
 	allowedUsers map[string]bool
 	server       *http.Server
+	maxLen       int // longest message sent in one piece
 }
 
 func newQQChannel(cfg json.RawMessage, msgBus *bus.MessageBus) (Channel, error) {
@@ -45,18 +52,29 @@ func newQQChannel(cfg json.RawMessage, msgBus *bus.MessageBus) (Channel, error)
 	for _, u := range c.AllowedUsers {
 		allowed[u] = true
 	}
-	return &QQChannel{
+	ch := &QQChannel{
 		appID:        c.AppID,
 		token:        c.Token,
+		appSecret:    c.AppSecret,
 		bus:          msgBus,
 		allowedUsers: allowed,
 		server:       &http.Server{Addr: fmt.Sprintf(":%d", c.WebhookPort)},
-	}, nil
+		maxLen:       maxMessageLength(c.MaxMessageLength, qqMaxMessage),
+	}
+	ch.accessToken.fetch = ch.fetchToken
+	return ch, nil
 }
 
 func (c *QQChannel) Name() string { return "qq" }
 
 func (c *QQChannel) Start(ctx context.Context) error {
+	if c.appSecret != "" {
+		if err := c.accessToken.refresh(); err != nil {
+			return fmt.Errorf("qq: get access token: %w", err)
+		}
+		go c.accessToken.keepFresh(ctx, "qq")
+	}
+
 	mux := http.NewServeMux()
 	mux.HandleFunc("/", c.handleEvent)
 	c.server.Handler = mux
@@ -75,6 +93,45 @@ func (c *QQChannel) Start(ctx context.Context) error {
 	return nil
 }
 
+// fetchToken requests a new app access token and reports how long it is valid.
+func (c *QQChannel) fetchToken() (string, time.Duration, error) {
+	body, _ := json.Marshal(map[string]string{
+		"appId":        c.appID,
+		"clientSecret": c.appSecret,
+	})
+	resp, err := http.Post("https://bots.qq.com/app/getAppAccessToken", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return "", 0, err
+	}
+	defer resp.Body.Close()
+	var result struct {
+		AccessToken string      `json:"access_token"`
+		ExpiresIn   json.Number `json:"expires_in"` // seconds, sent as a string
+		Code        int         `json:"code"`
+		Message     string      `json:"message"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", 0, err
+	}
+	if result.AccessToken == "" {
+		return "", 0, fmt.Errorf("qq auth error %d: %s", result.Code, result.Message)
+	}
+	expiresIn, _ := result.ExpiresIn.Int64()
+	return result.AccessToken, time.Duration(expiresIn) * time.Second, nil
+}
+
+// authorization returns the Authorization header value for API requests.
+func (c *QQChannel) authorization() (string, error) {
+	if c.appSecret == "" {
+		return fmt.Sprintf("Bot %s.%s", c.appID, c.token), nil
+	}
+	token, err := c.accessToken.get()
+	if err != nil {
+		return "", err
+	}
+	return "QQBot " + token, nil
+}
+
 func (c *QQChannel) handleEvent(w http.ResponseWriter, r *http.Request) {
 	data, err := io.ReadAll(r.Body)
 	if err != nil {
@@ -103,9 +160,11 @@ func (c *QQChannel) handleEvent(w http.ResponseWriter, r *http.Request) {
 			ID        string `json:"id"`
 			ChannelID string `json:"channel_id"`
 			Author    struct {
-				ID string `json:"id"`
+				ID       string `json:"id"`
+				Username string `json:"username"`
 			} `json:"author"`
-			Content string `json:"content"`
+			Content   string `json:"content"`
+			Timestamp string `json:"timestamp"` // RFC 3339
 		} `json:"d"`
 	}
 	if err := json.Unmarshal(data, &event); err != nil {
@@ -125,11 +184,13 @@ func (c *QQChannel) handleEvent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	sent, _ := time.Parse(time.RFC3339, event.D.Timestamp)
 	c.bus.PublishInbound(bus.InboundMessage{
 		Channel:  "qq",
 		SenderID: senderID,
 		ChatID:   event.D.ChannelID,
 		Content:  event.D.Content,
+		Metadata: inboundMeta(event.D.ID, event.D.Author.Username, sent),
 	})
 	w.WriteHeader(http.StatusOK)
 }
@@ -143,14 +204,18 @@ func (c *QQChannel) Send(msg bus.OutboundMessage) error {
 		"content": msg.Content,
 	})
 	url := fmt.Sprintf("https://api.sgroup.qq.com/channels/%s/messages", msg.ChatID)
+	auth, err := c.authorization()
+	if err != nil {
+		return fmt.Errorf("qq: get access token: %w", err)
+	}
 	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
 	if err != nil {
 		return err
 	}
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bot %s.%s", c.appID, c.token))
+	req.Header.Set("Authorization", auth)
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := doWithRetry(http.DefaultClient, req)
 	if err != nil {
 		return fmt.Errorf("qq: send message: %w", err)
 	}
@@ -168,3 +233,6 @@ func (c *QQChannel) IsAllowed(senderID string) bool {
 	}
 	return c.allowedUsers[senderID]
 }
+
+// MaxMessageLength implements MessageLimiter.
+func (c *QQChannel) MaxMessageLength() int { return c.maxLen }
@@ -0,0 +1,98 @@
+package channels
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// sendRetry controls how outbound API calls are retried on transient
+// failures. It is a variable so tests can shorten the delays.
+var sendRetry = retryPolicy{
+	attempts:  4,
+	baseDelay: 500 * time.Millisecond,
+	maxDelay:  30 * time.Second,
+}
+
+// retryPolicy is the number of attempts for a request and the bounds of the
+// exponential backoff between them.
+type retryPolicy struct {
+	attempts  int
+	baseDelay time.Duration
+	maxDelay  time.Duration
+}
+
+// doWithRetry sends req with client, retrying on network errors, 429 and 5xx
+// responses. The wait between attempts doubles from the base delay unless the
+// server asks for a specific one with Retry-After. The last response or error
+// is returned for the caller to handle as usual; other responses are returned
+// at once. req's body must be replayable, which http.NewRequest arranges for
+// in-memory readers.
+func doWithRetry(client *http.Client, req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	for attempt := 1; ; attempt++ {
+		r := req
+		if attempt > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			r = req.Clone(ctx)
+			r.Body = body
+		}
+
+		resp, err := client.Do(r)
+		if attempt >= sendRetry.attempts || !retryable(resp, err) {
+			return resp, err
+		}
+
+		delay := sendRetry.backoff(attempt)
+		if resp != nil {
+			if d, ok := retryAfter(resp.Header.Get("Retry-After")); ok {
+				delay = min(d, sendRetry.maxDelay)
+			}
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// retryable reports whether a request that ended with resp or err may
+// succeed if sent again.
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+}
+
+// backoff returns the wait after the given failed attempt, counting from 1.
+func (p retryPolicy) backoff(attempt int) time.Duration {
+	d := p.baseDelay << (attempt - 1)
+	if d <= 0 || d > p.maxDelay {
+		return p.maxDelay
+	}
+	return d
+}
+
+// retryAfter parses a Retry-After header given in seconds or as an HTTP date.
+func retryAfter(v string) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(time.Until(t), 0), true
+	}
+	return 0, false
+}
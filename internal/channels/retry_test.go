@@ -0,0 +1,101 @@
+package channels
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/coopco/nanobot/internal/bus"
+)
+
+// fastRetries shortens the send retry delays for the duration of a test.
+func fastRetries(t *testing.T) {
+	t.Helper()
+	saved := sendRetry
+	sendRetry = retryPolicy{attempts: 3, baseDelay: time.Millisecond, maxDelay: 5 * time.Millisecond}
+	t.Cleanup(func() { sendRetry = saved })
+}
+
+func TestSendRetriesTransientFailures(t *testing.T) {
+	fastRetries(t)
+	var calls atomic.Int32
+	var delivered string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch calls.Add(1) {
+		case 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			body, _ := io.ReadAll(r.Body)
+			delivered = string(body)
+		}
+	}))
+	defer srv.Close()
+
+	mc := &MochatChannel{baseURL: srv.URL}
+	if err := mc.Send(bus.OutboundMessage{ChatID: "c1", Content: "hello"}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if calls.Load() != 3 {
+		t.Errorf("server saw %d requests, want 3", calls.Load())
+	}
+	if !strings.Contains(delivered, "hello") {
+		t.Errorf("delivered body = %q, want the full message on the retried request", delivered)
+	}
+}
+
+func TestSendDoesNotRetryClientErrors(t *testing.T) {
+	fastRetries(t)
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	mc := &MochatChannel{baseURL: srv.URL}
+	if err := mc.Send(bus.OutboundMessage{ChatID: "c1", Content: "hello"}); err == nil {
+		t.Fatal("expected error for 400 response")
+	}
+	if calls.Load() != 1 {
+		t.Errorf("server saw %d requests, want 1", calls.Load())
+	}
+}
+
+func TestSendGivesUpAfterMaxAttempts(t *testing.T) {
+	fastRetries(t)
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	mc := &MochatChannel{baseURL: srv.URL}
+	err := mc.Send(bus.OutboundMessage{ChatID: "c1", Content: "hello"})
+	if err == nil || !strings.Contains(err.Error(), "502") {
+		t.Errorf("Send error = %v, want the final 502", err)
+	}
+	if calls.Load() != int32(sendRetry.attempts) {
+		t.Errorf("server saw %d requests, want %d", calls.Load(), sendRetry.attempts)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	if d, ok := retryAfter("7"); !ok || d != 7*time.Second {
+		t.Errorf("retryAfter(7) = %v, %v", d, ok)
+	}
+	date := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	if d, ok := retryAfter(date); !ok || d < 59*time.Minute {
+		t.Errorf("retryAfter(date) = %v, %v", d, ok)
+	}
+	if _, ok := retryAfter("soon"); ok {
+		t.Error("expected an unparseable Retry-After to be ignored")
+	}
+}
@@ -17,9 +17,10 @@ func init() {
 }
 
 type slackConfig struct {
-	BotToken     string   `json:"botToken"`
-	AppToken     string   `json:"appToken"`
-	AllowedUsers []string `json:"allowedUsers"`
+	BotToken         string   `json:"botToken"`
+	AppToken         string   `json:"appToken"`
+	AllowedUsers     []string `json:"allowedUsers"`
+	MaxMessageLength int      `json:"maxMessageLength"`
 }
 
 // SlackChannel implements Channel for Slack via socket mode.
@@ -28,6 +29,7 @@ type SlackChannel struct {
 	socketClient *socketmode.Client
 	bus          *bus.MessageBus
 	allowedUsers map[string]bool
+	maxLen       int // longest message sent in one piece
 }
 
 func newSlackChannel(cfg json.RawMessage, msgBus *bus.MessageBus) (Channel, error) {
@@ -46,6 +48,7 @@ func newSlackChannel(cfg json.RawMessage, msgBus *bus.MessageBus) (Channel, erro
 		socketClient: socketClient,
 		bus:          msgBus,
 		allowedUsers: allowed,
+		maxLen:       maxMessageLength(c.MaxMessageLength, slackMaxMessage),
 	}, nil
 }
 
@@ -106,3 +109,6 @@ func (c *SlackChannel) IsAllowed(senderID string) bool {
 	}
 	return c.allowedUsers[senderID]
 }
+
+// MaxMessageLength implements MessageLimiter.
+func (c *SlackChannel) MaxMessageLength() int { return c.maxLen }
@@ -4,8 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log/slog"
+	"net/http"
 	"strconv"
+	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 
@@ -17,8 +20,9 @@ func init() {
 }
 
 type telegramConfig struct {
-	Token        string   `json:"token"`
-	AllowedUsers []string `json:"allowedUsers"`
+	Token            string   `json:"token"`
+	AllowedUsers     []string `json:"allowedUsers"`
+	MaxMessageLength int      `json:"maxMessageLength"`
 }
 
 type TelegramChannel struct {
@@ -26,6 +30,8 @@ type TelegramChannel struct {
 	bus          *bus.MessageBus
 	allowedUsers map[string]bool
 	stopCh       chan struct{}
+	maxLen       int // longest message sent in one piece
+	typing       typingLoop
 }
 
 func newTelegramChannel(cfg json.RawMessage, msgBus *bus.MessageBus) (Channel, error) {
@@ -41,12 +47,15 @@ func newTelegramChannel(cfg json.RawMessage, msgBus *bus.MessageBus) (Channel, e
 	for _, u := range tcfg.AllowedUsers {
 		allowed[u] = true
 	}
-	return &TelegramChannel{
+	c := &TelegramChannel{
 		bot:          bot,
 		bus:          msgBus,
 		allowedUsers: allowed,
 		stopCh:       make(chan struct{}),
-	}, nil
+		maxLen:       maxMessageLength(tcfg.MaxMessageLength, telegramMaxMessage),
+	}
+	c.typing = typingLoop{interval: telegramTypingInterval, send: c.sendTyping}
+	return c, nil
 }
 
 func (c *TelegramChannel) Name() string { return "telegram" }
@@ -72,12 +81,21 @@ func (c *TelegramChannel) Start(ctx context.Context) error {
 					continue
 				}
 				chatID := strconv.FormatInt(update.Message.Chat.ID, 10)
-				c.bus.PublishInbound(bus.InboundMessage{
+				content := update.Message.Text
+				if content == "" {
+					content = update.Message.Caption
+				}
+				err := c.bus.PublishInboundContext(ctx, bus.InboundMessage{
 					Channel:  "telegram",
 					SenderID: senderID,
 					ChatID:   chatID,
-					Content:  update.Message.Text,
+					Content:  content,
+					Media:    c.photoMedia(update.Message.Photo),
 				})
+				if err != nil {
+					c.bot.StopReceivingUpdates()
+					return
+				}
 			case <-ctx.Done():
 				c.bot.StopReceivingUpdates()
 				return
@@ -105,9 +123,89 @@ func (c *TelegramChannel) Send(msg bus.OutboundMessage) error {
 	return err
 }
 
+// SendFile implements FileSender. Images are sent as photos, anything else
+// as a document.
+func (c *TelegramChannel) SendFile(chatID string, file bus.Attachment) error {
+	id, err := strconv.ParseInt(chatID, 10, 64)
+	if err != nil {
+		return fmt.Errorf("telegram: invalid chatID %q: %w", chatID, err)
+	}
+	data := tgbotapi.FileBytes{Name: file.Name, Bytes: file.Data}
+	var m tgbotapi.Chattable = tgbotapi.NewDocument(id, data)
+	if file.MimeType == "image/jpeg" || file.MimeType == "image/png" {
+		m = tgbotapi.NewPhoto(id, data)
+	}
+	if _, err := c.bot.Send(m); err != nil {
+		return fmt.Errorf("telegram: send file: %w", err)
+	}
+	return nil
+}
+
+// telegramTypingInterval is how often the typing action is repeated; Telegram
+// shows it for five seconds or until the bot's next message.
+const telegramTypingInterval = 4 * time.Second
+
+// StartTyping implements Typer.
+func (c *TelegramChannel) StartTyping(chatID string) error { return c.typing.start(chatID) }
+
+// StopTyping implements Typer. Telegram has no call to clear the indicator,
+// so it is left to expire.
+func (c *TelegramChannel) StopTyping(chatID string) error {
+	c.typing.stop(chatID)
+	return nil
+}
+
+func (c *TelegramChannel) sendTyping(chatID string) error {
+	id, err := strconv.ParseInt(chatID, 10, 64)
+	if err != nil {
+		return fmt.Errorf("telegram: invalid chatID %q: %w", chatID, err)
+	}
+	if _, err := c.bot.Request(tgbotapi.NewChatAction(id, tgbotapi.ChatTyping)); err != nil {
+		return fmt.Errorf("telegram: send chat action: %w", err)
+	}
+	return nil
+}
+
 func (c *TelegramChannel) IsAllowed(senderID string) bool {
 	if len(c.allowedUsers) == 0 {
 		return true
 	}
 	return c.allowedUsers[senderID]
 }
+
+// MaxMessageLength implements MessageLimiter.
+func (c *TelegramChannel) MaxMessageLength() int { return c.maxLen }
+
+// telegramFileClient downloads photos attached to incoming messages.
+var telegramFileClient = &http.Client{Timeout: 30 * time.Second}
+
+// photoMedia downloads the largest size of an attached photo. The bytes are
+// inlined rather than passing the file URL on, because that URL embeds the
+// bot token. A failed download is logged and the photo skipped.
+func (c *TelegramChannel) photoMedia(sizes []tgbotapi.PhotoSize) []bus.Media {
+	if len(sizes) == 0 {
+		return nil
+	}
+	url, err := c.bot.GetFileDirectURL(sizes[len(sizes)-1].FileID)
+	if err != nil {
+		slog.Warn("telegram: failed to resolve photo", "err", err)
+		return nil
+	}
+	resp, err := telegramFileClient.Get(url)
+	if err != nil {
+		slog.Warn("telegram: failed to download photo", "err", err)
+		return nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		slog.Warn("telegram: failed to download photo", "status", resp.StatusCode)
+		return nil
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		slog.Warn("telegram: failed to read photo", "err", err)
+		return nil
+	}
+	// Telegram re-encodes every photo size as JPEG.
+	return []bus.Media{{Type: "image", MimeType: "image/jpeg", Data: data}}
+}
@@ -0,0 +1,88 @@
+package channels
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// tokenRefreshMargin is how long before expiry an access token is renewed.
+const tokenRefreshMargin = 5 * time.Minute
+
+// tokenRetryDelay is how long the background refresher waits after a failure.
+const tokenRetryDelay = time.Minute
+
+// expiringToken caches a platform access token that expires, such as a Feishu
+// tenant_access_token or a DingTalk app token, and renews it with fetch.
+// A token with no known expiry is used until replaced.
+type expiringToken struct {
+	fetch func() (token string, expiresIn time.Duration, err error)
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+// get returns a token that is not about to expire, fetching a new one if
+// needed.
+func (t *expiringToken) get() (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.token != "" && (t.expiry.IsZero() || time.Until(t.expiry) > tokenRefreshMargin) {
+		return t.token, nil
+	}
+	if err := t.refreshLocked(); err != nil {
+		return "", err
+	}
+	return t.token, nil
+}
+
+// refresh fetches a new token unconditionally.
+func (t *expiringToken) refresh() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.refreshLocked()
+}
+
+func (t *expiringToken) refreshLocked() error {
+	token, expiresIn, err := t.fetch()
+	if err != nil {
+		return err
+	}
+	t.token = token
+	t.expiry = time.Time{}
+	if expiresIn > 0 {
+		t.expiry = time.Now().Add(expiresIn)
+	}
+	return nil
+}
+
+// keepFresh renews the token shortly before each expiry until ctx is
+// cancelled, so sends rarely wait on a refresh. name labels log lines.
+func (t *expiringToken) keepFresh(ctx context.Context, name string) {
+	for {
+		t.mu.Lock()
+		expiry := t.expiry
+		t.mu.Unlock()
+		if expiry.IsZero() {
+			return
+		}
+
+		timer := time.NewTimer(max(time.Until(expiry)-tokenRefreshMargin, 0))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		if err := t.refresh(); err != nil {
+			slog.Warn(name+": access token refresh failed", "err", err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(tokenRetryDelay):
+			}
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package channels
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/coopco/nanobot/internal/bus"
+)
+
+// redirectDefaultTransport sends every request made through the default
+// HTTP client to srv for the rest of the test.
+func redirectDefaultTransport(t *testing.T, srv *httptest.Server) {
+	t.Helper()
+	orig := http.DefaultTransport
+	http.DefaultTransport = &redirectTransport{target: srv.URL, base: orig}
+	t.Cleanup(func() { http.DefaultTransport = orig })
+}
+
+func TestFeishuSendRefreshesExpiredToken(t *testing.T) {
+	var issued atomic.Int32
+	var lastAuth atomic.Value
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "tenant_access_token") {
+			n := issued.Add(1)
+			fmt.Fprintf(w, `{"code":0,"msg":"ok","tenant_access_token":"t-%d","expire":7200}`, n)
+			return
+		}
+		lastAuth.Store(r.Header.Get("Authorization"))
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+	redirectDefaultTransport(t, srv)
+
+	ch := newTestFeishu(t, nil)
+	if err := ch.token.refresh(); err != nil {
+		t.Fatal(err)
+	}
+	if err := ch.Send(bus.OutboundMessage{ChatID: "c1", Content: "one"}); err != nil {
+		t.Fatal(err)
+	}
+	if lastAuth.Load() != "Bearer t-1" || issued.Load() != 1 {
+		t.Fatalf("first send used %v after %d token requests", lastAuth.Load(), issued.Load())
+	}
+
+	// Simulate the token reaching its expiry.
+	ch.token.mu.Lock()
+	ch.token.expiry = time.Now().Add(-time.Second)
+	ch.token.mu.Unlock()
+
+	if err := ch.Send(bus.OutboundMessage{ChatID: "c1", Content: "two"}); err != nil {
+		t.Fatal(err)
+	}
+	if lastAuth.Load() != "Bearer t-2" || issued.Load() != 2 {
+		t.Errorf("second send used %v after %d token requests, want a refreshed token", lastAuth.Load(), issued.Load())
+	}
+}
+
+func TestDingTalkSendRefreshesExpiredToken(t *testing.T) {
+	var issued atomic.Int32
+	var lastToken atomic.Value
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "oauth2/accessToken") {
+			n := issued.Add(1)
+			fmt.Fprintf(w, `{"accessToken":"d-%d","expireIn":7200}`, n)
+			return
+		}
+		lastToken.Store(r.Header.Get("x-acs-dingtalk-access-token"))
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+	redirectDefaultTransport(t, srv)
+
+	ch, err := newDingTalkChannel([]byte(`{"clientId":"cid","clientSecret":"sec"}`), bus.NewMessageBus(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dc := ch.(*DingTalkChannel)
+	if err := dc.token.refresh(); err != nil {
+		t.Fatal(err)
+	}
+	dc.token.mu.Lock()
+	dc.token.expiry = time.Now().Add(tokenRefreshMargin / 2) // inside the refresh margin
+	dc.token.mu.Unlock()
+
+	if err := dc.Send(bus.OutboundMessage{ChatID: "u1", Content: "hi"}); err != nil {
+		t.Fatal(err)
+	}
+	if lastToken.Load() != "d-2" || issued.Load() != 2 {
+		t.Errorf("send used %v after %d token requests, want a refreshed token", lastToken.Load(), issued.Load())
+	}
+}
+
+func TestQQSendUsesAppAccessToken(t *testing.T) {
+	var issued atomic.Int32
+	var lastAuth atomic.Value
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "getAppAccessToken") {
+			n := issued.Add(1)
+			fmt.Fprintf(w, `{"access_token":"q-%d","expires_in":"7200"}`, n)
+			return
+		}
+		lastAuth.Store(r.Header.Get("Authorization"))
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+	redirectDefaultTransport(t, srv)
+
+	ch, err := newQQChannel([]byte(`{"appId":"aid","appSecret":"sec"}`), bus.NewMessageBus(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	qc := ch.(*QQChannel)
+	if err := qc.Send(bus.OutboundMessage{ChatID: "c1", Content: "one"}); err != nil {
+		t.Fatal(err)
+	}
+	if lastAuth.Load() != "QQBot q-1" || issued.Load() != 1 {
+		t.Fatalf("first send used %v after %d token requests", lastAuth.Load(), issued.Load())
+	}
+
+	qc.accessToken.mu.Lock()
+	qc.accessToken.expiry = time.Now().Add(-time.Second)
+	qc.accessToken.mu.Unlock()
+
+	if err := qc.Send(bus.OutboundMessage{ChatID: "c1", Content: "two"}); err != nil {
+		t.Fatal(err)
+	}
+	if lastAuth.Load() != "QQBot q-2" || issued.Load() != 2 {
+		t.Errorf("second send used %v after %d token requests, want a refreshed token", lastAuth.Load(), issued.Load())
+	}
+}
+
+func TestExpiringTokenKeepFresh(t *testing.T) {
+	var fetches atomic.Int32
+	tok := &expiringToken{fetch: func() (string, time.Duration, error) {
+		n := fetches.Add(1)
+		// Each token expires just past the refresh margin, so the refresher
+		// renews it almost immediately.
+		return fmt.Sprintf("t-%d", n), tokenRefreshMargin + 20*time.Millisecond, nil
+	}}
+	if err := tok.refresh(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		tok.keepFresh(ctx, "test")
+		close(done)
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for fetches.Load() < 3 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	<-done
+	if fetches.Load() < 3 {
+		t.Errorf("background refresher fetched %d tokens, want it to keep renewing", fetches.Load())
+	}
+}
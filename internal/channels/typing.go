@@ -0,0 +1,112 @@
+package channels
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Typer is implemented by channels whose platform can show the bot as
+// typing. The manager starts the indicator when the agent begins working on
+// a message and stops it when the agent is done.
+type Typer interface {
+	// StartTyping shows the indicator in chatID until StopTyping is called.
+	StartTyping(chatID string) error
+	StopTyping(chatID string) error
+}
+
+// typingLoop keeps a typing indicator up by repeating the platform's typing
+// action, which expires after a few seconds, every interval. Starts and
+// stops for the same chat are counted, so the indicator stays up while any
+// message in the chat is being worked on.
+type typingLoop struct {
+	interval time.Duration
+	send     func(chatID string) error
+
+	mu    sync.Mutex
+	chats map[string]*typingChat
+}
+
+type typingChat struct {
+	refs int
+	stop chan struct{}
+}
+
+// start shows the indicator in chatID and keeps it up until a matching stop.
+// The error is that of the first send; later failures are only logged.
+func (l *typingLoop) start(chatID string) error {
+	l.mu.Lock()
+	if c, ok := l.chats[chatID]; ok {
+		c.refs++
+		l.mu.Unlock()
+		return nil
+	}
+	c := &typingChat{refs: 1, stop: make(chan struct{})}
+	if l.chats == nil {
+		l.chats = make(map[string]*typingChat)
+	}
+	l.chats[chatID] = c
+	l.mu.Unlock()
+
+	go func() {
+		t := time.NewTicker(l.interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-c.stop:
+				return
+			case <-t.C:
+				if err := l.send(chatID); err != nil {
+					slog.Debug("failed to refresh typing indicator", "chat", chatID, "error", err)
+				}
+			}
+		}
+	}()
+	return l.send(chatID)
+}
+
+// stop ends one start for chatID, letting the indicator lapse after the last.
+func (l *typingLoop) stop(chatID string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	c, ok := l.chats[chatID]
+	if !ok {
+		return
+	}
+	if c.refs--; c.refs == 0 {
+		close(c.stop)
+		delete(l.chats, chatID)
+	}
+}
+
+// StartTyping implements agent.TypingIndicator by starting the indicator on
+// the named channel, if it has one.
+func (m *Manager) StartTyping(channel, chatID string) {
+	if t, ok := m.typer(channel); ok {
+		if err := t.StartTyping(chatID); err != nil {
+			slog.Warn("failed to show typing indicator", "channel", channel, "error", err)
+		}
+	}
+}
+
+// StopTyping implements agent.TypingIndicator.
+func (m *Manager) StopTyping(channel, chatID string) {
+	if t, ok := m.typer(channel); ok {
+		if err := t.StopTyping(chatID); err != nil {
+			slog.Warn("failed to clear typing indicator", "channel", channel, "error", err)
+		}
+	}
+}
+
+// typer returns the named channel if it can show a typing indicator.
+func (m *Manager) typer(name string) (Typer, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, ch := range m.channels {
+		if ch.Name() == name {
+			t, ok := ch.(Typer)
+			return t, ok
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,131 @@
+package channels
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/coopco/nanobot/internal/bus"
+)
+
+// typingChannel is a mockChannel that records typing calls.
+type typingChannel struct {
+	mockChannel
+	mu    sync.Mutex
+	calls []string
+}
+
+func (c *typingChannel) StartTyping(chatID string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls = append(c.calls, "start "+chatID)
+	return nil
+}
+
+func (c *typingChannel) StopTyping(chatID string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls = append(c.calls, "stop "+chatID)
+	return errors.New("logged, not returned")
+}
+
+func TestManagerForwardsTyping(t *testing.T) {
+	typer := &typingChannel{mockChannel: mockChannel{name: "typer"}}
+	plain := &mockChannel{name: "plain"}
+	mgr := NewManager(bus.NewMessageBus(4))
+	mgr.channels = []Channel{typer, plain}
+
+	mgr.StartTyping("typer", "c1")
+	mgr.StartTyping("plain", "c2")
+	mgr.StartTyping("missing", "c3")
+	mgr.StopTyping("typer", "c1")
+
+	if got := strings.Join(typer.calls, ","); got != "start c1,stop c1" {
+		t.Errorf("typing calls = %s", got)
+	}
+	if len(plain.sent) != 0 {
+		t.Errorf("channel without typing support was sent %+v", plain.sent)
+	}
+}
+
+func TestTypingLoopRefreshesUntilLastStop(t *testing.T) {
+	var sends atomic.Int32
+	l := &typingLoop{interval: 10 * time.Millisecond, send: func(chatID string) error {
+		if chatID != "c1" {
+			t.Errorf("typing sent to %q", chatID)
+		}
+		sends.Add(1)
+		return nil
+	}}
+
+	if err := l.start("c1"); err != nil {
+		t.Fatal(err)
+	}
+	l.start("c1") // a second message in the same chat
+	time.Sleep(50 * time.Millisecond)
+	if n := sends.Load(); n < 2 {
+		t.Fatalf("typing sent %d times in 50ms, want it refreshed", n)
+	}
+
+	l.stop("c1")
+	time.Sleep(30 * time.Millisecond)
+	before := sends.Load()
+	time.Sleep(30 * time.Millisecond)
+	if sends.Load() == before {
+		t.Fatal("indicator stopped while a message was still being worked on")
+	}
+
+	l.stop("c1")
+	time.Sleep(20 * time.Millisecond)
+	before = sends.Load()
+	time.Sleep(40 * time.Millisecond)
+	if n := sends.Load(); n != before {
+		t.Errorf("typing sent %d more times after the last stop", n-before)
+	}
+	l.stop("c1") // unmatched stops are ignored
+}
+
+func TestTelegramTypingSendsChatAction(t *testing.T) {
+	actions := make(chan [2]string, 4)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/getMe") {
+			w.Write([]byte(`{"ok":true,"result":{"id":1,"is_bot":true,"first_name":"bot","username":"bot"}}`))
+			return
+		}
+		r.ParseForm()
+		if strings.HasSuffix(r.URL.Path, "/sendChatAction") {
+			actions <- [2]string{r.FormValue("chat_id"), r.FormValue("action")}
+		}
+		w.Write([]byte(`{"ok":true,"result":true}`))
+	}))
+	defer srv.Close()
+	redirectDefaultTransport(t, srv)
+
+	ch, err := newTelegramChannel(json.RawMessage(`{"token":"tok"}`), bus.NewMessageBus(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tg := ch.(*TelegramChannel)
+	if err := tg.StartTyping("42"); err != nil {
+		t.Fatal(err)
+	}
+	defer tg.StopTyping("42")
+	select {
+	case got := <-actions:
+		if got != [2]string{"42", "typing"} {
+			t.Errorf("chat action = %v, want typing in chat 42", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no chat action sent")
+	}
+	if err := tg.StartTyping("not-a-number"); err == nil {
+		t.Error("expected error for invalid chat ID")
+	}
+	tg.StopTyping("not-a-number")
+}
@@ -7,7 +7,12 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"mime/multipart"
 	"net/http"
+	"net/textproto"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/coopco/nanobot/internal/bus"
 )
@@ -17,11 +22,13 @@ func init() {
 }
 
 type whatsAppConfig struct {
-	AccessToken   string   `json:"access_token"`
-	PhoneNumberID string   `json:"phone_number_id"`
-	VerifyToken   string   `json:"verify_token"`
-	WebhookPort   int      `json:"webhook_port"`
-	AllowedUsers  []string `json:"allowed_users"`
+	AccessToken      string   `json:"access_token"`
+	PhoneNumberID    string   `json:"phone_number_id"`
+	VerifyToken      string   `json:"verify_token"`
+	WebhookPort      int      `json:"webhook_port"`
+	AllowedUsers     []string `json:"allowed_users"`
+	MaxMessageLength int      `json:"max_message_length"`
+	MarkRead         bool     `json:"mark_read"`
 }
 
 // WhatsAppChannel implements Channel for WhatsApp via the Cloud API (HTTP webhooks).
@@ -32,6 +39,10 @@ type WhatsAppChannel struct {
 	bus           *bus.MessageBus
 	allowedUsers  map[string]bool
 	server        *http.Server
+	client        *http.Client
+	maxLen        int            // longest message sent in one piece
+	markRead      bool           // send read receipts for incoming messages
+	receipts      sync.WaitGroup // read receipts still being sent
 }
 
 func newWhatsAppChannel(cfg json.RawMessage, msgBus *bus.MessageBus) (Channel, error) {
@@ -53,6 +64,9 @@ func newWhatsAppChannel(cfg json.RawMessage, msgBus *bus.MessageBus) (Channel, e
 		bus:           msgBus,
 		allowedUsers:  allowed,
 		server:        &http.Server{Addr: fmt.Sprintf(":%d", c.WebhookPort)},
+		client:        &http.Client{Timeout: 30 * time.Second},
+		maxLen:        maxMessageLength(c.MaxMessageLength, whatsappMaxMessage),
+		markRead:      c.MarkRead,
 	}, nil
 }
 
@@ -77,8 +91,12 @@ func (c *WhatsAppChannel) Start(ctx context.Context) error {
 	return nil
 }
 
+// Stop shuts down the webhook server and waits for read receipts that are
+// still being sent.
 func (c *WhatsAppChannel) Stop() error {
-	return c.server.Shutdown(context.Background())
+	err := c.server.Shutdown(context.Background())
+	c.receipts.Wait()
+	return err
 }
 
 func (c *WhatsAppChannel) handleWebhook(w http.ResponseWriter, r *http.Request) {
@@ -107,10 +125,17 @@ func (c *WhatsAppChannel) handleWebhook(w http.ResponseWriter, r *http.Request)
 		Entry []struct {
 			Changes []struct {
 				Value struct {
+					Contacts []struct {
+						WaID    string `json:"wa_id"`
+						Profile struct {
+							Name string `json:"name"`
+						} `json:"profile"`
+					} `json:"contacts"`
 					Messages []struct {
-						From string `json:"from"`
-						ID   string `json:"id"`
-						Text struct {
+						From      string `json:"from"`
+						ID        string `json:"id"`
+						Timestamp string `json:"timestamp"` // seconds since the epoch
+						Text      struct {
 							Body string `json:"body"`
 						} `json:"text"`
 						Type string `json:"type"`
@@ -126,6 +151,10 @@ func (c *WhatsAppChannel) handleWebhook(w http.ResponseWriter, r *http.Request)
 
 	for _, entry := range payload.Entry {
 		for _, change := range entry.Changes {
+			names := make(map[string]string)
+			for _, contact := range change.Value.Contacts {
+				names[contact.WaID] = contact.Profile.Name
+			}
 			for _, msg := range change.Value.Messages {
 				if msg.Type != "text" {
 					continue
@@ -135,12 +164,24 @@ func (c *WhatsAppChannel) handleWebhook(w http.ResponseWriter, r *http.Request)
 					slog.Warn("whatsapp: message from disallowed user", "user", senderID)
 					continue
 				}
+				var sent time.Time
+				if secs, err := strconv.ParseInt(msg.Timestamp, 10, 64); err == nil {
+					sent = time.Unix(secs, 0)
+				}
 				c.bus.PublishInbound(bus.InboundMessage{
 					Channel:  "whatsapp",
 					SenderID: senderID,
 					ChatID:   senderID,
 					Content:  msg.Text.Body,
+					Metadata: inboundMeta(msg.ID, names[senderID], sent),
 				})
+				if c.markRead && msg.ID != "" {
+					c.receipts.Add(1)
+					go func(id string) {
+						defer c.receipts.Done()
+						c.markAsRead(id)
+					}(msg.ID)
+				}
 			}
 		}
 	}
@@ -148,12 +189,106 @@ func (c *WhatsAppChannel) handleWebhook(w http.ResponseWriter, r *http.Request)
 }
 
 func (c *WhatsAppChannel) Send(msg bus.OutboundMessage) error {
-	body, _ := json.Marshal(map[string]any{
+	err := c.postMessages(map[string]any{
 		"messaging_product": "whatsapp",
 		"to":                msg.ChatID,
 		"type":              "text",
 		"text":              map[string]string{"body": msg.Content},
 	})
+	if err != nil {
+		return fmt.Errorf("whatsapp: send message: %w", err)
+	}
+	return nil
+}
+
+// SendFile implements FileSender. The file is uploaded to WhatsApp's media
+// store and sent as an image if it is a JPEG or PNG, otherwise as a
+// document.
+func (c *WhatsAppChannel) SendFile(chatID string, file bus.Attachment) error {
+	mediaID, err := c.uploadMedia(file)
+	if err != nil {
+		return fmt.Errorf("whatsapp: upload file: %w", err)
+	}
+	msgType, media := "document", map[string]string{"id": mediaID, "filename": file.Name}
+	if file.MimeType == "image/jpeg" || file.MimeType == "image/png" {
+		msgType, media = "image", map[string]string{"id": mediaID}
+	}
+	err = c.postMessages(map[string]any{
+		"messaging_product": "whatsapp",
+		"to":                chatID,
+		"type":              msgType,
+		msgType:             media,
+	})
+	if err != nil {
+		return fmt.Errorf("whatsapp: send file: %w", err)
+	}
+	return nil
+}
+
+// uploadMedia stores file with the phone number's media endpoint and returns
+// its media ID.
+func (c *WhatsAppChannel) uploadMedia(file bus.Attachment) (string, error) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("messaging_product", "whatsapp")
+	mw.WriteField("type", file.MimeType)
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", multipart.FileContentDisposition("file", file.Name))
+	h.Set("Content-Type", file.MimeType)
+	part, err := mw.CreatePart(h)
+	if err != nil {
+		return "", err
+	}
+	part.Write(file.Data)
+	if err := mw.Close(); err != nil {
+		return "", err
+	}
+
+	url := fmt.Sprintf("https://graph.facebook.com/v21.0/%s/media", c.phoneNumberID)
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body.Bytes()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+c.accessToken)
+
+	resp, err := doWithRetry(c.client, req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		b, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("status %d: %s", resp.StatusCode, b)
+	}
+	var result struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", err
+	}
+	if result.ID == "" {
+		return "", fmt.Errorf("no media ID in response")
+	}
+	return result.ID, nil
+}
+
+// markAsRead sends a read receipt for an incoming message so the sender sees
+// it as read. Failures are only logged.
+func (c *WhatsAppChannel) markAsRead(messageID string) {
+	err := c.postMessages(map[string]any{
+		"messaging_product": "whatsapp",
+		"status":            "read",
+		"message_id":        messageID,
+	})
+	if err != nil {
+		slog.Warn("whatsapp: failed to mark message as read", "id", messageID, "err", err)
+	}
+}
+
+// postMessages POSTs payload to the phone number's messages endpoint.
+func (c *WhatsAppChannel) postMessages(payload any) error {
+	body, _ := json.Marshal(payload)
 	url := fmt.Sprintf("https://graph.facebook.com/v21.0/%s/messages", c.phoneNumberID)
 	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
 	if err != nil {
@@ -162,14 +297,14 @@ func (c *WhatsAppChannel) Send(msg bus.OutboundMessage) error {
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer "+c.accessToken)
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := doWithRetry(c.client, req)
 	if err != nil {
-		return fmt.Errorf("whatsapp: send message: %w", err)
+		return err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode >= 300 {
 		b, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("whatsapp: send message status %d: %s", resp.StatusCode, b)
+		return fmt.Errorf("status %d: %s", resp.StatusCode, b)
 	}
 	return nil
 }
@@ -180,3 +315,6 @@ func (c *WhatsAppChannel) IsAllowed(senderID string) bool {
 	}
 	return c.allowedUsers[senderID]
 }
+
+// MaxMessageLength implements MessageLimiter.
+func (c *WhatsAppChannel) MaxMessageLength() int { return c.maxLen }
@@ -127,6 +127,80 @@ func TestWhatsAppIncomingMessage(t *testing.T) {
 	}
 }
 
+func TestWhatsAppIncomingMessageMetadata(t *testing.T) {
+	wa := newTestWhatsApp(t, nil)
+
+	payload := `{
+		"entry": [{
+			"changes": [{
+				"value": {
+					"contacts": [{"wa_id": "15551234567", "profile": {"name": "Ana"}}],
+					"messages": [{
+						"from": "15551234567",
+						"id": "wamid.abc",
+						"timestamp": "1700000000",
+						"type": "text",
+						"text": {"body": "hello"}
+					}]
+				}
+			}]
+		}]
+	}`
+	req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(payload))
+	wa.handleWebhook(httptest.NewRecorder(), req)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	received, err := wa.bus.ConsumeInbound(ctx)
+	if err != nil {
+		t.Fatalf("expected inbound message, got error: %v", err)
+	}
+	assertMeta(t, received.Metadata, map[string]string{
+		bus.MetaMessageID:  "wamid.abc",
+		bus.MetaSenderName: "Ana",
+		bus.MetaTimestamp:  "2023-11-14T22:13:20Z",
+	})
+}
+
+func TestWhatsAppMarksIncomingMessageRead(t *testing.T) {
+	receipts := make(chan string, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		if r.URL.Path != "/v21.0/pid/messages" || r.Header.Get("Authorization") != "Bearer tok" {
+			t.Errorf("unexpected request %s with auth %q", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		receipts <- string(b)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+	redirectDefaultTransport(t, srv)
+
+	raw, _ := json.Marshal(whatsAppConfig{AccessToken: "tok", PhoneNumberID: "pid", VerifyToken: "v", MarkRead: true})
+	ch, err := newWhatsAppChannel(raw, bus.NewMessageBus(16))
+	if err != nil {
+		t.Fatalf("newWhatsAppChannel: %v", err)
+	}
+	payload := `{"entry":[{"changes":[{"value":{"messages":[{"from":"1555","id":"wamid.1","type":"text","text":{"body":"hi"}}]}}]}]}`
+	wa := ch.(*WhatsAppChannel)
+	req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(payload))
+	wa.handleWebhook(httptest.NewRecorder(), req)
+	// Stop waits for the receipt, so it is sent before the transport is restored.
+	defer wa.Stop()
+
+	select {
+	case body := <-receipts:
+		var got map[string]string
+		if err := json.Unmarshal([]byte(body), &got); err != nil {
+			t.Fatalf("receipt body %q: %v", body, err)
+		}
+		if got["status"] != "read" || got["message_id"] != "wamid.1" || got["messaging_product"] != "whatsapp" {
+			t.Errorf("unexpected receipt %v", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no read receipt sent")
+	}
+}
+
 func TestWhatsAppIncomingNonTextIgnored(t *testing.T) {
 	msgBus := bus.NewMessageBus(16)
 	cfg := whatsAppConfig{AccessToken: "tok", PhoneNumberID: "pid", VerifyToken: "v"}
@@ -246,6 +320,7 @@ func (r *redirectTransport) RoundTrip(req *http.Request) (*http.Response, error)
 }
 
 func TestWhatsAppSendNon200Error(t *testing.T) {
+	fastRetries(t)
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write([]byte("server error"))
@@ -313,3 +388,58 @@ func TestWhatsAppDisallowedUserIgnored(t *testing.T) {
 		t.Error("expected no inbound message for disallowed user")
 	}
 }
+
+func TestWhatsAppSendFile(t *testing.T) {
+	var uploaded struct{ product, mimeType, name, data string }
+	var sent []map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer tok" {
+			t.Errorf("unexpected auth %q", r.Header.Get("Authorization"))
+		}
+		switch r.URL.Path {
+		case "/v21.0/pid/media":
+			f, hdr, err := r.FormFile("file")
+			if err != nil {
+				t.Fatalf("upload has no file part: %v", err)
+			}
+			data, _ := io.ReadAll(f)
+			uploaded.product, uploaded.mimeType = r.FormValue("messaging_product"), r.FormValue("type")
+			uploaded.name, uploaded.data = hdr.Filename, string(data)
+			w.Write([]byte(`{"id":"media-1"}`))
+		case "/v21.0/pid/messages":
+			var m map[string]any
+			json.NewDecoder(r.Body).Decode(&m)
+			sent = append(sent, m)
+			w.WriteHeader(http.StatusOK)
+		default:
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+	}))
+	defer srv.Close()
+	redirectDefaultTransport(t, srv)
+
+	raw, _ := json.Marshal(whatsAppConfig{AccessToken: "tok", PhoneNumberID: "pid"})
+	ch, _ := newWhatsAppChannel(raw, bus.NewMessageBus(16))
+	wa := ch.(*WhatsAppChannel)
+
+	if err := wa.SendFile("1555", bus.Attachment{Name: "report.pdf", MimeType: "application/pdf", Data: []byte("%PDF")}); err != nil {
+		t.Fatal(err)
+	}
+	if uploaded.product != "whatsapp" || uploaded.mimeType != "application/pdf" || uploaded.name != "report.pdf" || uploaded.data != "%PDF" {
+		t.Errorf("unexpected upload %+v", uploaded)
+	}
+	if err := wa.SendFile("1555", bus.Attachment{Name: "chart.png", MimeType: "image/png", Data: []byte("png")}); err != nil {
+		t.Fatal(err)
+	}
+	if len(sent) != 2 {
+		t.Fatalf("sent %d messages, want 2", len(sent))
+	}
+	doc, _ := sent[0]["document"].(map[string]any)
+	if sent[0]["to"] != "1555" || sent[0]["type"] != "document" || doc["id"] != "media-1" || doc["filename"] != "report.pdf" {
+		t.Errorf("unexpected document message %v", sent[0])
+	}
+	img, _ := sent[1]["image"].(map[string]any)
+	if sent[1]["type"] != "image" || img["id"] != "media-1" {
+		t.Errorf("unexpected image message %v", sent[1])
+	}
+}
@@ -0,0 +1,78 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// envRefPattern matches ${VAR} and ${VAR:-default}.
+var envRefPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// expandEnvRefs replaces ${VAR} and ${VAR:-default} references in every string
+// field of cfg with values from the environment. A reference to an unset
+// variable without a default is an error; the default is also used when the
+// variable is set but empty.
+func expandEnvRefs(cfg *Config) error {
+	var missing []string
+	expandValue(reflect.ValueOf(cfg).Elem(), &missing)
+	if len(missing) > 0 {
+		return fmt.Errorf("config references unset environment variables: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// expandValue walks v and expands string values in place, recording
+// unresolved variable names in missing.
+func expandValue(v reflect.Value, missing *[]string) {
+	switch v.Kind() {
+	case reflect.String:
+		if v.CanSet() {
+			v.SetString(expandEnvString(v.String(), missing))
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				expandValue(v.Field(i), missing)
+			}
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			expandValue(v.Index(i), missing)
+		}
+	case reflect.Map:
+		// Map elements are not addressable: expand a copy and store it back.
+		iter := v.MapRange()
+		for iter.Next() {
+			elem := reflect.New(iter.Value().Type()).Elem()
+			elem.Set(iter.Value())
+			expandValue(elem, missing)
+			v.SetMapIndex(iter.Key(), elem)
+		}
+	case reflect.Pointer:
+		if !v.IsNil() {
+			expandValue(v.Elem(), missing)
+		}
+	}
+}
+
+func expandEnvString(s string, missing *[]string) string {
+	if !strings.Contains(s, "${") {
+		return s
+	}
+	return envRefPattern.ReplaceAllStringFunc(s, func(ref string) string {
+		m := envRefPattern.FindStringSubmatch(ref)
+		name, hasDefault, def := m[1], m[2] != "", m[3]
+		val, ok := os.LookupEnv(name)
+		if hasDefault && val == "" {
+			return def
+		}
+		if !ok {
+			*missing = append(*missing, name)
+			return ref
+		}
+		return val
+	})
+}
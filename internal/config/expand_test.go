@@ -0,0 +1,70 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEnvRefExpansion(t *testing.T) {
+	t.Setenv("NB_TEST_OPENAI_KEY", "sk-from-env")
+	t.Setenv("NB_TEST_TOKEN", "tg-token")
+	t.Setenv("NB_TEST_HOST", "example.com")
+	t.Setenv("NB_TEST_EMPTY", "")
+
+	jsonData := `{
+		"providers": {"openai": {"apiKey": "${NB_TEST_OPENAI_KEY}", "extraHeaders": {"X-Host": "${NB_TEST_HOST}"}}},
+		"agents": {"defaults": {"model": "${NB_TEST_MODEL:-gpt-4o-mini}"}},
+		"channels": {"telegram": {"token": "${NB_TEST_TOKEN}", "allowedUsers": ["${NB_TEST_EMPTY:-admin}"]}},
+		"mcp": {"fs": {"command": "mcp-fs", "args": ["--url", "https://${NB_TEST_HOST}/api"], "env": {"TOKEN": "${NB_TEST_TOKEN}"}}},
+		"gateway": {"host": "literal-$HOME-value"}
+	}`
+
+	cfg, err := LoadFromReader(strings.NewReader(jsonData))
+	if err != nil {
+		t.Fatalf("LoadFromReader failed: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"apiKey", cfg.Providers.OpenAI.APIKey, "sk-from-env"},
+		{"extraHeaders", cfg.Providers.OpenAI.ExtraHeaders["X-Host"], "example.com"},
+		{"default fallback", cfg.Agents.Defaults.Model, "gpt-4o-mini"},
+		{"channel token", cfg.Channels.Telegram.Token, "tg-token"},
+		{"empty uses default", cfg.Channels.Telegram.AllowedUsers[0], "admin"},
+		{"embedded reference", cfg.MCP["fs"].Args[1], "https://example.com/api"},
+		{"map in map value", cfg.MCP["fs"].Env["TOKEN"], "tg-token"},
+		{"literal untouched", cfg.Gateway.Host, "literal-$HOME-value"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.got != tc.want {
+				t.Errorf("got %q, want %q", tc.got, tc.want)
+			}
+		})
+	}
+}
+
+func TestEnvRefUnsetWithoutDefault(t *testing.T) {
+	jsonData := `{"providers": {"openai": {"apiKey": "${NB_TEST_DEFINITELY_UNSET}"}}}`
+	_, err := LoadFromReader(strings.NewReader(jsonData))
+	if err == nil {
+		t.Fatal("expected error for unset variable without default")
+	}
+	if !strings.Contains(err.Error(), "NB_TEST_DEFINITELY_UNSET") {
+		t.Errorf("error should name the variable: %v", err)
+	}
+}
+
+func TestEnvRefSetButEmptyWithoutDefault(t *testing.T) {
+	t.Setenv("NB_TEST_EMPTY", "")
+	cfg, err := LoadFromReader(strings.NewReader(`{"providers": {"openai": {"apiKey": "${NB_TEST_EMPTY}"}}}`))
+	if err != nil {
+		t.Fatalf("LoadFromReader failed: %v", err)
+	}
+	if cfg.Providers.OpenAI.APIKey != "" {
+		t.Errorf("apiKey = %q, want empty", cfg.Providers.OpenAI.APIKey)
+	}
+}
@@ -1,33 +1,64 @@
 package config
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
 )
 
-// Load loads config from the default path (~/.nanobot/config.json).
+// Load loads config from the default path (~/.nanobot/config.json), falling
+// back to config.yaml or config.yml when no JSON config exists.
 func Load() (*Config, error) {
 	home, err := os.UserHomeDir()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get home directory: %w", err)
 	}
-	return LoadFromFile(filepath.Join(home, ".nanobot", "config.json"))
+	dir := filepath.Join(home, ".nanobot")
+	path := filepath.Join(dir, "config.json")
+	for _, name := range []string{"config.yaml", "config.yml"} {
+		if _, err := os.Stat(path); err == nil {
+			break
+		}
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			path = filepath.Join(dir, name)
+		}
+	}
+	return LoadFromFile(path)
 }
 
-// LoadFromFile loads config from a specific file path.
+// LoadFromFile loads config from a specific file path and validates it. Files
+// with a .yaml or .yml extension are parsed as YAML; anything else as JSON.
 func LoadFromFile(path string) (*Config, error) {
 	f, err := os.Open(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open config file %s: %w", path, err)
 	}
 	defer f.Close()
-	return LoadFromReader(f)
+
+	var cfg *Config
+	if isYAMLPath(path) {
+		cfg, err = LoadFromYAMLReader(f)
+	} else {
+		cfg, err = LoadFromReader(f)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
 }
 
-// LoadFromReader loads config from an io.Reader, applying defaults and env overrides.
+// LoadFromReader loads config from an io.Reader, applying defaults, ${VAR}
+// references, and env overrides.
 func LoadFromReader(r io.Reader) (*Config, error) {
 	cfg := DefaultConfig()
 
@@ -35,13 +66,43 @@ func LoadFromReader(r io.Reader) (*Config, error) {
 		return nil, fmt.Errorf("failed to parse config: %w", err)
 	}
 
+	if err := expandEnvRefs(cfg); err != nil {
+		return nil, err
+	}
 	applyEnvOverrides(cfg)
 	expandWorkspacePath(cfg)
 
 	return cfg, nil
 }
 
+// LoadFromYAMLReader loads a YAML config from an io.Reader. Keys match the
+// JSON field names, so the same document can be written in either format.
+func LoadFromYAMLReader(r io.Reader) (*Config, error) {
+	var doc interface{}
+	if err := yaml.NewDecoder(r).Decode(&doc); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to parse YAML config: %w", err)
+	}
+	if doc == nil {
+		doc = map[string]interface{}{}
+	}
+
+	// Round-trip through JSON so the struct's json tags drive decoding.
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert YAML config: %w", err)
+	}
+	return LoadFromReader(bytes.NewReader(data))
+}
+
+// isYAMLPath reports whether path has a YAML file extension.
+func isYAMLPath(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".yaml" || ext == ".yml"
+}
+
 // applyEnvOverrides applies NANOBOT_-prefixed environment variable overrides.
+// Variable names are the config path with JSON keys upper-cased, e.g.
+// NANOBOT_CHANNELS_WHATSAPP_ACCESS_TOKEN for channels.whatsapp.access_token.
 func applyEnvOverrides(cfg *Config) {
 	envMap := map[string]*string{
 		"NANOBOT_PROVIDERS_OPENAI_APIKEY":     &cfg.Providers.OpenAI.APIKey,
@@ -59,6 +120,34 @@ func applyEnvOverrides(cfg *Config) {
 		"NANOBOT_PROVIDERS_CUSTOM_APIKEY":     &cfg.Providers.Custom.APIKey,
 		"NANOBOT_AGENTS_DEFAULTS_MODEL":       &cfg.Agents.Defaults.Model,
 		"NANOBOT_AGENTS_DEFAULTS_WORKSPACE":   &cfg.Agents.Defaults.Workspace,
+
+		"NANOBOT_CHANNELS_TELEGRAM_TOKEN":           &cfg.Channels.Telegram.Token,
+		"NANOBOT_CHANNELS_DISCORD_TOKEN":            &cfg.Channels.Discord.Token,
+		"NANOBOT_CHANNELS_SLACK_BOTTOKEN":           &cfg.Channels.Slack.BotToken,
+		"NANOBOT_CHANNELS_SLACK_APPTOKEN":           &cfg.Channels.Slack.AppToken,
+		"NANOBOT_CHANNELS_WHATSAPP_ACCESS_TOKEN":    &cfg.Channels.WhatsApp.AccessToken,
+		"NANOBOT_CHANNELS_WHATSAPP_PHONE_NUMBER_ID": &cfg.Channels.WhatsApp.PhoneNumberID,
+		"NANOBOT_CHANNELS_WHATSAPP_VERIFY_TOKEN":    &cfg.Channels.WhatsApp.VerifyToken,
+		"NANOBOT_CHANNELS_FEISHU_APPID":             &cfg.Channels.Feishu.AppID,
+		"NANOBOT_CHANNELS_FEISHU_APPSECRET":         &cfg.Channels.Feishu.AppSecret,
+		"NANOBOT_CHANNELS_DINGTALK_CLIENTID":        &cfg.Channels.DingTalk.ClientID,
+		"NANOBOT_CHANNELS_DINGTALK_CLIENTSECRET":    &cfg.Channels.DingTalk.ClientSecret,
+		"NANOBOT_CHANNELS_QQ_APPID":                 &cfg.Channels.QQ.AppID,
+		"NANOBOT_CHANNELS_QQ_TOKEN":                 &cfg.Channels.QQ.Token,
+		"NANOBOT_CHANNELS_QQ_APPSECRET":             &cfg.Channels.QQ.AppSecret,
+		"NANOBOT_CHANNELS_EMAIL_IMAPSERVER":         &cfg.Channels.Email.IMAPServer,
+		"NANOBOT_CHANNELS_EMAIL_SMTPSERVER":         &cfg.Channels.Email.SMTPServer,
+		"NANOBOT_CHANNELS_EMAIL_USERNAME":           &cfg.Channels.Email.Username,
+		"NANOBOT_CHANNELS_EMAIL_PASSWORD":           &cfg.Channels.Email.Password,
+		"NANOBOT_CHANNELS_EMAIL_AUTHMODE":           &cfg.Channels.Email.AuthMode,
+		"NANOBOT_CHANNELS_EMAIL_ACCESSTOKEN":        &cfg.Channels.Email.AccessToken,
+		"NANOBOT_CHANNELS_EMAIL_REFRESHTOKEN":       &cfg.Channels.Email.RefreshToken,
+		"NANOBOT_CHANNELS_EMAIL_CLIENTSECRET":       &cfg.Channels.Email.ClientSecret,
+		"NANOBOT_CHANNELS_MOCHAT_URL":               &cfg.Channels.Mochat.URL,
+
+		"NANOBOT_LOGGING_LEVEL":  &cfg.Logging.Level,
+		"NANOBOT_LOGGING_FORMAT": &cfg.Logging.Format,
+		"NANOBOT_LOGGING_FILE":   &cfg.Logging.File,
 	}
 
 	for env, ptr := range envMap {
@@ -66,6 +155,36 @@ func applyEnvOverrides(cfg *Config) {
 			*ptr = val
 		}
 	}
+
+	// Allowed-user lists are given as comma-separated values.
+	listEnvMap := map[string]*[]string{
+		"NANOBOT_CHANNELS_TELEGRAM_ALLOWEDUSERS":  &cfg.Channels.Telegram.AllowedUsers,
+		"NANOBOT_CHANNELS_DISCORD_ALLOWEDUSERS":   &cfg.Channels.Discord.AllowedUsers,
+		"NANOBOT_CHANNELS_SLACK_ALLOWEDUSERS":     &cfg.Channels.Slack.AllowedUsers,
+		"NANOBOT_CHANNELS_WHATSAPP_ALLOWED_USERS": &cfg.Channels.WhatsApp.AllowedUsers,
+		"NANOBOT_CHANNELS_FEISHU_ALLOWEDUSERS":    &cfg.Channels.Feishu.AllowedUsers,
+		"NANOBOT_CHANNELS_DINGTALK_ALLOWEDUSERS":  &cfg.Channels.DingTalk.AllowedUsers,
+		"NANOBOT_CHANNELS_QQ_ALLOWEDUSERS":        &cfg.Channels.QQ.AllowedUsers,
+		"NANOBOT_CHANNELS_EMAIL_ALLOWEDUSERS":     &cfg.Channels.Email.AllowedUsers,
+		"NANOBOT_CHANNELS_MOCHAT_ALLOWEDUSERS":    &cfg.Channels.Mochat.AllowedUsers,
+	}
+
+	for env, ptr := range listEnvMap {
+		if val := os.Getenv(env); val != "" {
+			*ptr = splitList(val)
+		}
+	}
+}
+
+// splitList splits a comma-separated value, trimming spaces and dropping empty items.
+func splitList(val string) []string {
+	var items []string
+	for _, item := range strings.Split(val, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
 }
 
 // expandWorkspacePath expands a leading ~ in the workspace path.
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -37,6 +38,95 @@ func TestLoadFromFileInvalidJSON(t *testing.T) {
 	}
 }
 
+func TestLoadFromFileYAMLMatchesJSON(t *testing.T) {
+	dir := t.TempDir()
+	jsonContent := `{
+		"providers": {"openai": {"apiKey": "sk-test", "extraHeaders": {"X-Org": "acme"}}},
+		"agents": {
+			"defaults": {"workspace": "~/ws", "model": "gpt-4", "maxTokens": 2048, "temperature": 0.2},
+			"named": {"coder": {"model": "claude-3", "maxToolIterations": 10}}
+		},
+		"tools": {"disabled": ["run_shell"]},
+		"channels": {"telegram": {"token": "tg", "allowedUsers": ["1", "2"]}},
+		"mcp": {"fs": {"command": "mcp-fs", "args": ["--root", "/tmp"], "toolTimeout": 60}},
+		"gateway": {"port": 9090}
+	}`
+	yamlContent := `# same config in YAML
+providers:
+  openai:
+    apiKey: sk-test
+    extraHeaders:
+      X-Org: acme
+agents:
+  defaults:
+    workspace: ~/ws
+    model: gpt-4
+    maxTokens: 2048
+    temperature: 0.2
+  named:
+    coder:
+      model: claude-3
+      maxToolIterations: 10
+tools:
+  disabled: [run_shell]
+channels:
+  telegram:
+    token: tg
+    allowedUsers: ["1", "2"]
+mcp:
+  fs:
+    command: mcp-fs
+    args: [--root, /tmp]
+    toolTimeout: 60
+gateway:
+  port: 9090
+`
+	jsonPath := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(jsonPath, []byte(jsonContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+	want, err := LoadFromFile(jsonPath)
+	if err != nil {
+		t.Fatalf("LoadFromFile(json) failed: %v", err)
+	}
+
+	for _, name := range []string{"config.yaml", "config.yml"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, name)
+			if err := os.WriteFile(path, []byte(yamlContent), 0644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := LoadFromFile(path)
+			if err != nil {
+				t.Fatalf("LoadFromFile(yaml) failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("YAML config differs from JSON config:\n got  %+v\n want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestLoadFromFileInvalidYAML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("agents: [unclosed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadFromFile(path); err == nil {
+		t.Fatal("expected error for invalid YAML")
+	}
+}
+
+func TestLoadFromYAMLReaderEmpty(t *testing.T) {
+	cfg, err := LoadFromYAMLReader(strings.NewReader(""))
+	if err != nil {
+		t.Fatalf("LoadFromYAMLReader failed: %v", err)
+	}
+	if cfg.Agents.Defaults.Model != "gpt-4o" {
+		t.Errorf("expected default model, got %q", cfg.Agents.Defaults.Model)
+	}
+}
+
 func TestEnvOverrideAgentsDefaultsModel(t *testing.T) {
 	os.Setenv("NANOBOT_AGENTS_DEFAULTS_MODEL", "env-model-xyz")
 	defer os.Unsetenv("NANOBOT_AGENTS_DEFAULTS_MODEL")
@@ -104,6 +194,7 @@ func TestDefaultConfigValues(t *testing.T) {
 		{"maxToolIterations", cfg.Agents.Defaults.MaxToolIterations, 40},
 		{"gatewayHost", cfg.Gateway.Host, "0.0.0.0"},
 		{"gatewayPort", cfg.Gateway.Port, 8080},
+		{"toolTimeout", cfg.Tools.Timeout, 300},
 	}
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
@@ -174,3 +265,32 @@ func TestAllProviderEnvOverrides(t *testing.T) {
 		}
 	}
 }
+
+func TestChannelEnvOverrides(t *testing.T) {
+	t.Setenv("NANOBOT_CHANNELS_TELEGRAM_TOKEN", "tg-env-token")
+	t.Setenv("NANOBOT_CHANNELS_TELEGRAM_ALLOWEDUSERS", "111, 222,,333")
+	t.Setenv("NANOBOT_CHANNELS_WHATSAPP_ACCESS_TOKEN", "wa-env-token")
+	t.Setenv("NANOBOT_CHANNELS_SLACK_BOTTOKEN", "xoxb-env")
+	t.Setenv("NANOBOT_CHANNELS_EMAIL_PASSWORD", "mail-secret")
+
+	cfg, err := LoadFromReader(strings.NewReader(`{"channels": {"telegram": {"token": "file-token", "allowedUsers": ["999"]}}}`))
+	if err != nil {
+		t.Fatalf("LoadFromReader failed: %v", err)
+	}
+
+	checks := []struct{ name, got, want string }{
+		{"telegram token", cfg.Channels.Telegram.Token, "tg-env-token"},
+		{"whatsapp access token", cfg.Channels.WhatsApp.AccessToken, "wa-env-token"},
+		{"slack bot token", cfg.Channels.Slack.BotToken, "xoxb-env"},
+		{"email password", cfg.Channels.Email.Password, "mail-secret"},
+	}
+	for _, c := range checks {
+		if c.got != c.want {
+			t.Errorf("%s = %q, want %q", c.name, c.got, c.want)
+		}
+	}
+
+	if !reflect.DeepEqual(cfg.Channels.Telegram.AllowedUsers, []string{"111", "222", "333"}) {
+		t.Errorf("telegram allowedUsers = %v, want [111 222 333]", cfg.Channels.Telegram.AllowedUsers)
+	}
+}
@@ -128,3 +128,35 @@ func TestPartialConfig(t *testing.T) {
 		t.Errorf("expected default port 8080, got %d", cfg.Gateway.Port)
 	}
 }
+
+func TestAgentProvider(t *testing.T) {
+	jsonData := `{
+		"providers": {
+			"groq": {"apiKey": "gsk-1", "baseUrl": "https://api.groq.com/openai/v1"},
+			"anthropic": {"apiKey": "sk-ant-1"}
+		},
+		"agents": {
+			"named": {
+				"fast": {"provider": "groq", "model": "llama-3.1-8b-instant"},
+				"smart": {"provider": "anthropic", "model": "claude-sonnet-4"},
+				"plain": {"model": "gpt-4o"}
+			}
+		}
+	}`
+	cfg, err := LoadFromReader(strings.NewReader(jsonData))
+	if err != nil {
+		t.Fatalf("LoadFromReader failed: %v", err)
+	}
+
+	name, p, ok := cfg.AgentProvider("fast")
+	if !ok || name != "groq" || p.APIKey != "gsk-1" || p.BaseURL != "https://api.groq.com/openai/v1" {
+		t.Errorf("fast agent provider = %q %+v %v, want groq settings", name, p, ok)
+	}
+	name, p, ok = cfg.AgentProvider("smart")
+	if !ok || name != "anthropic" || p.APIKey != "sk-ant-1" {
+		t.Errorf("smart agent provider = %q %+v %v, want anthropic settings", name, p, ok)
+	}
+	if _, _, ok := cfg.AgentProvider("plain"); ok {
+		t.Error("expected no provider binding for an agent without one")
+	}
+}
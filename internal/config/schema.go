@@ -1,5 +1,11 @@
 package config
 
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
 // Config is the top-level configuration
 type Config struct {
 	Providers ProvidersConfig            `json:"providers"`
@@ -7,6 +13,9 @@ type Config struct {
 	Tools     ToolsConfig                `json:"tools"`
 	Channels  ChannelsConfig             `json:"channels"`
 	Gateway   GatewayConfig              `json:"gateway"`
+	Sessions  SessionsConfig             `json:"sessions"`
+	Cron      CronConfig                 `json:"cron"`
+	Logging   LoggingConfig              `json:"logging"`
 	MCP       map[string]MCPServerConfig `json:"mcp"`
 }
 
@@ -25,18 +34,57 @@ type ProvidersConfig struct {
 	OpenRouter ProviderConfig `json:"openrouter"`
 	AiHubMix   ProviderConfig `json:"aihubmix"`
 	Custom     ProviderConfig `json:"custom"`
+	// DryRun replaces every provider with providers.DryRunProvider, which
+	// logs each request and echoes it back instead of calling the API.
+	DryRun bool `json:"dryRun,omitempty"`
+}
+
+// ByName returns the provider settings keyed by provider registry name.
+func (p ProvidersConfig) ByName() map[string]ProviderConfig {
+	return map[string]ProviderConfig{
+		"openai":     p.OpenAI,
+		"anthropic":  p.Anthropic,
+		"deepseek":   p.DeepSeek,
+		"moonshot":   p.Moonshot,
+		"zhipu":      p.Zhipu,
+		"dashscope":  p.DashScope,
+		"groq":       p.Groq,
+		"xai":        p.XAI,
+		"mistral":    p.Mistral,
+		"cohere":     p.Cohere,
+		"openrouter": p.OpenRouter,
+		"aihubmix":   p.AiHubMix,
+		"custom":     p.Custom,
+	}
+}
+
+// AgentProvider returns the provider name and settings bound to the named
+// agent. ok is false if the agent names no provider or an unknown one.
+func (c *Config) AgentProvider(agent string) (name string, p ProviderConfig, ok bool) {
+	name = c.Agents.Named[agent].Provider
+	if name == "" {
+		return "", ProviderConfig{}, false
+	}
+	p, ok = c.Providers.ByName()[name]
+	return name, p, ok
 }
 
 type ProviderConfig struct {
-	APIKey       string            `json:"apiKey"`
-	BaseURL      string            `json:"baseUrl"`
-	DefaultModel string            `json:"defaultModel"`
-	ExtraHeaders map[string]string `json:"extraHeaders"`
+	APIKey         string            `json:"apiKey"`
+	BaseURL        string            `json:"baseUrl"`
+	DefaultModel   string            `json:"defaultModel"`
+	ExtraHeaders   map[string]string `json:"extraHeaders"`
+	TimeoutSeconds int               `json:"timeoutSeconds"` // per-request HTTP timeout; 0 uses the provider default
+	// CAFile is a PEM bundle trusted for a self-hosted baseUrl on top of the
+	// system roots. InsecureSkipVerify accepts any certificate; use it only
+	// for testing.
+	CAFile             string `json:"caFile,omitempty"`
+	InsecureSkipVerify bool   `json:"insecureSkipVerify,omitempty"`
 }
 
 type AgentsConfig struct {
-	Defaults AgentDefaults            `json:"defaults"`
-	Named    map[string]AgentConfig   `json:"named"`
+	Defaults AgentDefaults          `json:"defaults"`
+	Named    map[string]AgentConfig `json:"named"`
 }
 
 type AgentDefaults struct {
@@ -46,9 +94,27 @@ type AgentDefaults struct {
 	Temperature       float64 `json:"temperature"`
 	MaxToolIterations int     `json:"maxToolIterations"`
 	SystemPromptFile  string  `json:"systemPromptFile"`
+	CommandPrefix     string  `json:"commandPrefix"`         // starts built-in chat commands; default "/"
+	MessageTimeout    int     `json:"messageTimeout"`        // seconds to handle one inbound message, 0 disables
+	MaxConcurrent     int     `json:"maxConcurrentMessages"` // inbound messages processed at once, 0 is unbounded
+	ThinkingBudget    int     `json:"thinkingBudget"`        // extended thinking tokens per model call, 0 disables
+	MaxToolResultSize int     `json:"maxToolResultSize"`     // bytes of each tool result kept in the conversation; 0 uses the default, negative disables
+	// Fallback lists providers to try, in order, when the primary fails.
+	Fallback []FallbackConfig `json:"fallback,omitempty"`
+}
+
+// FallbackConfig names a provider from ProvidersConfig and the model to ask
+// it for; an empty Model uses the provider's defaultModel.
+type FallbackConfig struct {
+	Provider string `json:"provider"`
+	Model    string `json:"model,omitempty"`
 }
 
 type AgentConfig struct {
+	// Provider binds the agent to one entry of ProvidersConfig by name
+	// ("groq", "anthropic", ...). When empty the provider is picked from the
+	// model name.
+	Provider          string  `json:"provider,omitempty"`
 	Model             string  `json:"model,omitempty"`
 	MaxTokens         int     `json:"maxTokens,omitempty"`
 	Temperature       float64 `json:"temperature,omitempty"`
@@ -57,8 +123,35 @@ type AgentConfig struct {
 }
 
 type ToolsConfig struct {
-	Enabled  []string `json:"enabled"`
-	Disabled []string `json:"disabled"`
+	Enabled  []string       `json:"enabled"`
+	Disabled []string       `json:"disabled"`
+	Timeout  int            `json:"timeout"`  // seconds per tool call, 0 disables
+	Timeouts map[string]int `json:"timeouts"` // per-tool overrides in seconds
+	// RestrictToWorkspace confines the file tools to the agent workspace and
+	// runs shell commands from it.
+	RestrictToWorkspace bool `json:"restrictToWorkspace"`
+	// AllowedHosts limits web_get and read_url to these hosts and their
+	// subdomains. Empty allows any host.
+	AllowedHosts []string        `json:"allowedHosts"`
+	WebSearch    WebSearchConfig `json:"webSearch"`
+	Shell        ShellConfig     `json:"shell"`
+	// ExportAnySession lets export_session export any session instead of
+	// only the conversation it is called from.
+	ExportAnySession bool `json:"exportAnySession"`
+}
+
+// ShellConfig restricts the programs run_shell may start. Every command in a
+// line, such as each side of a pipe, is checked by its program name.
+type ShellConfig struct {
+	Disabled bool     `json:"disabled"` // refuse every command
+	Allow    []string `json:"allow"`    // programs that may run; empty allows any not denied
+	Deny     []string `json:"deny"`     // programs that may never run
+}
+
+// WebSearchConfig selects the backend of the web_search tool.
+type WebSearchConfig struct {
+	Provider string `json:"provider"` // brave or tavily; default brave
+	APIKey   string `json:"apiKey"`
 }
 
 type ChannelsConfig struct {
@@ -74,46 +167,54 @@ type ChannelsConfig struct {
 }
 
 type TelegramConfig struct {
-	Token        string   `json:"token"`
-	AllowedUsers []string `json:"allowedUsers"`
+	Token            string   `json:"token"`
+	AllowedUsers     []string `json:"allowedUsers"`
+	MaxMessageLength int      `json:"maxMessageLength,omitempty"` // 0 uses the platform default
 }
 
 type DiscordConfig struct {
-	Token        string   `json:"token"`
-	AllowedUsers []string `json:"allowedUsers"`
+	Token            string   `json:"token"`
+	AllowedUsers     []string `json:"allowedUsers"`
+	MaxMessageLength int      `json:"maxMessageLength,omitempty"` // 0 uses the platform default
 }
 
 type SlackConfig struct {
-	BotToken     string   `json:"botToken"`
-	AppToken     string   `json:"appToken"`
-	AllowedUsers []string `json:"allowedUsers"`
+	BotToken         string   `json:"botToken"`
+	AppToken         string   `json:"appToken"`
+	AllowedUsers     []string `json:"allowedUsers"`
+	MaxMessageLength int      `json:"maxMessageLength,omitempty"` // 0 uses the platform default
 }
 
 type WhatsAppConfig struct {
-	AccessToken   string   `json:"access_token"`
-	PhoneNumberID string   `json:"phone_number_id"`
-	VerifyToken   string   `json:"verify_token"`
-	WebhookPort   int      `json:"webhook_port"`
-	AllowedUsers  []string `json:"allowed_users"`
+	AccessToken      string   `json:"access_token"`
+	PhoneNumberID    string   `json:"phone_number_id"`
+	VerifyToken      string   `json:"verify_token"`
+	WebhookPort      int      `json:"webhook_port"`
+	AllowedUsers     []string `json:"allowed_users"`
+	MaxMessageLength int      `json:"max_message_length,omitempty"` // 0 uses the platform default
+	MarkRead         bool     `json:"mark_read,omitempty"`          // send read receipts for incoming messages
 }
 
 type FeishuConfig struct {
-	AppID        string   `json:"appId"`
-	AppSecret    string   `json:"appSecret"`
-	AllowedUsers []string `json:"allowedUsers"`
+	AppID            string   `json:"appId"`
+	AppSecret        string   `json:"appSecret"`
+	AllowedUsers     []string `json:"allowedUsers"`
+	MaxMessageLength int      `json:"maxMessageLength,omitempty"` // 0 uses the platform default
 }
 
 type DingTalkConfig struct {
-	ClientID     string   `json:"clientId"`
-	ClientSecret string   `json:"clientSecret"`
-	AllowedUsers []string `json:"allowedUsers"`
+	ClientID         string   `json:"clientId"`
+	ClientSecret     string   `json:"clientSecret"`
+	AllowedUsers     []string `json:"allowedUsers"`
+	MaxMessageLength int      `json:"maxMessageLength,omitempty"` // 0 uses the platform default
 }
 
 type QQConfig struct {
-	AppID        string   `json:"appId"`
-	Token        string   `json:"token"`
-	AppSecret    string   `json:"appSecret"`
-	AllowedUsers []string `json:"allowedUsers"`
+	AppID            string   `json:"appId"`
+	Token            string   `json:"token"` // deprecated bot token; used only when appSecret is empty
+	AppSecret        string   `json:"appSecret"`
+	AllowedUsers     []string `json:"allowedUsers"`
+	MaxMessageLength int      `json:"maxMessageLength,omitempty"` // 0 uses the platform default
 }
 
 type EmailConfig struct {
@@ -122,25 +223,81 @@ type EmailConfig struct {
 	Username     string   `json:"username"`
 	Password     string   `json:"password"`
 	AllowedUsers []string `json:"allowedUsers"`
+	Folder       string   `json:"folder,omitempty"`    // mailbox to poll; default INBOX
+	SinceDays    int      `json:"sinceDays,omitempty"` // ignore mail older than this many days; 0 takes all unread
+	// AuthMode is "password" (the default) or "xoauth2". XOAUTH2 uses
+	// AccessToken, or refreshes one from TokenURL with RefreshToken and the
+	// client credentials.
+	AuthMode     string `json:"authMode,omitempty"`
+	AccessToken  string `json:"accessToken,omitempty"`
+	RefreshToken string `json:"refreshToken,omitempty"`
+	TokenURL     string `json:"tokenUrl,omitempty"`
+	ClientID     string `json:"clientId,omitempty"`
+	ClientSecret string `json:"clientSecret,omitempty"`
+	// CAFile and InsecureSkipVerify control certificate checks for the IMAP
+	// and SMTP servers, as for providers.
+	CAFile             string `json:"caFile,omitempty"`
+	InsecureSkipVerify bool   `json:"insecureSkipVerify,omitempty"`
 }
 
 type MochatConfig struct {
 	URL          string   `json:"url"`
 	AllowedUsers []string `json:"allowedUsers"`
+	// CAFile and InsecureSkipVerify control certificate checks for URL, as
+	// for providers.
+	CAFile             string `json:"caFile,omitempty"`
+	InsecureSkipVerify bool   `json:"insecureSkipVerify,omitempty"`
+}
+
+type SessionsConfig struct {
+	TTLHours  int `json:"ttlHours"`  // delete sessions idle this long, 0 keeps them forever
+	MaxCached int `json:"maxCached"` // sessions held in memory before LRU eviction, 0 is unbounded
+}
+
+type CronConfig struct {
+	Seconds bool `json:"seconds"` // cron expressions take a leading seconds field, e.g. "*/30 * * * * *"
+}
+
+// LoggingConfig controls the process-wide slog logger.
+type LoggingConfig struct {
+	Level  string `json:"level"`  // debug, info, warn or error; default info
+	Format string `json:"format"` // text or json; default text
+	File   string `json:"file"`   // append to this file instead of stderr
+}
+
+// SlogLevel parses Level. An empty level means info.
+func (l LoggingConfig) SlogLevel() (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(l.Level)) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return 0, fmt.Errorf("unknown log level %q", l.Level)
 }
 
 type GatewayConfig struct {
-	Host string `json:"host"`
-	Port int    `json:"port"`
+	Host    string   `json:"host"`
+	Port    int      `json:"port"`
+	APIKeys []string `json:"apiKeys"` // bearer tokens accepted by the HTTP API; empty disables auth
 }
 
 type MCPServerConfig struct {
-	Command     string            `json:"command"`
-	Args        []string          `json:"args"`
-	Env         map[string]string `json:"env"`
-	URL         string            `json:"url"`
-	Headers     map[string]string `json:"headers"`
-	ToolTimeout int               `json:"toolTimeout"` // seconds, default 30
+	Command string            `json:"command"`
+	Args    []string          `json:"args"`
+	Env     map[string]string `json:"env"`
+	// EnvPassthrough restricts the inherited host environment to these names
+	// or globs (e.g. "GITHUB_*"); empty inherits everything.
+	EnvPassthrough []string          `json:"envPassthrough"`
+	URL            string            `json:"url"`
+	Headers        map[string]string `json:"headers"`
+	ToolTimeout    int               `json:"toolTimeout"`   // seconds, default 30
+	MaxReconnects  int               `json:"maxReconnects"` // restart attempts after a crash, 0 disables
+	NamePrefix     string            `json:"namePrefix"`    // prefix for registered tool names, default "mcp_<server>_"
 }
 
 // DefaultConfig returns a Config with sensible defaults applied.
@@ -155,6 +312,9 @@ func DefaultConfig() *Config {
 				MaxToolIterations: 40,
 			},
 		},
+		Tools: ToolsConfig{
+			Timeout: 300,
+		},
 		Gateway: GatewayConfig{
 			Host: "0.0.0.0",
 			Port: 8080,
@@ -0,0 +1,287 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+)
+
+// minThinkingBudget is the smallest extended thinking budget the Anthropic
+// API accepts.
+const minThinkingBudget = 1024
+
+// ValidationError lists every problem found in a Config.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid config:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// Validate checks the config for missing or malformed settings. Channels are
+// only checked once any of their fields is set. All problems are reported
+// together in a *ValidationError.
+func (c *Config) Validate() error {
+	v := &validator{}
+
+	c.validateAgents(v)
+	c.validateProviders(v)
+	c.validateChannels(v)
+	c.validateMCP(v)
+
+	if c.Tools.Timeout < 0 {
+		v.addf("tools.timeout must not be negative (got %d)", c.Tools.Timeout)
+	}
+	for _, name := range sortedKeys(c.Tools.Timeouts) {
+		if c.Tools.Timeouts[name] < 0 {
+			v.addf("tools.timeouts.%s must not be negative (got %d)", name, c.Tools.Timeouts[name])
+		}
+	}
+	switch strings.ToLower(c.Tools.WebSearch.Provider) {
+	case "", "brave", "tavily":
+	default:
+		v.addf("tools.webSearch.provider %q is not supported (want brave or tavily)", c.Tools.WebSearch.Provider)
+	}
+	if c.Sessions.TTLHours < 0 {
+		v.addf("sessions.ttlHours must not be negative (got %d)", c.Sessions.TTLHours)
+	}
+	if c.Sessions.MaxCached < 0 {
+		v.addf("sessions.maxCached must not be negative (got %d)", c.Sessions.MaxCached)
+	}
+	if c.Gateway.Port < 0 || c.Gateway.Port > 65535 {
+		v.addf("gateway.port must be between 0 and 65535 (got %d)", c.Gateway.Port)
+	}
+	if _, err := c.Logging.SlogLevel(); err != nil {
+		v.addf("logging.level must be debug, info, warn or error (got %q)", c.Logging.Level)
+	}
+	if f := strings.ToLower(c.Logging.Format); f != "" && f != "text" && f != "json" {
+		v.addf("logging.format must be text or json (got %q)", c.Logging.Format)
+	}
+	for i, key := range c.Gateway.APIKeys {
+		if strings.TrimSpace(key) == "" {
+			v.addf("gateway.apiKeys[%d] must not be empty", i)
+		}
+	}
+
+	if len(v.problems) == 0 {
+		return nil
+	}
+	return &ValidationError{Problems: v.problems}
+}
+
+type validator struct {
+	problems []string
+}
+
+func (v *validator) addf(format string, args ...interface{}) {
+	v.problems = append(v.problems, fmt.Sprintf(format, args...))
+}
+
+// require reports each named field whose value is empty.
+func (v *validator) require(prefix string, fields map[string]string) {
+	for _, name := range sortedKeys(fields) {
+		if strings.TrimSpace(fields[name]) == "" {
+			v.addf("%s.%s is required", prefix, name)
+		}
+	}
+}
+
+// checkURL reports raw if it is set but not an absolute http(s) URL.
+func (v *validator) checkURL(field, raw string) {
+	if raw == "" {
+		return
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		v.addf("%s must be an http(s) URL (got %q)", field, raw)
+	}
+}
+
+func (c *Config) validateAgents(v *validator) {
+	checkAgent := func(prefix string, maxTokens int, temperature float64, maxIter int) {
+		if maxTokens < 0 {
+			v.addf("%s.maxTokens must not be negative (got %d)", prefix, maxTokens)
+		}
+		if temperature < 0 || temperature > 2 {
+			v.addf("%s.temperature must be between 0 and 2 (got %g)", prefix, temperature)
+		}
+		if maxIter < 0 {
+			v.addf("%s.maxToolIterations must not be negative (got %d)", prefix, maxIter)
+		}
+	}
+
+	d := c.Agents.Defaults
+	if strings.TrimSpace(d.Model) == "" {
+		v.addf("agents.defaults.model is required")
+	}
+	if strings.TrimSpace(d.Workspace) == "" {
+		v.addf("agents.defaults.workspace is required")
+	}
+	checkAgent("agents.defaults", d.MaxTokens, d.Temperature, d.MaxToolIterations)
+	if d.MessageTimeout < 0 {
+		v.addf("agents.defaults.messageTimeout must not be negative (got %d)", d.MessageTimeout)
+	}
+	if d.MaxConcurrent < 0 {
+		v.addf("agents.defaults.maxConcurrentMessages must not be negative (got %d)", d.MaxConcurrent)
+	}
+	if d.ThinkingBudget != 0 && d.ThinkingBudget < minThinkingBudget {
+		v.addf("agents.defaults.thinkingBudget must be 0 or at least %d (got %d)", minThinkingBudget, d.ThinkingBudget)
+	}
+	for i, fb := range d.Fallback {
+		prefix := fmt.Sprintf("agents.defaults.fallback[%d]", i)
+		p, ok := c.Providers.ByName()[fb.Provider]
+		switch {
+		case !ok:
+			v.addf("%s.provider %q is not a configured provider", prefix, fb.Provider)
+		case fb.Model == "" && p.DefaultModel == "":
+			v.addf("%s.model is required because provider %s has no defaultModel", prefix, fb.Provider)
+		}
+	}
+
+	for _, name := range sortedKeys(c.Agents.Named) {
+		a := c.Agents.Named[name]
+		checkAgent("agents.named."+name, a.MaxTokens, a.Temperature, a.MaxToolIterations)
+		if _, ok := c.Providers.ByName()[a.Provider]; a.Provider != "" && !ok {
+			v.addf("agents.named.%s.provider %q is not a configured provider", name, a.Provider)
+		}
+	}
+}
+
+func (c *Config) validateProviders(v *validator) {
+	providers := c.Providers.ByName()
+	for _, name := range sortedKeys(providers) {
+		p := providers[name]
+		v.checkURL("providers."+name+".baseUrl", p.BaseURL)
+		if p.TimeoutSeconds < 0 {
+			v.addf("providers.%s.timeoutSeconds must not be negative (got %d)", name, p.TimeoutSeconds)
+		}
+		// Only a custom endpoint may run without a key (e.g. a local model server).
+		if name != "custom" && p.APIKey == "" && (p.BaseURL != "" || p.DefaultModel != "" || len(p.ExtraHeaders) > 0) {
+			v.addf("providers.%s.apiKey is required when the provider is configured", name)
+		}
+	}
+}
+
+func (c *Config) validateChannels(v *validator) {
+	ch := c.Channels
+
+	limits := map[string]int{
+		"telegram.maxMessageLength":   ch.Telegram.MaxMessageLength,
+		"discord.maxMessageLength":    ch.Discord.MaxMessageLength,
+		"slack.maxMessageLength":      ch.Slack.MaxMessageLength,
+		"whatsapp.max_message_length": ch.WhatsApp.MaxMessageLength,
+		"feishu.maxMessageLength":     ch.Feishu.MaxMessageLength,
+		"dingtalk.maxMessageLength":   ch.DingTalk.MaxMessageLength,
+		"qq.maxMessageLength":         ch.QQ.MaxMessageLength,
+	}
+	for _, field := range sortedKeys(limits) {
+		if limits[field] < 0 {
+			v.addf("channels.%s must not be negative (got %d)", field, limits[field])
+		}
+	}
+
+	if ch.Telegram.Token != "" || len(ch.Telegram.AllowedUsers) > 0 {
+		v.require("channels.telegram", map[string]string{"token": ch.Telegram.Token})
+	}
+	if ch.Discord.Token != "" || len(ch.Discord.AllowedUsers) > 0 {
+		v.require("channels.discord", map[string]string{"token": ch.Discord.Token})
+	}
+	if ch.Slack.BotToken != "" || ch.Slack.AppToken != "" || len(ch.Slack.AllowedUsers) > 0 {
+		v.require("channels.slack", map[string]string{
+			"botToken": ch.Slack.BotToken,
+			"appToken": ch.Slack.AppToken,
+		})
+	}
+	wa := ch.WhatsApp
+	if wa.AccessToken != "" || wa.PhoneNumberID != "" || wa.VerifyToken != "" || wa.WebhookPort != 0 || len(wa.AllowedUsers) > 0 {
+		v.require("channels.whatsapp", map[string]string{
+			"access_token":    wa.AccessToken,
+			"phone_number_id": wa.PhoneNumberID,
+			"verify_token":    wa.VerifyToken,
+		})
+		if wa.WebhookPort < 0 || wa.WebhookPort > 65535 {
+			v.addf("channels.whatsapp.webhook_port must be between 0 and 65535 (got %d)", wa.WebhookPort)
+		}
+	}
+	if ch.Feishu.AppID != "" || ch.Feishu.AppSecret != "" || len(ch.Feishu.AllowedUsers) > 0 {
+		v.require("channels.feishu", map[string]string{
+			"appId":     ch.Feishu.AppID,
+			"appSecret": ch.Feishu.AppSecret,
+		})
+	}
+	if ch.DingTalk.ClientID != "" || ch.DingTalk.ClientSecret != "" || len(ch.DingTalk.AllowedUsers) > 0 {
+		v.require("channels.dingtalk", map[string]string{
+			"clientId":     ch.DingTalk.ClientID,
+			"clientSecret": ch.DingTalk.ClientSecret,
+		})
+	}
+	if ch.QQ.AppID != "" || ch.QQ.Token != "" || ch.QQ.AppSecret != "" || len(ch.QQ.AllowedUsers) > 0 {
+		v.require("channels.qq", map[string]string{"appId": ch.QQ.AppID})
+		if ch.QQ.AppSecret == "" && ch.QQ.Token == "" {
+			v.addf("channels.qq needs an appSecret or a token")
+		}
+	}
+	em := ch.Email
+	if em.IMAPServer != "" || em.SMTPServer != "" || em.Username != "" || em.Password != "" || len(em.AllowedUsers) > 0 || em.AuthMode != "" {
+		v.require("channels.email", map[string]string{
+			"imapServer": em.IMAPServer,
+			"smtpServer": em.SMTPServer,
+			"username":   em.Username,
+		})
+		switch em.AuthMode {
+		case "", "password":
+			v.require("channels.email", map[string]string{"password": em.Password})
+		case "xoauth2":
+			if em.AccessToken == "" && (em.RefreshToken == "" || em.TokenURL == "") {
+				v.addf("channels.email xoauth2 needs an accessToken or a refreshToken and tokenUrl")
+			}
+			v.checkURL("channels.email.tokenUrl", em.TokenURL)
+		default:
+			v.addf("channels.email.authMode must be password or xoauth2 (got %q)", em.AuthMode)
+		}
+	}
+	if em.SinceDays < 0 {
+		v.addf("channels.email.sinceDays must not be negative (got %d)", em.SinceDays)
+	}
+	if ch.Mochat.URL != "" || len(ch.Mochat.AllowedUsers) > 0 {
+		v.require("channels.mochat", map[string]string{"url": ch.Mochat.URL})
+		v.checkURL("channels.mochat.url", ch.Mochat.URL)
+	}
+}
+
+func (c *Config) validateMCP(v *validator) {
+	for _, name := range sortedKeys(c.MCP) {
+		m := c.MCP[name]
+		prefix := "mcp." + name
+		switch {
+		case m.Command == "" && m.URL == "":
+			v.addf("%s needs either a command or a url", prefix)
+		case m.Command != "" && m.URL != "":
+			v.addf("%s sets both command and url; use one", prefix)
+		}
+		v.checkURL(prefix+".url", m.URL)
+		if m.ToolTimeout < 0 {
+			v.addf("%s.toolTimeout must not be negative (got %d)", prefix, m.ToolTimeout)
+		}
+		if m.MaxReconnects < 0 {
+			v.addf("%s.maxReconnects must not be negative (got %d)", prefix, m.MaxReconnects)
+		}
+		for _, p := range m.EnvPassthrough {
+			if _, err := path.Match(p, ""); err != nil {
+				v.addf("%s.envPassthrough has an invalid pattern %q", prefix, p)
+			}
+		}
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
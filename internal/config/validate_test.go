@@ -0,0 +1,227 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateDefaultConfig(t *testing.T) {
+	if err := DefaultConfig().Validate(); err != nil {
+		t.Fatalf("default config should be valid: %v", err)
+	}
+}
+
+func TestValidateInvalidConfigs(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(*Config)
+		want   []string
+	}{
+		{
+			name:   "telegram without token",
+			mutate: func(c *Config) { c.Channels.Telegram.AllowedUsers = []string{"1"} },
+			want:   []string{"channels.telegram.token is required"},
+		},
+		{
+			name:   "slack missing app token",
+			mutate: func(c *Config) { c.Channels.Slack.BotToken = "xoxb" },
+			want:   []string{"channels.slack.appToken is required"},
+		},
+		{
+			name: "email partially configured",
+			mutate: func(c *Config) {
+				c.Channels.Email.IMAPServer = "imap.example.com"
+				c.Channels.Email.Username = "bot"
+			},
+			want: []string{"channels.email.password is required", "channels.email.smtpServer is required"},
+		},
+		{
+			name: "email xoauth2 without token",
+			mutate: func(c *Config) {
+				c.Channels.Email = EmailConfig{IMAPServer: "imap.gmail.com:993", SMTPServer: "smtp.gmail.com:587", Username: "bot@example.com", AuthMode: "xoauth2", RefreshToken: "rt"}
+			},
+			want: []string{"channels.email xoauth2 needs an accessToken or a refreshToken and tokenUrl"},
+		},
+		{
+			name: "email unknown auth mode",
+			mutate: func(c *Config) {
+				c.Channels.Email = EmailConfig{IMAPServer: "i", SMTPServer: "s", Username: "u", AuthMode: "kerberos"}
+			},
+			want: []string{`channels.email.authMode must be password or xoauth2 (got "kerberos")`},
+		},
+		{
+			name:   "qq without credentials",
+			mutate: func(c *Config) { c.Channels.QQ.AppID = "app" },
+			want:   []string{"channels.qq needs an appSecret or a token"},
+		},
+		{
+			name:   "qq token without appId",
+			mutate: func(c *Config) { c.Channels.QQ.Token = "tok" },
+			want:   []string{"channels.qq.appId is required"},
+		},
+		{
+			name:   "email negative sinceDays",
+			mutate: func(c *Config) { c.Channels.Email.SinceDays = -1 },
+			want:   []string{"channels.email.sinceDays must not be negative"},
+		},
+		{
+			name: "negative message length limit",
+			mutate: func(c *Config) {
+				c.Channels.Telegram = TelegramConfig{Token: "t", MaxMessageLength: -1}
+			},
+			want: []string{"channels.telegram.maxMessageLength must not be negative"},
+		},
+		{
+			name:   "negative message timeout",
+			mutate: func(c *Config) { c.Agents.Defaults.MessageTimeout = -1 },
+			want:   []string{"agents.defaults.messageTimeout must not be negative"},
+		},
+		{
+			name:   "negative message concurrency",
+			mutate: func(c *Config) { c.Agents.Defaults.MaxConcurrent = -2 },
+			want:   []string{"agents.defaults.maxConcurrentMessages must not be negative"},
+		},
+		{
+			name:   "negative thinking budget",
+			mutate: func(c *Config) { c.Agents.Defaults.ThinkingBudget = -1 },
+			want:   []string{"agents.defaults.thinkingBudget must be 0 or at least 1024"},
+		},
+		{
+			name:   "thinking budget below the minimum",
+			mutate: func(c *Config) { c.Agents.Defaults.ThinkingBudget = 512 },
+			want:   []string{"agents.defaults.thinkingBudget must be 0 or at least 1024 (got 512)"},
+		},
+		{
+			name: "bad fallback chain",
+			mutate: func(c *Config) {
+				c.Agents.Defaults.Fallback = []FallbackConfig{{Provider: "acme"}, {Provider: "groq"}, {Provider: "openai", Model: "gpt-4o-mini"}}
+			},
+			want: []string{
+				`agents.defaults.fallback[0].provider "acme" is not a configured provider`,
+				"agents.defaults.fallback[1].model is required because provider groq has no defaultModel",
+			},
+		},
+		{
+			name:   "unknown web search provider",
+			mutate: func(c *Config) { c.Tools.WebSearch.Provider = "altavista" },
+			want:   []string{`tools.webSearch.provider "altavista" is not supported`},
+		},
+		{
+			name:   "mcp without command or url",
+			mutate: func(c *Config) { c.MCP = map[string]MCPServerConfig{"fs": {Args: []string{"x"}}} },
+			want:   []string{"mcp.fs needs either a command or a url"},
+		},
+		{
+			name: "mcp with both command and bad url",
+			mutate: func(c *Config) {
+				c.MCP = map[string]MCPServerConfig{"web": {Command: "srv", URL: "not a url", MaxReconnects: -1}}
+			},
+			want: []string{"mcp.web sets both command and url", "mcp.web.url must be an http(s) URL", "mcp.web.maxReconnects must not be negative"},
+		},
+		{
+			name: "mcp with bad env passthrough pattern",
+			mutate: func(c *Config) {
+				c.MCP = map[string]MCPServerConfig{"gh": {Command: "srv", EnvPassthrough: []string{"GITHUB_*", "[bad"}}}
+			},
+			want: []string{`mcp.gh.envPassthrough has an invalid pattern "[bad"`},
+		},
+		{
+			name: "provider configured without key",
+			mutate: func(c *Config) {
+				c.Providers.DeepSeek.DefaultModel = "deepseek-chat"
+				c.Providers.OpenAI.APIKey = "sk-test"
+				c.Providers.OpenAI.BaseURL = "ftp://example.com"
+				c.Providers.OpenAI.TimeoutSeconds = -5
+			},
+			want: []string{"providers.deepseek.apiKey is required", "providers.openai.baseUrl must be an http(s) URL", "providers.openai.timeoutSeconds must not be negative"},
+		},
+		{
+			name: "agent limits out of range",
+			mutate: func(c *Config) {
+				c.Agents.Defaults.Model = ""
+				c.Agents.Defaults.Temperature = 3
+				c.Agents.Named = map[string]AgentConfig{"coder": {MaxTokens: -1, Provider: "acme"}}
+			},
+			want: []string{"agents.defaults.model is required", "agents.defaults.temperature must be between 0 and 2", "agents.named.coder.maxTokens must not be negative", `agents.named.coder.provider "acme" is not a configured provider`},
+		},
+		{
+			name: "gateway settings invalid",
+			mutate: func(c *Config) {
+				c.Gateway.Port = 70000
+				c.Gateway.APIKeys = []string{"k1", " "}
+			},
+			want: []string{"gateway.port must be between 0 and 65535", "gateway.apiKeys[1] must not be empty"},
+		},
+		{
+			name: "logging settings invalid",
+			mutate: func(c *Config) {
+				c.Logging.Level = "loud"
+				c.Logging.Format = "xml"
+			},
+			want: []string{"logging.level must be debug, info, warn or error", "logging.format must be text or json"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			tt.mutate(cfg)
+
+			err := cfg.Validate()
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("expected *ValidationError, got %v", err)
+			}
+			if len(verr.Problems) != len(tt.want) {
+				t.Errorf("got %d problems, want %d:\n%v", len(verr.Problems), len(tt.want), err)
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(err.Error(), w) {
+					t.Errorf("error missing %q:\n%v", w, err)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateAllowsCustomProviderWithoutKey(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Providers.Custom.BaseURL = "http://localhost:11434/v1"
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("custom provider without key should be valid: %v", err)
+	}
+}
+
+func TestValidateAllowsQQToken(t *testing.T) {
+	for name, qq := range map[string]QQConfig{
+		"token":     {AppID: "app", Token: "tok"},
+		"appSecret": {AppID: "app", AppSecret: "secret"},
+	} {
+		cfg := DefaultConfig()
+		cfg.Channels.QQ = qq
+		if err := cfg.Validate(); err != nil {
+			t.Errorf("qq with appId and %s should be valid: %v", name, err)
+		}
+	}
+}
+
+func TestLoadFromFileRunsValidation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	content := `{"channels": {"discord": {"allowedUsers": ["42"]}}, "mcp": {"broken": {}}}`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := LoadFromFile(path)
+	if err == nil {
+		t.Fatal("expected validation error")
+	}
+	for _, w := range []string{"channels.discord.token is required", "mcp.broken needs either a command or a url"} {
+		if !strings.Contains(err.Error(), w) {
+			t.Errorf("error missing %q:\n%v", w, err)
+		}
+	}
+}
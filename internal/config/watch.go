@@ -0,0 +1,88 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce coalesces the burst of events editors emit for a single save.
+const watchDebounce = 200 * time.Millisecond
+
+// Watch reloads the config at path whenever the file changes and passes the
+// new Config to onChange. Reloads go through LoadFromFile, so env overrides
+// and validation are re-applied; a config that fails to load is logged and
+// skipped. Watch returns once the watcher is running and stops when ctx is
+// cancelled.
+func Watch(ctx context.Context, path string, onChange func(*Config)) error {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("failed to resolve config path: %w", err)
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create config watcher: %w", err)
+	}
+	// Watch the directory rather than the file: editors often save by
+	// renaming a temp file over the original, which drops a file watch.
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		watcher.Close()
+		return fmt.Errorf("failed to watch %s: %w", filepath.Dir(path), err)
+	}
+
+	go func() {
+		defer watcher.Close()
+
+		var timer *time.Timer
+		reload := make(chan struct{}, 1)
+		defer func() {
+			if timer != nil {
+				timer.Stop()
+			}
+		}()
+
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(event.Name) != path || event.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+					continue
+				}
+				if timer == nil {
+					timer = time.AfterFunc(watchDebounce, func() {
+						select {
+						case reload <- struct{}{}:
+						default:
+						}
+					})
+				} else {
+					timer.Reset(watchDebounce)
+				}
+			case <-reload:
+				cfg, err := LoadFromFile(path)
+				if err != nil {
+					slog.Warn("config reload failed", "path", path, "error", err)
+					continue
+				}
+				slog.Info("config reloaded", "path", path)
+				onChange(cfg)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				slog.Warn("config watcher error", "path", path, "error", err)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return nil
+}
@@ -0,0 +1,85 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWatchReloadsOnChange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"agents": {"defaults": {"model": "gpt-4o"}}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var calls atomic.Int32
+	changed := make(chan *Config, 4)
+	err := Watch(ctx, path, func(cfg *Config) {
+		calls.Add(1)
+		changed <- cfg
+	})
+	if err != nil {
+		t.Fatalf("Watch failed: %v", err)
+	}
+
+	// Several quick writes should be debounced into a single reload.
+	for _, model := range []string{"draft-1", "draft-2", "claude-3"} {
+		content := `{"agents": {"defaults": {"model": "` + model + `"}}}`
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	select {
+	case cfg := <-changed:
+		if cfg.Agents.Defaults.Model != "claude-3" {
+			t.Errorf("model = %q, want claude-3", cfg.Agents.Defaults.Model)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("onChange was not called")
+	}
+
+	time.Sleep(2 * watchDebounce)
+	if n := calls.Load(); n != 1 {
+		t.Errorf("onChange called %d times, want 1", n)
+	}
+}
+
+func TestWatchSkipsInvalidConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changed := make(chan *Config, 1)
+	if err := Watch(ctx, path, func(cfg *Config) { changed <- cfg }); err != nil {
+		t.Fatalf("Watch failed: %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte(`{not json`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case cfg := <-changed:
+		t.Fatalf("onChange called for invalid config: %+v", cfg)
+	case <-time.After(3 * watchDebounce):
+	}
+}
+
+func TestWatchMissingDirectory(t *testing.T) {
+	err := Watch(context.Background(), filepath.Join(t.TempDir(), "nope", "config.json"), func(*Config) {})
+	if err == nil {
+		t.Fatal("expected error watching a missing directory")
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -19,9 +21,12 @@ type Service struct {
 	bus       *bus.MessageBus
 	storePath string
 	jobs      map[string]robfigcron.EntryID
+	timers    map[string]*time.Timer // one-shot jobs armed while running
 	jobDefs   map[string]CronJob
 	mu        sync.Mutex
 	counter   int
+	running   bool
+	seconds   bool // cron expressions carry a leading seconds field
 }
 
 func NewService(storePath string, msgBus *bus.MessageBus) *Service {
@@ -30,54 +35,79 @@ func NewService(storePath string, msgBus *bus.MessageBus) *Service {
 		bus:       msgBus,
 		storePath: storePath,
 		jobs:      make(map[string]robfigcron.EntryID),
+		timers:    make(map[string]*time.Timer),
 		jobDefs:   make(map[string]CronJob),
 	}
 }
 
-// Start begins the cron scheduler.
+// SetSecondsField switches cron expressions between the standard five fields
+// and six fields with a leading seconds field, e.g. "*/30 * * * * *". Call it
+// before jobs are added or loaded.
+func (s *Service) SetSecondsField(enabled bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seconds = enabled
+}
+
+// Start begins the cron scheduler and arms pending one-shot jobs.
 func (s *Service) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.running = true
+	for id, job := range s.jobDefs {
+		if job.Enabled && job.Schedule.Type == ScheduleOnce {
+			s.armOnce(id, job)
+		}
+	}
 	s.scheduler.Start()
 }
 
-// Stop stops the cron scheduler.
+// Stop stops the cron scheduler and disarms one-shot jobs.
 func (s *Service) Stop() {
+	s.mu.Lock()
+	s.running = false
+	for id, timer := range s.timers {
+		timer.Stop()
+		delete(s.timers, id)
+	}
+	s.mu.Unlock()
+
 	s.scheduler.Stop()
 }
 
 // AddJob adds a new cron job. Returns the job ID.
 func (s *Service) AddJob(schedule CronSchedule, message, sessionKey string) (string, error) {
-	cronExpr, err := toCronExpr(schedule)
-	if err != nil {
-		return "", fmt.Errorf("invalid schedule: %w", err)
-	}
+	return s.AddJobTo(schedule, message, sessionKey, "", "")
+}
 
+// AddJobTo adds a new cron job whose replies are delivered to chatID on
+// channel, e.g. a reminder sent to a Telegram chat. Returns the job ID.
+func (s *Service) AddJobTo(schedule CronSchedule, message, sessionKey, channel, chatID string) (string, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	if err := validateSchedule(schedule, s.seconds); err != nil {
+		return "", fmt.Errorf("invalid schedule: %w", err)
+	}
+
 	id := fmt.Sprintf("cron_%d", s.counter)
 	s.counter++
 
 	job := CronJob{
-		ID:        id,
-		Schedule:  schedule,
-		Message:   message,
+		ID:         id,
+		Schedule:   schedule,
+		Message:    message,
 		SessionKey: sessionKey,
-		CreatedAt: time.Now(),
+		Channel:    channel,
+		ChatID:     chatID,
+		CreatedAt:  time.Now(),
+		Enabled:    true,
 	}
 
-	entryID, err := s.scheduler.AddFunc(cronExpr, func() {
-		s.bus.PublishInbound(bus.InboundMessage{
-			Channel:            "system",
-			Content:            message,
-			SessionKeyOverride: sessionKey,
-			Metadata:           map[string]string{"source": "cron", "job_id": id},
-		})
-	})
-	if err != nil {
-		return "", fmt.Errorf("failed to register cron job: %w", err)
+	if err := s.register(job); err != nil {
+		return "", err
 	}
-
-	s.jobs[id] = entryID
 	s.jobDefs[id] = job
 
 	if err := s.saveToDisk(); err != nil {
@@ -92,13 +122,11 @@ func (s *Service) RemoveJob(id string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	entryID, ok := s.jobs[id]
-	if !ok {
+	if _, ok := s.jobDefs[id]; !ok {
 		return fmt.Errorf("job %q not found", id)
 	}
 
-	s.scheduler.Remove(entryID)
-	delete(s.jobs, id)
+	s.unregister(id)
 	delete(s.jobDefs, id)
 
 	if err := s.saveToDisk(); err != nil {
@@ -108,18 +136,190 @@ func (s *Service) RemoveJob(id string) error {
 	return nil
 }
 
-// ListJobs returns all registered jobs.
+// DisableJob pauses a job without removing its definition.
+func (s *Service) DisableJob(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobDefs[id]
+	if !ok {
+		return fmt.Errorf("job %q not found", id)
+	}
+	if !job.Enabled {
+		return nil
+	}
+
+	s.unregister(id)
+	job.Enabled = false
+	s.jobDefs[id] = job
+
+	if err := s.saveToDisk(); err != nil {
+		slog.Warn("failed to persist cron jobs after disable", "error", err)
+	}
+	return nil
+}
+
+// EnableJob resumes a job previously paused with DisableJob.
+func (s *Service) EnableJob(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobDefs[id]
+	if !ok {
+		return fmt.Errorf("job %q not found", id)
+	}
+	if job.Enabled {
+		return nil
+	}
+
+	job.Enabled = true
+	if err := s.register(job); err != nil {
+		return err
+	}
+	s.jobDefs[id] = job
+
+	if err := s.saveToDisk(); err != nil {
+		slog.Warn("failed to persist cron jobs after enable", "error", err)
+	}
+	return nil
+}
+
+// register schedules job with the cron scheduler, or arms a timer for
+// one-shot jobs. Caller must hold s.mu.
+func (s *Service) register(job CronJob) error {
+	if job.Schedule.Type == ScheduleOnce {
+		if s.running {
+			s.armOnce(job.ID, job)
+		}
+		return nil
+	}
+
+	sched, err := parseSchedule(job.Schedule, s.seconds)
+	if err != nil {
+		return fmt.Errorf("invalid schedule: %w", err)
+	}
+
+	s.jobs[job.ID] = s.scheduler.Schedule(sched, robfigcron.FuncJob(func() { s.fire(job) }))
+	return nil
+}
+
+// unregister removes the scheduler entry or timer for id. Caller must hold s.mu.
+func (s *Service) unregister(id string) {
+	if entryID, ok := s.jobs[id]; ok {
+		s.scheduler.Remove(entryID)
+		delete(s.jobs, id)
+	}
+	if timer, ok := s.timers[id]; ok {
+		timer.Stop()
+		delete(s.timers, id)
+	}
+}
+
+// armOnce starts the timer for a one-shot job. Caller must hold s.mu.
+func (s *Service) armOnce(id string, job CronJob) {
+	at, err := parseOnce(job.Schedule)
+	if err != nil {
+		slog.Warn("invalid one-shot cron job", "id", id, "error", err)
+		return
+	}
+	s.timers[id] = time.AfterFunc(time.Until(at), func() { s.fireOnce(id) })
+}
+
+// fireOnce runs a one-shot job and removes it, including its store entry.
+func (s *Service) fireOnce(id string) {
+	s.mu.Lock()
+	job, ok := s.jobDefs[id]
+	if ok {
+		delete(s.jobDefs, id)
+		delete(s.timers, id)
+		if err := s.saveToDisk(); err != nil {
+			slog.Warn("failed to persist cron jobs after one-shot run", "error", err)
+		}
+	}
+	s.mu.Unlock()
+
+	if ok {
+		s.fire(job)
+	}
+}
+
+// fire publishes the job's message to the bus, naming the job's destination
+// as the origin so the reply is routed there.
+func (s *Service) fire(job CronJob) {
+	meta := map[string]string{"source": "cron", "job_id": job.ID}
+	if job.Channel != "" {
+		meta[bus.MetaOriginChannel] = job.Channel
+		meta[bus.MetaOriginChatID] = job.ChatID
+	}
+	s.bus.PublishInbound(bus.InboundMessage{
+		Channel:            bus.SystemChannel,
+		Content:            job.Message,
+		SessionKeyOverride: job.SessionKey,
+		Metadata:           meta,
+	})
+}
+
+// ListJobs returns all registered jobs ordered by creation time, with NextRun
+// set to when each will next fire.
 func (s *Service) ListJobs() []CronJob {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	now := time.Now()
 	result := make([]CronJob, 0, len(s.jobDefs))
-	for _, job := range s.jobDefs {
+	for id, job := range s.jobDefs {
+		if !job.Enabled {
+			result = append(result, job)
+			continue
+		}
+		if entryID, ok := s.jobs[id]; ok {
+			job.NextRun = s.scheduler.Entry(entryID).Next
+		}
+		if job.NextRun.IsZero() {
+			// The scheduler only computes Next once started.
+			if next, err := nextRun(job.Schedule, now, s.seconds); err == nil {
+				job.NextRun = next
+			}
+		}
 		result = append(result, job)
 	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].CreatedAt.Equal(result[j].CreatedAt) {
+			return result[i].ID < result[j].ID
+		}
+		return result[i].CreatedAt.Before(result[j].CreatedAt)
+	})
 	return result
 }
 
+// FormatJobs renders jobs as a human-readable listing, one job per line,
+// suitable for the manage_cron tool's list action.
+func FormatJobs(jobs []CronJob) string {
+	if len(jobs) == 0 {
+		return "No cron jobs scheduled."
+	}
+
+	var sb strings.Builder
+	for _, job := range jobs {
+		fmt.Fprintf(&sb, "- %s [%s %s", job.ID, job.Schedule.Type, job.Schedule.Expression)
+		if job.Schedule.Timezone != "" {
+			fmt.Fprintf(&sb, " %s", job.Schedule.Timezone)
+		}
+		sb.WriteString("]")
+		if !job.Enabled {
+			sb.WriteString(" (disabled)")
+		} else if !job.NextRun.IsZero() {
+			fmt.Fprintf(&sb, " next: %s", job.NextRun.Format(time.RFC3339))
+		}
+		fmt.Fprintf(&sb, " session: %s", job.SessionKey)
+		if job.Channel != "" {
+			fmt.Fprintf(&sb, " to: %s:%s", job.Channel, job.ChatID)
+		}
+		fmt.Fprintf(&sb, " message: %q\n", job.Message)
+	}
+	return sb.String()
+}
+
 // LoadFromDisk loads persisted jobs and re-registers them.
 func (s *Service) LoadFromDisk() error {
 	data, err := os.ReadFile(s.storePath)
@@ -136,8 +336,21 @@ func (s *Service) LoadFromDisk() error {
 	}
 
 	for _, job := range store.Jobs {
-		if _, err := s.AddJob(job.Schedule, job.Message, job.SessionKey); err != nil {
+		if job.Schedule.Type == ScheduleOnce {
+			if at, err := parseOnce(job.Schedule); err == nil && !at.After(time.Now()) {
+				slog.Info("skipping expired one-shot cron job", "id", job.ID, "at", at)
+				continue
+			}
+		}
+		id, err := s.AddJobTo(job.Schedule, job.Message, job.SessionKey, job.Channel, job.ChatID)
+		if err != nil {
 			slog.Warn("failed to restore cron job", "id", job.ID, "error", err)
+			continue
+		}
+		if !job.Enabled {
+			if err := s.DisableJob(id); err != nil {
+				slog.Warn("failed to restore disabled cron job", "id", job.ID, "error", err)
+			}
 		}
 	}
 	return nil
@@ -163,11 +376,45 @@ func (s *Service) saveToDisk() error {
 	return os.WriteFile(s.storePath, data, 0o644)
 }
 
+// validateSchedule checks that schedule can be registered.
+func validateSchedule(schedule CronSchedule, seconds bool) error {
+	if schedule.Type == ScheduleOnce {
+		at, err := parseOnce(schedule)
+		if err != nil {
+			return err
+		}
+		if !at.After(time.Now()) {
+			return fmt.Errorf("time %s is in the past", schedule.Expression)
+		}
+		return nil
+	}
+	_, err := parseSchedule(schedule, seconds)
+	return err
+}
+
+// parseOnce parses the RFC3339 timestamp of a one-shot schedule.
+func parseOnce(schedule CronSchedule) (time.Time, error) {
+	at, err := time.Parse(time.RFC3339, schedule.Expression)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timestamp %q, expected RFC3339 (e.g. 2025-12-01T14:00:00Z): %w", schedule.Expression, err)
+	}
+	return at, nil
+}
+
 // toCronExpr converts a CronSchedule to a robfig/cron expression string.
+// Wall-clock schedules carry their timezone as a CRON_TZ prefix.
 func toCronExpr(schedule CronSchedule) (string, error) {
+	tzPrefix := ""
+	if schedule.Timezone != "" {
+		if _, err := time.LoadLocation(schedule.Timezone); err != nil {
+			return "", fmt.Errorf("unknown timezone %q: %w", schedule.Timezone, err)
+		}
+		tzPrefix = "CRON_TZ=" + schedule.Timezone + " "
+	}
+
 	switch schedule.Type {
 	case ScheduleCron:
-		return schedule.Expression, nil
+		return tzPrefix + schedule.Expression, nil
 	case ScheduleEvery:
 		d, err := time.ParseDuration(schedule.Expression)
 		if err != nil {
@@ -182,8 +429,76 @@ func toCronExpr(schedule CronSchedule) (string, error) {
 		if h < 0 || h > 23 || m < 0 || m > 59 {
 			return "", fmt.Errorf("time %q out of range", schedule.Expression)
 		}
-		return fmt.Sprintf("%d %d * * *", m, h), nil
+		return fmt.Sprintf("%s%d %d * * *", tzPrefix, m, h), nil
+	case ScheduleOnce:
+		return "", fmt.Errorf("one-shot schedule %q has no cron expression", schedule.Expression)
 	default:
 		return "", fmt.Errorf("unknown schedule type %q", schedule.Type)
 	}
 }
+
+var (
+	standardParser = robfigcron.NewParser(robfigcron.Minute | robfigcron.Hour | robfigcron.Dom | robfigcron.Month | robfigcron.Dow | robfigcron.Descriptor)
+	secondsParser  = robfigcron.NewParser(robfigcron.Second | robfigcron.Minute | robfigcron.Hour | robfigcron.Dom | robfigcron.Month | robfigcron.Dow | robfigcron.Descriptor)
+)
+
+// parseSchedule parses a recurring schedule. With seconds set, cron
+// expressions take a leading seconds field; the expressions generated for
+// every and at schedules are always standard.
+func parseSchedule(schedule CronSchedule, seconds bool) (robfigcron.Schedule, error) {
+	expr, err := toCronExpr(schedule)
+	if err != nil {
+		return nil, err
+	}
+	parser := standardParser
+	if schedule.Type == ScheduleCron {
+		if err := checkFieldCount(schedule.Expression, seconds); err != nil {
+			return nil, err
+		}
+		if seconds {
+			parser = secondsParser
+		}
+	}
+	sched, err := parser.Parse(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: %w", expr, err)
+	}
+	return sched, nil
+}
+
+// checkFieldCount reports a cron expression written for the other mode with
+// an error that says which form is expected. Descriptors such as @daily are
+// accepted in both modes.
+func checkFieldCount(expr string, seconds bool) error {
+	fields := strings.Fields(expr)
+	if len(fields) > 0 && (strings.HasPrefix(fields[0], "CRON_TZ=") || strings.HasPrefix(fields[0], "TZ=")) {
+		fields = fields[1:]
+	}
+	if len(fields) > 0 && strings.HasPrefix(fields[0], "@") {
+		return nil
+	}
+	switch {
+	case seconds && len(fields) != 6:
+		return fmt.Errorf("cron expression %q has %d fields, want 6 (second minute hour day-of-month month day-of-week)", expr, len(fields))
+	case !seconds && len(fields) != 5:
+		return fmt.Errorf("cron expression %q has %d fields, want 5 (minute hour day-of-month month day-of-week); a seconds field needs the seconds option enabled", expr, len(fields))
+	}
+	return nil
+}
+
+// nextRun returns the first time after from at which schedule fires.
+func nextRun(schedule CronSchedule, from time.Time, seconds bool) (time.Time, error) {
+	if schedule.Type == ScheduleOnce {
+		at, err := parseOnce(schedule)
+		if err != nil || !at.After(from) {
+			return time.Time{}, err
+		}
+		return at, nil
+	}
+
+	sched, err := parseSchedule(schedule, seconds)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return sched.Next(from), nil
+}
@@ -2,7 +2,10 @@ package cron
 
 import (
 	"context"
+	"encoding/json"
+	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -113,6 +116,111 @@ func TestCronScheduleConversion(t *testing.T) {
 	}
 }
 
+func TestScheduleTimezone(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("tzdata unavailable: %v", err)
+	}
+
+	// 07:00 in New York on a winter day (UTC-5)
+	from := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name     string
+		schedule CronSchedule
+		want     time.Time
+	}{
+		{"at", CronSchedule{Type: ScheduleAt, Expression: "09:00", Timezone: "America/New_York"}, time.Date(2025, 1, 15, 9, 0, 0, 0, ny)},
+		{"cron", CronSchedule{Type: ScheduleCron, Expression: "0 9 * * *", Timezone: "America/New_York"}, time.Date(2025, 1, 15, 9, 0, 0, 0, ny)},
+		{"at utc", CronSchedule{Type: ScheduleAt, Expression: "09:00", Timezone: "UTC"}, time.Date(2025, 1, 16, 9, 0, 0, 0, time.UTC)},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := nextRun(tc.schedule, from, false)
+			if err != nil {
+				t.Fatalf("nextRun: %v", err)
+			}
+			if !got.Equal(tc.want) {
+				t.Errorf("next run = %v, want %v", got.UTC(), tc.want.UTC())
+			}
+		})
+	}
+}
+
+func TestSecondsField(t *testing.T) {
+	from := time.Date(2025, 1, 15, 12, 0, 10, 0, time.UTC)
+	cases := []struct {
+		name    string
+		seconds bool
+		expr    string
+		want    time.Time // zero means an error is expected
+		errMsg  string
+	}{
+		{"standard five fields", false, "*/5 * * * *", time.Date(2025, 1, 15, 12, 5, 0, 0, time.UTC), ""},
+		{"standard six fields", false, "*/30 * * * * *", time.Time{}, "want 5"},
+		{"seconds six fields", true, "*/30 * * * * *", time.Date(2025, 1, 15, 12, 0, 30, 0, time.UTC), ""},
+		{"seconds five fields", true, "*/5 * * * *", time.Time{}, "want 6"},
+		{"seconds descriptor", true, "@hourly", time.Date(2025, 1, 15, 13, 0, 0, 0, time.UTC), ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			schedule := CronSchedule{Type: ScheduleCron, Expression: tc.expr}
+			got, err := nextRun(schedule, from, tc.seconds)
+			if tc.want.IsZero() {
+				if err == nil || !strings.Contains(err.Error(), tc.errMsg) {
+					t.Fatalf("nextRun error = %v, want one containing %q", err, tc.errMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("nextRun: %v", err)
+			}
+			if !got.Equal(tc.want) {
+				t.Errorf("next run = %v, want %v", got, tc.want)
+			}
+		})
+	}
+
+	svc := NewService(filepath.Join(t.TempDir(), "cron.json"), bus.NewMessageBus(10))
+	svc.SetSecondsField(true)
+	if _, err := svc.AddJob(CronSchedule{Type: ScheduleCron, Expression: "*/30 * * * * *"}, "msg", "s"); err != nil {
+		t.Fatalf("AddJob with seconds: %v", err)
+	}
+	if _, err := svc.AddJob(CronSchedule{Type: ScheduleEvery, Expression: "5m"}, "msg", "s"); err != nil {
+		t.Fatalf("AddJob every with seconds: %v", err)
+	}
+	if _, err := svc.AddJob(CronSchedule{Type: ScheduleAt, Expression: "09:00"}, "msg", "s"); err != nil {
+		t.Fatalf("AddJob at with seconds: %v", err)
+	}
+}
+
+func TestScheduleUnknownTimezone(t *testing.T) {
+	svc := NewService(filepath.Join(t.TempDir(), "cron.json"), bus.NewMessageBus(10))
+	_, err := svc.AddJob(CronSchedule{Type: ScheduleAt, Expression: "09:00", Timezone: "Mars/Olympus"}, "msg", "s")
+	if err == nil {
+		t.Fatal("expected error for unknown timezone")
+	}
+}
+
+func TestPersistenceKeepsTimezone(t *testing.T) {
+	storePath := filepath.Join(t.TempDir(), "cron.json")
+	msgBus := bus.NewMessageBus(10)
+
+	svc1 := NewService(storePath, msgBus)
+	if _, err := svc1.AddJob(CronSchedule{Type: ScheduleAt, Expression: "09:00", Timezone: "UTC"}, "hello", "s1"); err != nil {
+		t.Fatalf("AddJob: %v", err)
+	}
+
+	svc2 := NewService(storePath, msgBus)
+	if err := svc2.LoadFromDisk(); err != nil {
+		t.Fatalf("LoadFromDisk: %v", err)
+	}
+	jobs := svc2.ListJobs()
+	if len(jobs) != 1 || jobs[0].Schedule.Timezone != "UTC" {
+		t.Fatalf("expected restored job with timezone UTC, got %+v", jobs)
+	}
+}
+
 func TestJobTrigger(t *testing.T) {
 	msgBus := bus.NewMessageBus(10)
 	svc := NewService(filepath.Join(t.TempDir(), "cron.json"), msgBus)
@@ -142,3 +250,219 @@ func TestJobTrigger(t *testing.T) {
 		t.Errorf("expected source=cron, got %q", msg.Metadata["source"])
 	}
 }
+
+func TestJobDestination(t *testing.T) {
+	storePath := filepath.Join(t.TempDir(), "cron.json")
+	msgBus := bus.NewMessageBus(10)
+	svc := NewService(storePath, msgBus)
+
+	if _, err := svc.AddJobTo(CronSchedule{Type: ScheduleEvery, Expression: "1s"}, "standup", "cron:standup", "telegram", "42"); err != nil {
+		t.Fatalf("AddJobTo: %v", err)
+	}
+
+	// The destination survives a restart.
+	restored := NewService(storePath, msgBus)
+	if err := restored.LoadFromDisk(); err != nil {
+		t.Fatalf("LoadFromDisk: %v", err)
+	}
+	jobs := restored.ListJobs()
+	if len(jobs) != 1 || jobs[0].Channel != "telegram" || jobs[0].ChatID != "42" {
+		t.Fatalf("restored jobs = %+v, want one delivered to telegram:42", jobs)
+	}
+
+	restored.Start()
+	defer restored.Stop()
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	msg, err := msgBus.ConsumeInbound(ctx)
+	if err != nil {
+		t.Fatalf("no message received within timeout: %v", err)
+	}
+	if msg.Channel != bus.SystemChannel || msg.SessionKey() != "cron:standup" {
+		t.Errorf("message arrived on %s for session %s, want system for cron:standup", msg.Channel, msg.SessionKey())
+	}
+	if channel, chatID := msg.ReplyTarget(); channel != "telegram" || chatID != "42" {
+		t.Errorf("reply target = %s:%s, want telegram:42", channel, chatID)
+	}
+}
+
+func TestOnceJobFiresAndCleansUp(t *testing.T) {
+	storePath := filepath.Join(t.TempDir(), "cron.json")
+	msgBus := bus.NewMessageBus(10)
+	svc := NewService(storePath, msgBus)
+	svc.Start()
+	defer svc.Stop()
+
+	at := time.Now().Add(300 * time.Millisecond).Format(time.RFC3339Nano)
+	id, err := svc.AddJob(CronSchedule{Type: ScheduleOnce, Expression: at}, "once", "s1")
+	if err != nil {
+		t.Fatalf("AddJob: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	msg, err := msgBus.ConsumeInbound(ctx)
+	if err != nil {
+		t.Fatalf("no message received: %v", err)
+	}
+	if msg.Content != "once" || msg.Metadata["job_id"] != id {
+		t.Errorf("unexpected message %+v", msg)
+	}
+
+	if jobs := svc.ListJobs(); len(jobs) != 0 {
+		t.Errorf("expected one-shot job to be removed, got %d jobs", len(jobs))
+	}
+	data, err := os.ReadFile(storePath)
+	if err != nil {
+		t.Fatalf("read store: %v", err)
+	}
+	if strings.Contains(string(data), id) {
+		t.Errorf("store still contains %s: %s", id, data)
+	}
+
+	shortCtx, shortCancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer shortCancel()
+	if extra, err := msgBus.ConsumeInbound(shortCtx); err == nil {
+		t.Errorf("one-shot job fired twice: %+v", extra)
+	}
+}
+
+func TestOnceJobValidation(t *testing.T) {
+	svc := NewService(filepath.Join(t.TempDir(), "cron.json"), bus.NewMessageBus(10))
+
+	cases := []struct {
+		name string
+		expr string
+	}{
+		{"past", time.Now().Add(-time.Hour).Format(time.RFC3339)},
+		{"not a timestamp", "tomorrow at noon"},
+		{"missing offset", "2099-12-01T14:00:00"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := svc.AddJob(CronSchedule{Type: ScheduleOnce, Expression: tc.expr}, "m", "s"); err == nil {
+				t.Errorf("expected error for %q", tc.expr)
+			}
+		})
+	}
+}
+
+func TestLoadFromDiskSkipsExpiredOnceJobs(t *testing.T) {
+	storePath := filepath.Join(t.TempDir(), "cron.json")
+	store := `{"jobs":[
+		{"id":"cron_0","schedule":{"type":"once","expression":"2001-01-01T00:00:00Z"},"message":"old","sessionKey":"s"},
+		{"id":"cron_1","schedule":{"type":"once","expression":"2099-01-01T00:00:00Z"},"message":"future","sessionKey":"s"}
+	]}`
+	if err := os.WriteFile(storePath, []byte(store), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	svc := NewService(storePath, bus.NewMessageBus(10))
+	if err := svc.LoadFromDisk(); err != nil {
+		t.Fatalf("LoadFromDisk: %v", err)
+	}
+	jobs := svc.ListJobs()
+	if len(jobs) != 1 || jobs[0].Message != "future" {
+		t.Fatalf("expected only the future job, got %+v", jobs)
+	}
+}
+
+func TestListJobsNextRun(t *testing.T) {
+	svc := NewService(filepath.Join(t.TempDir(), "cron.json"), bus.NewMessageBus(10))
+
+	if _, err := svc.AddJob(CronSchedule{Type: ScheduleEvery, Expression: "1h"}, "stopped", "s"); err != nil {
+		t.Fatalf("AddJob: %v", err)
+	}
+	svc.Start()
+	defer svc.Stop()
+	if _, err := svc.AddJob(CronSchedule{Type: ScheduleCron, Expression: "0 * * * *"}, "running", "s"); err != nil {
+		t.Fatalf("AddJob: %v", err)
+	}
+
+	now := time.Now()
+	jobs := svc.ListJobs()
+	if len(jobs) != 2 {
+		t.Fatalf("expected 2 jobs, got %d", len(jobs))
+	}
+	for _, job := range jobs {
+		if !job.NextRun.After(now) {
+			t.Errorf("job %s NextRun = %v, want a time after %v", job.ID, job.NextRun, now)
+		}
+	}
+
+	listing := FormatJobs(jobs)
+	if !strings.Contains(listing, "next: ") || !strings.Contains(listing, jobs[0].ID) {
+		t.Errorf("listing missing next run or job id:\n%s", listing)
+	}
+	if got := FormatJobs(nil); got != "No cron jobs scheduled." {
+		t.Errorf("FormatJobs(nil) = %q", got)
+	}
+}
+
+func TestDisableAndEnableJob(t *testing.T) {
+	storePath := filepath.Join(t.TempDir(), "cron.json")
+	msgBus := bus.NewMessageBus(10)
+	svc := NewService(storePath, msgBus)
+	svc.Start()
+	defer svc.Stop()
+
+	id, err := svc.AddJob(CronSchedule{Type: ScheduleEvery, Expression: "1s"}, "tick", "s")
+	if err != nil {
+		t.Fatalf("AddJob: %v", err)
+	}
+	if err := svc.DisableJob(id); err != nil {
+		t.Fatalf("DisableJob: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1500*time.Millisecond)
+	defer cancel()
+	if msg, err := msgBus.ConsumeInbound(ctx); err == nil {
+		t.Fatalf("disabled job fired: %+v", msg)
+	}
+
+	jobs := svc.ListJobs()
+	if len(jobs) != 1 || jobs[0].Enabled || !jobs[0].NextRun.IsZero() {
+		t.Fatalf("expected one disabled job without a next run, got %+v", jobs)
+	}
+
+	// the disabled state survives a restart
+	restored := NewService(storePath, msgBus)
+	if err := restored.LoadFromDisk(); err != nil {
+		t.Fatalf("LoadFromDisk: %v", err)
+	}
+	if rj := restored.ListJobs(); len(rj) != 1 || rj[0].Enabled {
+		t.Fatalf("expected restored job to stay disabled, got %+v", rj)
+	}
+
+	if err := svc.EnableJob(id); err != nil {
+		t.Fatalf("EnableJob: %v", err)
+	}
+	ctx2, cancel2 := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel2()
+	if _, err := msgBus.ConsumeInbound(ctx2); err != nil {
+		t.Fatalf("re-enabled job did not fire: %v", err)
+	}
+
+	if err := svc.DisableJob("missing"); err == nil {
+		t.Error("expected error disabling unknown job")
+	}
+	if err := svc.EnableJob("missing"); err == nil {
+		t.Error("expected error enabling unknown job")
+	}
+}
+
+func TestCronJobUnmarshalDefaultsEnabled(t *testing.T) {
+	var job CronJob
+	if err := json.Unmarshal([]byte(`{"id":"cron_0","message":"m"}`), &job); err != nil {
+		t.Fatal(err)
+	}
+	if !job.Enabled {
+		t.Error("expected job without an enabled field to default to enabled")
+	}
+	if err := json.Unmarshal([]byte(`{"id":"cron_0","enabled":false}`), &job); err != nil {
+		t.Fatal(err)
+	}
+	if job.Enabled {
+		t.Error("expected explicit enabled=false to be kept")
+	}
+}
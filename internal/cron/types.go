@@ -1,6 +1,9 @@
 package cron
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // ScheduleType defines how a cron job is scheduled.
 type ScheduleType string
@@ -9,19 +12,37 @@ const (
 	ScheduleAt    ScheduleType = "at"    // specific time (e.g. "14:30")
 	ScheduleEvery ScheduleType = "every" // interval (e.g. "30m", "2h")
 	ScheduleCron  ScheduleType = "cron"  // cron expression (e.g. "0 */2 * * *")
+	ScheduleOnce  ScheduleType = "once"  // single run at an RFC3339 timestamp (e.g. "2025-12-01T14:00:00Z")
 )
 
 type CronSchedule struct {
 	Type       ScheduleType `json:"type"`
-	Expression string       `json:"expression"` // cron expr, time, or duration
+	Expression string       `json:"expression"`         // cron expr, time, or duration
+	Timezone   string       `json:"timezone,omitempty"` // IANA zone (e.g. "America/New_York"); empty uses host local time
 }
 
 type CronJob struct {
 	ID         string       `json:"id"`
 	Schedule   CronSchedule `json:"schedule"`
-	Message    string       `json:"message"`    // message to send when triggered
-	SessionKey string       `json:"sessionKey"` // target session
+	Message    string       `json:"message"`           // message to send when triggered
+	SessionKey string       `json:"sessionKey"`        // target session
+	Channel    string       `json:"channel,omitempty"` // channel the reply is delivered to; empty leaves it to the session
+	ChatID     string       `json:"chatId,omitempty"`  // chat on Channel the reply is delivered to
 	CreatedAt  time.Time    `json:"createdAt"`
+	Enabled    bool         `json:"enabled"`
+	NextRun    time.Time    `json:"-"` // populated by ListJobs; zero if unknown or disabled
+}
+
+// UnmarshalJSON defaults Enabled to true for stores written before jobs could
+// be disabled.
+func (j *CronJob) UnmarshalJSON(data []byte) error {
+	type plain CronJob
+	p := plain{Enabled: true}
+	if err := json.Unmarshal(data, &p); err != nil {
+		return err
+	}
+	*j = CronJob(p)
+	return nil
 }
 
 // CronStore persists jobs to a JSON file.
@@ -0,0 +1,329 @@
+// Package gateway serves nanobot's HTTP API for clients that do not go
+// through a messaging channel.
+package gateway
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/coopco/nanobot/internal/agent"
+	"github.com/coopco/nanobot/internal/bus"
+	"github.com/coopco/nanobot/internal/metrics"
+	"github.com/coopco/nanobot/internal/providers"
+	"github.com/coopco/nanobot/internal/tools"
+)
+
+// maxRequestBody caps request bodies, which may carry inline media.
+const maxRequestBody = 20 << 20
+
+// sessionKeyPrefix namespaces the sessions of API clients so they cannot
+// read or write the history of channel users, such as "telegram:42".
+const sessionKeyPrefix = "api:"
+
+// defaultSessionKey is used for chat requests that name no session.
+const defaultSessionKey = sessionKeyPrefix + "default"
+
+// shutdownTimeout bounds how long in-flight requests may run after the
+// server is asked to stop.
+const shutdownTimeout = 10 * time.Second
+
+// Agent runs one chat turn; *agent.AgentLoop implements it.
+type Agent interface {
+	ProcessSession(ctx context.Context, sessionKey, content string, media []bus.Media) (agent.Reply, error)
+	ProcessSessionStream(ctx context.Context, sessionKey, content string, media []bus.Media, onEvent func(agent.Event)) (agent.Reply, error)
+}
+
+// Config holds the gateway's listen address and dependencies.
+type Config struct {
+	Host  string
+	Port  int
+	Agent Agent
+	Tools *tools.Registry
+	// APIKeys are the bearer tokens accepted on every route. Several may be
+	// listed so keys can be rotated. With none, the API is unauthenticated.
+	APIKeys []string
+}
+
+// Server is the gateway HTTP server.
+type Server struct {
+	addr    string
+	agent   Agent
+	tools   *tools.Registry
+	apiKeys []string
+	mux     *http.ServeMux
+}
+
+// NewServer creates a Server and registers its routes.
+func NewServer(cfg Config) *Server {
+	s := &Server{
+		addr:    net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)),
+		agent:   cfg.Agent,
+		tools:   cfg.Tools,
+		apiKeys: cfg.APIKeys,
+		mux:     http.NewServeMux(),
+	}
+	s.mux.HandleFunc("POST /chat", s.handleChat)
+	s.mux.HandleFunc("POST /chat/stream", s.handleChatStream)
+	s.mux.HandleFunc("GET /tools", s.handleTools)
+	s.mux.Handle("GET /metrics", metrics.Default.Handler())
+	return s
+}
+
+// Handler returns the HTTP handler serving the gateway routes.
+func (s *Server) Handler() http.Handler {
+	if len(s.apiKeys) == 0 {
+		return s.mux
+	}
+	return s.requireAPIKey(s.mux)
+}
+
+// requireAPIKey rejects requests without an "Authorization: Bearer" header
+// naming one of the configured keys.
+func (s *Server) requireAPIKey(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || !s.validKey(strings.TrimSpace(token)) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="nanobot"`)
+			writeError(w, http.StatusUnauthorized, "missing or invalid API key")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// validKey reports whether token matches a configured key. Every key is
+// compared in constant time so the response time does not leak a match.
+func (s *Server) validKey(token string) bool {
+	valid := false
+	for _, key := range s.apiKeys {
+		if key != "" && subtle.ConstantTimeCompare([]byte(token), []byte(key)) == 1 {
+			valid = true
+		}
+	}
+	return valid
+}
+
+// ListenAndServe serves the gateway until ctx is cancelled, then shuts down
+// gracefully.
+func (s *Server) ListenAndServe(ctx context.Context) error {
+	srv := &http.Server{
+		Addr:              s.addr,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	errCh := make(chan error, 1)
+	if len(s.apiKeys) == 0 {
+		slog.Warn("gateway has no API keys configured; requests are not authenticated", "addr", s.addr)
+	}
+	go func() {
+		slog.Info("gateway listening", "addr", s.addr)
+		errCh <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		return fmt.Errorf("gateway: %w", err)
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("gateway shutdown: %w", err)
+	}
+	if err := <-errCh; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("gateway: %w", err)
+	}
+	return nil
+}
+
+// chatRequest is the body of POST /chat. SessionKey is placed under
+// sessionKeyPrefix if it is not already.
+type chatRequest struct {
+	SessionKey string      `json:"session_key"`
+	Message    string      `json:"message"`
+	Media      []chatMedia `json:"media,omitempty"`
+}
+
+// chatMedia is an attachment sent with a chat request: either an http(s)
+// URL or base64-encoded data. Other URLs are refused, since the agent reads
+// local paths from disk.
+type chatMedia struct {
+	Type     string `json:"type"`
+	URL      string `json:"url,omitempty"`
+	MimeType string `json:"mime_type,omitempty"`
+	Data     []byte `json:"data,omitempty"`
+}
+
+// chatResponse is the body returned by POST /chat.
+type chatResponse struct {
+	SessionKey string          `json:"session_key"`
+	Content    string          `json:"content"`
+	Usage      providers.Usage `json:"usage"`
+}
+
+// handleChat runs a message through the agent and returns its final reply.
+func (s *Server) handleChat(w http.ResponseWriter, r *http.Request) {
+	if s.agent == nil {
+		writeError(w, http.StatusServiceUnavailable, "no agent configured")
+		return
+	}
+	req, ok := decodeChatRequest(w, r)
+	if !ok {
+		return
+	}
+	metrics.MessagesIn.Inc("api")
+
+	reply, err := s.agent.ProcessSession(r.Context(), req.SessionKey, req.Message, req.busMedia())
+	if err != nil {
+		slog.Error("gateway chat failed", "session", req.SessionKey, "err", err)
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, chatResponse{
+		SessionKey: req.SessionKey,
+		Content:    reply.Content,
+		Usage:      reply.Usage,
+	})
+}
+
+// streamEvent is the data of a non-final event on /chat/stream.
+type streamEvent struct {
+	Content string            `json:"content"`
+	Meta    map[string]string `json:"meta,omitempty"`
+}
+
+// handleChatStream runs a message through the agent and streams the turn as
+// Server-Sent Events: "delta" events carry model text, "tool_hint" and
+// "progress" report activity, and a final "done" event carries the whole
+// reply and usage (or an "error" event if the turn failed). Providers that
+// cannot stream produce a single delta with the full text.
+func (s *Server) handleChatStream(w http.ResponseWriter, r *http.Request) {
+	if s.agent == nil {
+		writeError(w, http.StatusServiceUnavailable, "no agent configured")
+		return
+	}
+	req, ok := decodeChatRequest(w, r)
+	if !ok {
+		return
+	}
+	metrics.MessagesIn.Inc("api")
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	rc := http.NewResponseController(w)
+	send := func(event string, data any) {
+		payload, err := json.Marshal(data)
+		if err != nil {
+			slog.Warn("gateway: failed to encode event", "event", event, "err", err)
+			return
+		}
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+		rc.Flush() // fails only once the client is gone; the turn still completes
+	}
+
+	reply, err := s.agent.ProcessSessionStream(r.Context(), req.SessionKey, req.Message, req.busMedia(), func(ev agent.Event) {
+		send(ev.Type, streamEvent{Content: ev.Content, Meta: ev.Meta})
+	})
+	if err != nil {
+		slog.Error("gateway chat stream failed", "session", req.SessionKey, "err", err)
+		send("error", map[string]string{"error": err.Error()})
+		return
+	}
+	send("done", chatResponse{
+		SessionKey: req.SessionKey,
+		Content:    reply.Content,
+		Usage:      reply.Usage,
+	})
+}
+
+// decodeChatRequest reads and validates a chat request body, writing a 400
+// response and reporting false if it is unusable.
+func decodeChatRequest(w http.ResponseWriter, r *http.Request) (chatRequest, bool) {
+	var req chatRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+		return req, false
+	}
+	if strings.TrimSpace(req.Message) == "" && len(req.Media) == 0 {
+		writeError(w, http.StatusBadRequest, "message is required")
+		return req, false
+	}
+	for i, m := range req.Media {
+		if err := validateMedia(m); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("media %d: %v", i, err))
+			return req, false
+		}
+	}
+	switch {
+	case req.SessionKey == "":
+		req.SessionKey = defaultSessionKey
+	case !strings.HasPrefix(req.SessionKey, sessionKeyPrefix):
+		req.SessionKey = sessionKeyPrefix + req.SessionKey
+	}
+	return req, true
+}
+
+// validateMedia accepts inline data and http(s) URLs.
+func validateMedia(m chatMedia) error {
+	if m.URL == "" {
+		if len(m.Data) == 0 {
+			return errors.New("url or data is required")
+		}
+		return nil
+	}
+	u, err := url.Parse(m.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("url must be http or https, got %q", m.URL)
+	}
+	return nil
+}
+
+func (req chatRequest) busMedia() []bus.Media {
+	if len(req.Media) == 0 {
+		return nil
+	}
+	media := make([]bus.Media, len(req.Media))
+	for i, m := range req.Media {
+		media[i] = bus.Media{Type: m.Type, URL: m.URL, MimeType: m.MimeType, Data: m.Data}
+	}
+	return media
+}
+
+// handleTools returns the catalog of registered tools as a JSON array.
+func (s *Server) handleTools(w http.ResponseWriter, r *http.Request) {
+	if s.tools == nil {
+		writeJSON(w, http.StatusOK, []tools.FunctionDef{})
+		return
+	}
+	data, err := s.tools.CatalogJSON()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Warn("gateway: failed to write response", "err", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
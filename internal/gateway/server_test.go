@@ -0,0 +1,337 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/coopco/nanobot/internal/agent"
+	"github.com/coopco/nanobot/internal/bus"
+	"github.com/coopco/nanobot/internal/providers"
+	"github.com/coopco/nanobot/internal/session"
+	"github.com/coopco/nanobot/internal/tools"
+)
+
+// replyProvider answers every request with a fixed reply and records what
+// it was sent.
+type replyProvider struct {
+	mu       sync.Mutex
+	content  string
+	requests []providers.ChatRequest
+}
+
+func (p *replyProvider) Chat(_ context.Context, req providers.ChatRequest) (*providers.ChatResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.requests = append(p.requests, req)
+	return &providers.ChatResponse{
+		Content: p.content,
+		Usage:   providers.Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15},
+	}, nil
+}
+
+func newTestAgent(t *testing.T, prov providers.Provider) (*agent.AgentLoop, *session.Manager) {
+	t.Helper()
+	sessions := session.NewManager(t.TempDir())
+	return agent.NewAgentLoop(agent.AgentLoopConfig{
+		Bus:           bus.NewMessageBus(10),
+		Provider:      prov,
+		Sessions:      sessions,
+		Tools:         tools.NewRegistry(),
+		Model:         "test-model",
+		MaxIterations: 5,
+	}), sessions
+}
+
+func postJSON(h http.Handler, path, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+type stubTool struct{ name string }
+
+func (t *stubTool) Name() string        { return t.name }
+func (t *stubTool) Description() string { return "stub " + t.name }
+func (t *stubTool) Parameters() json.RawMessage {
+	return json.RawMessage(`{"type":"object","properties":{"q":{"type":"string"}}}`)
+}
+func (t *stubTool) Execute(context.Context, json.RawMessage) (string, error) { return t.name, nil }
+
+func TestToolsEndpoint(t *testing.T) {
+	reg := tools.NewRegistry()
+	reg.Register(&stubTool{name: "search"})
+	reg.Register(&stubTool{name: "fetch"})
+	srv := NewServer(Config{Tools: reg})
+
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/tools", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	var got []tools.FunctionDef
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(got) != 2 || got[0].Name != "fetch" || got[1].Name != "search" {
+		t.Fatalf("tools = %+v, want fetch and search", got)
+	}
+	var schema map[string]any
+	if err := json.Unmarshal(got[1].Parameters, &schema); err != nil || schema["type"] != "object" {
+		t.Errorf("parameters = %s, want the tool's schema", got[1].Parameters)
+	}
+}
+
+func TestToolsEndpointRejectsPost(t *testing.T) {
+	srv := NewServer(Config{Tools: tools.NewRegistry()})
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/tools", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("status = %d, want 405", rec.Code)
+	}
+}
+
+func TestChatEndpoint(t *testing.T) {
+	prov := &replyProvider{content: "hello from the agent"}
+	loop, sessions := newTestAgent(t, prov)
+	srv := NewServer(Config{Agent: loop})
+
+	rec := postJSON(srv.Handler(), "/chat", `{"session_key":"api:alice","message":"hi","media":[{"type":"image","url":"https://example.com/a.png"}]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+	var got chatResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if got.Content != "hello from the agent" || got.SessionKey != "api:alice" {
+		t.Errorf("response = %+v", got)
+	}
+	if got.Usage.TotalTokens != 15 {
+		t.Errorf("usage = %+v, want the provider's usage", got.Usage)
+	}
+
+	msgs := prov.requests[0].Messages
+	if user := msgs[len(msgs)-1]; user.Content != "hi" || len(user.ContentParts) != 1 {
+		t.Errorf("provider got %+v, want the message with its image", user)
+	}
+	if history := sessions.GetOrCreate("api:alice").GetHistory(); len(history) != 2 {
+		t.Errorf("session has %d messages, want the exchange saved under session_key", len(history))
+	}
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	loop, _ := newTestAgent(t, &replyProvider{content: "ok"})
+	h := NewServer(Config{Agent: loop}).Handler()
+	const received = `nanobot_messages_received_total{channel="api"}`
+	const calls = `nanobot_provider_calls_total{model="test-model"}`
+	before := map[string]float64{received: scrapeMetric(t, h, received), calls: scrapeMetric(t, h, calls)}
+
+	if rec := postJSON(h, "/chat", `{"message":"hi"}`); rec.Code != http.StatusOK {
+		t.Fatalf("chat status = %d: %s", rec.Code, rec.Body)
+	}
+
+	for series, was := range before {
+		if got := scrapeMetric(t, h, series); got != was+1 {
+			t.Errorf("%s = %v after one message, want %v", series, got, was+1)
+		}
+	}
+	if got := scrapeMetric(t, h, `nanobot_provider_latency_seconds_count{model="test-model"}`); got < 1 {
+		t.Errorf("provider latency count = %v, want the call observed", got)
+	}
+}
+
+// scrapeMetric fetches /metrics from h and returns the value of series, or
+// 0 if it is not there yet.
+func scrapeMetric(t *testing.T, h http.Handler, series string) float64 {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("metrics status = %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	for _, line := range strings.Split(rec.Body.String(), "\n") {
+		if value, ok := strings.CutPrefix(line, series+" "); ok {
+			v, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				t.Fatalf("bad value in %q: %v", line, err)
+			}
+			return v
+		}
+	}
+	return 0
+}
+
+func TestChatEndpointBadRequests(t *testing.T) {
+	loop, _ := newTestAgent(t, &replyProvider{content: "x"})
+	srv := NewServer(Config{Agent: loop})
+
+	for name, body := range map[string]string{
+		"invalid json":  `{"message":`,
+		"empty message": `{"session_key":"k","message":"  "}`,
+		"local path":    `{"message":"hi","media":[{"type":"file","url":"/etc/shadow"}]}`,
+		"file url":      `{"message":"hi","media":[{"type":"file","url":"file:///etc/shadow"}]}`,
+		"path and data": `{"message":"hi","media":[{"type":"file","url":"/etc/passwd","data":"aGk="}]}`,
+		"empty media":   `{"message":"hi","media":[{"type":"image"}]}`,
+	} {
+		if rec := postJSON(srv.Handler(), "/chat", body); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", name, rec.Code)
+		}
+	}
+}
+
+func TestChatEndpointNamespacesSessionKey(t *testing.T) {
+	loop, sessions := newTestAgent(t, &replyProvider{content: "ok"})
+	sessions.GetOrCreate("telegram:42").AppendMessage(session.Message{Role: "user", Content: "private"})
+	srv := NewServer(Config{Agent: loop})
+
+	rec := postJSON(srv.Handler(), "/chat", `{"session_key":"telegram:42","message":"hi"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	var got chatResponse
+	json.Unmarshal(rec.Body.Bytes(), &got)
+	if got.SessionKey != "api:telegram:42" {
+		t.Errorf("session_key = %q, want it under api:", got.SessionKey)
+	}
+	if history := sessions.GetOrCreate("telegram:42").GetHistory(); len(history) != 1 {
+		t.Errorf("channel session has %d messages, want it untouched", len(history))
+	}
+}
+
+// streamingProvider streams its reply in fixed fragments.
+type streamingProvider struct {
+	replyProvider
+	fragments []string
+}
+
+func (p *streamingProvider) ChatStream(ctx context.Context, req providers.ChatRequest, onDelta func(string)) (*providers.ChatResponse, error) {
+	for _, f := range p.fragments {
+		onDelta(f)
+	}
+	return p.Chat(ctx, req)
+}
+
+type sseEvent struct {
+	name string
+	data string
+}
+
+func readSSE(t *testing.T, body string) []sseEvent {
+	t.Helper()
+	var events []sseEvent
+	for _, block := range strings.Split(strings.TrimSpace(body), "\n\n") {
+		var ev sseEvent
+		for _, line := range strings.Split(block, "\n") {
+			if v, ok := strings.CutPrefix(line, "event: "); ok {
+				ev.name = v
+			} else if v, ok := strings.CutPrefix(line, "data: "); ok {
+				ev.data = v
+			}
+		}
+		events = append(events, ev)
+	}
+	return events
+}
+
+func TestChatStreamEndpoint(t *testing.T) {
+	tests := []struct {
+		name       string
+		provider   providers.Provider
+		wantDeltas int
+	}{
+		{"streaming provider", &streamingProvider{replyProvider: replyProvider{content: "Hello, world"}, fragments: []string{"Hel", "lo, ", "world"}}, 3},
+		{"non-streaming provider", &replyProvider{content: "Hello, world"}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loop, _ := newTestAgent(t, tt.provider)
+			srv := NewServer(Config{Agent: loop})
+
+			rec := postJSON(srv.Handler(), "/chat/stream", `{"session_key":"api:s","message":"hi"}`)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "text/event-stream" {
+				t.Errorf("Content-Type = %q", ct)
+			}
+
+			var text strings.Builder
+			var deltas int
+			var done *chatResponse
+			for _, ev := range readSSE(t, rec.Body.String()) {
+				switch ev.name {
+				case "delta":
+					var d streamEvent
+					if err := json.Unmarshal([]byte(ev.data), &d); err != nil {
+						t.Fatalf("bad delta %q: %v", ev.data, err)
+					}
+					deltas++
+					text.WriteString(d.Content)
+				case "done":
+					done = &chatResponse{}
+					if err := json.Unmarshal([]byte(ev.data), done); err != nil {
+						t.Fatalf("bad done event %q: %v", ev.data, err)
+					}
+				default:
+					t.Errorf("unexpected event %+v", ev)
+				}
+			}
+			if text.String() != "Hello, world" {
+				t.Errorf("reconstructed %q, want Hello, world", text.String())
+			}
+			if deltas != tt.wantDeltas {
+				t.Errorf("got %d deltas, want %d", deltas, tt.wantDeltas)
+			}
+			if done == nil || done.Content != "Hello, world" || done.Usage.TotalTokens != 15 {
+				t.Errorf("done event = %+v, want the full reply and usage", done)
+			}
+		})
+	}
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	srv := NewServer(Config{Tools: tools.NewRegistry(), APIKeys: []string{"old-key", "new-key"}})
+
+	tests := []struct {
+		name   string
+		header string
+		want   int
+	}{
+		{"valid key", "Bearer new-key", http.StatusOK},
+		{"rotated key still valid", "Bearer old-key", http.StatusOK},
+		{"missing header", "", http.StatusUnauthorized},
+		{"wrong key", "Bearer nope", http.StatusUnauthorized},
+		{"wrong scheme", "Basic new-key", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/tools", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			srv.Handler().ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if tt.want == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+				t.Error("401 without a WWW-Authenticate header")
+			}
+		})
+	}
+}
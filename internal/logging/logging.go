@@ -0,0 +1,59 @@
+// Package logging configures the process-wide slog logger from config.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/coopco/nanobot/internal/config"
+)
+
+// NewHandler returns a slog handler writing to w at the configured level and
+// in the configured format.
+func NewHandler(w io.Writer, cfg config.LoggingConfig) (slog.Handler, error) {
+	level, err := cfg.SlogLevel()
+	if err != nil {
+		return nil, err
+	}
+	opts := &slog.HandlerOptions{Level: level}
+	switch strings.ToLower(cfg.Format) {
+	case "", "text":
+		return slog.NewTextHandler(w, opts), nil
+	case "json":
+		return slog.NewJSONHandler(w, opts), nil
+	}
+	return nil, fmt.Errorf("unknown log format %q", cfg.Format)
+}
+
+// Setup installs a logger built from cfg as the slog default. Output from
+// the standard log package is routed through it as well. The returned Closer
+// releases the log file, if one was opened, and should be closed on exit.
+func Setup(cfg config.LoggingConfig) (io.Closer, error) {
+	var w io.WriteCloser = nopCloser{os.Stderr}
+	if cfg.File != "" {
+		if err := os.MkdirAll(filepath.Dir(cfg.File), 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create log directory: %w", err)
+		}
+		f, err := os.OpenFile(cfg.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open log file: %w", err)
+		}
+		w = f
+	}
+
+	h, err := NewHandler(w, cfg)
+	if err != nil {
+		w.Close()
+		return nil, err
+	}
+	slog.SetDefault(slog.New(h))
+	return w, nil
+}
+
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
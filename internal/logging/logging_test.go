@@ -0,0 +1,87 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/coopco/nanobot/internal/config"
+)
+
+func TestNewHandlerLevel(t *testing.T) {
+	var buf bytes.Buffer
+	h, err := NewHandler(&buf, config.LoggingConfig{Level: "error"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := slog.New(h)
+
+	logger.Info("routine detail")
+	logger.Warn("minor issue")
+	if buf.Len() != 0 {
+		t.Errorf("level=error let lower levels through: %s", buf.String())
+	}
+	logger.Error("broken")
+	if !strings.Contains(buf.String(), "broken") {
+		t.Errorf("error record missing: %q", buf.String())
+	}
+}
+
+func TestNewHandlerJSON(t *testing.T) {
+	var buf bytes.Buffer
+	h, err := NewHandler(&buf, config.LoggingConfig{Format: "json", Level: "debug"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	slog.New(h).Debug("hello", "channel", "telegram")
+
+	var rec map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, buf.String())
+	}
+	if rec["msg"] != "hello" || rec["channel"] != "telegram" || rec["level"] != "DEBUG" {
+		t.Errorf("record = %v", rec)
+	}
+}
+
+func TestNewHandlerRejectsUnknownSettings(t *testing.T) {
+	if _, err := NewHandler(os.Stderr, config.LoggingConfig{Level: "loud"}); err == nil {
+		t.Error("expected error for unknown level")
+	}
+	if _, err := NewHandler(os.Stderr, config.LoggingConfig{Format: "xml"}); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
+
+func TestSetupWritesToFile(t *testing.T) {
+	prev := slog.Default()
+	defer slog.SetDefault(prev)
+
+	path := filepath.Join(t.TempDir(), "logs", "nanobot.log")
+	closer, err := Setup(config.LoggingConfig{Level: "warn", File: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	slog.Info("dropped")
+	slog.Warn("kept")
+	log.Printf("legacy logger")
+	closer.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(data)
+	if !strings.Contains(out, "kept") || strings.Contains(out, "dropped") {
+		t.Errorf("log file = %q, want only warn and above", out)
+	}
+	// The standard log package logs at info, below the configured level.
+	if strings.Contains(out, "legacy logger") {
+		t.Errorf("log.Printf output bypassed the level: %q", out)
+	}
+}
@@ -0,0 +1,99 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCounter(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("test_messages_total", "Messages, by channel.", "channel")
+	c.Inc("telegram")
+	c.Inc("telegram")
+	c.Add(3, "discord")
+	c.Add(-1, "discord") // counters never go down
+
+	if got := c.Value("telegram"); got != 2 {
+		t.Errorf("telegram = %v, want 2", got)
+	}
+	if got := c.Value("slack"); got != 0 {
+		t.Errorf("slack = %v, want 0", got)
+	}
+
+	var sb strings.Builder
+	if err := r.WriteText(&sb); err != nil {
+		t.Fatal(err)
+	}
+	want := "# HELP test_messages_total Messages, by channel.\n" +
+		"# TYPE test_messages_total counter\n" +
+		`test_messages_total{channel="discord"} 3` + "\n" +
+		`test_messages_total{channel="telegram"} 2` + "\n"
+	if sb.String() != want {
+		t.Errorf("output =\n%s\nwant\n%s", sb.String(), want)
+	}
+}
+
+func TestCounterWithoutLabels(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounter("test_restarts_total", "Restarts.").Inc()
+
+	var sb strings.Builder
+	r.WriteText(&sb)
+	if !strings.Contains(sb.String(), "\ntest_restarts_total 1\n") {
+		t.Errorf("output =\n%s", sb.String())
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	r := NewRegistry()
+	h := r.NewHistogram("test_duration_seconds", "Durations.", []float64{0.1, 1}, "tool")
+	h.Observe(0.05, "shell")
+	h.Observe(0.1, "shell")
+	h.Observe(0.5, "shell")
+	h.Observe(3, "shell")
+
+	var sb strings.Builder
+	r.WriteText(&sb)
+	want := "# HELP test_duration_seconds Durations.\n" +
+		"# TYPE test_duration_seconds histogram\n" +
+		`test_duration_seconds_bucket{tool="shell",le="0.1"} 2` + "\n" +
+		`test_duration_seconds_bucket{tool="shell",le="1"} 3` + "\n" +
+		`test_duration_seconds_bucket{tool="shell",le="+Inf"} 4` + "\n" +
+		`test_duration_seconds_sum{tool="shell"} 3.65` + "\n" +
+		`test_duration_seconds_count{tool="shell"} 4` + "\n"
+	if sb.String() != want {
+		t.Errorf("output =\n%s\nwant\n%s", sb.String(), want)
+	}
+}
+
+func TestLabelEscaping(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounter("test_total", "Help with a \\ and\nnewline.", "name").Inc("say \"hi\"\n\\")
+
+	var sb strings.Builder
+	r.WriteText(&sb)
+	for _, want := range []string{
+		`# HELP test_total Help with a \\ and\nnewline.`,
+		`test_total{name="say \"hi\"\n\\"} 1`,
+	} {
+		if !strings.Contains(sb.String(), want) {
+			t.Errorf("output missing %s:\n%s", want, sb.String())
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounter("test_total", "Test.").Inc()
+
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); ct != "text/plain; version=0.0.4; charset=utf-8" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if !strings.Contains(rec.Body.String(), "test_total 1") {
+		t.Errorf("body =\n%s", rec.Body.String())
+	}
+}
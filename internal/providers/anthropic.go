@@ -2,11 +2,17 @@ package providers
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"strings"
+	"time"
 
 	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/anthropics/anthropic-sdk-go/option"
+
+	"github.com/coopco/nanobot/internal/tlsutil"
 )
 
 const (
@@ -15,31 +21,93 @@ const (
 )
 
 type AnthropicProvider struct {
-	client       *anthropic.Client
-	defaultModel string
+	client        *anthropic.Client
+	httpClient    *http.Client // shared with client so SetTimeout takes effect
+	defaultModel  string
+	overrides     map[string]map[string]any // per-model parameter quirks from the registry
+	promptCaching bool                      // mark the system prompt and tools as cacheable
 }
 
 func NewAnthropicProvider(apiKey string) *AnthropicProvider {
-	client := anthropic.NewClient(option.WithAPIKey(apiKey))
-	return &AnthropicProvider{
+	return newAnthropicProvider(apiKey)
+}
+
+func newAnthropicProvider(apiKey string, opts ...option.RequestOption) *AnthropicProvider {
+	httpClient := &http.Client{Timeout: defaultRequestTimeout}
+	opts = append([]option.RequestOption{option.WithAPIKey(apiKey), option.WithHTTPClient(httpClient)}, opts...)
+	client := anthropic.NewClient(opts...)
+	p := &AnthropicProvider{
 		client:       &client,
+		httpClient:   httpClient,
 		defaultModel: defaultAnthropicModel,
 	}
+	if spec := FindByName("anthropic"); spec != nil {
+		p.overrides = spec.ModelOverrides
+		p.promptCaching = spec.PromptCaching
+	}
+	return p
+}
+
+// SetPromptCaching turns cache_control breakpoints on the system prompt and
+// tool definitions on or off.
+func (p *AnthropicProvider) SetPromptCaching(enabled bool) {
+	p.promptCaching = enabled
+}
+
+// SetTimeout caps each HTTP attempt at d; the SDK may retry a timed-out
+// attempt. d <= 0 restores the default. Call it before the provider is used.
+func (p *AnthropicProvider) SetTimeout(d time.Duration) {
+	if d <= 0 {
+		d = defaultRequestTimeout
+	}
+	p.httpClient.Timeout = d
+}
+
+// SetTLS changes how the provider verifies the server's certificate, for
+// gateways with a private CA. Call it before the provider is used.
+func (p *AnthropicProvider) SetTLS(opts tlsutil.Options) error {
+	transport, err := opts.Transport()
+	if err != nil {
+		return err
+	}
+	p.httpClient.Transport = transport
+	return nil
 }
 
 func (p *AnthropicProvider) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	params, err := p.buildParams(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.client.Messages.New(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("anthropic chat failed: %w", err)
+	}
+
+	return convertResponse(resp), nil
+}
+
+// buildParams converts req into Messages API parameters.
+func (p *AnthropicProvider) buildParams(req ChatRequest) (anthropic.MessageNewParams, error) {
 	model := req.Model
 	if model == "" {
 		model = p.defaultModel
 	}
+	applyOverrides(&req, matchOverrides(p.overrides, model))
 	maxTokens := req.MaxTokens
 	if maxTokens == 0 {
 		maxTokens = defaultMaxTokens
 	}
+	// Thinking tokens count toward max_tokens, which must exceed the budget;
+	// raise it so the answer keeps its room.
+	if req.ThinkingBudget > 0 && maxTokens <= req.ThinkingBudget {
+		maxTokens += req.ThinkingBudget
+	}
 
 	messages, err := convertMessages(req.Messages)
 	if err != nil {
-		return nil, fmt.Errorf("failed to convert messages: %w", err)
+		return anthropic.MessageNewParams{}, fmt.Errorf("failed to convert messages: %w", err)
 	}
 
 	params := anthropic.MessageNewParams{
@@ -47,55 +115,152 @@ func (p *AnthropicProvider) Chat(ctx context.Context, req ChatRequest) (*ChatRes
 		MaxTokens: int64(maxTokens),
 		Messages:  messages,
 	}
+	if req.ThinkingBudget > 0 {
+		params.Thinking = anthropic.ThinkingConfigParamOfEnabled(int64(req.ThinkingBudget))
+	}
 
 	if req.SystemPrompt != "" {
 		params.System = []anthropic.TextBlockParam{{Text: req.SystemPrompt}}
+		if p.promptCaching {
+			params.System[0].CacheControl = anthropic.NewCacheControlEphemeralParam()
+		}
 	}
 
 	if len(req.Tools) > 0 {
 		params.Tools = convertTools(req.Tools)
+		// A breakpoint on the last tool caches the whole tool list.
+		if p.promptCaching {
+			params.Tools[len(params.Tools)-1].OfTool.CacheControl = anthropic.NewCacheControlEphemeralParam()
+		}
 	}
 
-	resp, err := p.client.Messages.New(ctx, params)
-	if err != nil {
-		return nil, fmt.Errorf("anthropic chat failed: %w", err)
-	}
-
-	return convertResponse(resp), nil
+	return params, nil
 }
 
 func convertMessages(msgs []Message) ([]anthropic.MessageParam, error) {
 	var out []anthropic.MessageParam
-	for _, m := range msgs {
+	for _, m := range normalizeToolCallIDs(msgs) {
 		switch m.Role {
 		case "user":
+			if len(m.ContentParts) > 0 {
+				out = append(out, anthropic.NewUserMessage(convertContentParts(m.Content, m.ContentParts)...))
+				continue
+			}
 			out = append(out, anthropic.NewUserMessage(anthropic.NewTextBlock(m.Content)))
 		case "assistant":
-			if len(m.ToolCalls) > 0 {
-				var blocks []anthropic.ContentBlockParamUnion
-				if m.Content != "" {
-					blocks = append(blocks, anthropic.NewTextBlock(m.Content))
-				}
-				for _, tc := range m.ToolCalls {
-					var input any
-					if err := json.Unmarshal([]byte(tc.Arguments), &input); err != nil {
-						input = tc.Arguments
-					}
-					blocks = append(blocks, anthropic.NewToolUseBlock(tc.ID, input, tc.Name))
+			blocks := thinkingBlocks(m.ThinkingBlocks)
+			if m.Content != "" || len(m.ToolCalls) == 0 {
+				blocks = append(blocks, anthropic.NewTextBlock(m.Content))
+			}
+			for _, tc := range m.ToolCalls {
+				var input any
+				if err := json.Unmarshal([]byte(tc.Arguments), &input); err != nil {
+					input = tc.Arguments
 				}
-				out = append(out, anthropic.NewAssistantMessage(blocks...))
-			} else {
-				out = append(out, anthropic.NewAssistantMessage(anthropic.NewTextBlock(m.Content)))
+				blocks = append(blocks, anthropic.NewToolUseBlock(tc.ID, input, tc.Name))
 			}
+			out = append(out, anthropic.NewAssistantMessage(blocks...))
 		case "tool":
 			out = append(out, anthropic.NewUserMessage(
-				anthropic.NewToolResultBlock(m.ToolCallID, m.Content, false),
+				anthropic.NewToolResultBlock(m.ToolCallID, m.Content, m.IsError),
 			))
 		}
 	}
 	return out, nil
 }
 
+// thinkingBlocks converts the thinking blocks of an assistant message back to
+// request blocks. With extended thinking on, the API requires them ahead of
+// the tool calls they led to.
+func thinkingBlocks(in []ThinkingBlock) []anthropic.ContentBlockParamUnion {
+	var blocks []anthropic.ContentBlockParamUnion
+	for _, b := range in {
+		switch b.Type {
+		case "thinking":
+			blocks = append(blocks, anthropic.NewThinkingBlock(b.Signature, b.Thinking))
+		case "redacted_thinking":
+			blocks = append(blocks, anthropic.NewRedactedThinkingBlock(b.Data))
+		}
+	}
+	return blocks
+}
+
+// convertContentParts builds the blocks of a multimodal user message. Images
+// given as data URIs are sent inline; anything else is passed as a URL. PDFs
+// and text files become document blocks. Anthropic does not take audio or
+// other files, so those are replaced by a note saying they were left out.
+func convertContentParts(text string, parts []ContentPart) []anthropic.ContentBlockParamUnion {
+	var blocks []anthropic.ContentBlockParamUnion
+	if text != "" {
+		blocks = append(blocks, anthropic.NewTextBlock(text))
+	}
+	for _, p := range parts {
+		switch p.Type {
+		case "text":
+			blocks = append(blocks, anthropic.NewTextBlock(p.Text))
+		case "image_url":
+			if p.ImageURL == nil {
+				continue
+			}
+			if mediaType, data, ok := parseDataURI(p.ImageURL.URL); ok {
+				blocks = append(blocks, anthropic.NewImageBlockBase64(mediaType, data))
+			} else {
+				blocks = append(blocks, anthropic.NewImageBlock(anthropic.URLImageSourceParam{URL: p.ImageURL.URL}))
+			}
+		case "input_audio":
+			if p.InputAudio != nil {
+				blocks = append(blocks, anthropic.NewTextBlock("[Audio attachment omitted: this model does not accept audio.]"))
+			}
+		case "file":
+			if p.File != nil {
+				blocks = append(blocks, documentBlock(p.File))
+			}
+		}
+	}
+	return blocks
+}
+
+// documentBlock converts a file part to a document block, or to a note when
+// it is neither a PDF nor text.
+func documentBlock(f *FilePart) anthropic.ContentBlockParamUnion {
+	mediaType, data, _ := parseDataURI(f.FileData)
+	mediaType, _, _ = strings.Cut(mediaType, ";")
+	var block anthropic.ContentBlockParamUnion
+	switch {
+	case mediaType == "application/pdf":
+		block = anthropic.NewDocumentBlock(anthropic.Base64PDFSourceParam{Data: data})
+	case strings.HasPrefix(mediaType, "text/"):
+		text, err := base64.StdEncoding.DecodeString(data)
+		if err != nil {
+			return anthropic.NewTextBlock(fmt.Sprintf("[File %q omitted: it could not be decoded.]", f.Filename))
+		}
+		block = anthropic.NewDocumentBlock(anthropic.PlainTextSourceParam{Data: string(text)})
+	default:
+		return anthropic.NewTextBlock(fmt.Sprintf("[File %q omitted: this model does not accept %s files.]", f.Filename, mediaType))
+	}
+	if f.Filename != "" {
+		block.OfDocument.Title = anthropic.String(f.Filename)
+	}
+	return block
+}
+
+// parseDataURI splits a "data:<type>;base64,<data>" URI.
+func parseDataURI(uri string) (mediaType, data string, ok bool) {
+	rest, found := strings.CutPrefix(uri, "data:")
+	if !found {
+		return "", "", false
+	}
+	meta, data, found := strings.Cut(rest, ",")
+	if !found {
+		return "", "", false
+	}
+	mediaType, found = strings.CutSuffix(meta, ";base64")
+	if !found {
+		return "", "", false
+	}
+	return mediaType, data, true
+}
+
 func convertTools(tools []ToolDef) []anthropic.ToolUnionParam {
 	out := make([]anthropic.ToolUnionParam, len(tools))
 	for i, t := range tools {
@@ -115,13 +280,19 @@ func convertTools(tools []ToolDef) []anthropic.ToolUnionParam {
 }
 
 func convertResponse(resp *anthropic.Message) *ChatResponse {
-	var text string
+	var text, reasoning string
 	var toolCalls []ToolCall
+	var thinking []ThinkingBlock
 
 	for _, block := range resp.Content {
 		switch block.Type {
 		case "text":
 			text += block.Text
+		case "thinking":
+			reasoning += block.Thinking
+			thinking = append(thinking, ThinkingBlock{Type: "thinking", Thinking: block.Thinking, Signature: block.Signature})
+		case "redacted_thinking":
+			thinking = append(thinking, ThinkingBlock{Type: "redacted_thinking", Data: block.Data})
 		case "tool_use":
 			args, _ := json.Marshal(block.Input)
 			toolCalls = append(toolCalls, ToolCall{
@@ -132,14 +303,21 @@ func convertResponse(resp *anthropic.Message) *ChatResponse {
 		}
 	}
 
+	// InputTokens excludes cached tokens; count them as prompt tokens too.
+	prompt := resp.Usage.InputTokens + resp.Usage.CacheReadInputTokens + resp.Usage.CacheCreationInputTokens
+
 	return &ChatResponse{
-		Content:   text,
-		ToolCalls: toolCalls,
-		StopReason: string(resp.StopReason),
+		Content:          text,
+		ToolCalls:        toolCalls,
+		StopReason:       normalizeStopReason(string(resp.StopReason)),
+		ReasoningContent: reasoning,
+		ThinkingBlocks:   thinking,
 		Usage: Usage{
-			PromptTokens:     int(resp.Usage.InputTokens),
-			CompletionTokens: int(resp.Usage.OutputTokens),
-			TotalTokens:      int(resp.Usage.InputTokens + resp.Usage.OutputTokens),
+			PromptTokens:        int(prompt),
+			CompletionTokens:    int(resp.Usage.OutputTokens),
+			TotalTokens:         int(prompt + resp.Usage.OutputTokens),
+			CacheReadTokens:     int(resp.Usage.CacheReadInputTokens),
+			CacheCreationTokens: int(resp.Usage.CacheCreationInputTokens),
 		},
 	}
 }
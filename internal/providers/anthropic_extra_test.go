@@ -1,10 +1,16 @@
 package providers
 
 import (
+	"context"
 	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/option"
 )
 
 func TestConvertResponse_TextOnly(t *testing.T) {
@@ -19,8 +25,8 @@ func TestConvertResponse_TextOnly(t *testing.T) {
 	if resp.Content != "Hello world" {
 		t.Errorf("Content = %q, want %q", resp.Content, "Hello world")
 	}
-	if resp.StopReason != "end_turn" {
-		t.Errorf("StopReason = %q, want end_turn", resp.StopReason)
+	if resp.StopReason != StopReasonStop {
+		t.Errorf("StopReason = %q, want stop", resp.StopReason)
 	}
 	if resp.Usage.PromptTokens != 10 {
 		t.Errorf("PromptTokens = %d, want 10", resp.Usage.PromptTokens)
@@ -110,6 +116,67 @@ func TestConvertMessages_AllRoles(t *testing.T) {
 	}
 }
 
+func TestConvertMessages_Multimodal(t *testing.T) {
+	msgs := []Message{{
+		Role:    "user",
+		Content: "compare these",
+		ContentParts: []ContentPart{
+			{Type: "image_url", ImageURL: &ImageURL{URL: "https://example.com/a.png"}},
+			{Type: "image_url", ImageURL: &ImageURL{URL: "data:image/jpeg;base64,QUJD"}},
+		},
+	}}
+	out, err := convertMessages(msgs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 1 || len(out[0].Content) != 3 {
+		t.Fatalf("got %+v, want one message with three blocks", out)
+	}
+	blocks := out[0].Content
+	if blocks[0].OfText == nil || blocks[0].OfText.Text != "compare these" {
+		t.Errorf("block 0 = %+v, want the text", blocks[0])
+	}
+	if img := blocks[1].OfImage; img == nil || img.Source.OfURL == nil || img.Source.OfURL.URL != "https://example.com/a.png" {
+		t.Errorf("block 1 = %+v, want a URL image", blocks[1])
+	}
+	if img := blocks[2].OfImage; img == nil || img.Source.OfBase64 == nil ||
+		img.Source.OfBase64.Data != "QUJD" || string(img.Source.OfBase64.MediaType) != "image/jpeg" {
+		t.Errorf("block 2 = %+v, want an inline JPEG", blocks[2])
+	}
+}
+
+func TestConvertMessages_AudioAndDocuments(t *testing.T) {
+	msgs := []Message{{
+		Role: "user",
+		ContentParts: []ContentPart{
+			{Type: "file", File: &FilePart{Filename: "report.pdf", FileData: "data:application/pdf;base64,JVBERg=="}},
+			{Type: "file", File: &FilePart{Filename: "notes.txt", FileData: "data:text/plain; charset=utf-8;base64,aGVsbG8="}},
+			{Type: "file", File: &FilePart{Filename: "data.zip", FileData: "data:application/zip;base64,UEs="}},
+			{Type: "input_audio", InputAudio: &InputAudio{Data: "UklGRg==", Format: "wav"}},
+		},
+	}}
+	out, err := convertMessages(msgs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 1 || len(out[0].Content) != 4 {
+		t.Fatalf("got %+v, want one message with four blocks", out)
+	}
+	blocks := out[0].Content
+	if doc := blocks[0].OfDocument; doc == nil || doc.Source.OfBase64 == nil || doc.Source.OfBase64.Data != "JVBERg==" || doc.Title.Value != "report.pdf" {
+		t.Errorf("block 0 = %+v, want a PDF document", blocks[0])
+	}
+	if doc := blocks[1].OfDocument; doc == nil || doc.Source.OfText == nil || doc.Source.OfText.Data != "hello" {
+		t.Errorf("block 1 = %+v, want a text document", blocks[1])
+	}
+	if txt := blocks[2].OfText; txt == nil || !strings.Contains(txt.Text, `"data.zip"`) {
+		t.Errorf("block 2 = %+v, want a note for the unsupported file", blocks[2])
+	}
+	if txt := blocks[3].OfText; txt == nil || !strings.Contains(txt.Text, "Audio") {
+		t.Errorf("block 3 = %+v, want a note for the audio", blocks[3])
+	}
+}
+
 func TestConvertTools_Multiple(t *testing.T) {
 	tools := []ToolDef{
 		{Type: "function", Function: FunctionDef{Name: "a", Description: "desc a", Parameters: json.RawMessage(`{"type":"object"}`)}},
@@ -123,3 +190,236 @@ func TestConvertTools_Multiple(t *testing.T) {
 		t.Errorf("unexpected tool names: %q, %q", out[0].OfTool.Name, out[1].OfTool.Name)
 	}
 }
+
+func TestBuildParams_PromptCaching(t *testing.T) {
+	req := ChatRequest{
+		Model:        "claude-sonnet-4-20250514",
+		SystemPrompt: "You are helpful.",
+		Messages:     []Message{{Role: "user", Content: "hi"}},
+		Tools: []ToolDef{
+			{Type: "function", Function: FunctionDef{Name: "a", Parameters: json.RawMessage(`{"type":"object"}`)}},
+			{Type: "function", Function: FunctionDef{Name: "b", Parameters: json.RawMessage(`{"type":"object"}`)}},
+		},
+	}
+
+	p := NewAnthropicProvider("test-key")
+	params, err := p.buildParams(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := json.Marshal(params)
+	var body struct {
+		System []map[string]any `json:"system"`
+		Tools  []map[string]any `json:"tools"`
+	}
+	if err := json.Unmarshal(raw, &body); err != nil {
+		t.Fatal(err)
+	}
+	if cc, _ := body.System[0]["cache_control"].(map[string]any); cc["type"] != "ephemeral" {
+		t.Errorf("system block cache_control = %v, want ephemeral", body.System[0]["cache_control"])
+	}
+	if _, ok := body.Tools[0]["cache_control"]; ok {
+		t.Error("only the last tool should carry a cache breakpoint")
+	}
+	if _, ok := body.Tools[1]["cache_control"]; !ok {
+		t.Error("last tool missing cache_control")
+	}
+
+	p.SetPromptCaching(false)
+	params, _ = p.buildParams(req)
+	raw, _ = json.Marshal(params)
+	if strings.Contains(string(raw), "cache_control") {
+		t.Errorf("cache_control sent with caching disabled: %s", raw)
+	}
+}
+
+func TestConvertResponse_CacheUsage(t *testing.T) {
+	resp := &anthropic.Message{
+		Content: []anthropic.ContentBlockUnion{{Type: "text", Text: "hi"}},
+		Usage: anthropic.Usage{
+			InputTokens:              5,
+			OutputTokens:             3,
+			CacheReadInputTokens:     100,
+			CacheCreationInputTokens: 20,
+		},
+	}
+	out := convertResponse(resp)
+	if out.Usage.CacheReadTokens != 100 || out.Usage.CacheCreationTokens != 20 {
+		t.Errorf("cache usage = %+v", out.Usage)
+	}
+	if out.Usage.PromptTokens != 125 || out.Usage.TotalTokens != 128 {
+		t.Errorf("PromptTokens = %d, TotalTokens = %d, want 125 and 128", out.Usage.PromptTokens, out.Usage.TotalTokens)
+	}
+}
+
+func TestAnthropicChat_Timeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	p := newAnthropicProvider("key", option.WithBaseURL(srv.URL), option.WithMaxRetries(0))
+	p.SetTimeout(100 * time.Millisecond)
+
+	start := time.Now()
+	_, err := p.Chat(context.Background(), ChatRequest{Messages: []Message{{Role: "user", Content: "hi"}}})
+	if err == nil {
+		t.Fatal("expected a timeout error")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Chat took %v, want it to give up after the timeout", elapsed)
+	}
+}
+
+func TestAnthropicChat_ThinkingWithToolCalls(t *testing.T) {
+	var bodies []map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		bodies = append(bodies, body)
+		w.Header().Set("Content-Type", "application/json")
+		if len(bodies) == 1 {
+			w.Write([]byte(`{"id":"m1","type":"message","role":"assistant","model":"claude","stop_reason":"tool_use",
+				"content":[
+					{"type":"thinking","thinking":"I should look it up.","signature":"sig-1"},
+					{"type":"redacted_thinking","data":"opaque"},
+					{"type":"tool_use","id":"toolu_1","name":"search","input":{"q":"go"}}
+				],"usage":{"input_tokens":10,"output_tokens":5}}`))
+			return
+		}
+		w.Write([]byte(`{"id":"m2","type":"message","role":"assistant","model":"claude","stop_reason":"end_turn",
+			"content":[{"type":"text","text":"Found it."}],"usage":{"input_tokens":20,"output_tokens":3}}`))
+	}))
+	defer srv.Close()
+
+	p := newAnthropicProvider("key", option.WithBaseURL(srv.URL), option.WithMaxRetries(0))
+	req := ChatRequest{Model: "claude", MaxTokens: 4096, ThinkingBudget: 2048, Messages: []Message{{Role: "user", Content: "search go"}}}
+	first, err := p.Chat(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Continue the turn the way the agent loop does.
+	req.Messages = append(req.Messages,
+		Message{Role: "assistant", Content: first.Content, ToolCalls: first.ToolCalls, ThinkingBlocks: first.ThinkingBlocks},
+		Message{Role: "tool", ToolCallID: first.ToolCalls[0].ID, Content: "results"},
+	)
+	if _, err := p.Chat(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+
+	msgs := bodies[1]["messages"].([]any)
+	blocks := msgs[1].(map[string]any)["content"].([]any)
+	if len(blocks) != 3 {
+		t.Fatalf("assistant message has %d blocks, want thinking, redacted thinking and tool use: %v", len(blocks), blocks)
+	}
+	thinking, redacted, toolUse := blocks[0].(map[string]any), blocks[1].(map[string]any), blocks[2].(map[string]any)
+	if thinking["type"] != "thinking" || thinking["signature"] != "sig-1" || thinking["thinking"] != "I should look it up." {
+		t.Errorf("first block = %v, want the thinking block with its signature", thinking)
+	}
+	if redacted["type"] != "redacted_thinking" || redacted["data"] != "opaque" {
+		t.Errorf("second block = %v, want the redacted thinking block", redacted)
+	}
+	if toolUse["type"] != "tool_use" || toolUse["id"] != "toolu_1" {
+		t.Errorf("third block = %v, want the tool call", toolUse)
+	}
+}
+
+func TestNormalizeStopReason(t *testing.T) {
+	tests := []struct {
+		provider string
+		raw      string
+		want     string
+	}{
+		{"anthropic", "end_turn", StopReasonStop},
+		{"anthropic", "stop_sequence", StopReasonStop},
+		{"anthropic", "pause_turn", StopReasonStop},
+		{"anthropic", "tool_use", StopReasonToolUse},
+		{"anthropic", "max_tokens", StopReasonLength},
+		{"anthropic", "model_context_window_exceeded", StopReasonLength},
+		{"anthropic", "refusal", StopReasonRefusal},
+		{"openai", "stop", StopReasonStop},
+		{"openai", "tool_calls", StopReasonToolUse},
+		{"openai", "function_call", StopReasonToolUse},
+		{"openai", "length", StopReasonLength},
+		{"openai", "content_filter", StopReasonRefusal},
+		{"any", "", ""},
+	}
+	for _, tt := range tests {
+		if got := normalizeStopReason(tt.raw); got != tt.want {
+			t.Errorf("%s %q -> %q, want %q", tt.provider, tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestConvertResponse_MaxTokens(t *testing.T) {
+	msg := &anthropic.Message{
+		Content:    []anthropic.ContentBlockUnion{{Type: "text", Text: "cut o"}},
+		StopReason: "max_tokens",
+	}
+	if resp := convertResponse(msg); resp.StopReason != StopReasonLength {
+		t.Errorf("StopReason = %q, want length", resp.StopReason)
+	}
+}
+
+func TestBuildParams_ThinkingBudget(t *testing.T) {
+	p := NewAnthropicProvider("test-key")
+	req := ChatRequest{
+		Model:          "claude-sonnet-4-20250514",
+		Messages:       []Message{{Role: "user", Content: "hi"}},
+		MaxTokens:      8000,
+		ThinkingBudget: 2048,
+	}
+	params, err := p.buildParams(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := json.Marshal(params)
+	var body struct {
+		MaxTokens int `json:"max_tokens"`
+		Thinking  struct {
+			Type         string `json:"type"`
+			BudgetTokens int    `json:"budget_tokens"`
+		} `json:"thinking"`
+	}
+	if err := json.Unmarshal(raw, &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Thinking.Type != "enabled" || body.Thinking.BudgetTokens != 2048 {
+		t.Errorf("thinking = %+v, want enabled with 2048 tokens", body.Thinking)
+	}
+	if body.MaxTokens != 8000 {
+		t.Errorf("max_tokens = %d, want 8000", body.MaxTokens)
+	}
+
+	// max_tokens must exceed the budget, so a smaller limit is raised.
+	req.MaxTokens = 1000
+	params, _ = p.buildParams(req)
+	if params.MaxTokens != 3048 {
+		t.Errorf("max_tokens = %d, want 3048", params.MaxTokens)
+	}
+
+	req.ThinkingBudget = 0
+	params, _ = p.buildParams(req)
+	if raw, _ := json.Marshal(params); strings.Contains(string(raw), "thinking") {
+		t.Errorf("thinking sent without a budget: %s", raw)
+	}
+}
+
+func TestConvertResponse_Thinking(t *testing.T) {
+	msg := &anthropic.Message{
+		Content: []anthropic.ContentBlockUnion{
+			{Type: "thinking", Thinking: "Six times seven is 42.", Signature: "sig"},
+			{Type: "text", Text: "The answer is 42."},
+		},
+		StopReason: "end_turn",
+	}
+	resp := convertResponse(msg)
+	if resp.Content != "The answer is 42." {
+		t.Errorf("Content = %q, want only the answer", resp.Content)
+	}
+	if resp.ReasoningContent != "Six times seven is 42." {
+		t.Errorf("ReasoningContent = %q", resp.ReasoningContent)
+	}
+}
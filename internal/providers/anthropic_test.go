@@ -168,3 +168,20 @@ func TestNewAnthropicProvider(t *testing.T) {
 		t.Errorf("defaultModel = %q, want %q", p.defaultModel, defaultAnthropicModel)
 	}
 }
+
+func TestConvertMessages_ToolResultIsError(t *testing.T) {
+	for _, isErr := range []bool{false, true} {
+		msgs := []Message{{Role: "tool", ToolCallID: "tc1", Content: "boom", IsError: isErr}}
+		out, err := convertMessages(msgs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block := out[0].Content[0].OfToolResult
+		if block == nil {
+			t.Fatal("expected a tool_result block")
+		}
+		if got := block.IsError.Value; got != isErr {
+			t.Errorf("IsError = %v, want %v", got, isErr)
+		}
+	}
+}
@@ -6,20 +6,37 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 )
 
-const codexResponsesAPI = "https://api.openai.com/v1/responses"
+const codexAPIBase = "https://api.openai.com/v1"
 const codexTokenRefreshURL = "https://auth.openai.com/oauth/token"
 
 // CodexProvider implements Provider using OpenAI's Responses API with OAuth.
 type CodexProvider struct {
+	mu         sync.Mutex    // guards auth and refreshing
+	refreshing *codexRefresh // in-flight token refresh, nil when idle
 	auth       codexAuth
+	authPath   string // refreshed tokens are written back here; empty skips persistence
 	httpClient *http.Client
+	apiBase    string // empty means codexAPIBase
+	tokenURL   string // empty means codexTokenRefreshURL
+}
+
+// CodexOptions overrides where CodexProvider sends requests and reads its
+// credentials. Empty fields keep the defaults.
+type CodexOptions struct {
+	APIBase    string       // Responses API base URL, default https://api.openai.com/v1
+	TokenURL   string       // OAuth token refresh endpoint
+	AuthFile   string       // path to auth.json, default ~/.codex/auth.json
+	HTTPClient *http.Client // default has a 120s timeout
 }
 
 type codexAuth struct {
@@ -30,11 +47,20 @@ type codexAuth struct {
 
 // NewCodexProvider reads ~/.codex/auth.json and returns a CodexProvider.
 func NewCodexProvider() (*CodexProvider, error) {
-	home, err := os.UserHomeDir()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get home dir: %w", err)
+	return NewCodexProviderWithOptions(CodexOptions{})
+}
+
+// NewCodexProviderWithOptions reads the auth file named by opts and returns a
+// CodexProvider that talks to the configured endpoints.
+func NewCodexProviderWithOptions(opts CodexOptions) (*CodexProvider, error) {
+	authPath := opts.AuthFile
+	if authPath == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get home dir: %w", err)
+		}
+		authPath = filepath.Join(home, ".codex", "auth.json")
 	}
-	authPath := filepath.Join(home, ".codex", "auth.json")
 	data, err := os.ReadFile(authPath)
 	if err != nil {
 		return nil, fmt.Errorf("codex auth.json not found at %s: %w", authPath, err)
@@ -43,40 +69,169 @@ func NewCodexProvider() (*CodexProvider, error) {
 	if err := json.Unmarshal(data, &auth); err != nil {
 		return nil, fmt.Errorf("failed to parse codex auth.json: %w", err)
 	}
+	client := opts.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 120 * time.Second}
+	}
 	return &CodexProvider{
 		auth:       auth,
-		httpClient: &http.Client{Timeout: 120 * time.Second},
+		authPath:   authPath,
+		httpClient: client,
+		apiBase:    opts.APIBase,
+		tokenURL:   opts.TokenURL,
 	}, nil
 }
 
+func (p *CodexProvider) responsesURL() string {
+	base := p.apiBase
+	if base == "" {
+		base = codexAPIBase
+	}
+	return strings.TrimRight(base, "/") + "/responses"
+}
+
+func (p *CodexProvider) refreshURL() string {
+	if p.tokenURL == "" {
+		return codexTokenRefreshURL
+	}
+	return p.tokenURL
+}
+
+// accessToken returns a valid access token, refreshing it if it is about to
+// expire. Concurrent callers share a single in-flight refresh; each stops
+// waiting when its own ctx is done.
 func (p *CodexProvider) accessToken(ctx context.Context) (string, error) {
+	p.mu.Lock()
 	if time.Now().Unix() < p.auth.ExpiresAt-60 {
-		return p.auth.AccessToken, nil
+		token := p.auth.AccessToken
+		p.mu.Unlock()
+		return token, nil
+	}
+	f := p.refreshing
+	if f == nil {
+		f = &codexRefresh{done: make(chan struct{})}
+		p.refreshing = f
+		go p.refresh(f, p.auth.RefreshToken)
+	}
+	p.mu.Unlock()
+
+	select {
+	case <-f.done:
+		if f.err != nil {
+			return "", f.err
+		}
+		return f.token, nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// codexRefresh is a token refresh shared by every caller that needed it.
+// token and err are set before done is closed.
+type codexRefresh struct {
+	done  chan struct{}
+	token string
+	err   error
+}
+
+// refresh exchanges refreshToken for new credentials and completes f. It is
+// not tied to any caller's context so one cancelled request cannot fail the
+// refresh for the others; the HTTP client timeout bounds it instead.
+func (p *CodexProvider) refresh(f *codexRefresh, refreshToken string) {
+	refreshed, err := p.requestToken(context.Background(), refreshToken)
+
+	p.mu.Lock()
+	if err == nil {
+		// Some servers rotate the refresh token only occasionally.
+		if refreshed.RefreshToken == "" {
+			refreshed.RefreshToken = refreshToken
+		}
+		p.auth = refreshed
+		f.token = refreshed.AccessToken
+		if err := p.saveAuth(); err != nil {
+			// The new token still works for this process; only restarts are affected.
+			slog.Warn("codex: failed to persist refreshed token", "path", p.authPath, "error", err)
+		}
 	}
-	// Refresh the token
+	f.err = err
+	p.refreshing = nil
+	p.mu.Unlock()
+	close(f.done)
+}
+
+func (p *CodexProvider) requestToken(ctx context.Context, refreshToken string) (codexAuth, error) {
 	body, _ := json.Marshal(map[string]string{
 		"grant_type":    "refresh_token",
-		"refresh_token": p.auth.RefreshToken,
+		"refresh_token": refreshToken,
 	})
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, codexTokenRefreshURL, bytes.NewReader(body))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.refreshURL(), bytes.NewReader(body))
 	if err != nil {
-		return "", fmt.Errorf("failed to build refresh request: %w", err)
+		return codexAuth{}, fmt.Errorf("failed to build refresh request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
 	resp, err := p.httpClient.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("token refresh failed: %w", err)
+		return codexAuth{}, fmt.Errorf("token refresh failed: %w", err)
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("token refresh returned status %d", resp.StatusCode)
+		return codexAuth{}, fmt.Errorf("token refresh returned status %d", resp.StatusCode)
 	}
 	var refreshed codexAuth
 	if err := json.NewDecoder(resp.Body).Decode(&refreshed); err != nil {
-		return "", fmt.Errorf("failed to decode refresh response: %w", err)
+		return codexAuth{}, fmt.Errorf("failed to decode refresh response: %w", err)
 	}
-	p.auth = refreshed
-	return p.auth.AccessToken, nil
+	return refreshed, nil
+}
+
+// saveAuth writes the current tokens back to the auth file, keeping any other
+// fields it holds. The file is replaced atomically. p.mu must be held.
+func (p *CodexProvider) saveAuth() error {
+	if p.authPath == "" {
+		return nil
+	}
+
+	fields := map[string]json.RawMessage{}
+	if data, err := os.ReadFile(p.authPath); err == nil {
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return fmt.Errorf("failed to parse %s: %w", p.authPath, err)
+		}
+	}
+	updated, err := json.Marshal(p.auth)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(updated, &fields); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(fields, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(p.authPath), ".auth.json.tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := f.Name()
+	defer os.Remove(tmpPath) // no-op once renamed
+
+	if err := f.Chmod(0o600); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, p.authPath)
 }
 
 // Chat implements Provider.
@@ -92,7 +247,7 @@ func (p *CodexProvider) Chat(ctx context.Context, req ChatRequest) (*ChatRespons
 		return nil, fmt.Errorf("codex: failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, codexResponsesAPI, bytes.NewReader(bodyBytes))
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.responsesURL(), bytes.NewReader(bodyBytes))
 	if err != nil {
 		return nil, fmt.Errorf("codex: failed to build request: %w", err)
 	}
@@ -124,13 +279,23 @@ type codexRequest struct {
 }
 
 type codexInputItem struct {
-	Type       string            `json:"type"`
-	Role       string            `json:"role,omitempty"`
-	Content    string            `json:"content,omitempty"`
-	CallID     string            `json:"call_id,omitempty"`
-	Name       string            `json:"name,omitempty"`
-	Arguments  string            `json:"arguments,omitempty"`
-	Output     string            `json:"output,omitempty"`
+	Type string `json:"type"`
+	Role string `json:"role,omitempty"`
+	// Content is a string, or []codexInputPart for a multimodal message.
+	Content   any    `json:"content,omitempty"`
+	CallID    string `json:"call_id,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments,omitempty"`
+	Output    string `json:"output,omitempty"`
+}
+
+type codexInputPart struct {
+	Type     string `json:"type"` // "input_text", "input_image" or "input_file"
+	Text     string `json:"text,omitempty"`
+	ImageURL string `json:"image_url,omitempty"` // URL or data URI
+	Detail   string `json:"detail,omitempty"`
+	Filename string `json:"filename,omitempty"`
+	FileData string `json:"file_data,omitempty"` // data URI
 }
 
 type codexTool struct {
@@ -142,12 +307,16 @@ type codexTool struct {
 
 func buildCodexRequest(req ChatRequest) codexRequest {
 	var items []codexInputItem
-	for _, m := range req.Messages {
+	for _, m := range normalizeToolCallIDs(req.Messages) {
 		switch m.Role {
 		case "system":
 			// system messages become instructions; skip here
 		case "user":
-			items = append(items, codexInputItem{Type: "message", Role: "user", Content: m.Content})
+			if len(m.ContentParts) > 0 {
+				items = append(items, codexInputItem{Type: "message", Role: "user", Content: codexContentParts(m.Content, m.ContentParts)})
+			} else {
+				items = append(items, codexInputItem{Type: "message", Role: "user", Content: m.Content})
+			}
 		case "assistant":
 			if len(m.ToolCalls) > 0 {
 				if m.Content != "" {
@@ -203,17 +372,58 @@ func buildCodexRequest(req ChatRequest) codexRequest {
 	}
 }
 
+// codexContentParts builds the content of a multimodal user message, with
+// text first. Images pass through as URLs; data URIs are accepted as is.
+// Files are sent inline; audio is not accepted and is replaced by a note.
+func codexContentParts(text string, parts []ContentPart) []codexInputPart {
+	var out []codexInputPart
+	if text != "" {
+		out = append(out, codexInputPart{Type: "input_text", Text: text})
+	}
+	for _, p := range parts {
+		switch p.Type {
+		case "text":
+			out = append(out, codexInputPart{Type: "input_text", Text: p.Text})
+		case "image_url":
+			if p.ImageURL == nil {
+				continue
+			}
+			out = append(out, codexInputPart{Type: "input_image", ImageURL: p.ImageURL.URL, Detail: p.ImageURL.Detail})
+		case "input_audio":
+			if p.InputAudio != nil {
+				out = append(out, codexInputPart{Type: "input_text", Text: "[Audio attachment omitted: this model does not accept audio.]"})
+			}
+		case "file":
+			if p.File == nil {
+				continue
+			}
+			out = append(out, codexInputPart{Type: "input_file", Filename: p.File.Filename, FileData: p.File.FileData})
+		}
+	}
+	return out
+}
+
 // --- SSE parsing ---
 
 type codexSSEEvent struct {
 	Type string          `json:"type"`
 	Item json.RawMessage `json:"item,omitempty"`
+	// Position of the event in the stream and of the output it belongs to.
+	// Older streams omit them.
+	SequenceNumber *int `json:"sequence_number,omitempty"`
+	OutputIndex    *int `json:"output_index,omitempty"`
+	ContentIndex   int  `json:"content_index,omitempty"`
+	// for response.output_text.delta
+	Delta string `json:"delta,omitempty"`
 	// for response.completed
 	Response *codexResponseBody `json:"response,omitempty"`
 }
 
 type codexResponseBody struct {
-	Usage *codexUsage `json:"usage,omitempty"`
+	Usage             *codexUsage `json:"usage,omitempty"`
+	IncompleteDetails *struct {
+		Reason string `json:"reason"` // "max_output_tokens" or "content_filter"
+	} `json:"incomplete_details,omitempty"`
 }
 
 type codexUsage struct {
@@ -237,9 +447,10 @@ type codexContentPart struct {
 }
 
 func parseCodexSSE(body interface{ Read([]byte) (int, error) }) (*ChatResponse, error) {
-	var textParts []string
-	var toolCalls []ToolCall
+	var out codexOutput
 	var usage Usage
+	var incomplete string
+	seen := make(map[int]bool) // sequence numbers already applied
 
 	scanner := bufio.NewScanner(body)
 	var dataLine string
@@ -260,26 +471,25 @@ func parseCodexSSE(body interface{ Read([]byte) (int, error) }) (*ChatResponse,
 				dataLine = ""
 				continue
 			}
+			dataLine = ""
+			if ev.SequenceNumber != nil {
+				if seen[*ev.SequenceNumber] {
+					continue
+				}
+				seen[*ev.SequenceNumber] = true
+			}
 			switch ev.Type {
+			case "response.output_text.delta":
+				out.slot(ev.OutputIndex).addDelta(ev.ContentIndex, ev.Delta)
 			case "response.output_item.done":
 				var item codexOutputItem
 				if err := json.Unmarshal(ev.Item, &item); err == nil {
-					switch item.Type {
-					case "message":
-						for _, part := range item.Content {
-							if part.Type == "output_text" || part.Type == "text" {
-								textParts = append(textParts, part.Text)
-							}
-						}
-					case "function_call":
-						toolCalls = append(toolCalls, ToolCall{
-							ID:        item.CallID,
-							Name:      item.Name,
-							Arguments: item.Arguments,
-						})
-					}
+					out.slot(ev.OutputIndex).item = &item
+				}
+			case "response.completed", "response.incomplete":
+				if ev.Response != nil && ev.Response.IncompleteDetails != nil {
+					incomplete = ev.Response.IncompleteDetails.Reason
 				}
-			case "response.completed":
 				if ev.Response != nil && ev.Response.Usage != nil {
 					u := ev.Response.Usage
 					usage = Usage{
@@ -289,22 +499,122 @@ func parseCodexSSE(body interface{ Read([]byte) (int, error) }) (*ChatResponse,
 					}
 				}
 			}
-			dataLine = ""
 		}
 	}
 	if err := scanner.Err(); err != nil {
 		return nil, fmt.Errorf("codex: SSE read error: %w", err)
 	}
 
-	stopReason := "stop"
-	if len(toolCalls) > 0 {
-		stopReason = "tool_use"
+	content, toolCalls := out.assemble()
+	stopReason := StopReasonStop
+	switch {
+	case incomplete == "max_output_tokens":
+		stopReason = StopReasonLength
+	case incomplete == "content_filter":
+		stopReason = StopReasonRefusal
+	case len(toolCalls) > 0:
+		stopReason = StopReasonToolUse
 	}
 
 	return &ChatResponse{
-		Content:    strings.Join(textParts, ""),
+		Content:    content,
 		ToolCalls:  toolCalls,
 		Usage:      usage,
 		StopReason: stopReason,
 	}, nil
 }
+
+// codexOutput collects the output items of one response. Items are keyed by
+// their output_index so they assemble in order however the events arrive;
+// items from streams without indices keep their arrival order after any
+// indexed ones, with deltas belonging to the item that follows them.
+type codexOutput struct {
+	indexed   map[int]*codexOutputSlot
+	unindexed []*codexOutputSlot
+}
+
+// codexOutputSlot is one output item: the finished item once its done event
+// arrives, and until then the text deltas streamed for each content part.
+type codexOutputSlot struct {
+	item   *codexOutputItem
+	deltas map[int]*strings.Builder // content_index -> text so far
+}
+
+func (o *codexOutput) slot(index *int) *codexOutputSlot {
+	if index == nil {
+		if n := len(o.unindexed); n > 0 && o.unindexed[n-1].item == nil {
+			return o.unindexed[n-1]
+		}
+		s := &codexOutputSlot{}
+		o.unindexed = append(o.unindexed, s)
+		return s
+	}
+	if o.indexed == nil {
+		o.indexed = make(map[int]*codexOutputSlot)
+	}
+	s, ok := o.indexed[*index]
+	if !ok {
+		s = &codexOutputSlot{}
+		o.indexed[*index] = s
+	}
+	return s
+}
+
+func (s *codexOutputSlot) addDelta(contentIndex int, delta string) {
+	if s.deltas == nil {
+		s.deltas = make(map[int]*strings.Builder)
+	}
+	b, ok := s.deltas[contentIndex]
+	if !ok {
+		b = &strings.Builder{}
+		s.deltas[contentIndex] = b
+	}
+	b.WriteString(delta)
+}
+
+// assemble returns the response text and tool calls in output order. A
+// finished item supersedes the deltas streamed for it; deltas alone are used
+// when the stream ended before the item was done.
+func (o *codexOutput) assemble() (string, []ToolCall) {
+	indices := make([]int, 0, len(o.indexed))
+	for i := range o.indexed {
+		indices = append(indices, i)
+	}
+	sort.Ints(indices)
+	slots := make([]*codexOutputSlot, 0, len(indices)+len(o.unindexed))
+	for _, i := range indices {
+		slots = append(slots, o.indexed[i])
+	}
+	slots = append(slots, o.unindexed...)
+
+	var text strings.Builder
+	var toolCalls []ToolCall
+	for _, s := range slots {
+		if s.item == nil {
+			parts := make([]int, 0, len(s.deltas))
+			for i := range s.deltas {
+				parts = append(parts, i)
+			}
+			sort.Ints(parts)
+			for _, i := range parts {
+				text.WriteString(s.deltas[i].String())
+			}
+			continue
+		}
+		switch s.item.Type {
+		case "message":
+			for _, part := range s.item.Content {
+				if part.Type == "output_text" || part.Type == "text" {
+					text.WriteString(part.Text)
+				}
+			}
+		case "function_call":
+			toolCalls = append(toolCalls, ToolCall{
+				ID:        s.item.CallID,
+				Name:      s.item.Name,
+				Arguments: s.item.Arguments,
+			})
+		}
+	}
+	return text.String(), toolCalls
+}
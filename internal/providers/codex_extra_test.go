@@ -5,6 +5,10 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -66,7 +70,6 @@ func TestCodexAccessToken_Expired_RefreshError(t *testing.T) {
 	}))
 	defer srv.Close()
 
-	// Can't redirect codexTokenRefreshURL, so just verify the boundary check
 	p := &CodexProvider{
 		auth: codexAuth{
 			AccessToken:  "edge-token",
@@ -74,10 +77,11 @@ func TestCodexAccessToken_Expired_RefreshError(t *testing.T) {
 			ExpiresAt:    time.Now().Unix() + 59, // within 60s buffer = needs refresh
 		},
 		httpClient: &http.Client{Timeout: 1 * time.Second},
+		tokenURL:   srv.URL,
+	}
+	if _, err := p.accessToken(context.Background()); err == nil {
+		t.Fatal("expected error when the refresh endpoint fails")
 	}
-	// Will try to refresh against real URL and fail quickly due to timeout
-	_, _ = p.accessToken(context.Background())
-	// We just verify it doesn't hang
 }
 
 func TestFindByName_Found(t *testing.T) {
@@ -123,3 +127,202 @@ func TestFindGateway_NoMatch(t *testing.T) {
 		t.Errorf("expected nil for no match, got %+v", spec)
 	}
 }
+
+func TestCodexChat_EndToEndWithRefresh(t *testing.T) {
+	var refreshes int
+	var gotAuth string
+	var gotBody map[string]any
+	mux := http.NewServeMux()
+	mux.HandleFunc("/oauth/token", func(w http.ResponseWriter, r *http.Request) {
+		refreshes++
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["refresh_token"] != "old-refresh" {
+			t.Errorf("refresh_token = %q, want old-refresh", body["refresh_token"])
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"access_token": "fresh-token",
+			"expires_at":   time.Now().Unix() + 3600,
+		})
+	})
+	mux.HandleFunc("/v1/responses", func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		json.NewDecoder(r.Body).Decode(&gotBody)
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(buildSSE(
+			`{"type":"response.output_item.done","item":{"type":"message","content":[{"type":"output_text","text":"hi from codex"}]}}`,
+			`{"type":"response.completed","response":{"usage":{"input_tokens":7,"output_tokens":3,"total_tokens":10}}}`,
+			"[DONE]",
+		)))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	authFile := filepath.Join(t.TempDir(), "auth.json")
+	auth, _ := json.Marshal(codexAuth{AccessToken: "stale", RefreshToken: "old-refresh", ExpiresAt: time.Now().Unix() - 10})
+	if err := os.WriteFile(authFile, auth, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := NewCodexProviderWithOptions(CodexOptions{
+		APIBase:  srv.URL + "/v1/",
+		TokenURL: srv.URL + "/oauth/token",
+		AuthFile: authFile,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		resp, err := p.Chat(context.Background(), ChatRequest{
+			Model:    "codex-mini",
+			Messages: []Message{{Role: "user", Content: "hello"}},
+		})
+		if err != nil {
+			t.Fatalf("Chat: %v", err)
+		}
+		if resp.Content != "hi from codex" || resp.Usage.TotalTokens != 10 {
+			t.Errorf("unexpected response: %+v", resp)
+		}
+	}
+	if refreshes != 1 {
+		t.Errorf("token refreshed %d times, want 1", refreshes)
+	}
+	if gotAuth != "Bearer fresh-token" {
+		t.Errorf("Authorization = %q, want the refreshed token", gotAuth)
+	}
+	if gotBody["model"] != "codex-mini" {
+		t.Errorf("request model = %v", gotBody["model"])
+	}
+	if p.auth.RefreshToken != "old-refresh" {
+		t.Errorf("refresh token lost after refresh: %q", p.auth.RefreshToken)
+	}
+}
+
+func TestNewCodexProviderWithOptions_MissingAuthFile(t *testing.T) {
+	_, err := NewCodexProviderWithOptions(CodexOptions{AuthFile: filepath.Join(t.TempDir(), "missing.json")})
+	if err == nil {
+		t.Fatal("expected error for missing auth file")
+	}
+}
+
+func TestCodexAccessToken_PersistsRefresh(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"access_token":  "fresh-token",
+			"refresh_token": "rotated-refresh",
+			"expires_at":    time.Now().Unix() + 3600,
+		})
+	}))
+	defer srv.Close()
+
+	authFile := filepath.Join(t.TempDir(), "auth.json")
+	initial := `{"access_token":"stale","refresh_token":"old","expires_at":1,"account_id":"acct-123"}`
+	if err := os.WriteFile(authFile, []byte(initial), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := NewCodexProviderWithOptions(CodexOptions{TokenURL: srv.URL, AuthFile: authFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.accessToken(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(authFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved map[string]any
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatalf("auth file is not valid JSON: %v\n%s", err, data)
+	}
+	if saved["access_token"] != "fresh-token" || saved["refresh_token"] != "rotated-refresh" {
+		t.Errorf("auth file not updated: %s", data)
+	}
+	if saved["account_id"] != "acct-123" {
+		t.Errorf("unrelated auth fields were dropped: %s", data)
+	}
+	if info, _ := os.Stat(authFile); info.Mode().Perm() != 0o600 {
+		t.Errorf("auth file mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	// A new provider picks up the persisted token without refreshing.
+	p2, err := NewCodexProviderWithOptions(CodexOptions{TokenURL: "http://127.0.0.1:1", AuthFile: authFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token, err := p2.accessToken(context.Background()); err != nil || token != "fresh-token" {
+		t.Errorf("restarted provider token = %q, %v", token, err)
+	}
+}
+
+func TestCodexAccessToken_ConcurrentRefreshOnce(t *testing.T) {
+	var refreshes atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		refreshes.Add(1)
+		time.Sleep(50 * time.Millisecond)
+		json.NewEncoder(w).Encode(map[string]any{
+			"access_token": "fresh-token",
+			"expires_at":   time.Now().Unix() + 3600,
+		})
+	}))
+	defer srv.Close()
+
+	p := &CodexProvider{
+		auth:       codexAuth{AccessToken: "stale", RefreshToken: "refresh", ExpiresAt: 1},
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+		tokenURL:   srv.URL,
+	}
+
+	var wg sync.WaitGroup
+	tokens := make([]string, 20)
+	errs := make([]error, len(tokens))
+	for i := range tokens {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			tokens[i], errs[i] = p.accessToken(context.Background())
+		}(i)
+	}
+	wg.Wait()
+
+	for i := range tokens {
+		if errs[i] != nil || tokens[i] != "fresh-token" {
+			t.Errorf("caller %d got (%q, %v), want fresh-token", i, tokens[i], errs[i])
+		}
+	}
+	if n := refreshes.Load(); n != 1 {
+		t.Errorf("refresh endpoint called %d times, want 1", n)
+	}
+	if p.auth.RefreshToken != "refresh" {
+		t.Errorf("refresh token = %q, want the old one kept", p.auth.RefreshToken)
+	}
+}
+
+func TestCodexAccessToken_WaiterHonoursContext(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		json.NewEncoder(w).Encode(map[string]any{"access_token": "fresh-token", "expires_at": time.Now().Unix() + 3600})
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	p := &CodexProvider{
+		auth:       codexAuth{AccessToken: "stale", RefreshToken: "refresh", ExpiresAt: 1},
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+		tokenURL:   srv.URL,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := p.accessToken(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("err = %v, want context.DeadlineExceeded", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Error("accessToken did not return promptly after its context expired")
+	}
+}
@@ -26,6 +26,64 @@ func TestBuildCodexRequest_UserMessage(t *testing.T) {
 	}
 }
 
+func TestBuildCodexRequest_ImageContent(t *testing.T) {
+	req := ChatRequest{
+		Messages: []Message{{
+			Role:    "user",
+			Content: "what is this?",
+			ContentParts: []ContentPart{
+				{Type: "image_url", ImageURL: &ImageURL{URL: "data:image/png;base64,iVBORw0KGgo=", Detail: "low"}},
+				{Type: "image_url", ImageURL: &ImageURL{URL: "https://example.com/cat.jpg"}},
+			},
+		}},
+	}
+	data, err := json.Marshal(buildCodexRequest(req))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		Input []struct {
+			Role    string           `json:"role"`
+			Content []codexInputPart `json:"content"`
+		} `json:"input"`
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("content is not a list of parts: %v\n%s", err, data)
+	}
+	want := []codexInputPart{
+		{Type: "input_text", Text: "what is this?"},
+		{Type: "input_image", ImageURL: "data:image/png;base64,iVBORw0KGgo=", Detail: "low"},
+		{Type: "input_image", ImageURL: "https://example.com/cat.jpg"},
+	}
+	if len(got.Input) != 1 || got.Input[0].Role != "user" {
+		t.Fatalf("input = %s, want one user message", data)
+	}
+	if len(got.Input[0].Content) != len(want) {
+		t.Fatalf("content = %+v, want %+v", got.Input[0].Content, want)
+	}
+	for i := range want {
+		if got.Input[0].Content[i] != want[i] {
+			t.Errorf("part %d = %+v, want %+v", i, got.Input[0].Content[i], want[i])
+		}
+	}
+}
+
+func TestCodexContentParts_AudioAndFile(t *testing.T) {
+	got := codexContentParts("", []ContentPart{
+		{Type: "file", File: &FilePart{Filename: "report.pdf", FileData: "data:application/pdf;base64,JVBERg=="}},
+		{Type: "input_audio", InputAudio: &InputAudio{Data: "UklGRg==", Format: "wav"}},
+	})
+	if len(got) != 2 {
+		t.Fatalf("parts = %+v, want 2", got)
+	}
+	if want := (codexInputPart{Type: "input_file", Filename: "report.pdf", FileData: "data:application/pdf;base64,JVBERg=="}); got[0] != want {
+		t.Errorf("part 0 = %+v, want %+v", got[0], want)
+	}
+	if got[1].Type != "input_text" || !strings.Contains(got[1].Text, "Audio") {
+		t.Errorf("part 1 = %+v, want a note for the audio", got[1])
+	}
+}
+
 func TestBuildCodexRequest_SystemPromptExtracted(t *testing.T) {
 	req := ChatRequest{
 		Messages: []Message{
@@ -170,6 +228,30 @@ func TestParseCodexSSE_TextOutput(t *testing.T) {
 	}
 }
 
+func TestParseCodexSSE_Incomplete(t *testing.T) {
+	tests := []struct {
+		reason string
+		want   string
+	}{
+		{"max_output_tokens", StopReasonLength},
+		{"content_filter", StopReasonRefusal},
+	}
+	for _, tt := range tests {
+		sse := buildSSE(
+			`{"type":"response.output_item.done","item":{"type":"message","content":[{"type":"output_text","text":"partial"}]}}`,
+			`{"type":"response.incomplete","response":{"incomplete_details":{"reason":"`+tt.reason+`"}}}`,
+			"[DONE]",
+		)
+		resp, err := parseCodexSSE(strings.NewReader(sse))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StopReason != tt.want {
+			t.Errorf("%s: StopReason = %q, want %q", tt.reason, resp.StopReason, tt.want)
+		}
+	}
+}
+
 func TestParseCodexSSE_FunctionCall(t *testing.T) {
 	sse := buildSSE(
 		`{"type":"response.output_item.done","item":{"type":"function_call","name":"my_tool","arguments":"{\"x\":1}","call_id":"call1"}}`,
@@ -233,6 +315,66 @@ func TestParseCodexSSE_TextTypeText(t *testing.T) {
 	}
 }
 
+func TestParseCodexSSE_OutOfOrderItems(t *testing.T) {
+	sse := buildSSE(
+		`{"type":"response.output_item.done","sequence_number":7,"output_index":2,"item":{"type":"function_call","name":"later","arguments":"{}","call_id":"c2"}}`,
+		`{"type":"response.output_item.done","sequence_number":5,"output_index":1,"item":{"type":"message","content":[{"type":"output_text","text":"second "},{"type":"output_text","text":"part"}]}}`,
+		`{"type":"response.output_item.done","sequence_number":3,"output_index":0,"item":{"type":"message","content":[{"type":"output_text","text":"first, "}]}}`,
+		// A repeated event is applied once.
+		`{"type":"response.output_item.done","sequence_number":5,"output_index":1,"item":{"type":"message","content":[{"type":"output_text","text":"duplicate"}]}}`,
+		"[DONE]",
+	)
+	resp, err := parseCodexSSE(strings.NewReader(sse))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Content != "first, second part" {
+		t.Errorf("Content = %q, want %q", resp.Content, "first, second part")
+	}
+	if len(resp.ToolCalls) != 1 || resp.ToolCalls[0].Name != "later" {
+		t.Errorf("ToolCalls = %+v, want one call to later", resp.ToolCalls)
+	}
+}
+
+func TestParseCodexSSE_TextDeltas(t *testing.T) {
+	sse := buildSSE(
+		`{"type":"response.output_text.delta","sequence_number":4,"output_index":1,"content_index":0,"delta":"world"}`,
+		`{"type":"response.output_text.delta","sequence_number":1,"output_index":0,"content_index":0,"delta":"Hel"}`,
+		`{"type":"response.output_text.delta","sequence_number":2,"output_index":0,"content_index":0,"delta":"lo, "}`,
+		`{"type":"response.output_text.delta","sequence_number":2,"output_index":0,"content_index":0,"delta":"lo, "}`,
+		`{"type":"response.output_text.delta","sequence_number":5,"output_index":1,"content_index":0,"delta":"!"}`,
+		`{"type":"response.completed","sequence_number":6,"response":{"usage":{"input_tokens":3,"output_tokens":4,"total_tokens":7}}}`,
+		"[DONE]",
+	)
+	resp, err := parseCodexSSE(strings.NewReader(sse))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Content != "Hello, world!" {
+		t.Errorf("Content = %q, want %q", resp.Content, "Hello, world!")
+	}
+	if resp.Usage.TotalTokens != 7 {
+		t.Errorf("TotalTokens = %d, want 7", resp.Usage.TotalTokens)
+	}
+}
+
+func TestParseCodexSSE_DoneItemSupersedesDeltas(t *testing.T) {
+	sse := buildSSE(
+		`{"type":"response.output_text.delta","delta":"Hi "}`,
+		`{"type":"response.output_text.delta","delta":"there"}`,
+		`{"type":"response.output_item.done","item":{"type":"message","content":[{"type":"output_text","text":"Hi there"}]}}`,
+		`{"type":"response.output_text.delta","delta":", bye"}`,
+		"[DONE]",
+	)
+	resp, err := parseCodexSSE(strings.NewReader(sse))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Content != "Hi there, bye" {
+		t.Errorf("Content = %q, want %q", resp.Content, "Hi there, bye")
+	}
+}
+
 // buildSSE formats SSE events as a stream string.
 func buildSSE(events ...string) string {
 	var sb strings.Builder
@@ -0,0 +1,52 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+)
+
+// DryRunProvider never calls an API. It logs each request and answers with
+// the request itself, so operators can see exactly what the agent would send
+// without paying for it. Use it in place of the real provider, or install it
+// for a single backend with ProviderRouter.Set.
+type DryRunProvider struct{}
+
+// NewDryRunProvider creates a DryRunProvider.
+func NewDryRunProvider() *DryRunProvider {
+	return &DryRunProvider{}
+}
+
+// dryRunRequest is ChatRequest as dumped by DryRunProvider. SystemPrompt is
+// left out of ChatRequest's JSON, so it is carried separately.
+type dryRunRequest struct {
+	SystemPrompt string `json:"system_prompt,omitempty"`
+	ChatRequest
+}
+
+// Chat logs req and returns it as the reply content, after a summary line
+// giving the model and the number of messages and tools.
+func (d *DryRunProvider) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	body, err := json.MarshalIndent(dryRunRequest{SystemPrompt: req.SystemPrompt, ChatRequest: req}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("dry run: encode request: %w", err)
+	}
+	slog.Info("dry run: provider request not sent", "model", req.Model, "messages", len(req.Messages), "tools", len(req.Tools), "request", string(body))
+
+	content := fmt.Sprintf("[dry run] model=%s messages=%d tools=%d\n%s", req.Model, len(req.Messages), len(req.Tools), body)
+	return &ChatResponse{Content: content, StopReason: StopReasonStop}, nil
+}
+
+// ChatStream delivers the Chat reply as a single delta.
+func (d *DryRunProvider) ChatStream(ctx context.Context, req ChatRequest, onDelta func(string)) (*ChatResponse, error) {
+	resp, err := d.Chat(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	onDelta(resp.Content)
+	return resp, nil
+}
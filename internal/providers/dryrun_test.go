@@ -0,0 +1,45 @@
+package providers
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestDryRunProviderEchoesRequest(t *testing.T) {
+	var p StreamingProvider = NewDryRunProvider()
+	req := ChatRequest{
+		Model:        "gpt-4o",
+		SystemPrompt: "be brief",
+		Messages: []Message{
+			{Role: "user", Content: "hello"},
+			{Role: "assistant", Content: "hi"},
+			{Role: "user", Content: "what is 2+2?"},
+		},
+		Tools: []ToolDef{{Type: "function", Function: FunctionDef{Name: "calc"}}},
+	}
+
+	resp, err := p.Chat(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(resp.Content, "[dry run] model=gpt-4o messages=3 tools=1\n") {
+		t.Errorf("unexpected summary line in %q", resp.Content)
+	}
+	for _, want := range []string{`"system_prompt": "be brief"`, `"what is 2+2?"`, `"calc"`} {
+		if !strings.Contains(resp.Content, want) {
+			t.Errorf("reply is missing %s:\n%s", want, resp.Content)
+		}
+	}
+	if resp.StopReason != StopReasonStop || len(resp.ToolCalls) != 0 {
+		t.Errorf("stop reason %q with %d tool calls, want a plain stop", resp.StopReason, len(resp.ToolCalls))
+	}
+
+	var deltas []string
+	if _, err := p.ChatStream(context.Background(), req, func(d string) { deltas = append(deltas, d) }); err != nil {
+		t.Fatal(err)
+	}
+	if len(deltas) != 1 || deltas[0] != resp.Content {
+		t.Errorf("streamed %d deltas, want the whole reply once", len(deltas))
+	}
+}
@@ -0,0 +1,64 @@
+package providers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/coopco/nanobot/internal/tlsutil"
+)
+
+// Factory builds backends for explicitly named providers, for setups where
+// different agents talk to different providers or accounts. One backend is
+// cached per distinct provider, API key and base URL, so agents with the same
+// settings share a client.
+type Factory struct {
+	mu        sync.Mutex
+	providers map[factoryKey]Provider
+}
+
+type factoryKey struct {
+	name    string
+	apiKey  string
+	baseURL string
+	tls     tlsutil.Options
+	headers string // Credentials.Headers in a comparable form, see headerKey
+}
+
+// NewFactory creates an empty Factory.
+func NewFactory() *Factory {
+	return &Factory{providers: make(map[factoryKey]Provider)}
+}
+
+// Get returns the backend for the named provider ("groq", "anthropic", ...)
+// configured with c, building it on first use.
+func (f *Factory) Get(name string, c Credentials) (Provider, error) {
+	spec := FindByName(name)
+	if spec == nil {
+		return nil, fmt.Errorf("unknown provider %q", name)
+	}
+
+	key := factoryKey{name: name, apiKey: c.APIKey, baseURL: c.BaseURL, tls: c.TLS, headers: headerKey(c.Headers)}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if p, ok := f.providers[key]; ok {
+		return p, nil
+	}
+	p, err := newProvider(spec, c)
+	if err != nil {
+		return nil, err
+	}
+	f.providers[key] = p
+	return p, nil
+}
+
+// headerKey encodes headers as sorted "name: value" lines.
+func headerKey(headers map[string]string) string {
+	lines := make([]string, 0, len(headers))
+	for k, v := range headers {
+		lines = append(lines, k+": "+v)
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,80 @@
+package providers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestFactoryPerAgentProviders(t *testing.T) {
+	var fastHits, smartHits atomic.Int32
+	fast := httptest.NewServer(countingChatHandler(&fastHits))
+	defer fast.Close()
+	smart := httptest.NewServer(countingChatHandler(&smartHits))
+	defer smart.Close()
+
+	// Two named agents bound to different providers and endpoints.
+	agents := map[string]struct {
+		provider string
+		creds    Credentials
+	}{
+		"fast":  {"groq", Credentials{APIKey: "gsk-test", BaseURL: fast.URL}},
+		"smart": {"openai", Credentials{APIKey: "sk-test", BaseURL: smart.URL}},
+	}
+
+	f := NewFactory()
+	resolved := map[string]Provider{}
+	for name, a := range agents {
+		p, err := f.Get(a.provider, a.creds)
+		if err != nil {
+			t.Fatalf("agent %s: %v", name, err)
+		}
+		resolved[name] = p
+	}
+	if resolved["fast"] == resolved["smart"] {
+		t.Fatal("expected distinct provider instances per agent")
+	}
+
+	for _, name := range []string{"fast", "smart"} {
+		if _, err := resolved[name].Chat(context.Background(), ChatRequest{Model: "m", Messages: []Message{{Role: "user", Content: "hi"}}}); err != nil {
+			t.Fatalf("agent %s chat: %v", name, err)
+		}
+	}
+	if fastHits.Load() != 1 || smartHits.Load() != 1 {
+		t.Errorf("fast server got %d requests, smart got %d; want one each", fastHits.Load(), smartHits.Load())
+	}
+}
+
+func TestFactoryCachesByCredentials(t *testing.T) {
+	f := NewFactory()
+	a, err := f.Get("groq", Credentials{APIKey: "k1", BaseURL: "http://groq.test/v1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := f.Get("groq", Credentials{APIKey: "k1", BaseURL: "http://groq.test/v1"})
+	c, _ := f.Get("groq", Credentials{APIKey: "k2", BaseURL: "http://groq.test/v1"})
+	if a != b {
+		t.Error("expected the same instance for identical settings")
+	}
+	if a == c {
+		t.Error("expected a separate instance for a different API key")
+	}
+	d, _ := f.Get("groq", Credentials{APIKey: "k1", BaseURL: "http://groq.test/v1", Headers: map[string]string{"X-Org": "a"}})
+	if a == d {
+		t.Error("expected a separate instance for different headers")
+	}
+
+	if _, err := f.Get("nope", Credentials{APIKey: "k"}); err == nil {
+		t.Error("expected an error for an unknown provider")
+	}
+}
+
+func countingChatHandler(hits *atomic.Int32) http.HandlerFunc {
+	chat := defaultChatHandler("ok", nil)
+	return func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		chat(w, r)
+	}
+}
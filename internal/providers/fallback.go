@@ -0,0 +1,88 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+// FallbackTarget is one link in a FallbackProvider chain.
+type FallbackTarget struct {
+	Name     string // labels log lines and errors
+	Provider Provider
+	Model    string // replaces the request's model when set
+}
+
+// FallbackProvider tries its targets in order and returns the first
+// successful response, so a request can fall over from a hosted API to, say,
+// a local Ollama model during an outage. Backends already retry transient
+// failures themselves, so any error they return moves on to the next target.
+// A cancelled or expired context stops the chain.
+type FallbackProvider struct {
+	targets []FallbackTarget
+}
+
+// NewFallbackProvider creates a provider that tries targets in order.
+func NewFallbackProvider(targets ...FallbackTarget) *FallbackProvider {
+	return &FallbackProvider{targets: targets}
+}
+
+// Chat sends req to each target in turn until one succeeds.
+func (f *FallbackProvider) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	return f.try(ctx, req, func(t FallbackTarget, req ChatRequest) (*ChatResponse, bool, error) {
+		resp, err := t.Provider.Chat(ctx, req)
+		return resp, false, err
+	})
+}
+
+// ChatStream streams from each target in turn until one succeeds. Once a
+// target has delivered text it is not abandoned, since the caller has already
+// seen part of its reply; its error is returned instead. Targets that cannot
+// stream deliver their whole reply as a single delta.
+func (f *FallbackProvider) ChatStream(ctx context.Context, req ChatRequest, onDelta func(string)) (*ChatResponse, error) {
+	return f.try(ctx, req, func(t FallbackTarget, req ChatRequest) (*ChatResponse, bool, error) {
+		sp, ok := t.Provider.(StreamingProvider)
+		if !ok {
+			resp, err := t.Provider.Chat(ctx, req)
+			if err == nil && resp.Content != "" {
+				onDelta(resp.Content)
+			}
+			return resp, false, err
+		}
+		streamed := false
+		resp, err := sp.ChatStream(ctx, req, func(delta string) {
+			streamed = true
+			onDelta(delta)
+		})
+		return resp, streamed, err
+	})
+}
+
+// try runs call against each target until one succeeds, call reports that
+// the failure must not fall over, or ctx is done.
+func (f *FallbackProvider) try(ctx context.Context, req ChatRequest, call func(FallbackTarget, ChatRequest) (*ChatResponse, bool, error)) (*ChatResponse, error) {
+	if len(f.targets) == 0 {
+		return nil, errors.New("fallback: no providers configured")
+	}
+	var errs []error
+	for i, t := range f.targets {
+		r := req
+		if t.Model != "" {
+			r.Model = t.Model
+		}
+		resp, final, err := call(t, r)
+		if err == nil {
+			return resp, nil
+		}
+		err = fmt.Errorf("%s: %w", t.Name, err)
+		if final || ctx.Err() != nil {
+			return nil, err
+		}
+		errs = append(errs, err)
+		if i < len(f.targets)-1 {
+			slog.Warn("provider failed, falling back", "provider", t.Name, "next", f.targets[i+1].Name, "err", err)
+		}
+	}
+	return nil, fmt.Errorf("all providers failed: %w", errors.Join(errs...))
+}
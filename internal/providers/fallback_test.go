@@ -0,0 +1,106 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// scriptedProvider records the models it was asked for and fails with err
+// when set.
+type scriptedProvider struct {
+	content string
+	err     error
+	models  []string
+	deltas  []string // streamed before failing or returning, when set
+}
+
+func (s *scriptedProvider) Chat(_ context.Context, req ChatRequest) (*ChatResponse, error) {
+	s.models = append(s.models, req.Model)
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &ChatResponse{Content: s.content}, nil
+}
+
+func (s *scriptedProvider) ChatStream(ctx context.Context, req ChatRequest, onDelta func(string)) (*ChatResponse, error) {
+	for _, d := range s.deltas {
+		onDelta(d)
+	}
+	return s.Chat(ctx, req)
+}
+
+func TestFallbackProviderUsesNextOnError(t *testing.T) {
+	primary := &scriptedProvider{err: errors.New("401 unauthorized")}
+	local := &scriptedProvider{content: "from ollama"}
+	f := NewFallbackProvider(
+		FallbackTarget{Name: "openai", Provider: primary},
+		FallbackTarget{Name: "ollama", Provider: local, Model: "llama3.2"},
+	)
+
+	resp, err := f.Chat(context.Background(), ChatRequest{Model: "gpt-4o"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Content != "from ollama" {
+		t.Errorf("content = %q, want the fallback's", resp.Content)
+	}
+	if primary.models[0] != "gpt-4o" || local.models[0] != "llama3.2" {
+		t.Errorf("models = %v then %v, want gpt-4o then llama3.2", primary.models, local.models)
+	}
+}
+
+func TestFallbackProviderAllFail(t *testing.T) {
+	f := NewFallbackProvider(
+		FallbackTarget{Name: "a", Provider: &scriptedProvider{err: errors.New("down")}},
+		FallbackTarget{Name: "b", Provider: &scriptedProvider{err: errors.New("also down")}},
+	)
+	_, err := f.Chat(context.Background(), ChatRequest{})
+	if err == nil || !strings.Contains(err.Error(), "a: down") || !strings.Contains(err.Error(), "b: also down") {
+		t.Errorf("err = %v, want both failures reported", err)
+	}
+}
+
+func TestFallbackProviderStopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	next := &scriptedProvider{content: "unused"}
+	f := NewFallbackProvider(
+		FallbackTarget{Name: "a", Provider: &scriptedProvider{err: context.Canceled}},
+		FallbackTarget{Name: "b", Provider: next},
+	)
+	if _, err := f.Chat(ctx, ChatRequest{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+	if len(next.models) != 0 {
+		t.Error("fallback tried after the context was cancelled")
+	}
+}
+
+func TestFallbackProviderStream(t *testing.T) {
+	var got strings.Builder
+	f := NewFallbackProvider(
+		FallbackTarget{Name: "a", Provider: &scriptedProvider{err: errors.New("down")}},
+		FallbackTarget{Name: "b", Provider: &scriptedProvider{content: "hello", deltas: []string{"hel", "lo"}}},
+	)
+	if _, err := f.ChatStream(context.Background(), ChatRequest{}, func(d string) { got.WriteString(d) }); err != nil {
+		t.Fatal(err)
+	}
+	if got.String() != "hello" {
+		t.Errorf("streamed %q, want hello", got.String())
+	}
+
+	// A target that fails after streaming text is not abandoned.
+	next := &scriptedProvider{content: "unused"}
+	f = NewFallbackProvider(
+		FallbackTarget{Name: "a", Provider: &scriptedProvider{err: errors.New("reset"), deltas: []string{"par"}}},
+		FallbackTarget{Name: "b", Provider: next},
+	)
+	if _, err := f.ChatStream(context.Background(), ChatRequest{}, func(string) {}); err == nil {
+		t.Error("expected the mid-stream failure to be returned")
+	}
+	if len(next.models) != 0 {
+		t.Error("fell back after text was already streamed")
+	}
+}
@@ -2,18 +2,29 @@ package providers
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
 	"strings"
+	"time"
 
 	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/coopco/nanobot/internal/tlsutil"
 )
 
 // OpenAICompatProvider works with OpenAI and any OpenAI-compatible API.
 type OpenAICompatProvider struct {
 	client       *openai.Client
+	httpClient   *http.Client          // shared with client so SetTimeout takes effect
+	streamClient *openai.Client        // like client, but with no overall timeout
+	streamHTTP   *http.Client          // shared with streamClient
+	parts        *contentPartTransport // innermost transport; SetTLS replaces its base
 	defaultModel string
 	modelPrefix  string
 	skipPrefixes []string
+	overrides    map[string]map[string]any // per-model parameter quirks, see ProviderSpec.ModelOverrides
 }
 
 // NewOpenAICompatProvider creates a provider with an explicit base URL.
@@ -22,8 +33,20 @@ func NewOpenAICompatProvider(apiKey, baseURL, defaultModel string) *OpenAICompat
 	if baseURL != "" {
 		cfg.BaseURL = baseURL
 	}
+	parts := &contentPartTransport{}
+	httpClient := &http.Client{Timeout: defaultRequestTimeout, Transport: parts}
+	cfg.HTTPClient = httpClient
+	// http.Client.Timeout covers reading the body, which would cut long
+	// streams off; ChatStream applies the timeout between chunks instead.
+	streamCfg := cfg
+	streamHTTP := &http.Client{Transport: parts}
+	streamCfg.HTTPClient = streamHTTP
 	return &OpenAICompatProvider{
 		client:       openai.NewClientWithConfig(cfg),
+		httpClient:   httpClient,
+		streamClient: openai.NewClientWithConfig(streamCfg),
+		streamHTTP:   streamHTTP,
+		parts:        parts,
 		defaultModel: defaultModel,
 	}
 }
@@ -37,9 +60,59 @@ func NewOpenAICompatProviderFromSpec(spec *ProviderSpec, apiKey, baseURL string)
 	p := NewOpenAICompatProvider(apiKey, base, "")
 	p.modelPrefix = spec.ModelPrefix
 	p.skipPrefixes = spec.SkipPrefixes
+	p.overrides = spec.ModelOverrides
 	return p
 }
 
+// SetTimeout caps each HTTP request at d. For streams it caps the wait for
+// the response and for each chunk after it instead. d <= 0 restores the
+// default. Call it before the provider is used.
+func (p *OpenAICompatProvider) SetTimeout(d time.Duration) {
+	if d <= 0 {
+		d = defaultRequestTimeout
+	}
+	p.httpClient.Timeout = d
+}
+
+// SetTLS changes how the provider verifies the server's certificate, for
+// self-hosted endpoints with a private CA. Call it before the provider is
+// used.
+func (p *OpenAICompatProvider) SetTLS(opts tlsutil.Options) error {
+	transport, err := opts.Transport()
+	if err != nil {
+		return err
+	}
+	p.parts.base = transport
+	return nil
+}
+
+// SetHeaders adds headers to every request, replacing any set before, for
+// gateways that want more than the API key, such as OpenRouter's
+// HTTP-Referer and X-Title. Call it before the provider is used.
+func (p *OpenAICompatProvider) SetHeaders(headers map[string]string) {
+	var transport http.RoundTripper = p.parts
+	if len(headers) > 0 {
+		transport = &headerTransport{base: p.parts, headers: headers}
+	}
+	p.httpClient.Transport = transport
+	p.streamHTTP.Transport = transport
+}
+
+// headerTransport sets fixed headers on each request before sending it
+// with base.
+type headerTransport struct {
+	base    http.RoundTripper
+	headers map[string]string
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	out := req.Clone(req.Context())
+	for k, v := range t.headers {
+		out.Header.Set(k, v)
+	}
+	return t.base.RoundTrip(out)
+}
+
 // resolveModel applies the model prefix if needed.
 func (p *OpenAICompatProvider) resolveModel(model string) string {
 	if p.modelPrefix == "" {
@@ -55,11 +128,145 @@ func (p *OpenAICompatProvider) resolveModel(model string) string {
 
 // Chat sends a chat completion request and returns the response.
 func (p *OpenAICompatProvider) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	if err := req.ResponseFormat.Validate(); err != nil {
+		return nil, err
+	}
+	resp, err := p.client.CreateChatCompletion(ctx, p.buildRequest(req))
+	if err != nil {
+		return nil, fmt.Errorf("chat completion failed: %w", err)
+	}
+
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("no choices in response")
+	}
+
+	choice := resp.Choices[0]
+	out := &ChatResponse{
+		Content:          choice.Message.Content,
+		ReasoningContent: choice.Message.ReasoningContent,
+		StopReason:       normalizeStopReason(string(choice.FinishReason)),
+		Usage:            convertOpenAIUsage(resp.Usage),
+	}
+
+	for _, tc := range choice.Message.ToolCalls {
+		out.ToolCalls = append(out.ToolCalls, ToolCall{
+			ID:        tc.ID,
+			Name:      tc.Function.Name,
+			Arguments: tc.Function.Arguments,
+		})
+	}
+
+	return out, nil
+}
+
+// ChatStream implements StreamingProvider. Text is passed to onDelta as it
+// arrives; tool calls are assembled from their fragments and returned with
+// the complete response.
+func (p *OpenAICompatProvider) ChatStream(ctx context.Context, req ChatRequest, onDelta func(string)) (*ChatResponse, error) {
+	if err := req.ResponseFormat.Validate(); err != nil {
+		return nil, err
+	}
+	oaiReq := p.buildRequest(req)
+	oaiReq.Stream = true
+	oaiReq.StreamOptions = &openai.StreamOptions{IncludeUsage: true}
+
+	// The stream is abandoned if the server goes quiet for longer than the
+	// request timeout, however long the reply as a whole takes.
+	timeout := p.httpClient.Timeout
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	idle := time.AfterFunc(timeout, cancel)
+	defer idle.Stop()
+	stalled := func(err error) error {
+		if ctx.Err() == nil && streamCtx.Err() != nil {
+			return fmt.Errorf("chat completion stream failed: no data for %s", timeout)
+		}
+		return fmt.Errorf("chat completion stream failed: %w", err)
+	}
+
+	stream, err := p.streamClient.CreateChatCompletionStream(streamCtx, oaiReq)
+	if err != nil {
+		return nil, stalled(err)
+	}
+	defer stream.Close()
+
+	var (
+		content   strings.Builder
+		reasoning strings.Builder
+		out       ChatResponse
+		calls     []ToolCall // indexed by the tool call's stream index
+	)
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, stalled(err)
+		}
+		idle.Reset(timeout)
+		if chunk.Usage != nil {
+			out.Usage = convertOpenAIUsage(*chunk.Usage)
+		}
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+		choice := chunk.Choices[0]
+		if choice.FinishReason != "" {
+			out.StopReason = normalizeStopReason(string(choice.FinishReason))
+		}
+		reasoning.WriteString(choice.Delta.ReasoningContent)
+		if d := choice.Delta.Content; d != "" {
+			content.WriteString(d)
+			onDelta(d)
+		}
+		for _, tc := range choice.Delta.ToolCalls {
+			i := len(calls)
+			if tc.Index != nil {
+				i = *tc.Index
+			}
+			for len(calls) <= i {
+				calls = append(calls, ToolCall{})
+			}
+			if tc.ID != "" {
+				calls[i].ID = tc.ID
+			}
+			if tc.Function.Name != "" {
+				calls[i].Name = tc.Function.Name
+			}
+			calls[i].Arguments += tc.Function.Arguments
+		}
+	}
+
+	out.Content = content.String()
+	out.ReasoningContent = reasoning.String()
+	out.ToolCalls = calls
+	return &out, nil
+}
+
+func convertOpenAIUsage(u openai.Usage) Usage {
+	out := Usage{
+		PromptTokens:     u.PromptTokens,
+		CompletionTokens: u.CompletionTokens,
+		TotalTokens:      u.TotalTokens,
+	}
+	if d := u.PromptTokensDetails; d != nil {
+		out.CacheReadTokens = d.CachedTokens
+	}
+	if d := u.CompletionTokensDetails; d != nil {
+		out.ReasoningTokens = d.ReasoningTokens
+	}
+	return out
+}
+
+// buildRequest converts req into a chat completion request for the API.
+func (p *OpenAICompatProvider) buildRequest(req ChatRequest) openai.ChatCompletionRequest {
 	model := req.Model
 	if model == "" {
 		model = p.defaultModel
 	}
 	model = p.resolveModel(model)
+	useMaxCompletion := applyOverrides(&req, matchOverrides(p.overrides, model))
 
 	var msgs []openai.ChatCompletionMessage
 
@@ -71,7 +278,7 @@ func (p *OpenAICompatProvider) Chat(ctx context.Context, req ChatRequest) (*Chat
 		})
 	}
 
-	for _, m := range req.Messages {
+	for _, m := range normalizeToolCallIDs(req.Messages) {
 		msg := openai.ChatCompletionMessage{
 			Role: m.Role,
 		}
@@ -94,6 +301,10 @@ func (p *OpenAICompatProvider) Chat(ctx context.Context, req ChatRequest) (*Chat
 							},
 						})
 					}
+				case "input_audio", "file":
+					if part, ok := rawContentPart(p); ok {
+						msg.MultiContent = append(msg.MultiContent, part)
+					}
 				}
 			}
 			// Prepend text content as a text part if both are set.
@@ -131,11 +342,41 @@ func (p *OpenAICompatProvider) Chat(ctx context.Context, req ChatRequest) (*Chat
 		Messages: msgs,
 	}
 	if req.MaxTokens > 0 {
-		oaiReq.MaxTokens = req.MaxTokens
+		if useMaxCompletion {
+			oaiReq.MaxCompletionTokens = req.MaxTokens
+		} else {
+			oaiReq.MaxTokens = req.MaxTokens
+		}
 	}
 	if req.Temperature != 0 {
 		oaiReq.Temperature = float32(req.Temperature)
 	}
+	if req.TopP != 0 {
+		oaiReq.TopP = float32(req.TopP)
+	}
+	if len(req.Stop) > 0 {
+		oaiReq.Stop = req.Stop
+	}
+	if req.FrequencyPenalty != 0 {
+		oaiReq.FrequencyPenalty = float32(req.FrequencyPenalty)
+	}
+	if req.PresencePenalty != 0 {
+		oaiReq.PresencePenalty = float32(req.PresencePenalty)
+	}
+	if f := req.ResponseFormat; f != nil {
+		oaiReq.ResponseFormat = &openai.ChatCompletionResponseFormat{Type: openai.ChatCompletionResponseFormatType(f.Type)}
+		if f.Type == ResponseFormatJSONSchema {
+			name := f.Name
+			if name == "" {
+				name = "response"
+			}
+			oaiReq.ResponseFormat.JSONSchema = &openai.ChatCompletionResponseFormatJSONSchema{
+				Name:   name,
+				Schema: f.Schema,
+				Strict: f.Strict,
+			}
+		}
+	}
 
 	for _, t := range req.Tools {
 		oaiReq.Tools = append(oaiReq.Tools, openai.Tool{
@@ -148,33 +389,5 @@ func (p *OpenAICompatProvider) Chat(ctx context.Context, req ChatRequest) (*Chat
 		})
 	}
 
-	resp, err := p.client.CreateChatCompletion(ctx, oaiReq)
-	if err != nil {
-		return nil, fmt.Errorf("chat completion failed: %w", err)
-	}
-
-	if len(resp.Choices) == 0 {
-		return nil, fmt.Errorf("no choices in response")
-	}
-
-	choice := resp.Choices[0]
-	out := &ChatResponse{
-		Content:    choice.Message.Content,
-		StopReason: string(choice.FinishReason),
-		Usage: Usage{
-			PromptTokens:     resp.Usage.PromptTokens,
-			CompletionTokens: resp.Usage.CompletionTokens,
-			TotalTokens:      resp.Usage.TotalTokens,
-		},
-	}
-
-	for _, tc := range choice.Message.ToolCalls {
-		out.ToolCalls = append(out.ToolCalls, ToolCall{
-			ID:        tc.ID,
-			Name:      tc.Function.Name,
-			Arguments: tc.Function.Arguments,
-		})
-	}
-
-	return out, nil
+	return oaiReq
 }
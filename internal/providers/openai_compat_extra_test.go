@@ -3,9 +3,17 @@ package providers
 import (
 	"context"
 	"encoding/json"
+	"encoding/pem"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/coopco/nanobot/internal/tlsutil"
 )
 
 // mockOpenAIServer creates a test server that returns a valid ChatCompletion response.
@@ -231,6 +239,61 @@ func TestOpenAIChat_MultimodalContentParts(t *testing.T) {
 	}
 }
 
+func TestOpenAIChat_AudioAndFileParts(t *testing.T) {
+	var receivedBody struct {
+		Messages []struct {
+			Content []map[string]any `json:"content"`
+		} `json:"messages"`
+	}
+	srv := mockOpenAIServer(t, func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&receivedBody)
+		defaultChatHandler("ok", nil)(w, r)
+	})
+	defer srv.Close()
+
+	p := NewOpenAICompatProvider("test-key", srv.URL, "gpt-4o-audio-preview")
+	_, err := p.Chat(context.Background(), ChatRequest{
+		Messages: []Message{{
+			Role:    "user",
+			Content: "summarise these",
+			ContentParts: []ContentPart{
+				{Type: "input_audio", InputAudio: &InputAudio{Data: "UklGRg==", Format: "wav"}},
+				{Type: "file", File: &FilePart{Filename: "report.pdf", FileData: "data:application/pdf;base64,JVBERg=="}},
+			},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(receivedBody.Messages) != 1 || len(receivedBody.Messages[0].Content) != 3 {
+		t.Fatalf("messages = %+v, want one message with three parts", receivedBody.Messages)
+	}
+	parts := receivedBody.Messages[0].Content
+	if parts[0]["type"] != "text" || parts[0]["text"] != "summarise these" {
+		t.Errorf("part 0 = %v, want the text", parts[0])
+	}
+	audio, _ := parts[1]["input_audio"].(map[string]any)
+	if parts[1]["type"] != "input_audio" || audio["data"] != "UklGRg==" || audio["format"] != "wav" || parts[1]["text"] != nil {
+		t.Errorf("part 1 = %v, want an input_audio block", parts[1])
+	}
+	file, _ := parts[2]["file"].(map[string]any)
+	if parts[2]["type"] != "file" || file["filename"] != "report.pdf" || file["file_data"] != "data:application/pdf;base64,JVBERg==" || parts[2]["text"] != nil {
+		t.Errorf("part 2 = %v, want a file block", parts[2])
+	}
+}
+
+func TestRewriteContentPartsLeavesOtherBodiesAlone(t *testing.T) {
+	for _, body := range []string{
+		`{"messages":[{"role":"user","content":"hi"}]}`,
+		`{"messages":[{"role":"user","content":[{"type":"text","text":"{\"type\":\"file\"}"}]}]}`,
+		`not json "type":"file"`,
+	} {
+		if _, ok := rewriteContentParts([]byte(body)); ok {
+			t.Errorf("rewrote %s", body)
+		}
+	}
+}
+
 func TestOpenAIChat_ToolCallIDAndToolRole(t *testing.T) {
 	srv := mockOpenAIServer(t, defaultChatHandler("final answer", nil))
 	defer srv.Close()
@@ -250,3 +313,405 @@ func TestOpenAIChat_ToolCallIDAndToolRole(t *testing.T) {
 		t.Errorf("Content = %q, want %q", resp.Content, "final answer")
 	}
 }
+
+func TestOpenAIChat_SamplingControls(t *testing.T) {
+	var receivedBody map[string]any
+	srv := mockOpenAIServer(t, func(w http.ResponseWriter, r *http.Request) {
+		receivedBody = nil
+		json.NewDecoder(r.Body).Decode(&receivedBody)
+		defaultChatHandler("ok", nil)(w, r)
+	})
+	defer srv.Close()
+
+	p := NewOpenAICompatProvider("test-key", srv.URL, "gpt-4o")
+	_, err := p.Chat(context.Background(), ChatRequest{
+		Messages:         []Message{{Role: "user", Content: "hi"}},
+		TopP:             0.9,
+		Stop:             []string{"\n\n", "END"},
+		FrequencyPenalty: 0.5,
+		PresencePenalty:  -0.25,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	approx := func(key string, want float64) {
+		got, ok := receivedBody[key].(float64)
+		if !ok || got < want-1e-6 || got > want+1e-6 {
+			t.Errorf("%s = %v, want %v", key, receivedBody[key], want)
+		}
+	}
+	approx("top_p", 0.9)
+	approx("frequency_penalty", 0.5)
+	approx("presence_penalty", -0.25)
+	stop, _ := receivedBody["stop"].([]any)
+	if len(stop) != 2 || stop[1] != "END" {
+		t.Errorf("stop = %v, want [\\n\\n END]", receivedBody["stop"])
+	}
+
+	// Unset controls are omitted entirely.
+	if _, err := p.Chat(context.Background(), ChatRequest{Messages: []Message{{Role: "user", Content: "hi"}}}); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"top_p", "stop", "frequency_penalty", "presence_penalty"} {
+		if _, ok := receivedBody[key]; ok {
+			t.Errorf("%s sent although unset", key)
+		}
+	}
+}
+
+func TestOpenAIChat_ResponseFormat(t *testing.T) {
+	var receivedBody map[string]any
+	srv := mockOpenAIServer(t, func(w http.ResponseWriter, r *http.Request) {
+		receivedBody = nil
+		json.NewDecoder(r.Body).Decode(&receivedBody)
+		defaultChatHandler(`{"ok":true}`, nil)(w, r)
+	})
+	defer srv.Close()
+	p := NewOpenAICompatProvider("test-key", srv.URL, "gpt-4o")
+	msgs := []Message{{Role: "user", Content: "hi"}}
+
+	_, err := p.Chat(context.Background(), ChatRequest{Messages: msgs, ResponseFormat: &ResponseFormat{Type: ResponseFormatJSONObject}})
+	if err != nil {
+		t.Fatalf("json_object: %v", err)
+	}
+	rf, _ := receivedBody["response_format"].(map[string]any)
+	if rf["type"] != "json_object" || rf["json_schema"] != nil {
+		t.Errorf("response_format = %v, want {type: json_object}", receivedBody["response_format"])
+	}
+
+	schema := json.RawMessage(`{"type":"object","properties":{"ok":{"type":"boolean"}},"required":["ok"]}`)
+	_, err = p.Chat(context.Background(), ChatRequest{
+		Messages:       msgs,
+		ResponseFormat: &ResponseFormat{Type: ResponseFormatJSONSchema, Name: "status", Schema: schema, Strict: true},
+	})
+	if err != nil {
+		t.Fatalf("json_schema: %v", err)
+	}
+	rf, _ = receivedBody["response_format"].(map[string]any)
+	js, _ := rf["json_schema"].(map[string]any)
+	if rf["type"] != "json_schema" || js["name"] != "status" || js["strict"] != true {
+		t.Errorf("response_format = %v, want a strict json_schema named status", receivedBody["response_format"])
+	}
+	if got, _ := json.Marshal(js["schema"]); !strings.Contains(string(got), `"required":["ok"]`) {
+		t.Errorf("schema = %s, want the request schema", got)
+	}
+
+	if _, err := p.Chat(context.Background(), ChatRequest{Messages: msgs}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := receivedBody["response_format"]; ok {
+		t.Error("response_format sent although unset")
+	}
+}
+
+func TestResponseFormatValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  *ResponseFormat
+		wantErr string
+	}{
+		{"nil", nil, ""},
+		{"json object", &ResponseFormat{Type: ResponseFormatJSONObject}, ""},
+		{"json schema", &ResponseFormat{Type: ResponseFormatJSONSchema, Schema: json.RawMessage(`{"type":"object"}`)}, ""},
+		{"schema missing", &ResponseFormat{Type: ResponseFormatJSONSchema}, "requires a schema"},
+		{"schema malformed", &ResponseFormat{Type: ResponseFormatJSONSchema, Schema: json.RawMessage(`{"type":`)}, "not valid JSON"},
+		{"schema on json object", &ResponseFormat{Type: ResponseFormatJSONObject, Schema: json.RawMessage(`{}`)}, "does not take a schema"},
+		{"unknown type", &ResponseFormat{Type: "yaml"}, "unknown response format"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.format.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	// The provider refuses an invalid format before calling the API.
+	called := false
+	srv := mockOpenAIServer(t, func(w http.ResponseWriter, r *http.Request) { called = true })
+	defer srv.Close()
+	p := NewOpenAICompatProvider("test-key", srv.URL, "gpt-4o")
+	_, err := p.Chat(context.Background(), ChatRequest{
+		Messages:       []Message{{Role: "user", Content: "hi"}},
+		ResponseFormat: &ResponseFormat{Type: ResponseFormatJSONSchema},
+	})
+	if err == nil || called {
+		t.Errorf("err = %v, called = %v; want an error without an API call", err, called)
+	}
+}
+
+func TestOpenAIChat_ModelOverrides(t *testing.T) {
+	var receivedBody map[string]any
+	srv := mockOpenAIServer(t, func(w http.ResponseWriter, r *http.Request) {
+		receivedBody = nil
+		json.NewDecoder(r.Body).Decode(&receivedBody)
+		defaultChatHandler("ok", nil)(w, r)
+	})
+	defer srv.Close()
+
+	p := NewOpenAICompatProviderFromSpec(FindByName("openai"), "test-key", srv.URL)
+	req := ChatRequest{
+		Messages:    []Message{{Role: "user", Content: "hi"}},
+		MaxTokens:   1000,
+		Temperature: 0.7,
+		TopP:        0.9,
+	}
+
+	req.Model = "o1-mini"
+	if _, err := p.Chat(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"temperature", "top_p", "max_tokens"} {
+		if _, ok := receivedBody[key]; ok {
+			t.Errorf("o1-mini request still carries %s", key)
+		}
+	}
+	if mct, _ := receivedBody["max_completion_tokens"].(float64); mct != 1000 {
+		t.Errorf("max_completion_tokens = %v, want 1000", receivedBody["max_completion_tokens"])
+	}
+
+	req.Model = "gpt-4o"
+	if _, err := p.Chat(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := receivedBody["temperature"]; !ok {
+		t.Error("gpt-4o request lost its temperature")
+	}
+	if _, ok := receivedBody["max_tokens"]; !ok {
+		t.Error("gpt-4o request lost max_tokens")
+	}
+}
+
+func TestOpenAIChat_Timeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := mockOpenAIServer(t, func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	})
+	defer srv.Close()
+	defer close(release)
+
+	p := NewOpenAICompatProvider("key", srv.URL, "gpt-4o")
+	p.SetTimeout(100 * time.Millisecond)
+
+	start := time.Now()
+	_, err := p.Chat(context.Background(), ChatRequest{Messages: []Message{{Role: "user", Content: "hi"}}})
+	if err == nil {
+		t.Fatal("expected a timeout error")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Chat took %v, want it to give up after the timeout", elapsed)
+	}
+}
+
+func TestOpenAIChat_ExtraHeaders(t *testing.T) {
+	var got http.Header
+	chat := defaultChatHandler("ok", nil)
+	srv := mockOpenAIServer(t, func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		chat(w, r)
+	})
+	defer srv.Close()
+
+	r := NewProviderRouter(map[string]Credentials{
+		"openrouter": {
+			APIKey:  "key",
+			BaseURL: srv.URL,
+			Headers: map[string]string{"HTTP-Referer": "https://example.com", "X-Title": "nanobot"},
+		},
+	}, nil)
+	if _, err := r.Chat(context.Background(), ChatRequest{Model: "openrouter/gpt-4o", Messages: []Message{{Role: "user", Content: "hi"}}}); err != nil {
+		t.Fatal(err)
+	}
+	if got.Get("HTTP-Referer") != "https://example.com" || got.Get("X-Title") != "nanobot" {
+		t.Errorf("extra headers missing from request: %v", got)
+	}
+	if got.Get("Authorization") != "Bearer key" {
+		t.Errorf("Authorization = %q, want the API key kept", got.Get("Authorization"))
+	}
+}
+
+func TestOpenAIChatStream(t *testing.T) {
+	chunks := []string{
+		`{"choices":[{"index":0,"delta":{"role":"assistant","content":"Let me "}}]}`,
+		`{"choices":[{"index":0,"delta":{"content":"check."}}]}`,
+		`{"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"search","arguments":"{\"q\":"}}]}}]}`,
+		`{"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"go\"}"}}]}}]}`,
+		`{"choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}`,
+		`{"choices":[],"usage":{"prompt_tokens":12,"completion_tokens":7,"total_tokens":19}}`,
+	}
+	srv := mockOpenAIServer(t, func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["stream"] != true {
+			t.Errorf("request did not ask for a stream: %v", body)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, c := range chunks {
+			fmt.Fprintf(w, "data: %s\n\n", c)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	})
+	defer srv.Close()
+
+	p := NewOpenAICompatProvider("key", srv.URL, "gpt-4o")
+	var deltas []string
+	resp, err := p.ChatStream(context.Background(), ChatRequest{Messages: []Message{{Role: "user", Content: "hi"}}}, func(d string) {
+		deltas = append(deltas, d)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(deltas, "|") != "Let me |check." {
+		t.Errorf("deltas = %q", deltas)
+	}
+	if resp.Content != "Let me check." || resp.StopReason != StopReasonToolUse {
+		t.Errorf("response = %+v", resp)
+	}
+	if len(resp.ToolCalls) != 1 || resp.ToolCalls[0].ID != "call_1" || resp.ToolCalls[0].Name != "search" || resp.ToolCalls[0].Arguments != `{"q":"go"}` {
+		t.Errorf("tool calls = %+v, want one assembled search call", resp.ToolCalls)
+	}
+	if resp.Usage.TotalTokens != 19 {
+		t.Errorf("usage = %+v, want the final usage chunk", resp.Usage)
+	}
+}
+
+// slowStreamServer streams one word every gap, then ends the stream, or
+// stalls after the first word when stall is set.
+func slowStreamServer(t *testing.T, words []string, gap time.Duration, stall bool) *httptest.Server {
+	return mockOpenAIServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i, word := range words {
+			if i > 0 {
+				if stall {
+					<-r.Context().Done()
+					return
+				}
+				time.Sleep(gap)
+			}
+			fmt.Fprintf(w, "data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":%q}}]}\n\n", word)
+			w.(http.Flusher).Flush()
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	})
+}
+
+func TestOpenAIChatStream_OutlastsRequestTimeout(t *testing.T) {
+	words := []string{"a ", "slow ", "but ", "steady ", "reply"}
+	srv := slowStreamServer(t, words, 100*time.Millisecond, false)
+	defer srv.Close()
+
+	p := NewOpenAICompatProvider("key", srv.URL, "gpt-4o")
+	p.SetTimeout(250 * time.Millisecond)
+	resp, err := p.ChatStream(context.Background(), ChatRequest{Messages: []Message{{Role: "user", Content: "hi"}}}, func(string) {})
+	if err != nil {
+		t.Fatalf("stream longer than the request timeout failed: %v", err)
+	}
+	if resp.Content != strings.Join(words, "") {
+		t.Errorf("content = %q, want the whole reply", resp.Content)
+	}
+}
+
+func TestOpenAIChatStream_StalledStreamTimesOut(t *testing.T) {
+	srv := slowStreamServer(t, []string{"first ", "never"}, 0, true)
+	defer srv.Close()
+
+	p := NewOpenAICompatProvider("key", srv.URL, "gpt-4o")
+	p.SetTimeout(200 * time.Millisecond)
+	start := time.Now()
+	_, err := p.ChatStream(context.Background(), ChatRequest{Messages: []Message{{Role: "user", Content: "hi"}}}, func(string) {})
+	if err == nil || !strings.Contains(err.Error(), "no data for 200ms") {
+		t.Fatalf("err = %v, want a stall error", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("stalled stream took %v to fail", elapsed)
+	}
+}
+
+func TestOpenAIChat_ReasoningUsage(t *testing.T) {
+	srv := mockOpenAIServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{
+			"choices": [{"index": 0, "finish_reason": "stop", "message": {
+				"role": "assistant", "content": "42", "reasoning_content": "Six times seven."}}],
+			"usage": {"prompt_tokens": 10, "completion_tokens": 30, "total_tokens": 40,
+				"completion_tokens_details": {"reasoning_tokens": 25}}
+		}`)
+	})
+	defer srv.Close()
+
+	p := NewOpenAICompatProvider("key", srv.URL, "deepseek-reasoner")
+	resp, err := p.Chat(context.Background(), ChatRequest{Messages: []Message{{Role: "user", Content: "6*7?"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Content != "42" || resp.ReasoningContent != "Six times seven." {
+		t.Errorf("response = %+v, want answer and reasoning kept apart", resp)
+	}
+	if resp.Usage.ReasoningTokens != 25 || resp.Usage.CompletionTokens != 30 {
+		t.Errorf("usage = %+v, want 25 reasoning of 30 completion tokens", resp.Usage)
+	}
+}
+
+func TestOpenAIChatStream_Reasoning(t *testing.T) {
+	chunks := []string{
+		`{"choices":[{"index":0,"delta":{"role":"assistant","reasoning_content":"Six "}}]}`,
+		`{"choices":[{"index":0,"delta":{"reasoning_content":"times seven."}}]}`,
+		`{"choices":[{"index":0,"delta":{"content":"42"},"finish_reason":"stop"}]}`,
+		`{"choices":[],"usage":{"prompt_tokens":10,"completion_tokens":30,"total_tokens":40,"completion_tokens_details":{"reasoning_tokens":25}}}`,
+	}
+	srv := mockOpenAIServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, c := range chunks {
+			fmt.Fprintf(w, "data: %s\n\n", c)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	})
+	defer srv.Close()
+
+	p := NewOpenAICompatProvider("key", srv.URL, "deepseek-reasoner")
+	var deltas []string
+	resp, err := p.ChatStream(context.Background(), ChatRequest{Messages: []Message{{Role: "user", Content: "6*7?"}}}, func(d string) {
+		deltas = append(deltas, d)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(deltas, "|") != "42" {
+		t.Errorf("deltas = %q, want only answer text", deltas)
+	}
+	if resp.ReasoningContent != "Six times seven." || resp.Usage.ReasoningTokens != 25 {
+		t.Errorf("response = %+v, want assembled reasoning and reasoning tokens", resp)
+	}
+}
+
+func TestOpenAIChat_CustomCA(t *testing.T) {
+	srv := httptest.NewTLSServer(defaultChatHandler("secure hello", nil))
+	defer srv.Close()
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}), 0o644)
+	req := ChatRequest{Messages: []Message{{Role: "user", Content: "hi"}}}
+
+	p := NewOpenAICompatProvider("key", srv.URL, "local-model")
+	if _, err := p.Chat(context.Background(), req); err == nil {
+		t.Fatal("expected a certificate error without the CA")
+	}
+
+	if err := p.SetTLS(tlsutil.Options{CAFile: caFile}); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := p.Chat(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Chat with the server's CA: %v", err)
+	}
+	if resp.Content != "secure hello" {
+		t.Errorf("content = %q", resp.Content)
+	}
+}
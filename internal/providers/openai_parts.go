@@ -0,0 +1,112 @@
+package providers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// go-openai's ChatMessagePart only models text and image parts. Audio and
+// file parts are therefore built as parts of their own type carrying the
+// block as JSON in Text, and contentPartTransport moves that JSON to the
+// field the API expects before the request is sent:
+//
+//	{"type":"file","text":"{\"filename\":...}"} -> {"type":"file","file":{"filename":...}}
+
+// rawContentPart returns the placeholder part for an input_audio or file
+// part, or false if p has no content.
+func rawContentPart(p ContentPart) (openai.ChatMessagePart, bool) {
+	var block any
+	switch {
+	case p.Type == "input_audio" && p.InputAudio != nil:
+		block = p.InputAudio
+	case p.Type == "file" && p.File != nil:
+		block = p.File
+	default:
+		return openai.ChatMessagePart{}, false
+	}
+	raw, _ := json.Marshal(block)
+	return openai.ChatMessagePart{Type: openai.ChatMessagePartType(p.Type), Text: string(raw)}, true
+}
+
+// contentPartTransport rewrites placeholder parts made by rawContentPart in
+// request bodies, then sends the request with base, or
+// http.DefaultTransport when base is nil.
+type contentPartTransport struct {
+	base http.RoundTripper
+}
+
+func (t *contentPartTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if req.Body == nil || req.Method != http.MethodPost {
+		return base.RoundTrip(req)
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	if rewritten, ok := rewriteContentParts(body); ok {
+		body = rewritten
+	}
+	out := req.Clone(req.Context())
+	out.Body = io.NopCloser(bytes.NewReader(body))
+	out.ContentLength = int64(len(body))
+	out.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	return base.RoundTrip(out)
+}
+
+// rewriteContentParts replaces the placeholder parts in a chat completion
+// request body. It reports false, leaving body alone, when there are none.
+func rewriteContentParts(body []byte) ([]byte, bool) {
+	if !bytes.Contains(body, []byte(`"type":"input_audio"`)) && !bytes.Contains(body, []byte(`"type":"file"`)) {
+		return nil, false
+	}
+	var req map[string]json.RawMessage
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, false
+	}
+	var msgs []map[string]json.RawMessage
+	if err := json.Unmarshal(req["messages"], &msgs); err != nil {
+		return nil, false
+	}
+	changed := false
+	for _, msg := range msgs {
+		var parts []map[string]json.RawMessage
+		if err := json.Unmarshal(msg["content"], &parts); err != nil {
+			continue // plain string content
+		}
+		partsChanged := false
+		for _, part := range parts {
+			var typ, text string
+			json.Unmarshal(part["type"], &typ)
+			if (typ != "input_audio" && typ != "file") || json.Unmarshal(part["text"], &text) != nil {
+				continue
+			}
+			delete(part, "text")
+			part[typ] = json.RawMessage(text)
+			partsChanged = true
+		}
+		if partsChanged {
+			msg["content"], _ = json.Marshal(parts)
+			changed = true
+		}
+	}
+	if !changed {
+		return nil, false
+	}
+	req["messages"], _ = json.Marshal(msgs)
+	out, err := json.Marshal(req)
+	if err != nil {
+		return nil, false
+	}
+	return out, true
+}
@@ -3,13 +3,28 @@ package providers
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
 )
 
+// defaultRequestTimeout bounds a single HTTP call to an LLM API so a stuck
+// upstream cannot hang the agent loop when the caller's context has no deadline.
+const defaultRequestTimeout = 120 * time.Second
+
 // Provider is the LLM provider interface
 type Provider interface {
 	Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error)
 }
 
+// StreamingProvider is implemented by providers that can deliver response
+// text incrementally. ChatStream calls onDelta with each text fragment as it
+// arrives and returns the complete response, as Chat would, once done.
+type StreamingProvider interface {
+	Provider
+	ChatStream(ctx context.Context, req ChatRequest, onDelta func(string)) (*ChatResponse, error)
+}
+
 type ChatRequest struct {
 	Model        string    `json:"model"`
 	Messages     []Message `json:"messages"`
@@ -17,20 +32,116 @@ type ChatRequest struct {
 	MaxTokens    int       `json:"max_tokens,omitempty"`
 	Temperature  float64   `json:"temperature,omitempty"`
 	SystemPrompt string    `json:"-"` // handled separately by some providers
+
+	// Optional sampling controls; zero values leave the provider default.
+	TopP             float64  `json:"top_p,omitempty"`
+	Stop             []string `json:"stop,omitempty"`
+	FrequencyPenalty float64  `json:"frequency_penalty,omitempty"`
+	PresencePenalty  float64  `json:"presence_penalty,omitempty"`
+
+	// ResponseFormat asks for JSON output. Nil leaves the reply free-form.
+	// Providers without JSON mode ignore it.
+	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
+
+	// ThinkingBudget turns on extended thinking, letting the model spend up
+	// to this many tokens reasoning before it answers. Zero disables it.
+	// Providers without extended thinking ignore it.
+	ThinkingBudget int `json:"thinking_budget,omitempty"`
+}
+
+// Values of ResponseFormat.Type.
+const (
+	ResponseFormatText       = "text"
+	ResponseFormatJSONObject = "json_object" // any valid JSON object
+	ResponseFormatJSONSchema = "json_schema" // JSON matching Schema
+)
+
+// ResponseFormat constrains the shape of a reply.
+type ResponseFormat struct {
+	Type   string          `json:"type"`
+	Name   string          `json:"name,omitempty"`   // names the schema; defaults to "response"
+	Schema json.RawMessage `json:"schema,omitempty"` // JSON Schema, required for ResponseFormatJSONSchema
+	Strict bool            `json:"strict,omitempty"` // require the reply to follow Schema exactly
+}
+
+// Validate reports a response format that no provider could honour. A nil
+// format is valid.
+func (f *ResponseFormat) Validate() error {
+	if f == nil {
+		return nil
+	}
+	switch f.Type {
+	case ResponseFormatText, ResponseFormatJSONObject:
+		if len(f.Schema) > 0 {
+			return fmt.Errorf("response format %s does not take a schema", f.Type)
+		}
+	case ResponseFormatJSONSchema:
+		if len(f.Schema) == 0 {
+			return errors.New("response format json_schema requires a schema")
+		}
+		if !json.Valid(f.Schema) {
+			return errors.New("response format schema is not valid JSON")
+		}
+	default:
+		return fmt.Errorf("unknown response format type %q", f.Type)
+	}
+	return nil
 }
 
 type ChatResponse struct {
 	Content    string     `json:"content"`
 	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
 	Usage      Usage      `json:"usage"`
-	StopReason string     `json:"stop_reason"`
+	StopReason string     `json:"stop_reason"` // one of the StopReason constants
+	// ReasoningContent is the reasoning or thinking text some models return
+	// alongside their answer. It is informational and not sent back to the model.
+	ReasoningContent string `json:"reasoning_content,omitempty"`
+	// ThinkingBlocks are the extended thinking blocks of an Anthropic reply,
+	// signatures included. They must be copied to the assistant Message so
+	// they can be sent back while the model is using tools.
+	ThinkingBlocks []ThinkingBlock `json:"thinking_blocks,omitempty"`
+}
+
+// ThinkingBlock is one extended thinking block as the model returned it.
+type ThinkingBlock struct {
+	Type      string `json:"type"` // "thinking" or "redacted_thinking"
+	Thinking  string `json:"thinking,omitempty"`
+	Signature string `json:"signature,omitempty"`
+	Data      string `json:"data,omitempty"` // encrypted content of a redacted block
+}
+
+// Provider-independent values of ChatResponse.StopReason.
+const (
+	StopReasonStop    = "stop"     // the model finished its answer
+	StopReasonToolUse = "tool_use" // the model is waiting for tool results
+	StopReasonLength  = "length"   // the output was cut off at the token limit
+	StopReasonRefusal = "refusal"  // the model declined or a content filter intervened
+)
+
+// normalizeStopReason maps a provider's finish reason onto the StopReason
+// constants. Anthropic and OpenAI values do not overlap, so one table serves
+// both. Unknown reasons count as a normal stop; empty stays empty.
+func normalizeStopReason(raw string) string {
+	switch raw {
+	case "":
+		return ""
+	case "tool_use", "tool_calls", "function_call":
+		return StopReasonToolUse
+	case "max_tokens", "length", "model_context_window_exceeded":
+		return StopReasonLength
+	case "refusal", "content_filter":
+		return StopReasonRefusal
+	}
+	return StopReasonStop
 }
 
 // ContentPart represents a part of a multimodal message.
 type ContentPart struct {
-	Type     string    `json:"type"`               // "text" or "image_url"
-	Text     string    `json:"text,omitempty"`
-	ImageURL *ImageURL `json:"image_url,omitempty"`
+	Type       string      `json:"type"` // "text", "image_url", "input_audio" or "file"
+	Text       string      `json:"text,omitempty"`
+	ImageURL   *ImageURL   `json:"image_url,omitempty"`
+	InputAudio *InputAudio `json:"input_audio,omitempty"`
+	File       *FilePart   `json:"file,omitempty"`
 }
 
 // ImageURL holds the URL and optional detail level for an image content part.
@@ -39,12 +150,28 @@ type ImageURL struct {
 	Detail string `json:"detail,omitempty"` // "auto", "low", "high"
 }
 
+// InputAudio holds the recording for an input_audio content part.
+type InputAudio struct {
+	Data   string `json:"data"`   // base64-encoded audio
+	Format string `json:"format"` // "wav", "mp3", ...
+}
+
+// FilePart holds a document, such as a PDF, for a file content part.
+type FilePart struct {
+	Filename string `json:"filename,omitempty"`
+	FileData string `json:"file_data"` // data URI
+}
+
 type Message struct {
 	Role         string        `json:"role"` // "system", "user", "assistant", "tool"
 	Content      string        `json:"content,omitempty"`
 	ContentParts []ContentPart `json:"content_parts,omitempty"` // for multimodal
 	ToolCallID   string        `json:"tool_call_id,omitempty"`
 	ToolCalls    []ToolCall    `json:"tool_calls,omitempty"`
+	IsError      bool          `json:"is_error,omitempty"` // tool result reports a failure
+	// ThinkingBlocks of an assistant message are sent back ahead of its
+	// content, see ChatResponse.ThinkingBlocks.
+	ThinkingBlocks []ThinkingBlock `json:"thinking_blocks,omitempty"`
 }
 
 type ToolCall struct {
@@ -68,4 +195,20 @@ type Usage struct {
 	PromptTokens     int `json:"prompt_tokens"`
 	CompletionTokens int `json:"completion_tokens"`
 	TotalTokens      int `json:"total_tokens"`
+	// Prompt tokens served from or written to the provider's prompt cache;
+	// both are included in PromptTokens.
+	CacheReadTokens     int `json:"cache_read_tokens,omitempty"`
+	CacheCreationTokens int `json:"cache_creation_tokens,omitempty"`
+	// Completion tokens spent on hidden reasoning; included in CompletionTokens.
+	ReasoningTokens int `json:"reasoning_tokens,omitempty"`
+}
+
+// Add accumulates o into u.
+func (u *Usage) Add(o Usage) {
+	u.PromptTokens += o.PromptTokens
+	u.CompletionTokens += o.CompletionTokens
+	u.TotalTokens += o.TotalTokens
+	u.CacheReadTokens += o.CacheReadTokens
+	u.CacheCreationTokens += o.CacheCreationTokens
+	u.ReasoningTokens += o.ReasoningTokens
 }
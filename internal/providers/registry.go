@@ -19,12 +19,19 @@ type ProviderSpec struct {
 	ModelOverrides    map[string]map[string]any // per-model parameter overrides
 }
 
+// reasoningModelOverrides covers OpenAI reasoning models, which reject sampling
+// parameters and take max_completion_tokens instead of max_tokens.
+var reasoningModelOverrides = map[string]map[string]any{
+	"o1": {"temperature": nil, "top_p": nil, "frequency_penalty": nil, "presence_penalty": nil, "max_completion_tokens": true},
+	"o3": {"temperature": nil, "top_p": nil, "frequency_penalty": nil, "presence_penalty": nil, "max_completion_tokens": true},
+}
+
 // Providers is the complete registry of known LLM providers
 var Providers = []ProviderSpec{
 	{Name: "openrouter", Keywords: []string{"openrouter"}, EnvKey: "OPENROUTER_API_KEY", DefaultAPIBase: "https://openrouter.ai/api/v1", IsGateway: true, DetectByKeyPrefix: "sk-or-"},
 	{Name: "aihubmix", Keywords: []string{"aihubmix"}, EnvKey: "AIHUBMIX_API_KEY", DefaultAPIBase: "https://aihubmix.com/v1", IsGateway: true, DetectByKeyPrefix: "sk-aihub"},
 	{Name: "anthropic", Keywords: []string{"claude", "anthropic"}, EnvKey: "ANTHROPIC_API_KEY", PromptCaching: true},
-	{Name: "openai", Keywords: []string{"gpt", "o1", "o3", "chatgpt"}, EnvKey: "OPENAI_API_KEY", PromptCaching: true},
+	{Name: "openai", Keywords: []string{"gpt", "o1", "o3", "chatgpt"}, EnvKey: "OPENAI_API_KEY", PromptCaching: true, ModelOverrides: reasoningModelOverrides},
 	{Name: "deepseek", Keywords: []string{"deepseek"}, EnvKey: "DEEPSEEK_API_KEY", DefaultAPIBase: "https://api.deepseek.com/v1"},
 	{Name: "moonshot", Keywords: []string{"moonshot", "kimi"}, EnvKey: "MOONSHOT_API_KEY", DefaultAPIBase: "https://api.moonshot.cn/v1"},
 	{Name: "zhipu", Keywords: []string{"glm", "zhipu"}, EnvKey: "ZHIPUAI_API_KEY", DefaultAPIBase: "https://open.bigmodel.cn/api/paas/v4"},
@@ -78,3 +85,78 @@ func FindByName(name string) *ProviderSpec {
 	}
 	return nil
 }
+
+// OverridesFor returns the ModelOverrides entry whose key is the longest
+// prefix of model, ignoring any "vendor/" routing prefix. It returns nil if
+// none match.
+func (s *ProviderSpec) OverridesFor(model string) map[string]any {
+	return matchOverrides(s.ModelOverrides, model)
+}
+
+func matchOverrides(overrides map[string]map[string]any, model string) map[string]any {
+	if i := strings.LastIndex(model, "/"); i >= 0 {
+		model = model[i+1:]
+	}
+	model = strings.ToLower(model)
+	var best string
+	for key := range overrides {
+		if strings.HasPrefix(model, strings.ToLower(key)) && len(key) > len(best) {
+			best = key
+		}
+	}
+	if best == "" {
+		return nil
+	}
+	return overrides[best]
+}
+
+// applyOverrides rewrites req's sampling parameters from a ModelOverrides
+// entry. A nil value drops the parameter; a number replaces it. It reports
+// whether the model wants max_completion_tokens in place of max_tokens.
+// Unknown keys are ignored.
+func applyOverrides(req *ChatRequest, overrides map[string]any) (maxCompletionTokens bool) {
+	for key, val := range overrides {
+		switch key {
+		case "max_completion_tokens":
+			maxCompletionTokens, _ = val.(bool)
+			continue
+		case "stop":
+			if val == nil {
+				req.Stop = nil
+			}
+			continue
+		}
+
+		num, ok := toFloat(val)
+		if val != nil && !ok {
+			continue
+		}
+		switch key {
+		case "temperature":
+			req.Temperature = num
+		case "top_p":
+			req.TopP = num
+		case "frequency_penalty":
+			req.FrequencyPenalty = num
+		case "presence_penalty":
+			req.PresencePenalty = num
+		case "max_tokens":
+			req.MaxTokens = int(num)
+		}
+	}
+	return maxCompletionTokens
+}
+
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	}
+	return 0, false
+}
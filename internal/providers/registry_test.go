@@ -61,3 +61,49 @@ func TestFindByName(t *testing.T) {
 		t.Errorf("FindByName(anthropic).Name = %q, want anthropic", spec.Name)
 	}
 }
+
+func TestOverridesFor(t *testing.T) {
+	spec := &ProviderSpec{ModelOverrides: map[string]map[string]any{
+		"o1":      {"temperature": nil},
+		"o1-mini": {"temperature": 1.0},
+	}}
+	tests := []struct {
+		model string
+		want  any // expected "temperature" entry; "none" means no match
+	}{
+		{"o1-preview", nil},
+		{"o1-mini-2024-09-12", 1.0},
+		{"openai/o1-preview", nil},
+		{"gpt-4o", "none"},
+	}
+	for _, tt := range tests {
+		got := spec.OverridesFor(tt.model)
+		if tt.want == "none" {
+			if got != nil {
+				t.Errorf("OverridesFor(%q) = %v, want nil", tt.model, got)
+			}
+			continue
+		}
+		if got == nil || got["temperature"] != tt.want {
+			t.Errorf("OverridesFor(%q) = %v, want temperature %v", tt.model, got, tt.want)
+		}
+	}
+}
+
+func TestApplyOverrides(t *testing.T) {
+	req := ChatRequest{Temperature: 0.7, TopP: 0.9, MaxTokens: 100, Stop: []string{"x"}}
+	maxCompletion := applyOverrides(&req, map[string]any{
+		"temperature":           nil,
+		"top_p":                 0.5,
+		"max_tokens":            2000,
+		"stop":                  nil,
+		"max_completion_tokens": true,
+		"unknown":               "ignored",
+	})
+	if !maxCompletion {
+		t.Error("expected max_completion_tokens flag")
+	}
+	if req.Temperature != 0 || req.TopP != 0.5 || req.MaxTokens != 2000 || req.Stop != nil {
+		t.Errorf("unexpected request after overrides: %+v", req)
+	}
+}
@@ -0,0 +1,158 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go/option"
+
+	"github.com/coopco/nanobot/internal/tlsutil"
+)
+
+// Credentials holds the API key and optional endpoint for one provider.
+type Credentials struct {
+	APIKey  string
+	BaseURL string
+	Timeout time.Duration   // per-request HTTP timeout; zero keeps the default
+	TLS     tlsutil.Options // certificate verification for self-hosted endpoints
+	// Headers are added to every request, e.g. OpenRouter's HTTP-Referer.
+	// The Codex backend ignores them.
+	Headers map[string]string
+}
+
+// ProviderRouter is a Provider that picks the backend for each request from
+// its model name, so one deployment can serve several model families.
+// Backends are built on first use from the registry spec and the configured
+// credentials, falling back to the spec's environment variable for the key.
+type ProviderRouter struct {
+	creds    map[string]Credentials // keyed by ProviderSpec.Name
+	fallback Provider               // used when the model matches no spec; may be nil
+
+	mu        sync.Mutex
+	providers map[string]Provider
+}
+
+// NewProviderRouter creates a router over creds, keyed by provider name
+// ("anthropic", "openai", ...). fallback serves models the registry does not
+// recognise and may be nil.
+func NewProviderRouter(creds map[string]Credentials, fallback Provider) *ProviderRouter {
+	return &ProviderRouter{
+		creds:     creds,
+		fallback:  fallback,
+		providers: make(map[string]Provider),
+	}
+}
+
+// Set installs p as the backend for the named provider, replacing any
+// backend the router would otherwise construct.
+func (r *ProviderRouter) Set(name string, p Provider) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.providers[name] = p
+}
+
+// Resolve returns the backend that serves model.
+func (r *ProviderRouter) Resolve(model string) (Provider, error) {
+	spec := FindByModel(model)
+	if spec == nil {
+		if r.fallback != nil {
+			return r.fallback, nil
+		}
+		return nil, fmt.Errorf("no provider matches model %q", model)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if p, ok := r.providers[spec.Name]; ok {
+		return p, nil
+	}
+	p, err := newProvider(spec, r.creds[spec.Name])
+	if err != nil {
+		return nil, fmt.Errorf("model %q: %w", model, err)
+	}
+	r.providers[spec.Name] = p
+	return p, nil
+}
+
+// Chat routes req to the backend selected by req.Model.
+func (r *ProviderRouter) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	p, err := r.Resolve(req.Model)
+	if err != nil {
+		return nil, err
+	}
+	return p.Chat(ctx, req)
+}
+
+// ChatStream routes req like Chat. Backends that cannot stream deliver
+// their whole reply as a single delta.
+func (r *ProviderRouter) ChatStream(ctx context.Context, req ChatRequest, onDelta func(string)) (*ChatResponse, error) {
+	p, err := r.Resolve(req.Model)
+	if err != nil {
+		return nil, err
+	}
+	if sp, ok := p.(StreamingProvider); ok {
+		return sp.ChatStream(ctx, req, onDelta)
+	}
+	resp, err := p.Chat(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Content != "" {
+		onDelta(resp.Content)
+	}
+	return resp, nil
+}
+
+// newProvider builds the backend for spec from c, taking the API key from
+// the spec's environment variable when c has none.
+func newProvider(spec *ProviderSpec, c Credentials) (Provider, error) {
+	if spec.IsOAuth {
+		opts := CodexOptions{APIBase: c.BaseURL}
+		transport, err := c.TLS.Transport()
+		if err != nil {
+			return nil, fmt.Errorf("provider %s: %w", spec.Name, err)
+		}
+		if c.Timeout > 0 || transport != nil {
+			timeout := c.Timeout
+			if timeout <= 0 {
+				timeout = defaultRequestTimeout
+			}
+			opts.HTTPClient = &http.Client{Timeout: timeout, Transport: transport}
+		}
+		return NewCodexProviderWithOptions(opts)
+	}
+
+	if c.APIKey == "" && spec.EnvKey != "" {
+		c.APIKey = os.Getenv(spec.EnvKey)
+	}
+	if c.APIKey == "" && !spec.IsLocal {
+		return nil, fmt.Errorf("no API key configured for provider %s", spec.Name)
+	}
+
+	if spec.Name == "anthropic" {
+		var opts []option.RequestOption
+		if c.BaseURL != "" {
+			opts = append(opts, option.WithBaseURL(c.BaseURL))
+		}
+		for k, v := range c.Headers {
+			opts = append(opts, option.WithHeader(k, v))
+		}
+		p := newAnthropicProvider(c.APIKey, opts...)
+		p.SetTimeout(c.Timeout)
+		if err := p.SetTLS(c.TLS); err != nil {
+			return nil, fmt.Errorf("provider %s: %w", spec.Name, err)
+		}
+		return p, nil
+	}
+	p := NewOpenAICompatProviderFromSpec(spec, c.APIKey, c.BaseURL)
+	p.SetTimeout(c.Timeout)
+	p.SetHeaders(c.Headers)
+	if err := p.SetTLS(c.TLS); err != nil {
+		return nil, fmt.Errorf("provider %s: %w", spec.Name, err)
+	}
+	return p, nil
+}
@@ -0,0 +1,111 @@
+package providers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/coopco/nanobot/internal/tlsutil"
+)
+
+type stubProvider struct{ name string }
+
+func (s *stubProvider) Chat(context.Context, ChatRequest) (*ChatResponse, error) {
+	return &ChatResponse{Content: s.name}, nil
+}
+
+func TestProviderRouterResolve(t *testing.T) {
+	r := NewProviderRouter(map[string]Credentials{
+		"anthropic": {APIKey: "sk-ant"},
+		"openai":    {APIKey: "sk-openai"},
+	}, nil)
+
+	p, err := r.Resolve("claude-3")
+	if err != nil {
+		t.Fatalf("Resolve(claude-3): %v", err)
+	}
+	if _, ok := p.(*AnthropicProvider); !ok {
+		t.Errorf("claude-3 routed to %T, want *AnthropicProvider", p)
+	}
+
+	p, err = r.Resolve("gpt-4o")
+	if err != nil {
+		t.Fatalf("Resolve(gpt-4o): %v", err)
+	}
+	if _, ok := p.(*OpenAICompatProvider); !ok {
+		t.Errorf("gpt-4o routed to %T, want *OpenAICompatProvider", p)
+	}
+
+	again, _ := r.Resolve("gpt-4o-mini")
+	if again != p {
+		t.Error("expected the openai backend to be reused across models")
+	}
+}
+
+func TestProviderRouterEnvKey(t *testing.T) {
+	t.Setenv("DEEPSEEK_API_KEY", "sk-env")
+	r := NewProviderRouter(nil, nil)
+	if _, err := r.Resolve("deepseek-chat"); err != nil {
+		t.Fatalf("expected key from environment, got %v", err)
+	}
+}
+
+func TestProviderRouterMissingKey(t *testing.T) {
+	t.Setenv("MISTRAL_API_KEY", "")
+	r := NewProviderRouter(nil, nil)
+	if _, err := r.Resolve("mistral-large"); err == nil {
+		t.Fatal("expected error when no key is configured")
+	}
+}
+
+func TestProviderRouterBadCAFile(t *testing.T) {
+	r := NewProviderRouter(map[string]Credentials{
+		"openai": {APIKey: "sk-openai", TLS: tlsutil.Options{CAFile: "/nonexistent/ca.pem"}},
+	}, nil)
+	if _, err := r.Resolve("gpt-4o"); err == nil || !strings.Contains(err.Error(), "CA file") {
+		t.Fatalf("err = %v, want the CA file error", err)
+	}
+}
+
+func TestProviderRouterFallback(t *testing.T) {
+	fallback := &stubProvider{name: "fallback"}
+	r := NewProviderRouter(nil, fallback)
+	r.Set("anthropic", &stubProvider{name: "anthropic"})
+
+	tests := []struct {
+		model string
+		want  string
+	}{
+		{"claude-3-5-sonnet", "anthropic"},
+		{"my-finetune", "fallback"},
+	}
+	for _, tt := range tests {
+		resp, err := r.Chat(context.Background(), ChatRequest{Model: tt.model})
+		if err != nil {
+			t.Fatalf("Chat(%s): %v", tt.model, err)
+		}
+		if resp.Content != tt.want {
+			t.Errorf("Chat(%s) served by %q, want %q", tt.model, resp.Content, tt.want)
+		}
+	}
+
+	if _, err := NewProviderRouter(nil, nil).Resolve("my-finetune"); err == nil {
+		t.Error("expected error for unknown model without fallback")
+	}
+}
+
+func TestProviderRouterChatStreamFallsBackToChat(t *testing.T) {
+	r := NewProviderRouter(nil, nil)
+	r.Set("anthropic", &stubProvider{name: "whole reply"})
+
+	var deltas []string
+	resp, err := r.ChatStream(context.Background(), ChatRequest{Model: "claude-3"}, func(d string) {
+		deltas = append(deltas, d)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deltas) != 1 || deltas[0] != "whole reply" || resp.Content != "whole reply" {
+		t.Errorf("deltas = %q, content = %q; want the reply as a single delta", deltas, resp.Content)
+	}
+}
@@ -0,0 +1,93 @@
+package providers
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxToolCallIDLen is the longest tool-call ID every supported API accepts;
+// OpenAI's limit is the tightest.
+const maxToolCallIDLen = 40
+
+// normalizeToolCallIDs rewrites the tool-call IDs in msgs so any provider
+// accepts them, whichever provider produced the history. IDs are limited to
+// letters, digits, '_' and '-' and to maxToolCallIDLen characters, missing
+// IDs are generated, and every tool result is pointed at the rewritten ID of
+// its call. A tool result with no ID is matched to the oldest unanswered
+// call of the assistant message before it. msgs is not modified.
+func normalizeToolCallIDs(msgs []Message) []Message {
+	out := make([]Message, len(msgs))
+	ids := make(map[string]string) // original ID -> rewritten ID
+	used := make(map[string]bool)  // rewritten IDs already handed out
+	var pending []string           // rewritten IDs of calls still awaiting a result
+	generated := 0
+
+	// assign gives a call a fresh ID even if its original was seen before,
+	// as some providers reuse IDs such as "call_0" from turn to turn.
+	assign := func(orig string) string {
+		id := sanitizeToolCallID(orig)
+		for id == "" || used[id] {
+			generated++
+			id = fmt.Sprintf("call_%d", generated)
+		}
+		used[id] = true
+		if orig != "" {
+			ids[orig] = id
+		}
+		return id
+	}
+
+	for i, m := range msgs {
+		switch {
+		case m.Role == "assistant" && len(m.ToolCalls) > 0:
+			calls := make([]ToolCall, len(m.ToolCalls))
+			pending = pending[:0]
+			for j, tc := range m.ToolCalls {
+				tc.ID = assign(tc.ID)
+				calls[j] = tc
+				pending = append(pending, tc.ID)
+			}
+			m.ToolCalls = calls
+		case m.Role == "tool":
+			id, ok := ids[m.ToolCallID]
+			if m.ToolCallID == "" && len(pending) > 0 {
+				id, ok = pending[0], true
+			}
+			if ok && removePending(&pending, id) {
+				m.ToolCallID = id
+			} else {
+				m.ToolCallID = sanitizeToolCallID(m.ToolCallID)
+			}
+		}
+		out[i] = m
+	}
+	return out
+}
+
+// sanitizeToolCallID replaces characters outside [A-Za-z0-9_-] with '_' and
+// truncates id to maxToolCallIDLen.
+func sanitizeToolCallID(id string) string {
+	id = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-':
+			return r
+		}
+		return '_'
+	}, id)
+	if len(id) > maxToolCallIDLen {
+		id = id[:maxToolCallIDLen]
+	}
+	return id
+}
+
+// removePending drops id from the pending calls, reporting whether it was
+// there.
+func removePending(pending *[]string, id string) bool {
+	for i, p := range *pending {
+		if p == id {
+			*pending = append((*pending)[:i], (*pending)[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
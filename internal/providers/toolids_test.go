@@ -0,0 +1,113 @@
+package providers
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeToolCallIDs(t *testing.T) {
+	long := "call_" + strings.Repeat("x", 60)
+	msgs := []Message{
+		{Role: "user", Content: "weather?"},
+		{Role: "assistant", ToolCalls: []ToolCall{
+			{ID: "functions.get_weather:0", Name: "get_weather"},
+			{ID: "", Name: "get_time"},
+			{ID: long, Name: "get_date"},
+		}},
+		{Role: "tool", ToolCallID: "functions.get_weather:0", Content: "sunny"},
+		{Role: "tool", ToolCallID: "", Content: "noon"},
+		{Role: "tool", ToolCallID: long, Content: "monday"},
+		// A provider that numbers calls per turn reuses IDs.
+		{Role: "assistant", ToolCalls: []ToolCall{{ID: "functions.get_weather:0", Name: "get_weather"}}},
+		{Role: "tool", ToolCallID: "functions.get_weather:0", Content: "rain"},
+	}
+	out := normalizeToolCallIDs(msgs)
+
+	first, second := out[1].ToolCalls, out[5].ToolCalls
+	if first[0].ID != "functions_get_weather_0" || first[1].ID != "call_1" || first[2].ID != long[:maxToolCallIDLen] {
+		t.Errorf("first turn IDs = %q, %q, %q", first[0].ID, first[1].ID, first[2].ID)
+	}
+	if second[0].ID == first[0].ID {
+		t.Errorf("reused ID %q not made unique", second[0].ID)
+	}
+	for _, tc := range []struct {
+		result int
+		want   string
+	}{{2, first[0].ID}, {3, first[1].ID}, {4, first[2].ID}, {6, second[0].ID}} {
+		if got := out[tc.result].ToolCallID; got != tc.want {
+			t.Errorf("message %d answers %q, want %q", tc.result, got, tc.want)
+		}
+	}
+	if msgs[1].ToolCalls[0].ID != "functions.get_weather:0" || msgs[2].ToolCallID != "functions.get_weather:0" {
+		t.Error("input messages were modified")
+	}
+}
+
+func TestNormalizeToolCallIDs_KeepsValidIDs(t *testing.T) {
+	msgs := []Message{
+		{Role: "assistant", ToolCalls: []ToolCall{{ID: "call_abc123", Name: "a"}, {ID: "toolu_01XYZ", Name: "b"}}},
+		{Role: "tool", ToolCallID: "call_abc123", Content: "1"},
+		{Role: "tool", ToolCallID: "toolu_01XYZ", Content: "2"},
+	}
+	out := normalizeToolCallIDs(msgs)
+	if out[0].ToolCalls[0].ID != "call_abc123" || out[0].ToolCalls[1].ID != "toolu_01XYZ" ||
+		out[1].ToolCallID != "call_abc123" || out[2].ToolCallID != "toolu_01XYZ" {
+		t.Errorf("valid IDs were rewritten: %+v", out)
+	}
+}
+
+// A session recorded with an OpenAI-compatible provider must replay through
+// the Anthropic conversion after a fallback, with every tool_result naming a
+// tool_use from the turn before it.
+func TestConvertMessages_ReplaysOpenAIToolCalls(t *testing.T) {
+	msgs := []Message{
+		{Role: "user", Content: "what's in the repo?"},
+		{Role: "assistant", ToolCalls: []ToolCall{
+			{ID: "call_9f8e7d6c5b4a39281706f5e4d3c2b1a0ffeeddcc", Name: "list_dir", Arguments: `{"path":"."}`},
+			{ID: "functions.read_file:1", Name: "read_file", Arguments: `{"path":"go.mod"}`},
+		}},
+		{Role: "tool", ToolCallID: "call_9f8e7d6c5b4a39281706f5e4d3c2b1a0ffeeddcc", Content: "go.mod\nmain.go"},
+		{Role: "tool", ToolCallID: "functions.read_file:1", Content: "module example"},
+		{Role: "assistant", Content: "A Go module."},
+	}
+	converted, err := convertMessages(msgs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := json.Marshal(converted)
+	var body []struct {
+		Content []struct {
+			Type      string `json:"type"`
+			ID        string `json:"id"`
+			ToolUseID string `json:"tool_use_id"`
+		} `json:"content"`
+	}
+	if err := json.Unmarshal(raw, &body); err != nil {
+		t.Fatal(err)
+	}
+
+	valid := regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+	toolUses := make(map[string]bool)
+	results := 0
+	for _, msg := range body {
+		for _, block := range msg.Content {
+			switch block.Type {
+			case "tool_use":
+				if !valid.MatchString(block.ID) {
+					t.Errorf("tool_use id %q is not valid for Anthropic", block.ID)
+				}
+				toolUses[block.ID] = true
+			case "tool_result":
+				results++
+				if !toolUses[block.ToolUseID] {
+					t.Errorf("tool_result names unknown tool_use %q", block.ToolUseID)
+				}
+			}
+		}
+	}
+	if len(toolUses) != 2 || results != 2 {
+		t.Errorf("got %d tool_use and %d tool_result blocks, want 2 of each", len(toolUses), results)
+	}
+}
@@ -0,0 +1,73 @@
+package session
+
+import "container/list"
+
+// lruCache maps session keys to sessions in least-recently-used order. It is
+// not safe for concurrent use; Manager guards it with its own mutex.
+type lruCache struct {
+	items map[string]*list.Element
+	order *list.List // front is most recently used
+}
+
+func newLRUCache() *lruCache {
+	return &lruCache{
+		items: make(map[string]*list.Element),
+		order: list.New(),
+	}
+}
+
+// get returns the session for key and marks it most recently used.
+func (c *lruCache) get(key string) (*Session, bool) {
+	e, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(e)
+	return e.Value.(*Session), true
+}
+
+// peek returns the session for key without changing its recency.
+func (c *lruCache) peek(key string) (*Session, bool) {
+	e, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	return e.Value.(*Session), true
+}
+
+// put inserts or replaces the session for key as most recently used.
+func (c *lruCache) put(key string, s *Session) {
+	if e, ok := c.items[key]; ok {
+		e.Value = s
+		c.order.MoveToFront(e)
+		return
+	}
+	c.items[key] = c.order.PushFront(s)
+}
+
+func (c *lruCache) remove(key string) {
+	if e, ok := c.items[key]; ok {
+		c.order.Remove(e)
+		delete(c.items, key)
+	}
+}
+
+// oldest returns the least recently used session.
+func (c *lruCache) oldest() (*Session, bool) {
+	e := c.order.Back()
+	if e == nil {
+		return nil, false
+	}
+	return e.Value.(*Session), true
+}
+
+func (c *lruCache) len() int { return len(c.items) }
+
+// keys returns the cached keys from most to least recently used.
+func (c *lruCache) keys() []string {
+	keys := make([]string, 0, len(c.items))
+	for e := c.order.Front(); e != nil; e = e.Next() {
+		keys = append(keys, e.Value.(*Session).Meta.Key)
+	}
+	return keys
+}
@@ -2,8 +2,10 @@ package session
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
@@ -13,11 +15,24 @@ import (
 
 // Message represents a single message in a session
 type Message struct {
-	Role       string           `json:"role"`
-	Content    string           `json:"content"`
-	ToolCallID string           `json:"tool_call_id,omitempty"`
-	ToolCalls  []ToolCallRecord `json:"tool_calls,omitempty"`
-	Timestamp  string           `json:"timestamp,omitempty"`
+	Role         string           `json:"role"`
+	Content      string           `json:"content"`
+	ContentParts []ContentPart    `json:"content_parts,omitempty"` // media sent with a user message
+	ToolCallID   string           `json:"tool_call_id,omitempty"`
+	ToolCalls    []ToolCallRecord `json:"tool_calls,omitempty"`
+	Timestamp    string           `json:"timestamp,omitempty"`
+}
+
+// ContentPart holds one part of a multimodal message: an image, audio
+// recording or file.
+type ContentPart struct {
+	Type     string `json:"type"` // "text", "image_url", "input_audio" or "file"
+	Text     string `json:"text,omitempty"`
+	URL      string `json:"url,omitempty"` // image URL, or data URI of an image or file
+	Detail   string `json:"detail,omitempty"`
+	Data     string `json:"data,omitempty"`   // base64-encoded audio
+	Format   string `json:"format,omitempty"` // audio format
+	Filename string `json:"filename,omitempty"`
 }
 
 // ToolCallRecord holds a single tool call within a message
@@ -33,6 +48,7 @@ type SessionMeta struct {
 	CreatedAt        string `json:"created_at"`
 	UpdatedAt        string `json:"updated_at"`
 	LastConsolidated int    `json:"last_consolidated"`
+	Model            string `json:"model,omitempty"` // per-session model override; empty uses the agent default
 }
 
 // Session holds conversation state
@@ -82,19 +98,170 @@ func (s *Session) SetConsolidated(index int) {
 	s.Meta.LastConsolidated = index
 }
 
+// Model returns the session's model override, or "" for the agent default.
+func (s *Session) Model() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.Meta.Model
+}
+
+// SetModel overrides the model used for this session; "" restores the default.
+func (s *Session) SetModel(model string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Meta.Model = model
+}
+
 // Manager handles session persistence
 type Manager struct {
-	dataDir string
-	cache   map[string]*Session
-	mu      sync.RWMutex
+	dataDir   string
+	cache     *lruCache
+	mu        sync.RWMutex
+	ttl       time.Duration // sessions idle longer than this are pruned; 0 keeps them forever
+	maxCached int           // sessions kept in memory before the least recently used is evicted; 0 is unbounded
 }
 
 // NewManager creates a Manager rooted at dataDir
 func NewManager(dataDir string) *Manager {
 	return &Manager{
 		dataDir: dataDir,
-		cache:   make(map[string]*Session),
+		cache:   newLRUCache(),
+	}
+}
+
+// SetMaxCached caps how many sessions are held in memory. When the cap is
+// exceeded the least recently used session is saved and evicted; GetOrCreate
+// reloads it from disk on next use. Zero means no limit.
+func (m *Manager) SetMaxCached(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.maxCached = n
+	m.evictLocked()
+}
+
+// evictLocked saves and drops least recently used sessions until the cache
+// fits within maxCached. A session that fails to save stays cached so no
+// messages are lost. m.mu must be held.
+func (m *Manager) evictLocked() {
+	if m.maxCached <= 0 {
+		return
+	}
+	for m.cache.len() > m.maxCached {
+		s, _ := m.cache.oldest()
+		if err := m.Save(s); err != nil {
+			slog.Warn("failed to save session before eviction", "key", s.Meta.Key, "error", err)
+			return
+		}
+		m.cache.remove(s.Meta.Key)
+	}
+}
+
+// SetTTL sets how long a session may go without updates before Prune removes
+// it. Zero disables expiry.
+func (m *Manager) SetTTL(ttl time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ttl = ttl
+}
+
+// Prune evicts cached sessions and deletes session files whose UpdatedAt is
+// older than the TTL. It returns the number of sessions removed.
+func (m *Manager) Prune() (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.ttl <= 0 {
+		return 0, nil
+	}
+	cutoff := time.Now().Add(-m.ttl)
+	removed := make(map[string]bool)
+
+	for _, key := range m.cache.keys() {
+		s, _ := m.cache.peek(key)
+		s.mu.RLock()
+		updated := s.Meta.UpdatedAt
+		s.mu.RUnlock()
+		if isStale(updated, cutoff) {
+			m.cache.remove(key)
+			removed[key] = true
+		}
+	}
+
+	entries, err := os.ReadDir(m.dataDir)
+	if os.IsNotExist(err) {
+		return len(removed), nil
+	}
+	if err != nil {
+		return len(removed), fmt.Errorf("failed to read session dir: %w", err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".jsonl") {
+			continue
+		}
+		path := filepath.Join(m.dataDir, entry.Name())
+		meta, ok := readMeta(path)
+		if !ok {
+			continue
+		}
+		// A cached session may have newer, unsaved activity.
+		if _, cached := m.cache.peek(meta.Key); cached || !isStale(meta.UpdatedAt, cutoff) {
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			slog.Warn("failed to remove stale session", "path", path, "error", err)
+			continue
+		}
+		removed[meta.Key] = true
 	}
+
+	return len(removed), nil
+}
+
+// StartSweeper runs Prune every interval until ctx is cancelled.
+func (m *Manager) StartSweeper(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if n, err := m.Prune(); err != nil {
+					slog.Warn("session prune failed", "error", err)
+				} else if n > 0 {
+					slog.Info("pruned stale sessions", "count", n)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// isStale reports whether an RFC3339 timestamp is before cutoff. Unparseable
+// timestamps are treated as fresh so a bad file is never silently deleted.
+func isStale(updatedAt string, cutoff time.Time) bool {
+	t, err := time.Parse(time.RFC3339, updatedAt)
+	return err == nil && t.Before(cutoff)
+}
+
+// readMeta reads only the SessionMeta line of a session file.
+func readMeta(path string) (SessionMeta, bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return SessionMeta{}, false
+	}
+	defer f.Close()
+
+	var meta SessionMeta
+	scanner := bufio.NewScanner(f)
+	if !scanner.Scan() {
+		return meta, false
+	}
+	if err := json.Unmarshal(scanner.Bytes(), &meta); err != nil {
+		return meta, false
+	}
+	return meta, true
 }
 
 // keyToFilename replaces unsafe characters for use as a filename
@@ -108,7 +275,7 @@ func (m *Manager) GetOrCreate(key string) *Session {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if s, ok := m.cache[key]; ok {
+	if s, ok := m.cache.get(key); ok {
 		return s
 	}
 
@@ -124,11 +291,108 @@ func (m *Manager) GetOrCreate(key string) *Session {
 			Messages: []Message{},
 		}
 	}
-	m.cache[key] = s
+	m.cache.put(key, s)
+	m.evictLocked()
 	return s
 }
 
-// Save persists session to a JSONL file
+// Fork copies the session at key, with its messages and consolidation
+// pointer, to a new session at newKey and saves it, so the two conversations
+// can continue independently. It fails if key does not exist or newKey
+// already does.
+func (m *Manager) Fork(key, newKey string) (*Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if newKey == "" || newKey == key {
+		return nil, fmt.Errorf("invalid fork key %q", newKey)
+	}
+	if _, ok := m.cache.peek(newKey); ok {
+		return nil, fmt.Errorf("session %q already exists", newKey)
+	}
+	if _, err := os.Stat(filepath.Join(m.dataDir, keyToFilename(newKey))); err == nil {
+		return nil, fmt.Errorf("session %q already exists", newKey)
+	}
+
+	src, ok := m.cache.peek(key)
+	if !ok {
+		src = m.load(key)
+	}
+	if src == nil {
+		return nil, fmt.Errorf("session %q not found", key)
+	}
+
+	src.mu.RLock()
+	meta := src.Meta
+	messages := make([]Message, len(src.Messages))
+	for i, msg := range src.Messages {
+		msg.ToolCalls = append([]ToolCallRecord(nil), msg.ToolCalls...)
+		messages[i] = msg
+	}
+	src.mu.RUnlock()
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	meta.Key = newKey
+	meta.CreatedAt = now
+	meta.UpdatedAt = now
+	fork := &Session{Meta: meta, Messages: messages}
+	if err := m.Save(fork); err != nil {
+		return nil, fmt.Errorf("failed to save forked session: %w", err)
+	}
+	m.cache.put(newKey, fork)
+	m.evictLocked()
+	return fork, nil
+}
+
+// archiveDir is the subdirectory of the data dir holding archived sessions.
+const archiveDir = "archive"
+
+// Reset clears the session at key so the next turn starts fresh. The session
+// as it stood is first saved to a timestamped file under the archive
+// subdirectory, whose path is returned; it is empty if there was nothing to
+// archive. The cached *Session is cleared in place, so holders see the reset.
+func (m *Manager) Reset(key string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.cache.peek(key)
+	if !ok {
+		s = m.load(key)
+	}
+	if s == nil {
+		return "", nil
+	}
+
+	if err := m.Save(s); err != nil {
+		return "", fmt.Errorf("failed to save session before reset: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Join(m.dataDir, archiveDir), 0o755); err != nil {
+		return "", fmt.Errorf("failed to create archive dir: %w", err)
+	}
+	name := strings.TrimSuffix(keyToFilename(key), ".jsonl")
+	archived := filepath.Join(m.dataDir, archiveDir, name+"-"+time.Now().UTC().Format("20060102T150405.000Z")+".jsonl")
+	if err := os.Rename(filepath.Join(m.dataDir, keyToFilename(key)), archived); err != nil {
+		return "", fmt.Errorf("failed to archive session: %w", err)
+	}
+
+	s.mu.Lock()
+	s.Messages = []Message{}
+	s.Meta.LastConsolidated = 0
+	s.Meta.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+	s.mu.Unlock()
+	if err := m.Save(s); err != nil {
+		return archived, fmt.Errorf("failed to save reset session: %w", err)
+	}
+	if !ok {
+		m.cache.put(key, s)
+		m.evictLocked()
+	}
+	return archived, nil
+}
+
+// Save persists session to a JSONL file. The file is written to a temporary
+// path and renamed into place, so a crash mid-save never leaves a truncated
+// session behind.
 func (m *Manager) Save(s *Session) error {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -138,25 +402,49 @@ func (m *Manager) Save(s *Session) error {
 	}
 
 	path := filepath.Join(m.dataDir, keyToFilename(s.Meta.Key))
-	f, err := os.Create(path)
+	f, err := os.CreateTemp(m.dataDir, keyToFilename(s.Meta.Key)+".tmp-*")
 	if err != nil {
 		return fmt.Errorf("failed to create session file: %w", err)
 	}
-	defer f.Close()
+	tmpPath := f.Name()
+	defer os.Remove(tmpPath) // no-op once renamed
 
-	enc := json.NewEncoder(f)
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
 	if err := enc.Encode(s.Meta); err != nil {
+		f.Close()
 		return fmt.Errorf("failed to write session meta: %w", err)
 	}
 	for _, msg := range s.Messages {
 		if err := enc.Encode(msg); err != nil {
+			f.Close()
 			return fmt.Errorf("failed to write message: %w", err)
 		}
 	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write session file: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to sync session file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to close session file: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to replace session file: %w", err)
+	}
 	return nil
 }
 
-// load reads a session from disk; returns nil if the file does not exist
+// maxLineSize bounds a single JSONL line; tool results can be large.
+const maxLineSize = 16 * 1024 * 1024
+
+// load reads a session from disk. It returns nil if the file does not exist or
+// its meta line is unreadable, so the caller starts a fresh session. Damaged
+// message lines (e.g. a partial final line) are skipped.
 func (m *Manager) load(key string) *Session {
 	path := filepath.Join(m.dataDir, keyToFilename(key))
 	f, err := os.Open(path)
@@ -166,13 +454,15 @@ func (m *Manager) load(key string) *Session {
 	defer f.Close()
 
 	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
 
 	// First line is SessionMeta
 	if !scanner.Scan() {
 		return nil
 	}
 	var meta SessionMeta
-	if err := json.Unmarshal(scanner.Bytes(), &meta); err != nil {
+	if err := json.Unmarshal(scanner.Bytes(), &meta); err != nil || meta.Key == "" {
+		slog.Warn("ignoring session file with unreadable meta", "path", path, "error", err)
 		return nil
 	}
 
@@ -180,13 +470,113 @@ func (m *Manager) load(key string) *Session {
 	for scanner.Scan() {
 		var msg Message
 		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			slog.Warn("skipping damaged session line", "path", path, "error", err)
 			continue
 		}
 		messages = append(messages, msg)
 	}
+	if err := scanner.Err(); err != nil {
+		slog.Warn("session file read stopped early", "path", path, "error", err)
+	}
 	if messages == nil {
 		messages = []Message{}
 	}
+	if meta.LastConsolidated > len(messages) {
+		meta.LastConsolidated = len(messages)
+	}
 
 	return &Session{Meta: meta, Messages: messages}
 }
+
+// Export renders a session as a human-readable transcript. format is
+// "markdown" (or "md") for Markdown with role headings and collapsible tool
+// calls, or "text" (or "plain") for a plain transcript. Tool call IDs and other
+// internal plumbing are omitted.
+func (m *Manager) Export(key, format string) (string, error) {
+	m.mu.RLock()
+	s, ok := m.cache.peek(key)
+	m.mu.RUnlock()
+	if !ok {
+		s = m.load(key)
+	}
+	if s == nil {
+		return "", fmt.Errorf("session %q not found", key)
+	}
+
+	msgs := s.AllMessages()
+	switch strings.ToLower(format) {
+	case "", "markdown", "md":
+		return exportMarkdown(key, msgs), nil
+	case "text", "plain", "txt":
+		return exportText(msgs), nil
+	default:
+		return "", fmt.Errorf("unknown export format %q (use markdown or text)", format)
+	}
+}
+
+// toolNames maps tool call IDs to the names of the tools they invoked.
+func toolNames(msgs []Message) map[string]string {
+	names := make(map[string]string)
+	for _, msg := range msgs {
+		for _, tc := range msg.ToolCalls {
+			names[tc.ID] = tc.Name
+		}
+	}
+	return names
+}
+
+func roleTitle(role string) string {
+	if role == "" {
+		return "Unknown"
+	}
+	return strings.ToUpper(role[:1]) + role[1:]
+}
+
+func exportMarkdown(key string, msgs []Message) string {
+	names := toolNames(msgs)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# Session %s\n", key)
+	for _, msg := range msgs {
+		if msg.Role == "tool" {
+			name := names[msg.ToolCallID]
+			if name == "" {
+				name = "tool"
+			}
+			fmt.Fprintf(&sb, "\n<details>\n<summary>Result: %s</summary>\n\n```\n%s\n```\n\n</details>\n", name, msg.Content)
+			continue
+		}
+
+		fmt.Fprintf(&sb, "\n## %s\n", roleTitle(msg.Role))
+		if msg.Content != "" {
+			fmt.Fprintf(&sb, "\n%s\n", msg.Content)
+		}
+		for _, tc := range msg.ToolCalls {
+			fmt.Fprintf(&sb, "\n<details>\n<summary>Tool call: %s</summary>\n\n```json\n%s\n```\n\n</details>\n", tc.Name, tc.Arguments)
+		}
+	}
+	return sb.String()
+}
+
+func exportText(msgs []Message) string {
+	names := toolNames(msgs)
+
+	var sb strings.Builder
+	for _, msg := range msgs {
+		if msg.Role == "tool" {
+			name := names[msg.ToolCallID]
+			if name == "" {
+				name = "tool"
+			}
+			fmt.Fprintf(&sb, "[%s result] %s\n\n", name, msg.Content)
+			continue
+		}
+		if msg.Content != "" {
+			fmt.Fprintf(&sb, "%s: %s\n\n", roleTitle(msg.Role), msg.Content)
+		}
+		for _, tc := range msg.ToolCalls {
+			fmt.Fprintf(&sb, "[%s called %s] %s\n\n", roleTitle(msg.Role), tc.Name, tc.Arguments)
+		}
+	}
+	return strings.TrimRight(sb.String(), "\n") + "\n"
+}
@@ -2,8 +2,12 @@ package session
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 )
 
 func TestNewSession(t *testing.T) {
@@ -88,6 +92,38 @@ func TestSaveAndLoad(t *testing.T) {
 	}
 }
 
+func TestSaveAndLoadContentParts(t *testing.T) {
+	dir := t.TempDir()
+	m := NewManager(dir)
+	s := m.GetOrCreate("telegram:42")
+	parts := []ContentPart{
+		{Type: "image_url", URL: "data:image/png;base64,iVBORw0KGgo=", Detail: "auto"},
+		{Type: "image_url", URL: "https://example.com/cat.jpg"},
+		{Type: "file", URL: "data:application/pdf;base64,JVBERg==", Filename: "report.pdf"},
+	}
+	s.AppendMessage(Message{Role: "user", Content: "what are these?", ContentParts: parts})
+	s.AppendMessage(Message{Role: "assistant", Content: "a cat and a report"})
+	if err := m.Save(s); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	msgs := NewManager(dir).GetOrCreate("telegram:42").AllMessages()
+	if len(msgs) != 2 {
+		t.Fatalf("expected 2 messages after load, got %d", len(msgs))
+	}
+	if len(msgs[0].ContentParts) != len(parts) {
+		t.Fatalf("content parts after load = %+v, want %+v", msgs[0].ContentParts, parts)
+	}
+	for i := range parts {
+		if msgs[0].ContentParts[i] != parts[i] {
+			t.Errorf("part %d = %+v, want %+v", i, msgs[0].ContentParts[i], parts[i])
+		}
+	}
+	if msgs[1].ContentParts != nil {
+		t.Errorf("text-only message has parts %+v", msgs[1].ContentParts)
+	}
+}
+
 func TestGetOrCreate(t *testing.T) {
 	m := NewManager(t.TempDir())
 	s1 := m.GetOrCreate("cache:test")
@@ -138,3 +174,359 @@ func TestSessionKeyFilename(t *testing.T) {
 		}
 	}
 }
+
+func TestPrune(t *testing.T) {
+	dir := t.TempDir()
+	m := NewManager(dir)
+	m.SetTTL(24 * time.Hour)
+
+	old := time.Now().Add(-48 * time.Hour).UTC().Format(time.RFC3339)
+
+	// stale session saved to disk and still cached
+	stale := m.GetOrCreate("chat:stale")
+	stale.AppendMessage(Message{Role: "user", Content: "old"})
+	stale.Meta.UpdatedAt = old
+	if err := m.Save(stale); err != nil {
+		t.Fatal(err)
+	}
+
+	// stale session only on disk
+	diskOnly := NewManager(dir).GetOrCreate("chat:disk-only")
+	diskOnly.Meta.UpdatedAt = old
+	if err := m.Save(diskOnly); err != nil {
+		t.Fatal(err)
+	}
+
+	// fresh session
+	fresh := m.GetOrCreate("chat:fresh")
+	fresh.AppendMessage(Message{Role: "user", Content: "new"})
+	if err := m.Save(fresh); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := m.Prune()
+	if err != nil {
+		t.Fatalf("Prune: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("Prune removed %d sessions, want 2", n)
+	}
+
+	for _, key := range []string{"chat:stale", "chat:disk-only"} {
+		if _, err := os.Stat(filepath.Join(dir, keyToFilename(key))); !os.IsNotExist(err) {
+			t.Errorf("expected %s file to be deleted", key)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, keyToFilename("chat:fresh"))); err != nil {
+		t.Errorf("fresh session file should remain: %v", err)
+	}
+
+	if s := m.GetOrCreate("chat:stale"); len(s.Messages) != 0 {
+		t.Errorf("expected a new empty session after prune, got %d messages", len(s.Messages))
+	}
+	if s := m.GetOrCreate("chat:fresh"); len(s.Messages) != 1 {
+		t.Errorf("fresh session should be kept, got %d messages", len(s.Messages))
+	}
+}
+
+func TestPruneWithoutTTL(t *testing.T) {
+	m := NewManager(t.TempDir())
+	s := m.GetOrCreate("chat:old")
+	s.Meta.UpdatedAt = time.Now().Add(-365 * 24 * time.Hour).UTC().Format(time.RFC3339)
+	if err := m.Save(s); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := m.Prune()
+	if err != nil || n != 0 {
+		t.Fatalf("Prune() = %d, %v; want 0, nil", n, err)
+	}
+}
+
+func TestLoadPartiallyWrittenFile(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		wantMsgs  int
+		wantFresh bool
+	}{
+		{
+			name: "truncated last message",
+			content: `{"key":"chat:1","created_at":"2025-01-01T00:00:00Z","updated_at":"2025-01-01T00:00:00Z","last_consolidated":0}
+{"role":"user","content":"hello"}
+{"role":"assistant","content":"hi th`,
+			wantMsgs: 1,
+		},
+		{
+			name:      "truncated meta",
+			content:   `{"key":"chat:1","created_at":"2025-01`,
+			wantFresh: true,
+		},
+		{
+			name:      "empty file",
+			content:   "",
+			wantFresh: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, keyToFilename("chat:1")), []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			s := NewManager(dir).GetOrCreate("chat:1")
+			if s.Meta.Key != "chat:1" {
+				t.Errorf("Key = %q, want chat:1", s.Meta.Key)
+			}
+			if tt.wantFresh && s.Meta.CreatedAt == "2025-01-01T00:00:00Z" {
+				t.Error("expected a fresh session")
+			}
+			if len(s.Messages) != tt.wantMsgs {
+				t.Errorf("got %d messages, want %d", len(s.Messages), tt.wantMsgs)
+			}
+			if got := s.GetHistory(); len(got) != tt.wantMsgs {
+				t.Errorf("history has %d messages, want %d", len(got), tt.wantMsgs)
+			}
+		})
+	}
+}
+
+func TestSaveIsAtomic(t *testing.T) {
+	dir := t.TempDir()
+	m := NewManager(dir)
+	s := m.GetOrCreate("chat:atomic")
+	s.AppendMessage(Message{Role: "user", Content: "first"})
+	if err := m.Save(s); err != nil {
+		t.Fatal(err)
+	}
+	s.AppendMessage(Message{Role: "assistant", Content: "second"})
+	if err := m.Save(s); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != keyToFilename("chat:atomic") {
+		names := make([]string, 0, len(entries))
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		t.Fatalf("expected only the session file, got %v", names)
+	}
+
+	if loaded := NewManager(dir).GetOrCreate("chat:atomic"); len(loaded.Messages) != 2 {
+		t.Errorf("expected 2 messages after reload, got %d", len(loaded.Messages))
+	}
+}
+
+func newExportFixture(t *testing.T) *Manager {
+	t.Helper()
+	m := NewManager(t.TempDir())
+	s := m.GetOrCreate("chat:export")
+	s.AppendMessage(Message{Role: "user", Content: "What's the weather?"})
+	s.AppendMessage(Message{Role: "assistant", ToolCalls: []ToolCallRecord{
+		{ID: "call_abc123", Name: "web_get", Arguments: `{"url":"https://wttr.in"}`},
+	}})
+	s.AppendMessage(Message{Role: "tool", Content: "Sunny, 21C", ToolCallID: "call_abc123"})
+	s.AppendMessage(Message{Role: "assistant", Content: "It's sunny and 21C."})
+	if err := m.Save(s); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestExportMarkdown(t *testing.T) {
+	m := newExportFixture(t)
+
+	out, err := m.Export("chat:export", "markdown")
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+
+	order := []string{
+		"# Session chat:export",
+		"## User",
+		"What's the weather?",
+		"## Assistant",
+		"<summary>Tool call: web_get</summary>",
+		"<summary>Result: web_get</summary>",
+		"Sunny, 21C",
+		"It's sunny and 21C.",
+	}
+	pos := 0
+	for _, want := range order {
+		i := strings.Index(out[pos:], want)
+		if i < 0 {
+			t.Fatalf("missing %q after offset %d in:\n%s", want, pos, out)
+		}
+		pos += i + len(want)
+	}
+	if strings.Contains(out, "call_abc123") || strings.Contains(out, "tool_call_id") {
+		t.Errorf("export leaked tool call IDs:\n%s", out)
+	}
+}
+
+func TestExportText(t *testing.T) {
+	m := newExportFixture(t)
+
+	// load from disk rather than cache
+	out, err := NewManager(m.dataDir).Export("chat:export", "text")
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	want := "User: What's the weather?\n\n" +
+		"[Assistant called web_get] {\"url\":\"https://wttr.in\"}\n\n" +
+		"[web_get result] Sunny, 21C\n\n" +
+		"Assistant: It's sunny and 21C.\n"
+	if out != want {
+		t.Errorf("text export =\n%q\nwant\n%q", out, want)
+	}
+}
+
+func TestExportErrors(t *testing.T) {
+	m := newExportFixture(t)
+	if _, err := m.Export("chat:missing", "markdown"); err == nil {
+		t.Error("expected error for unknown session")
+	}
+	if _, err := m.Export("chat:export", "pdf"); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
+
+func TestLRUEviction(t *testing.T) {
+	dir := t.TempDir()
+	m := NewManager(dir)
+	m.SetMaxCached(2)
+
+	a := m.GetOrCreate("chat:a")
+	a.AppendMessage(Message{Role: "user", Content: "from a"})
+	m.GetOrCreate("chat:b")
+	m.GetOrCreate("chat:a") // a is now more recent than b
+	m.GetOrCreate("chat:c") // evicts b
+
+	if got := m.cache.len(); got != 2 {
+		t.Fatalf("cache holds %d sessions, want 2", got)
+	}
+	if _, ok := m.cache.peek("chat:b"); ok {
+		t.Error("least recently used session chat:b was not evicted")
+	}
+	if _, ok := m.cache.peek("chat:a"); !ok {
+		t.Error("recently used session chat:a was evicted")
+	}
+
+	m.GetOrCreate("chat:d") // evicts a, which has unsaved messages
+	if _, ok := m.cache.peek("chat:a"); ok {
+		t.Fatal("chat:a should have been evicted")
+	}
+	reloaded := m.GetOrCreate("chat:a")
+	if reloaded == a {
+		t.Fatal("expected a fresh instance loaded from disk")
+	}
+	msgs := reloaded.AllMessages()
+	if len(msgs) != 1 || msgs[0].Content != "from a" {
+		t.Errorf("reloaded messages = %+v, want the message saved on eviction", msgs)
+	}
+}
+
+func TestSetMaxCachedShrinksCache(t *testing.T) {
+	m := NewManager(t.TempDir())
+	for _, key := range []string{"k1", "k2", "k3"} {
+		m.GetOrCreate(key)
+	}
+	m.SetMaxCached(1)
+	if got := m.cache.keys(); len(got) != 1 || got[0] != "k3" {
+		t.Errorf("cache keys = %v, want [k3]", got)
+	}
+}
+
+func TestFork(t *testing.T) {
+	dir := t.TempDir()
+	m := NewManager(dir)
+	orig := m.GetOrCreate("cli:main")
+	orig.AppendMessage(Message{Role: "user", Content: "plan a trip"})
+	orig.AppendMessage(Message{Role: "assistant", ToolCalls: []ToolCallRecord{{ID: "c1", Name: "search", Arguments: `{"q":"paris"}`}}})
+	orig.SetConsolidated(1)
+
+	fork, err := m.Fork("cli:main", "cli:alt")
+	if err != nil {
+		t.Fatalf("Fork failed: %v", err)
+	}
+	if fork.Meta.Key != "cli:alt" || fork.Meta.LastConsolidated != 1 {
+		t.Errorf("fork meta = %+v, want new key and copied consolidation pointer", fork.Meta)
+	}
+	fork.AppendMessage(Message{Role: "user", Content: "go to rome instead"})
+	fork.Messages[1].ToolCalls[0].Name = "changed"
+
+	msgs := orig.AllMessages()
+	if len(msgs) != 2 || msgs[1].ToolCalls[0].Name != "search" {
+		t.Errorf("original changed by fork: %+v", msgs)
+	}
+	if m.GetOrCreate("cli:alt") != fork {
+		t.Error("expected fork to be cached under its new key")
+	}
+
+	// The fork is saved and survives a restart.
+	m2 := NewManager(dir)
+	if got := m2.GetOrCreate("cli:alt").AllMessages(); len(got) != 2 || got[0].Content != "plan a trip" {
+		t.Errorf("reloaded fork = %+v, want the copied messages", got)
+	}
+}
+
+func TestForkErrors(t *testing.T) {
+	m := NewManager(t.TempDir())
+	m.GetOrCreate("cli:main").AppendMessage(Message{Role: "user", Content: "hi"})
+	if err := m.Save(m.GetOrCreate("cli:main")); err != nil {
+		t.Fatal(err)
+	}
+	m.GetOrCreate("cli:cached")
+
+	for _, tt := range []struct{ key, newKey, want string }{
+		{"cli:missing", "cli:new", "not found"},
+		{"cli:main", "cli:cached", "already exists"},
+		{"cli:cached", "cli:main", "already exists"},
+		{"cli:main", "cli:main", "invalid fork key"},
+	} {
+		if _, err := m.Fork(tt.key, tt.newKey); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Fork(%q, %q) error = %v, want %q", tt.key, tt.newKey, err, tt.want)
+		}
+	}
+}
+
+func TestReset(t *testing.T) {
+	dir := t.TempDir()
+	m := NewManager(dir)
+	s := m.GetOrCreate("telegram:7")
+	s.AppendMessage(Message{Role: "user", Content: "remember this"})
+	s.AppendMessage(Message{Role: "assistant", Content: "noted"})
+
+	archived, err := m.Reset("telegram:7")
+	if err != nil {
+		t.Fatalf("Reset failed: %v", err)
+	}
+	if got := s.GetHistory(); len(got) != 0 {
+		t.Errorf("history after reset = %+v, want empty", got)
+	}
+	if got := NewManager(dir).GetOrCreate("telegram:7").AllMessages(); len(got) != 0 {
+		t.Errorf("saved session after reset = %+v, want empty", got)
+	}
+
+	if filepath.Dir(archived) != filepath.Join(dir, "archive") {
+		t.Errorf("archive path = %q, want it under the archive dir", archived)
+	}
+	data, err := os.ReadFile(archived)
+	if err != nil {
+		t.Fatalf("archive not written: %v", err)
+	}
+	if !strings.Contains(string(data), "remember this") {
+		t.Errorf("archive missing old history:\n%s", data)
+	}
+
+	// Nothing to archive for a session that was never used.
+	if archived, err := m.Reset("telegram:new"); err != nil || archived != "" {
+		t.Errorf("Reset of unknown session = %q, %v; want no archive", archived, err)
+	}
+}
@@ -0,0 +1,64 @@
+// Package tlsutil builds TLS client settings for endpoints that do not use a
+// publicly trusted certificate, such as self-hosted model servers.
+package tlsutil
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// Options selects how server certificates are verified. The zero value
+// verifies against the system roots.
+type Options struct {
+	// CAFile is a PEM bundle of certificates to trust in addition to the
+	// system roots.
+	CAFile string `json:"caFile,omitempty"`
+	// InsecureSkipVerify accepts any server certificate. It leaves the
+	// connection open to interception and is meant only for testing.
+	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
+}
+
+// IsZero reports whether o keeps the default verification.
+func (o Options) IsZero() bool {
+	return o.CAFile == "" && !o.InsecureSkipVerify
+}
+
+// Config returns the client TLS configuration for o, or nil when o is zero.
+func (o Options) Config() (*tls.Config, error) {
+	if o.IsZero() {
+		return nil, nil
+	}
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if o.CAFile != "" {
+		pem, err := os.ReadFile(o.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", o.CAFile)
+		}
+		cfg.RootCAs = pool
+	}
+	cfg.InsecureSkipVerify = o.InsecureSkipVerify
+	return cfg, nil
+}
+
+// Transport returns an HTTP transport that verifies servers according to o:
+// a copy of http.DefaultTransport with its TLS settings replaced. It returns
+// nil when o is zero, so callers keep their default transport.
+func (o Options) Transport() (http.RoundTripper, error) {
+	cfg, err := o.Config()
+	if err != nil || cfg == nil {
+		return nil, err
+	}
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.TLSClientConfig = cfg
+	return t, nil
+}
@@ -0,0 +1,76 @@
+package tlsutil
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeCA saves the certificate of srv, which signs itself, as a PEM bundle.
+func writeCA(t *testing.T, srv *httptest.Server) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func get(t *testing.T, opts Options, url string) error {
+	t.Helper()
+	transport, err := opts.Transport()
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := (&http.Client{Transport: transport}).Get(url)
+	if err == nil {
+		resp.Body.Close()
+	}
+	return err
+}
+
+func TestCustomCA(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	if err := get(t, Options{}, srv.URL); err == nil || !strings.Contains(err.Error(), "certificate") {
+		t.Errorf("default verification: err = %v, want a certificate error", err)
+	}
+	if err := get(t, Options{CAFile: writeCA(t, srv)}, srv.URL); err != nil {
+		t.Errorf("with the server's CA: %v", err)
+	}
+}
+
+func TestInsecureSkipVerify(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	if err := get(t, Options{InsecureSkipVerify: true}, srv.URL); err != nil {
+		t.Errorf("with verification off: %v", err)
+	}
+}
+
+func TestZeroOptions(t *testing.T) {
+	if cfg, err := (Options{}).Config(); cfg != nil || err != nil {
+		t.Errorf("Config() = %v, %v; want nil, nil", cfg, err)
+	}
+	if rt, err := (Options{}).Transport(); rt != nil || err != nil {
+		t.Errorf("Transport() = %v, %v; want nil, nil", rt, err)
+	}
+}
+
+func TestBadCAFile(t *testing.T) {
+	if _, err := (Options{CAFile: filepath.Join(t.TempDir(), "missing.pem")}).Config(); err == nil {
+		t.Error("expected error for a missing CA file")
+	}
+	junk := filepath.Join(t.TempDir(), "junk.pem")
+	os.WriteFile(junk, []byte("not a certificate"), 0o644)
+	if _, err := (Options{CAFile: junk}).Config(); err == nil || !strings.Contains(err.Error(), "no certificates") {
+		t.Errorf("err = %v, want no certificates found", err)
+	}
+}
@@ -4,37 +4,83 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
+	"time"
+
+	robfigcron "github.com/robfig/cron/v3"
+
+	"github.com/coopco/nanobot/internal/bus"
 )
 
+// CronSchedule mirrors cron.CronSchedule to keep this package independent of
+// the scheduler. Type is one of "cron", "every", "at", or "once".
+type CronSchedule struct {
+	Type       string
+	Expression string
+	Timezone   string
+}
+
 // CronManager defines the interface for managing cron jobs.
 type CronManager interface {
-	AddJob(schedule, message, sessionKey string) (string, error)
+	// AddJob schedules message for sessionKey. Replies go to chatID on
+	// channel; an empty channel leaves delivery to the session.
+	AddJob(schedule CronSchedule, message, sessionKey, channel, chatID string) (string, error)
 	RemoveJob(id string) error
+	EnableJob(id string) error
+	DisableJob(id string) error
+	// ListJobs returns a human-readable listing of jobs, including when each
+	// will next run (see cron.FormatJobs).
 	ListJobs() string
 }
 
 type ManageCronTool struct {
 	manager CronManager
+	seconds bool // cron expressions carry a leading seconds field
 }
 
 func NewManageCronTool(manager CronManager) *ManageCronTool {
 	return &ManageCronTool{manager: manager}
 }
 
-func (t *ManageCronTool) Name() string        { return "manage_cron" }
-func (t *ManageCronTool) Description() string { return "Add, remove, or list cron jobs" }
+// SetSecondsField makes the tool accept six-field cron expressions with a
+// leading seconds field. It should match cron.Service.SetSecondsField.
+func (t *ManageCronTool) SetSecondsField(enabled bool) { t.seconds = enabled }
+
+func (t *ManageCronTool) Name() string { return "manage_cron" }
+func (t *ManageCronTool) Description() string {
+	return "Add, remove, enable, disable, or list cron jobs. To add, prefer type and expression over raw cron syntax: " +
+		"type \"at\" with expression \"09:00\" and timezone \"Europe/London\" runs every day at 09:00 London time"
+}
 func (t *ManageCronTool) Parameters() json.RawMessage {
 	return json.RawMessage(`{
 		"type": "object",
 		"properties": {
 			"action": {
 				"type": "string",
-				"enum": ["add", "remove", "list"],
+				"enum": ["add", "remove", "enable", "disable", "list"],
 				"description": "Action to perform"
 			},
+			"type": {
+				"type": "string",
+				"enum": ["cron", "every", "at", "once"],
+				"description": "Kind of schedule (for add): cron for a cron expression, every for a fixed interval, at for a daily time, once for a single run"
+			},
+			"expression": {
+				"type": "string",
+				"description": "Schedule for the given type (for add): \"0 9 * * 1-5\" for cron, \"30m\" for every, \"09:00\" for at, \"2025-12-01T14:00:00Z\" for once"
+			},
 			"schedule": {
 				"type": "string",
-				"description": "Cron expression or interval (for add)"
+				"description": "Alternative to type and expression (for add): a 5-field cron expression like \"0 9 * * 1-5\", an interval like \"30m\", a daily time like \"14:30\", or an RFC3339 timestamp like \"2025-12-01T14:00:00Z\" for a single run"
+			},
+			"schedule_type": {
+				"type": "string",
+				"enum": ["cron", "every", "at", "once"],
+				"description": "Kind of schedule expression (for add); inferred from the expression when omitted"
+			},
+			"timezone": {
+				"type": "string",
+				"description": "IANA timezone for cron and at schedules, e.g. \"America/New_York\" (for add, optional; host local time when omitted)"
 			},
 			"message": {
 				"type": "string",
@@ -44,9 +90,17 @@ func (t *ManageCronTool) Parameters() json.RawMessage {
 				"type": "string",
 				"description": "Target session (for add)"
 			},
+			"channel": {
+				"type": "string",
+				"description": "Channel to deliver replies to, e.g. \"telegram\" (for add, optional; defaults to the current chat)"
+			},
+			"chat_id": {
+				"type": "string",
+				"description": "Chat on that channel to deliver replies to (for add, required with channel)"
+			},
 			"job_id": {
 				"type": "string",
-				"description": "Job ID (for remove)"
+				"description": "Job ID (for remove, enable, disable)"
 			}
 		},
 		"required": ["action"]
@@ -55,11 +109,17 @@ func (t *ManageCronTool) Parameters() json.RawMessage {
 
 func (t *ManageCronTool) Execute(ctx context.Context, params json.RawMessage) (string, error) {
 	var p struct {
-		Action     string `json:"action"`
-		Schedule   string `json:"schedule"`
-		Message    string `json:"message"`
-		SessionKey string `json:"session_key"`
-		JobID      string `json:"job_id"`
+		Action       string `json:"action"`
+		Type         string `json:"type"`
+		Expression   string `json:"expression"`
+		Schedule     string `json:"schedule"`
+		ScheduleType string `json:"schedule_type"`
+		Timezone     string `json:"timezone"`
+		Message      string `json:"message"`
+		SessionKey   string `json:"session_key"`
+		Channel      string `json:"channel"`
+		ChatID       string `json:"chat_id"`
+		JobID        string `json:"job_id"`
 	}
 	if err := json.Unmarshal(params, &p); err != nil {
 		return "", fmt.Errorf("invalid parameters: %w", err)
@@ -67,10 +127,31 @@ func (t *ManageCronTool) Execute(ctx context.Context, params json.RawMessage) (s
 
 	switch p.Action {
 	case "add":
-		if p.Schedule == "" || p.Message == "" || p.SessionKey == "" {
-			return "", fmt.Errorf("schedule, message, and session_key are required for add action")
+		scheduleType, expr, err := scheduleArgs(p.Type, p.Expression, p.ScheduleType, p.Schedule)
+		if err != nil {
+			return "", err
+		}
+		if expr == "" || p.Message == "" || p.SessionKey == "" {
+			return "", fmt.Errorf("expression (or schedule), message, and session_key are required for add action")
+		}
+		schedule, err := parseCronSchedule(scheduleType, expr, p.Timezone, t.seconds)
+		if err != nil {
+			return "", err
+		}
+		channel, chatID := p.Channel, p.ChatID
+		if channel == "" {
+			if chatID != "" {
+				return "", fmt.Errorf("chat_id requires channel")
+			}
+			// Deliver to the chat the job was requested from, unless that is
+			// itself internal traffic with nowhere to reply.
+			if ch, id := OriginFrom(ctx); ch != bus.SystemChannel {
+				channel, chatID = ch, id
+			}
+		} else if chatID == "" {
+			return "", fmt.Errorf("chat_id is required with channel")
 		}
-		jobID, err := t.manager.AddJob(p.Schedule, p.Message, p.SessionKey)
+		jobID, err := t.manager.AddJob(schedule, p.Message, p.SessionKey, channel, chatID)
 		if err != nil {
 			return "", fmt.Errorf("failed to add job: %w", err)
 		}
@@ -85,10 +166,129 @@ func (t *ManageCronTool) Execute(ctx context.Context, params json.RawMessage) (s
 		}
 		return fmt.Sprintf("Cron job removed: %s", p.JobID), nil
 
+	case "enable":
+		if p.JobID == "" {
+			return "", fmt.Errorf("job_id is required for enable action")
+		}
+		if err := t.manager.EnableJob(p.JobID); err != nil {
+			return "", fmt.Errorf("failed to enable job: %w", err)
+		}
+		return fmt.Sprintf("Cron job enabled: %s", p.JobID), nil
+
+	case "disable":
+		if p.JobID == "" {
+			return "", fmt.Errorf("job_id is required for disable action")
+		}
+		if err := t.manager.DisableJob(p.JobID); err != nil {
+			return "", fmt.Errorf("failed to disable job: %w", err)
+		}
+		return fmt.Sprintf("Cron job disabled: %s", p.JobID), nil
+
 	case "list":
 		return t.manager.ListJobs(), nil
 
 	default:
-		return "", fmt.Errorf("invalid action: %s (must be add, remove, or list)", p.Action)
+		return "", fmt.Errorf("invalid action: %s (must be add, remove, enable, disable, or list)", p.Action)
+	}
+}
+
+// scheduleArgs merges the structured type and expression parameters with the
+// older schedule_type and schedule ones, which mean the same thing. Giving
+// both forms is fine as long as they agree.
+func scheduleArgs(typ, expr, legacyType, legacyExpr string) (scheduleType, expression string, err error) {
+	typ, legacyType = strings.TrimSpace(typ), strings.TrimSpace(legacyType)
+	expr, legacyExpr = strings.TrimSpace(expr), strings.TrimSpace(legacyExpr)
+	if typ != "" && legacyType != "" && typ != legacyType {
+		return "", "", fmt.Errorf("type %q and schedule_type %q disagree; give only type", typ, legacyType)
+	}
+	if expr != "" && legacyExpr != "" && expr != legacyExpr {
+		return "", "", fmt.Errorf("expression %q and schedule %q disagree; give only expression", expr, legacyExpr)
+	}
+	if typ == "" {
+		typ = legacyType
+	}
+	if expr == "" {
+		expr = legacyExpr
+	}
+	if typ != "" && expr == "" {
+		return "", "", fmt.Errorf("expression is required with type %q", typ)
+	}
+	return typ, expr, nil
+}
+
+// parseCronSchedule builds and validates a schedule from tool arguments. When
+// scheduleType is empty it is inferred from the expression. Errors describe the
+// expected format so the model can correct its call. With seconds set, cron
+// expressions take a leading seconds field.
+func parseCronSchedule(scheduleType, expr, timezone string, seconds bool) (CronSchedule, error) {
+	expr = strings.TrimSpace(expr)
+	if scheduleType == "" {
+		scheduleType = inferScheduleType(expr)
+	}
+	schedule := CronSchedule{Type: scheduleType, Expression: expr, Timezone: timezone}
+
+	if timezone != "" {
+		switch scheduleType {
+		case "every":
+			return schedule, fmt.Errorf("timezone does not apply to every schedules, which run at a fixed interval")
+		case "once":
+			return schedule, fmt.Errorf("timezone does not apply to once schedules; give the offset in the timestamp instead, e.g. \"2025-12-01T14:00:00+01:00\"")
+		}
+		if _, err := time.LoadLocation(timezone); err != nil {
+			return schedule, fmt.Errorf("unknown timezone %q: expected an IANA name such as \"Europe/London\" or \"UTC\"", timezone)
+		}
+	}
+
+	switch scheduleType {
+	case "cron":
+		if seconds {
+			if _, err := cronSecondsParser.Parse(expr); err != nil {
+				return schedule, fmt.Errorf("invalid cron schedule %q: expected 6 fields \"second minute hour day-of-month month day-of-week\", e.g. \"*/30 * * * * *\" (%v)", expr, err)
+			}
+		} else if _, err := robfigcron.ParseStandard(expr); err != nil {
+			return schedule, fmt.Errorf("invalid cron schedule %q: expected 5 fields \"minute hour day-of-month month day-of-week\", e.g. \"0 9 * * 1-5\" (%v)", expr, err)
+		}
+	case "every":
+		d, err := time.ParseDuration(strings.TrimPrefix(expr, "@every "))
+		if err != nil || d <= 0 {
+			return schedule, fmt.Errorf("invalid interval %q: expected a positive duration such as \"30m\", \"2h\", or \"1h30m\"", expr)
+		}
+		schedule.Expression = d.String()
+	case "at":
+		var h, m int
+		if _, err := fmt.Sscanf(expr, "%d:%d", &h, &m); err != nil || h < 0 || h > 23 || m < 0 || m > 59 {
+			return schedule, fmt.Errorf("invalid daily time %q: expected 24-hour HH:MM, e.g. \"09:00\" or \"14:30\"", expr)
+		}
+	case "once":
+		at, err := time.Parse(time.RFC3339, expr)
+		if err != nil {
+			return schedule, fmt.Errorf("invalid timestamp %q: expected RFC3339 with a timezone offset, e.g. \"2025-12-01T14:00:00Z\"", expr)
+		}
+		if !at.After(time.Now()) {
+			return schedule, fmt.Errorf("timestamp %q is in the past", expr)
+		}
+	default:
+		return schedule, fmt.Errorf("invalid schedule_type %q: must be cron, every, at, or once", scheduleType)
+	}
+	return schedule, nil
+}
+
+// cronSecondsParser accepts cron expressions with a leading seconds field.
+var cronSecondsParser = robfigcron.NewParser(robfigcron.Second | robfigcron.Minute | robfigcron.Hour | robfigcron.Dom | robfigcron.Month | robfigcron.Dow | robfigcron.Descriptor)
+
+// inferScheduleType guesses the schedule type from the shape of expr.
+func inferScheduleType(expr string) string {
+	if strings.HasPrefix(expr, "@every ") {
+		return "every"
+	}
+	if _, err := time.ParseDuration(expr); err == nil {
+		return "every"
+	}
+	if _, err := time.Parse(time.RFC3339, expr); err == nil {
+		return "once"
+	}
+	if len(expr) <= 5 && strings.Count(expr, ":") == 1 {
+		return "at"
 	}
+	return "cron"
 }
@@ -6,27 +6,29 @@ import (
 	"fmt"
 	"strings"
 	"testing"
+	"time"
 )
 
 // mockCronManager implements CronManager for testing.
 type mockCronManager struct {
 	jobs    map[string]string // id -> description
+	paused  map[string]bool
 	nextID  int
 	addErr  error
 	rmErr   error
 }
 
 func newMockCronManager() *mockCronManager {
-	return &mockCronManager{jobs: make(map[string]string)}
+	return &mockCronManager{jobs: make(map[string]string), paused: make(map[string]bool)}
 }
 
-func (m *mockCronManager) AddJob(schedule, message, sessionKey string) (string, error) {
+func (m *mockCronManager) AddJob(schedule CronSchedule, message, sessionKey, channel, chatID string) (string, error) {
 	if m.addErr != nil {
 		return "", m.addErr
 	}
 	m.nextID++
 	id := fmt.Sprintf("job-%d", m.nextID)
-	m.jobs[id] = fmt.Sprintf("%s|%s|%s", schedule, message, sessionKey)
+	m.jobs[id] = fmt.Sprintf("%s %s|%s|%s|%s:%s", schedule.Type, schedule.Expression, message, sessionKey, channel, chatID)
 	return id, nil
 }
 
@@ -41,6 +43,22 @@ func (m *mockCronManager) RemoveJob(id string) error {
 	return nil
 }
 
+func (m *mockCronManager) EnableJob(id string) error {
+	if _, ok := m.jobs[id]; !ok {
+		return fmt.Errorf("job %s not found", id)
+	}
+	delete(m.paused, id)
+	return nil
+}
+
+func (m *mockCronManager) DisableJob(id string) error {
+	if _, ok := m.jobs[id]; !ok {
+		return fmt.Errorf("job %s not found", id)
+	}
+	m.paused[id] = true
+	return nil
+}
+
 func (m *mockCronManager) ListJobs() string {
 	if len(m.jobs) == 0 {
 		return "no jobs"
@@ -167,6 +185,41 @@ func TestManageCronTool_List_Empty(t *testing.T) {
 	}
 }
 
+func TestManageCronTool_EnableDisable(t *testing.T) {
+	mgr := newMockCronManager()
+	mgr.jobs["job-1"] = "schedule|msg|key"
+	tool := NewManageCronTool(mgr)
+
+	params, _ := json.Marshal(map[string]any{"action": "disable", "job_id": "job-1"})
+	result, err := tool.Execute(context.Background(), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(result, "disabled") || !mgr.paused["job-1"] {
+		t.Errorf("expected job-1 to be disabled, got %q", result)
+	}
+
+	params, _ = json.Marshal(map[string]any{"action": "enable", "job_id": "job-1"})
+	result, err = tool.Execute(context.Background(), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(result, "enabled") || mgr.paused["job-1"] {
+		t.Errorf("expected job-1 to be enabled, got %q", result)
+	}
+
+	for _, action := range []string{"enable", "disable"} {
+		params, _ = json.Marshal(map[string]any{"action": action})
+		if _, err := tool.Execute(context.Background(), params); err == nil {
+			t.Errorf("%s: expected error without job_id", action)
+		}
+		params, _ = json.Marshal(map[string]any{"action": action, "job_id": "missing"})
+		if _, err := tool.Execute(context.Background(), params); err == nil {
+			t.Errorf("%s: expected error for unknown job", action)
+		}
+	}
+}
+
 func TestManageCronTool_InvalidAction(t *testing.T) {
 	mgr := newMockCronManager()
 	tool := NewManageCronTool(mgr)
@@ -204,6 +257,223 @@ func TestManageCronTool_Add_ManagerError(t *testing.T) {
 	}
 }
 
+func TestManageCronTool_AddScheduleTypes(t *testing.T) {
+	future := time.Now().Add(24 * time.Hour).UTC().Format(time.RFC3339)
+
+	tests := []struct {
+		name         string
+		schedule     string
+		scheduleType string
+		timezone     string
+		want         string // stored "type expression" prefix; empty means an error is expected
+		errContains  string
+	}{
+		{"cron inferred", "0 9 * * 1-5", "", "", "cron 0 9 * * 1-5", ""},
+		{"cron with timezone", "0 9 * * *", "cron", "America/New_York", "cron 0 9 * * *", ""},
+		{"every inferred", "30m", "", "", "every 30m0s", ""},
+		{"every prefix", "@every 2h", "", "", "every 2h0m0s", ""},
+		{"at inferred", "14:30", "", "", "at 14:30", ""},
+		{"once inferred", future, "", "", "once " + future, ""},
+		{"malformed cron", "every morning", "", "", "", "expected 5 fields"},
+		{"cron too many fields", "0 9 * * * *", "cron", "", "", "expected 5 fields"},
+		{"bad interval", "soon", "every", "", "", "positive duration"},
+		{"negative interval", "-5m", "every", "", "", "positive duration"},
+		{"bad daily time", "25:00", "at", "", "", "HH:MM"},
+		{"past timestamp", "2001-01-01T00:00:00Z", "once", "", "", "in the past"},
+		{"bad timestamp", "2025-12-01 14:00", "once", "", "", "RFC3339"},
+		{"unknown type", "* * * * *", "hourly", "", "", "must be cron, every, at, or once"},
+		{"unknown timezone", "09:00", "at", "Mars/Olympus", "", "unknown timezone"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mgr := newMockCronManager()
+			tool := NewManageCronTool(mgr)
+
+			params, _ := json.Marshal(map[string]any{
+				"action":        "add",
+				"schedule":      tt.schedule,
+				"schedule_type": tt.scheduleType,
+				"timezone":      tt.timezone,
+				"message":       "m",
+				"session_key":   "k",
+			})
+			_, err := tool.Execute(context.Background(), params)
+			if tt.want == "" {
+				if err == nil {
+					t.Fatalf("expected error for %q", tt.schedule)
+				}
+				if !strings.Contains(err.Error(), tt.errContains) {
+					t.Errorf("error %q does not contain %q", err, tt.errContains)
+				}
+				if len(mgr.jobs) != 0 {
+					t.Error("invalid schedule reached the manager")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := mgr.jobs["job-1"]; !strings.HasPrefix(got, tt.want+"|") {
+				t.Errorf("stored %q, want prefix %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestManageCronTool_AddStructured(t *testing.T) {
+	future := time.Now().Add(24 * time.Hour).UTC().Format(time.RFC3339)
+
+	tests := []struct {
+		name        string
+		params      map[string]any
+		want        string // stored "type expression" prefix; empty means an error is expected
+		wantTZ      string
+		errContains string
+	}{
+		{"cron", map[string]any{"type": "cron", "expression": "0 9 * * 1-5"}, "cron 0 9 * * 1-5", "", ""},
+		{"cron with timezone", map[string]any{"type": "cron", "expression": "0 9 * * *", "timezone": "Asia/Tokyo"}, "cron 0 9 * * *", "Asia/Tokyo", ""},
+		{"every", map[string]any{"type": "every", "expression": "90m"}, "every 1h30m0s", "", ""},
+		{"at", map[string]any{"type": "at", "expression": "09:00"}, "at 09:00", "", ""},
+		{"at with timezone", map[string]any{"type": "at", "expression": "09:00", "timezone": "Europe/London"}, "at 09:00", "Europe/London", ""},
+		{"once", map[string]any{"type": "once", "expression": future}, "once " + future, "", ""},
+		{"expression inferred", map[string]any{"expression": "14:30"}, "at 14:30", "", ""},
+		{"agrees with legacy", map[string]any{"type": "at", "expression": "09:00", "schedule_type": "at", "schedule": "09:00"}, "at 09:00", "", ""},
+		{"type mismatch", map[string]any{"type": "at", "schedule_type": "cron", "expression": "09:00"}, "", "", "disagree"},
+		{"expression mismatch", map[string]any{"expression": "09:00", "schedule": "10:00"}, "", "", "disagree"},
+		{"type without expression", map[string]any{"type": "at"}, "", "", "expression is required"},
+		{"at out of range", map[string]any{"type": "at", "expression": "24:00", "timezone": "Europe/London"}, "", "", "HH:MM"},
+		{"every with timezone", map[string]any{"type": "every", "expression": "1h", "timezone": "Europe/London"}, "", "", "does not apply to every"},
+		{"once with timezone", map[string]any{"type": "once", "expression": future, "timezone": "Europe/London"}, "", "", "does not apply to once"},
+		{"unknown timezone", map[string]any{"type": "cron", "expression": "0 9 * * *", "timezone": "Europe/Atlantis"}, "", "", "unknown timezone"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mgr := &recordingCronManager{mockCronManager: newMockCronManager()}
+			tool := NewManageCronTool(mgr)
+
+			args := map[string]any{"action": "add", "message": "m", "session_key": "k"}
+			for k, v := range tt.params {
+				args[k] = v
+			}
+			params, _ := json.Marshal(args)
+			_, err := tool.Execute(context.Background(), params)
+			if tt.want == "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Errorf("error = %v, want one containing %q", err, tt.errContains)
+				}
+				if len(mgr.jobs) != 0 {
+					t.Error("invalid schedule reached the manager")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := mgr.jobs["job-1"]; !strings.HasPrefix(got, tt.want+"|") {
+				t.Errorf("stored %q, want prefix %q", got, tt.want)
+			}
+			if mgr.last.Timezone != tt.wantTZ {
+				t.Errorf("timezone = %q, want %q", mgr.last.Timezone, tt.wantTZ)
+			}
+		})
+	}
+}
+
+// recordingCronManager keeps the last schedule added, timezone included.
+type recordingCronManager struct {
+	*mockCronManager
+	last CronSchedule
+}
+
+func (m *recordingCronManager) AddJob(schedule CronSchedule, message, sessionKey, channel, chatID string) (string, error) {
+	m.last = schedule
+	return m.mockCronManager.AddJob(schedule, message, sessionKey, channel, chatID)
+}
+
+func TestManageCronTool_SecondsField(t *testing.T) {
+	tests := []struct {
+		name        string
+		seconds     bool
+		schedule    string
+		errContains string
+	}{
+		{"standard five fields", false, "*/5 * * * *", ""},
+		{"standard six fields", false, "*/30 * * * * *", "expected 5 fields"},
+		{"seconds six fields", true, "*/30 * * * * *", ""},
+		{"seconds five fields", true, "*/5 * * * *", "expected 6 fields"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mgr := newMockCronManager()
+			tool := NewManageCronTool(mgr)
+			tool.SetSecondsField(tt.seconds)
+
+			params, _ := json.Marshal(map[string]any{
+				"action":      "add",
+				"schedule":    tt.schedule,
+				"message":     "m",
+				"session_key": "k",
+			})
+			_, err := tool.Execute(context.Background(), params)
+			if tt.errContains == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+				t.Errorf("error = %v, want one containing %q", err, tt.errContains)
+			}
+		})
+	}
+}
+
+func TestManageCronTool_AddDestination(t *testing.T) {
+	tests := []struct {
+		name        string
+		origin      [2]string
+		channel     string
+		chatID      string
+		want        string // stored destination; empty means an error is expected
+		errContains string
+	}{
+		{"defaults to origin", [2]string{"telegram", "42"}, "", "", "telegram:42", ""},
+		{"explicit destination", [2]string{"telegram", "42"}, "discord", "c9", "discord:c9", ""},
+		{"system origin", [2]string{"system", ""}, "", "", ":", ""},
+		{"channel without chat", [2]string{}, "discord", "", "", "chat_id is required"},
+		{"chat without channel", [2]string{}, "", "c9", "", "requires channel"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mgr := newMockCronManager()
+			tool := NewManageCronTool(mgr)
+			ctx := WithOrigin(context.Background(), tt.origin[0], tt.origin[1])
+
+			params, _ := json.Marshal(map[string]any{
+				"action":      "add",
+				"schedule":    "0 9 * * *",
+				"message":     "m",
+				"session_key": "k",
+				"channel":     tt.channel,
+				"chat_id":     tt.chatID,
+			})
+			_, err := tool.Execute(ctx, params)
+			if tt.want == "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Errorf("error = %v, want one containing %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := mgr.jobs["job-1"]; !strings.HasSuffix(got, "|"+tt.want) {
+				t.Errorf("stored %q, want destination %q", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestManageCronTool_Name(t *testing.T) {
 	tool := NewManageCronTool(newMockCronManager())
 	if tool.Name() != "manage_cron" {
@@ -0,0 +1,132 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+)
+
+const (
+	defaultReadChars = 20000
+	maxReadChars     = 100000
+	maxReadPageLen   = 2 * 1024 * 1024 // 2MB of HTML, before extraction
+)
+
+var (
+	// boilerplatePattern matches elements that never hold the main content.
+	// Go's regexp has no backreferences, so each element gets its own
+	// alternative.
+	boilerplatePattern = regexp.MustCompile(`(?is)<!--.*?-->|<script\b.*?</script>|<style\b.*?</style>|<noscript\b.*?</noscript>|<template\b.*?</template>|<svg\b.*?</svg>|<iframe\b.*?</iframe>|<nav\b.*?</nav>|<header\b.*?</header>|<footer\b.*?</footer>|<aside\b.*?</aside>|<form\b.*?</form>`)
+	titlePattern       = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+	articlePattern     = regexp.MustCompile(`(?is)<article\b[^>]*>(.*?)</article>`)
+	mainPattern        = regexp.MustCompile(`(?is)<main\b[^>]*>(.*?)</main>`)
+	bodyPattern        = regexp.MustCompile(`(?is)<body\b[^>]*>(.*)</body>`)
+	listItemPattern    = regexp.MustCompile(`(?i)<li\b[^>]*>`)
+	lineBreakPattern   = regexp.MustCompile(`(?i)<(br|/tr|/dd|/dt)\b[^>]*>`)
+	blockTagPattern    = regexp.MustCompile(`(?i)</?(p|div|section|hr|h[1-6]|ul|ol|table|blockquote|pre)\b[^>]*>`)
+	tagPattern         = regexp.MustCompile(`<[^>]*>`)
+	blankLinesPattern  = regexp.MustCompile(`\n{3,}`)
+)
+
+// ExtractTool fetches a web page and returns its readable text, without the
+// markup, scripts and navigation that web_get passes through.
+type ExtractTool struct {
+	hostAllowlist
+}
+
+func NewExtractTool() *ExtractTool { return &ExtractTool{} }
+
+func (t *ExtractTool) cloneTool() Tool { c := *t; return &c }
+
+func (t *ExtractTool) Name() string { return "read_url" }
+func (t *ExtractTool) Description() string {
+	return "Fetch a web page and return its main content as plain text, without markup, scripts or navigation"
+}
+func (t *ExtractTool) Parameters() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"url": {"type": "string", "description": "URL of the page to read"},
+			"max_chars": {"type": "integer", "description": "Longest text to return (default 20000, at most 100000)"}
+		},
+		"required": ["url"]
+	}`)
+}
+
+func (t *ExtractTool) Execute(ctx context.Context, params json.RawMessage) (string, error) {
+	var p struct {
+		URL      string `json:"url"`
+		MaxChars int    `json:"max_chars"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return "", fmt.Errorf("invalid parameters: %w", err)
+	}
+	if p.URL == "" {
+		return "", fmt.Errorf("url is required")
+	}
+	limit := p.MaxChars
+	switch {
+	case limit <= 0:
+		limit = defaultReadChars
+	case limit > maxReadChars:
+		limit = maxReadChars
+	}
+
+	body, err := t.fetch(ctx, p.URL, maxReadPageLen)
+	if err != nil {
+		return "", err
+	}
+	text := extractText(string(body))
+	if text == "" {
+		return "The page has no readable text.", nil
+	}
+	if runes := []rune(text); len(runes) > limit {
+		text = string(runes[:limit]) + "\n[truncated]"
+	}
+	return text, nil
+}
+
+// extractText returns the readable text of an HTML page: its title followed
+// by the content of the first article or main element, or of the whole body
+// when there is neither. Block elements become paragraphs and list items are
+// bulleted.
+func extractText(page string) string {
+	var title string
+	if m := titlePattern.FindStringSubmatch(page); m != nil {
+		title = strings.TrimSpace(html.UnescapeString(tagPattern.ReplaceAllString(m[1], "")))
+	}
+
+	page = boilerplatePattern.ReplaceAllString(page, "")
+	content := page
+	for _, re := range []*regexp.Regexp{articlePattern, mainPattern, bodyPattern} {
+		if m := re.FindStringSubmatch(page); m != nil {
+			content = m[1]
+			break
+		}
+	}
+	content = titlePattern.ReplaceAllString(content, "")
+	// Line breaks in the source are plain whitespace in HTML; the tags
+	// decide where lines and paragraphs end.
+	content = strings.Join(strings.Fields(content), " ")
+	content = listItemPattern.ReplaceAllString(content, "\n- ")
+	content = lineBreakPattern.ReplaceAllString(content, "\n")
+	content = blockTagPattern.ReplaceAllString(content, "\n\n")
+	content = html.UnescapeString(tagPattern.ReplaceAllString(content, " "))
+
+	var lines []string
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.Join(strings.Fields(line), " ")
+		if line == "-" {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	text := blankLinesPattern.ReplaceAllString(strings.TrimSpace(strings.Join(lines, "\n")), "\n\n")
+	if title != "" {
+		text = "# " + title + "\n\n" + text
+	}
+	return strings.TrimSpace(text)
+}
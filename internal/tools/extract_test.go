@@ -0,0 +1,110 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+const articlePage = `<!DOCTYPE html>
+<html>
+<head>
+  <title>Release notes &amp; more</title>
+  <style>body { color: red; }</style>
+  <script>var tracking = "secret";</script>
+</head>
+<body>
+  <header><a href="/">Home</a> | <a href="/blog">Blog</a></header>
+  <nav><ul><li>Docs</li><li>Download</li></ul></nav>
+  <article>
+    <h1>Version 2.0</h1>
+    <p>This release adds <strong>streaming</strong> and fixes &lt;many&gt; bugs.</p>
+    <!-- editor's note: do not publish -->
+    <ul>
+      <li>Faster startup</li>
+      <li>Smaller binaries</li>
+    </ul>
+    <script>document.write("injected")</script>
+  </article>
+  <aside>Related posts</aside>
+  <footer>Copyright 2025</footer>
+</body>
+</html>`
+
+func TestExtractTool_ReadsMainContent(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(articlePage))
+	}))
+	defer srv.Close()
+
+	params, _ := json.Marshal(map[string]any{"url": srv.URL})
+	result, err := NewExtractTool().Execute(context.Background(), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "# Release notes & more\n\n" +
+		"Version 2.0\n\n" +
+		"This release adds streaming and fixes <many> bugs.\n\n" +
+		"- Faster startup\n" +
+		"- Smaller binaries"
+	if result != want {
+		t.Errorf("result =\n%s\nwant\n%s", result, want)
+	}
+	for _, unwanted := range []string{"<strong", "<li", "tracking", "injected", "color", "Home", "Download", "Related", "Copyright", "editor"} {
+		if strings.Contains(result, unwanted) {
+			t.Errorf("result contains %q:\n%s", unwanted, result)
+		}
+	}
+}
+
+func TestExtractTool_FallsBackToBody(t *testing.T) {
+	got := extractText(`<html><body><div>First</div><div>Second <em>line</em></div><script>x()</script></body></html>`)
+	if got != "First\n\nSecond line" {
+		t.Errorf("extractText = %q", got)
+	}
+}
+
+func TestExtractTool_Truncates(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<p>" + strings.Repeat("word ", 100) + "</p>"))
+	}))
+	defer srv.Close()
+
+	params, _ := json.Marshal(map[string]any{"url": srv.URL, "max_chars": 20})
+	result, err := NewExtractTool().Execute(context.Background(), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != "word word word word \n[truncated]" {
+		t.Errorf("result = %q", result)
+	}
+}
+
+func TestExtractTool_AllowedHosts(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<p>hello</p>"))
+	}))
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+	params, _ := json.Marshal(map[string]any{"url": srv.URL})
+
+	reg := NewRegistry()
+	tool := NewExtractTool()
+	reg.Register(tool)
+
+	reg.SetAllowedHosts([]string{"example.com"})
+	_, err := tool.Execute(context.Background(), params)
+	if err == nil || !strings.Contains(err.Error(), "not in the allowed hosts") {
+		t.Errorf("error = %v, want host rejected", err)
+	}
+
+	reg.SetAllowedHosts([]string{u.Hostname()})
+	if result, err := tool.Execute(context.Background(), params); err != nil || result != "hello" {
+		t.Errorf("result = %q, err = %v; want hello", result, err)
+	}
+}
@@ -9,12 +9,75 @@ import (
 	"strings"
 )
 
+// root confines the paths a tool may touch to a workspace directory. The
+// zero value allows any path.
+type root struct {
+	dir string
+}
+
+// SetRoot restricts the tool to paths inside dir; relative paths are taken
+// relative to it. An empty dir lifts the restriction.
+func (r *root) SetRoot(dir string) {
+	r.dir = dir
+}
+
+// resolve returns the path to operate on. When a root is set, it resolves
+// symlinks and rejects paths that end up outside the root.
+func (r *root) resolve(path string) (string, error) {
+	if r.dir == "" {
+		return path, nil
+	}
+	base, err := filepath.Abs(r.dir)
+	if err != nil {
+		return "", fmt.Errorf("invalid workspace root: %w", err)
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(base, path)
+	}
+	realBase, err := evalExisting(base)
+	if err != nil {
+		return "", fmt.Errorf("invalid workspace root: %w", err)
+	}
+	resolved, err := evalExisting(filepath.Clean(path))
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve path: %w", err)
+	}
+	rel, err := filepath.Rel(realBase, resolved)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("path %s is outside the workspace %s", path, r.dir)
+	}
+	return resolved, nil
+}
+
+// evalExisting resolves symlinks in the longest existing prefix of path and
+// appends the rest unchanged, so paths that do not exist yet can be checked.
+func evalExisting(path string) (string, error) {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err == nil {
+		return resolved, nil
+	}
+	if !os.IsNotExist(err) {
+		return "", err
+	}
+	parent := filepath.Dir(path)
+	if parent == path {
+		return path, nil
+	}
+	realParent, err := evalExisting(parent)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(realParent, filepath.Base(path)), nil
+}
+
 // read_file tool
 
-type ReadFileTool struct{}
+type ReadFileTool struct{ root }
 
 func NewReadFileTool() *ReadFileTool { return &ReadFileTool{} }
 
+func (t *ReadFileTool) cloneTool() Tool { c := *t; return &c }
+
 func (t *ReadFileTool) Name() string        { return "read_file" }
 func (t *ReadFileTool) Description() string { return "Read file content with optional line offset and limit" }
 func (t *ReadFileTool) Parameters() json.RawMessage {
@@ -38,7 +101,11 @@ func (t *ReadFileTool) Execute(_ context.Context, params json.RawMessage) (strin
 	if err := json.Unmarshal(params, &p); err != nil {
 		return "", fmt.Errorf("invalid parameters: %w", err)
 	}
-	data, err := os.ReadFile(p.Path)
+	path, err := t.resolve(p.Path)
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(path)
 	if err != nil {
 		return "", fmt.Errorf("failed to read file: %w", err)
 	}
@@ -63,10 +130,12 @@ func (t *ReadFileTool) Execute(_ context.Context, params json.RawMessage) (strin
 
 // write_file tool
 
-type WriteFileTool struct{}
+type WriteFileTool struct{ root }
 
 func NewWriteFileTool() *WriteFileTool { return &WriteFileTool{} }
 
+func (t *WriteFileTool) cloneTool() Tool { c := *t; return &c }
+
 func (t *WriteFileTool) Name() string        { return "write_file" }
 func (t *WriteFileTool) Description() string { return "Write content to a file, creating parent directories as needed" }
 func (t *WriteFileTool) Parameters() json.RawMessage {
@@ -88,10 +157,14 @@ func (t *WriteFileTool) Execute(_ context.Context, params json.RawMessage) (stri
 	if err := json.Unmarshal(params, &p); err != nil {
 		return "", fmt.Errorf("invalid parameters: %w", err)
 	}
-	if err := os.MkdirAll(filepath.Dir(p.Path), 0755); err != nil {
+	path, err := t.resolve(p.Path)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
 		return "", fmt.Errorf("failed to create directories: %w", err)
 	}
-	if err := os.WriteFile(p.Path, []byte(p.Content), 0644); err != nil {
+	if err := os.WriteFile(path, []byte(p.Content), 0644); err != nil {
 		return "", fmt.Errorf("failed to write file: %w", err)
 	}
 	return fmt.Sprintf("File written: %s", p.Path), nil
@@ -99,10 +172,12 @@ func (t *WriteFileTool) Execute(_ context.Context, params json.RawMessage) (stri
 
 // edit_file tool
 
-type EditFileTool struct{}
+type EditFileTool struct{ root }
 
 func NewEditFileTool() *EditFileTool { return &EditFileTool{} }
 
+func (t *EditFileTool) cloneTool() Tool { c := *t; return &c }
+
 func (t *EditFileTool) Name() string        { return "edit_file" }
 func (t *EditFileTool) Description() string { return "Replace first occurrence of old_text with new_text in a file" }
 func (t *EditFileTool) Parameters() json.RawMessage {
@@ -126,7 +201,11 @@ func (t *EditFileTool) Execute(_ context.Context, params json.RawMessage) (strin
 	if err := json.Unmarshal(params, &p); err != nil {
 		return "", fmt.Errorf("invalid parameters: %w", err)
 	}
-	data, err := os.ReadFile(p.Path)
+	path, err := t.resolve(p.Path)
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(path)
 	if err != nil {
 		return "", fmt.Errorf("failed to read file: %w", err)
 	}
@@ -135,7 +214,7 @@ func (t *EditFileTool) Execute(_ context.Context, params json.RawMessage) (strin
 		return "", fmt.Errorf("old_text not found in %s", p.Path)
 	}
 	updated := strings.Replace(content, p.OldText, p.NewText, 1)
-	if err := os.WriteFile(p.Path, []byte(updated), 0644); err != nil {
+	if err := os.WriteFile(path, []byte(updated), 0644); err != nil {
 		return "", fmt.Errorf("failed to write file: %w", err)
 	}
 	return fmt.Sprintf("File edited: %s", p.Path), nil
@@ -143,10 +222,12 @@ func (t *EditFileTool) Execute(_ context.Context, params json.RawMessage) (strin
 
 // list_dir tool
 
-type ListDirTool struct{}
+type ListDirTool struct{ root }
 
 func NewListDirTool() *ListDirTool { return &ListDirTool{} }
 
+func (t *ListDirTool) cloneTool() Tool { c := *t; return &c }
+
 func (t *ListDirTool) Name() string        { return "list_dir" }
 func (t *ListDirTool) Description() string { return "List directory contents with type indicators" }
 func (t *ListDirTool) Parameters() json.RawMessage {
@@ -166,7 +247,11 @@ func (t *ListDirTool) Execute(_ context.Context, params json.RawMessage) (string
 	if err := json.Unmarshal(params, &p); err != nil {
 		return "", fmt.Errorf("invalid parameters: %w", err)
 	}
-	entries, err := os.ReadDir(p.Path)
+	path, err := t.resolve(p.Path)
+	if err != nil {
+		return "", err
+	}
+	entries, err := os.ReadDir(path)
 	if err != nil {
 		return "", fmt.Errorf("failed to list directory: %w", err)
 	}
@@ -254,3 +254,60 @@ func TestFilesystemToolNames(t *testing.T) {
 		}
 	}
 }
+
+func TestFileTools_RootAllowsPathsInside(t *testing.T) {
+	dir := t.TempDir()
+	reg := NewRegistry()
+	reg.Register(NewWriteFileTool())
+	reg.Register(NewReadFileTool())
+	reg.SetWorkspaceRoot(dir)
+
+	if res := reg.ExecuteResult(context.Background(), "write_file", json.RawMessage(`{"path":"notes/a.txt","content":"hi"}`)); res.IsError {
+		t.Fatalf("write inside root failed: %s", res.Content)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "notes", "a.txt")); err != nil {
+		t.Fatalf("relative path not resolved against root: %v", err)
+	}
+	params, _ := json.Marshal(map[string]any{"path": filepath.Join(dir, "notes", "a.txt")})
+	if res := reg.ExecuteResult(context.Background(), "read_file", params); res.IsError || !strings.Contains(res.Content, "hi") {
+		t.Errorf("read inside root = %+v", res)
+	}
+}
+
+func TestFileTools_RootRejectsEscapes(t *testing.T) {
+	dir := t.TempDir()
+	outside := t.TempDir()
+	secret := filepath.Join(outside, "secret.txt")
+	os.WriteFile(secret, []byte("secret"), 0644)
+	if err := os.Symlink(outside, filepath.Join(dir, "link")); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+
+	read := NewReadFileTool()
+	read.SetRoot(dir)
+	write := NewWriteFileTool()
+	write.SetRoot(dir)
+	list := NewListDirTool()
+	list.SetRoot(dir)
+
+	cases := []struct {
+		tool   Tool
+		params map[string]any
+	}{
+		{read, map[string]any{"path": secret}},
+		{read, map[string]any{"path": "../" + filepath.Base(outside) + "/secret.txt"}},
+		{read, map[string]any{"path": "link/secret.txt"}},
+		{write, map[string]any{"path": "link/new.txt", "content": "x"}},
+		{list, map[string]any{"path": "link"}},
+	}
+	for _, tc := range cases {
+		params, _ := json.Marshal(tc.params)
+		_, err := tc.tool.Execute(context.Background(), params)
+		if err == nil || !strings.Contains(err.Error(), "outside the workspace") {
+			t.Errorf("%s %v: err = %v, want outside-workspace error", tc.tool.Name(), tc.params, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(outside, "new.txt")); err == nil {
+		t.Error("write through symlink escaped the root")
+	}
+}
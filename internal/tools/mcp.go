@@ -4,11 +4,15 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"os"
 	"os/exec"
+	"path"
+	"sort"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -18,22 +22,52 @@ import (
 type MCPClient struct {
 	cmd        *exec.Cmd
 	stdin      io.WriteCloser
-	stdout     *bufio.Reader
+	exited     chan struct{} // closed once the current process has been reaped and its exit handled
 	serverName string
+	cfg        MCPServerConfig
+	ctx        context.Context // parent context for (re)starting the process
 	mu         sync.Mutex
 	reqID      atomic.Int64
 	pending    map[int64]chan jsonRPCResponse
 	pendingMu  sync.Mutex
 	done       chan struct{}
+	closed     atomic.Bool
+	retrying   atomic.Bool
+	caps       mcpServerCapabilities
+
+	// registry, when set, is kept in sync with the server's tool list.
+	registry     *Registry
+	toolTimeout  time.Duration
+	toolNames    map[string]bool
+	resourceTool string
+	toolsMu      sync.Mutex
+	syncMu       sync.Mutex  // held for a whole syncTools pass so an older list never replaces a newer one
+	syncQueued   atomic.Bool // a list_changed refresh is waiting for syncMu
+}
+
+// mcpServerCapabilities records which optional features the server advertised
+// in its initialize response.
+type mcpServerCapabilities struct {
+	Tools     json.RawMessage `json:"tools,omitempty"`
+	Resources json.RawMessage `json:"resources,omitempty"`
 }
 
 // MCPServerConfig mirrors config.MCPServerConfig to avoid import cycle.
 type MCPServerConfig struct {
-	Command     string
-	Args        []string
-	Env         map[string]string
-	URL         string
-	ToolTimeout int // seconds, default 30
+	Command string
+	Args    []string
+	Env     map[string]string
+	// EnvPassthrough lists host variable names or path.Match globs (e.g.
+	// "GITHUB_*") to forward to the server. When set, only matching variables
+	// and Env are passed instead of the whole host environment, so include
+	// PATH if the server needs it.
+	EnvPassthrough []string
+	URL            string
+	ToolTimeout    int // seconds, default 30
+	MaxReconnects  int // restart attempts after the process exits, default 0 (disabled)
+	// NamePrefix is prepended to the server's tool names when they are
+	// registered. Empty uses "mcp_<server>_".
+	NamePrefix string
 }
 
 // jsonRPCRequest represents a JSON-RPC 2.0 request.
@@ -52,6 +86,22 @@ type jsonRPCResponse struct {
 	Error   *jsonRPCError   `json:"error,omitempty"`
 }
 
+// jsonRPCNotification represents a server-initiated JSON-RPC 2.0 notification.
+type jsonRPCNotification struct {
+	Method string          `json:"method"`
+	ID     json.RawMessage `json:"id,omitempty"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+// jsonRPCReply is the client's response to a server-initiated request. The
+// ID is echoed back verbatim because servers may use strings.
+type jsonRPCReply struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *jsonRPCError   `json:"error,omitempty"`
+}
+
 // jsonRPCError represents a JSON-RPC 2.0 error.
 type jsonRPCError struct {
 	Code    int             `json:"code"`
@@ -61,27 +111,58 @@ type jsonRPCError struct {
 
 // NewMCPClient starts an MCP server process and initializes the connection.
 func NewMCPClient(ctx context.Context, name string, cfg MCPServerConfig) (*MCPClient, error) {
+	return newMCPClient(ctx, name, cfg, nil)
+}
+
+// newMCPClient is NewMCPClient with a registry to keep in sync with the
+// server's tools. The registry and tool timeout are set before the read loop
+// starts, since a list_changed notification may arrive at any time after.
+func newMCPClient(ctx context.Context, name string, cfg MCPServerConfig, registry *Registry) (*MCPClient, error) {
 	if cfg.Command == "" {
 		return nil, fmt.Errorf("MCP server %s: command is required", name)
 	}
 
-	cmd := exec.CommandContext(ctx, cfg.Command, cfg.Args...)
+	timeout := time.Duration(cfg.ToolTimeout) * time.Second
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+
+	client := &MCPClient{
+		serverName:  name,
+		cfg:         cfg,
+		ctx:         ctx,
+		pending:     make(map[int64]chan jsonRPCResponse),
+		done:        make(chan struct{}),
+		registry:    registry,
+		toolTimeout: timeout,
+	}
 
-	// Set environment variables
-	cmd.Env = os.Environ()
-	for k, v := range cfg.Env {
-		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", k, v))
+	if err := client.connect(ctx); err != nil {
+		client.Close()
+		return nil, err
 	}
 
+	slog.Info("MCP client connected", "server", name)
+	return client, nil
+}
+
+// connect starts the server process, begins reading its output, and performs
+// the initialize handshake. It is used both for the first connection and for
+// reconnecting after the process has exited.
+func (c *MCPClient) connect(ctx context.Context) error {
+	cmd := exec.CommandContext(c.ctx, c.cfg.Command, c.cfg.Args...)
+
+	cmd.Env = serverEnv(os.Environ(), c.cfg)
+
 	stdin, err := cmd.StdinPipe()
 	if err != nil {
-		return nil, fmt.Errorf("failed to create stdin pipe: %w", err)
+		return fmt.Errorf("failed to create stdin pipe: %w", err)
 	}
 
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
 		stdin.Close()
-		return nil, fmt.Errorf("failed to create stdout pipe: %w", err)
+		return fmt.Errorf("failed to create stdout pipe: %w", err)
 	}
 
 	// Capture stderr for debugging
@@ -89,20 +170,23 @@ func NewMCPClient(ctx context.Context, name string, cfg MCPServerConfig) (*MCPCl
 
 	if err := cmd.Start(); err != nil {
 		stdin.Close()
-		return nil, fmt.Errorf("failed to start MCP server: %w", err)
+		return fmt.Errorf("failed to start MCP server: %w", err)
 	}
 
-	client := &MCPClient{
-		cmd:        cmd,
-		stdin:      stdin,
-		stdout:     bufio.NewReader(stdout),
-		serverName: name,
-		pending:    make(map[int64]chan jsonRPCResponse),
-		done:       make(chan struct{}),
+	exited := make(chan struct{})
+	c.mu.Lock()
+	c.cmd = cmd
+	c.stdin = stdin
+	c.exited = exited
+	c.mu.Unlock()
+
+	if c.closed.Load() {
+		cmd.Process.Kill()
+		return fmt.Errorf("MCP client %s is closed", c.serverName)
 	}
 
 	// Start read loop
-	go client.readLoop()
+	go c.readLoop(bufio.NewReader(stdout), cmd, exited)
 
 	// Initialize the connection
 	initParams := map[string]interface{}{
@@ -116,51 +200,109 @@ func NewMCPClient(ctx context.Context, name string, cfg MCPServerConfig) (*MCPCl
 
 	initParamsJSON, err := json.Marshal(initParams)
 	if err != nil {
-		client.Close()
-		return nil, fmt.Errorf("failed to marshal init params: %w", err)
+		return fmt.Errorf("failed to marshal init params: %w", err)
 	}
 
 	initCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
-	_, err = client.sendRequest(initCtx, "initialize", initParamsJSON)
+	initResult, err := c.sendRequest(initCtx, "initialize", initParamsJSON)
 	if err != nil {
-		client.Close()
-		return nil, fmt.Errorf("failed to initialize MCP server: %w", err)
+		return fmt.Errorf("failed to initialize MCP server: %w", err)
+	}
+
+	var initResp struct {
+		Capabilities mcpServerCapabilities `json:"capabilities"`
+	}
+	if err := json.Unmarshal(initResult, &initResp); err == nil {
+		c.caps = initResp.Capabilities
 	}
 
 	// Send initialized notification
-	if err := client.sendNotification("notifications/initialized", nil); err != nil {
-		client.Close()
-		return nil, fmt.Errorf("failed to send initialized notification: %w", err)
+	if err := c.sendNotification("notifications/initialized", nil); err != nil {
+		return fmt.Errorf("failed to send initialized notification: %w", err)
 	}
 
-	slog.Info("MCP client connected", "server", name)
-	return client, nil
+	return nil
 }
 
 // Close shuts down the MCP server process.
 func (c *MCPClient) Close() error {
+	if !c.closed.CompareAndSwap(false, true) {
+		return nil
+	}
 	close(c.done)
 
-	if c.stdin != nil {
-		c.stdin.Close()
+	c.mu.Lock()
+	stdin, cmd, exited := c.stdin, c.cmd, c.exited
+	c.mu.Unlock()
+
+	if stdin != nil {
+		stdin.Close()
 	}
 
-	if c.cmd != nil && c.cmd.Process != nil {
-		c.cmd.Process.Kill()
-		c.cmd.Wait()
+	if cmd != nil && cmd.Process != nil {
+		cmd.Process.Kill()
+		<-exited
 	}
 
 	return nil
 }
 
-// readLoop reads JSON-RPC responses from stdout.
-func (c *MCPClient) readLoop() {
-	scanner := bufio.NewScanner(c.stdout)
+// serverEnv builds the environment for a server process from the host
+// environment: all of it, or only the variables matching cfg.EnvPassthrough
+// when that is set. Variables in cfg.Env are added last and take precedence.
+func serverEnv(host []string, cfg MCPServerConfig) []string {
+	env := make([]string, 0, len(host)+len(cfg.Env))
+	for _, kv := range host {
+		key, _, _ := strings.Cut(kv, "=")
+		if _, ok := cfg.Env[key]; ok {
+			continue
+		}
+		if len(cfg.EnvPassthrough) > 0 && !matchesAny(key, cfg.EnvPassthrough) {
+			continue
+		}
+		env = append(env, kv)
+	}
+	keys := make([]string, 0, len(cfg.Env))
+	for k := range cfg.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		env = append(env, k+"="+cfg.Env[k])
+	}
+	return env
+}
+
+// matchesAny reports whether name matches one of the path.Match patterns.
+// Malformed patterns match nothing.
+func matchesAny(name string, patterns []string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// readLoop reads JSON-RPC messages from stdout until the process exits, then
+// reaps it and, unless the client was closed, handles the unexpected exit.
+func (c *MCPClient) readLoop(stdout *bufio.Reader, cmd *exec.Cmd, exited chan struct{}) {
+	scanner := bufio.NewScanner(stdout)
 	for scanner.Scan() {
 		line := scanner.Bytes()
 
+		var note jsonRPCNotification
+		if err := json.Unmarshal(line, &note); err == nil && note.Method != "" {
+			if len(note.ID) == 0 {
+				c.handleNotification(note.Method, note.Params)
+			} else {
+				c.handleRequest(note.ID, note.Method)
+			}
+			continue
+		}
+
 		var resp jsonRPCResponse
 		if err := json.Unmarshal(line, &resp); err != nil {
 			slog.Warn("failed to parse JSON-RPC response", "error", err, "line", string(line))
@@ -175,17 +317,281 @@ func (c *MCPClient) readLoop() {
 		c.pendingMu.Unlock()
 
 		if ok {
-			select {
-			case ch <- resp:
-			case <-c.done:
-				return
-			}
+			ch <- resp
 		}
 	}
 
-	if err := scanner.Err(); err != nil {
+	if err := scanner.Err(); err != nil && !c.closed.Load() {
 		slog.Warn("MCP read loop error", "server", c.serverName, "error", err)
 	}
+
+	waitErr := cmd.Wait()
+	defer close(exited)
+	c.failPending()
+
+	if c.closed.Load() {
+		return
+	}
+
+	slog.Warn("MCP server exited", "server", c.serverName, "error", waitErr)
+	c.removeTools()
+
+	if c.ctx.Err() == nil && c.cfg.MaxReconnects > 0 && c.retrying.CompareAndSwap(false, true) {
+		go c.reconnect()
+	}
+}
+
+// failPending fails every in-flight request after the server process has exited.
+func (c *MCPClient) failPending() {
+	c.pendingMu.Lock()
+	defer c.pendingMu.Unlock()
+	for id, ch := range c.pending {
+		ch <- jsonRPCResponse{ID: id, Error: &jsonRPCError{Code: -32000, Message: "MCP server exited"}}
+		delete(c.pending, id)
+	}
+}
+
+// reconnect restarts the server process with exponential backoff, up to
+// cfg.MaxReconnects attempts, and re-registers its tools on success.
+func (c *MCPClient) reconnect() {
+	defer c.retrying.Store(false)
+
+	backoff := time.Second
+	for attempt := 1; attempt <= c.cfg.MaxReconnects; attempt++ {
+		select {
+		case <-time.After(backoff):
+		case <-c.done:
+			return
+		case <-c.ctx.Done():
+			return
+		}
+
+		err := c.connect(c.ctx)
+		if err == nil {
+			ctx, cancel := context.WithTimeout(c.ctx, 10*time.Second)
+			err = c.syncTools(ctx)
+			if errors.Is(err, ErrToolExists) {
+				// The server is back; tools that collide were skipped and logged.
+				err = nil
+			}
+			if err == nil {
+				c.syncResources(ctx)
+			}
+			cancel()
+		}
+		if err == nil {
+			slog.Info("MCP client reconnected", "server", c.serverName, "attempt", attempt)
+			return
+		}
+
+		slog.Warn("MCP reconnect failed", "server", c.serverName, "attempt", attempt, "error", err)
+		c.mu.Lock()
+		cmd, exited := c.cmd, c.exited
+		c.mu.Unlock()
+		if cmd != nil && cmd.Process != nil {
+			cmd.Process.Kill()
+			// Wait while still retrying, so the failed process's exit does
+			// not start another round of attempts.
+			<-exited
+		}
+
+		backoff *= 2
+		if backoff > 30*time.Second {
+			backoff = 30 * time.Second
+		}
+	}
+	slog.Error("MCP server gave up reconnecting", "server", c.serverName, "attempts", c.cfg.MaxReconnects)
+}
+
+// removeTools unregisters every tool this client added to the registry.
+func (c *MCPClient) removeTools() {
+	if c.registry == nil {
+		return
+	}
+
+	c.toolsMu.Lock()
+	defer c.toolsMu.Unlock()
+
+	for name := range c.toolNames {
+		c.registry.Unregister(name)
+		slog.Info("Unregistered MCP tool", "server", c.serverName, "as", name)
+	}
+	c.toolNames = nil
+
+	if c.resourceTool != "" {
+		c.registry.Unregister(c.resourceTool)
+		c.resourceTool = ""
+	}
+}
+
+// syncResources registers a read_resource tool when the server advertises
+// resources. It is a no-op when no registry is attached.
+func (c *MCPClient) syncResources(ctx context.Context) {
+	if c.registry == nil || !c.SupportsResources() {
+		return
+	}
+
+	resources, err := c.ListResources(ctx)
+	if err != nil {
+		slog.Warn("failed to list MCP resources", "server", c.serverName, "error", err)
+		return
+	}
+	if len(resources) == 0 {
+		return
+	}
+
+	resTool := &MCPResourceTool{
+		client:     c,
+		serverName: c.serverName,
+		namePrefix: c.cfg.NamePrefix,
+		resources:  resources,
+		timeout:    c.toolTimeout,
+	}
+	c.toolsMu.Lock()
+	if c.resourceTool == resTool.Name() {
+		c.registry.Register(resTool)
+	} else if err := c.registry.RegisterUnique(resTool); err != nil {
+		c.toolsMu.Unlock()
+		slog.Warn("skipping MCP resource tool", "server", c.serverName, "error", err)
+		return
+	}
+	c.resourceTool = resTool.Name()
+	c.toolsMu.Unlock()
+
+	slog.Info("Registered MCP resource tool", "server", c.serverName, "resources", len(resources), "as", resTool.Name())
+}
+
+// handleNotification dispatches a server-initiated notification. It runs on the
+// read loop goroutine, so anything that issues requests must not block it.
+func (c *MCPClient) handleNotification(method string, params json.RawMessage) {
+	switch method {
+	case "notifications/tools/list_changed":
+		// A burst of notifications folds into the refresh already queued;
+		// it lists the tools after all of them arrived.
+		if c.syncQueued.Swap(true) {
+			return
+		}
+		go func() {
+			c.syncMu.Lock()
+			defer c.syncMu.Unlock()
+			c.syncQueued.Store(false)
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			if err := c.syncToolsLocked(ctx); err != nil {
+				slog.Warn("failed to refresh MCP tools", "server", c.serverName, "error", err)
+			}
+		}()
+	case "notifications/message":
+		var msg struct {
+			Level  string          `json:"level"`
+			Logger string          `json:"logger"`
+			Data   json.RawMessage `json:"data"`
+		}
+		if err := json.Unmarshal(params, &msg); err != nil {
+			slog.Warn("failed to parse MCP log notification", "server", c.serverName, "error", err)
+			return
+		}
+		var level slog.Level
+		switch msg.Level {
+		case "debug":
+			level = slog.LevelDebug
+		case "info", "notice":
+			level = slog.LevelInfo
+		case "warning":
+			level = slog.LevelWarn
+		default:
+			level = slog.LevelError
+		}
+		slog.Log(context.Background(), level, "MCP server log", "server", c.serverName, "logger", msg.Logger, "data", string(msg.Data))
+	default:
+		slog.Debug("unhandled MCP notification", "server", c.serverName, "method", method)
+	}
+}
+
+// handleRequest answers a server-initiated request. Only ping is supported;
+// anything else gets a method-not-found error so the server is not left
+// waiting for a reply.
+func (c *MCPClient) handleRequest(id json.RawMessage, method string) {
+	reply := jsonRPCReply{JSONRPC: "2.0", ID: id}
+	if method == "ping" {
+		reply.Result = json.RawMessage(`{}`)
+	} else {
+		slog.Debug("unsupported MCP server request", "server", c.serverName, "method", method)
+		reply.Error = &jsonRPCError{Code: -32601, Message: "method not found"}
+	}
+
+	replyJSON, err := json.Marshal(reply)
+	if err != nil {
+		slog.Warn("failed to marshal MCP reply", "server", c.serverName, "method", method, "error", err)
+		return
+	}
+	c.mu.Lock()
+	_, err = c.stdin.Write(append(replyJSON, '\n'))
+	c.mu.Unlock()
+	if err != nil {
+		slog.Warn("failed to answer MCP server request", "server", c.serverName, "method", method, "error", err)
+	}
+}
+
+// syncTools lists the server's tools and reconciles them with the attached
+// registry: newly advertised tools are registered and tools the server no
+// longer offers are removed. A tool whose name is already taken by another
+// tool is skipped, and the collisions are returned as an error wrapping
+// ErrToolExists once the rest are registered. It is a no-op when no registry
+// is attached. Passes never overlap.
+func (c *MCPClient) syncTools(ctx context.Context) error {
+	c.syncMu.Lock()
+	defer c.syncMu.Unlock()
+	return c.syncToolsLocked(ctx)
+}
+
+// syncToolsLocked is syncTools for a caller that holds syncMu.
+func (c *MCPClient) syncToolsLocked(ctx context.Context) error {
+	if c.registry == nil {
+		return nil
+	}
+
+	tools, err := c.ListTools(ctx)
+	if err != nil {
+		return err
+	}
+
+	c.toolsMu.Lock()
+	defer c.toolsMu.Unlock()
+
+	current := make(map[string]bool, len(tools))
+	var conflicts []error
+	for _, toolDef := range tools {
+		wrapper := &MCPToolWrapper{
+			client:     c,
+			serverName: c.serverName,
+			namePrefix: c.cfg.NamePrefix,
+			toolDef:    toolDef,
+			timeout:    c.toolTimeout,
+		}
+		name := wrapper.Name()
+		if c.toolNames[name] || current[name] {
+			// Already ours; refresh the definition.
+			c.registry.Register(wrapper)
+		} else if err := c.registry.RegisterUnique(wrapper); err != nil {
+			slog.Warn("skipping MCP tool", "server", c.serverName, "tool", toolDef.Name, "error", err)
+			conflicts = append(conflicts, err)
+			continue
+		} else {
+			slog.Info("Registered MCP tool", "server", c.serverName, "tool", toolDef.Name, "as", name)
+		}
+		current[name] = true
+	}
+
+	for name := range c.toolNames {
+		if !current[name] {
+			c.registry.Unregister(name)
+			slog.Info("Unregistered MCP tool", "server", c.serverName, "as", name)
+		}
+	}
+	c.toolNames = current
+
+	return errors.Join(conflicts...)
 }
 
 // sendRequest sends a JSON-RPC request and waits for the response.
@@ -285,8 +691,31 @@ func (c *MCPClient) ListTools(ctx context.Context) ([]MCPToolDef, error) {
 	return response.Tools, nil
 }
 
-// CallTool calls a specific tool on the MCP server.
+// MCPContent is a single content block in a tools/call result. Text blocks
+// populate Text; image and audio blocks populate Data (base64) and MimeType;
+// embedded resources populate Resource.
+type MCPContent struct {
+	Type     string              `json:"type"`
+	Text     string              `json:"text,omitempty"`
+	Data     string              `json:"data,omitempty"`
+	MimeType string              `json:"mimeType,omitempty"`
+	Resource *MCPResourceContent `json:"resource,omitempty"`
+}
+
+// CallTool calls a specific tool on the MCP server and returns its result as
+// text. Non-text content blocks are rendered as placeholders; use
+// CallToolContent to access them directly.
 func (c *MCPClient) CallTool(ctx context.Context, toolName string, args json.RawMessage) (string, error) {
+	contents, err := c.CallToolContent(ctx, toolName, args)
+	if err != nil {
+		return "", err
+	}
+	return FormatMCPContent(contents), nil
+}
+
+// CallToolContent calls a specific tool on the MCP server and returns every
+// content block of the result.
+func (c *MCPClient) CallToolContent(ctx context.Context, toolName string, args json.RawMessage) ([]MCPContent, error) {
 	params := map[string]interface{}{
 		"name":      toolName,
 		"arguments": args,
@@ -294,46 +723,156 @@ func (c *MCPClient) CallTool(ctx context.Context, toolName string, args json.Raw
 
 	paramsJSON, err := json.Marshal(params)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal tool params: %w", err)
+		return nil, fmt.Errorf("failed to marshal tool params: %w", err)
 	}
 
 	result, err := c.sendRequest(ctx, "tools/call", paramsJSON)
 	if err != nil {
-		return "", fmt.Errorf("failed to call tool: %w", err)
+		return nil, fmt.Errorf("failed to call tool: %w", err)
+	}
+
+	var response struct {
+		Content []MCPContent `json:"content"`
+	}
+
+	if err := json.Unmarshal(result, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse tool response: %w", err)
+	}
+
+	return response.Content, nil
+}
+
+// FormatMCPContent renders tool result content blocks as text. Text blocks are
+// concatenated as-is; binary blocks become a short placeholder so the model
+// knows the tool produced something it cannot see.
+func FormatMCPContent(contents []MCPContent) string {
+	var sb strings.Builder
+	for _, content := range contents {
+		switch content.Type {
+		case "text":
+			sb.WriteString(content.Text)
+		case "image", "audio":
+			fmt.Fprintf(&sb, "[%s content (%s), %d bytes base64]", content.Type, content.MimeType, len(content.Data))
+		case "resource":
+			if content.Resource == nil {
+				continue
+			}
+			if content.Resource.Text != "" {
+				sb.WriteString(content.Resource.Text)
+			} else {
+				fmt.Fprintf(&sb, "[binary resource %s (%s), %d bytes base64]", content.Resource.URI, content.Resource.MimeType, len(content.Resource.Blob))
+			}
+		default:
+			fmt.Fprintf(&sb, "[unsupported %s content]", content.Type)
+		}
+	}
+	return sb.String()
+}
+
+// MCPResourceDef represents a resource advertised by an MCP server.
+type MCPResourceDef struct {
+	URI         string `json:"uri"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType,omitempty"`
+}
+
+// MCPResourceContent holds the contents of a resource returned by resources/read.
+// Text resources populate Text; binary resources populate Blob (base64).
+type MCPResourceContent struct {
+	URI      string `json:"uri"`
+	MimeType string `json:"mimeType,omitempty"`
+	Text     string `json:"text,omitempty"`
+	Blob     string `json:"blob,omitempty"`
+}
+
+// SupportsResources reports whether the server advertised the resources capability.
+func (c *MCPClient) SupportsResources() bool {
+	return len(c.caps.Resources) > 0
+}
+
+// ListResources calls resources/list on the MCP server and returns resource definitions.
+func (c *MCPClient) ListResources(ctx context.Context) ([]MCPResourceDef, error) {
+	result, err := c.sendRequest(ctx, "resources/list", json.RawMessage("{}"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list resources: %w", err)
+	}
+
+	var response struct {
+		Resources []MCPResourceDef `json:"resources"`
+	}
+
+	if err := json.Unmarshal(result, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse resources list: %w", err)
+	}
+
+	return response.Resources, nil
+}
+
+// ReadResource calls resources/read for the given URI and returns its contents.
+func (c *MCPClient) ReadResource(ctx context.Context, uri string) ([]MCPResourceContent, error) {
+	paramsJSON, err := json.Marshal(map[string]string{"uri": uri})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal resource params: %w", err)
+	}
+
+	result, err := c.sendRequest(ctx, "resources/read", paramsJSON)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read resource: %w", err)
 	}
 
 	var response struct {
-		Content []struct {
-			Type string `json:"type"`
-			Text string `json:"text"`
-		} `json:"content"`
+		Contents []MCPResourceContent `json:"contents"`
 	}
 
 	if err := json.Unmarshal(result, &response); err != nil {
-		return "", fmt.Errorf("failed to parse tool response: %w", err)
+		return nil, fmt.Errorf("failed to parse resource contents: %w", err)
 	}
 
-	// Concatenate all text content
-	var output string
-	for _, content := range response.Content {
-		if content.Type == "text" {
-			output += content.Text
+	return response.Contents, nil
+}
+
+// ReadResourceText reads a resource and concatenates its text contents.
+// Binary contents are replaced by a short placeholder describing the blob.
+func (c *MCPClient) ReadResourceText(ctx context.Context, uri string) (string, error) {
+	contents, err := c.ReadResource(ctx, uri)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	for _, content := range contents {
+		if content.Text != "" {
+			sb.WriteString(content.Text)
+			continue
+		}
+		if content.Blob != "" {
+			fmt.Fprintf(&sb, "[binary resource %s (%s), %d bytes base64]", content.URI, content.MimeType, len(content.Blob))
 		}
 	}
+	return sb.String(), nil
+}
 
-	return output, nil
+// mcpToolPrefix returns the prefix for the registered names of server's
+// tools: prefix if set, otherwise "mcp_<server>_".
+func mcpToolPrefix(server, prefix string) string {
+	if prefix != "" {
+		return prefix
+	}
+	return "mcp_" + server + "_"
 }
 
 // MCPToolWrapper wraps an MCP server tool as a native nanobot Tool.
 type MCPToolWrapper struct {
 	client     *MCPClient
 	serverName string
+	namePrefix string // see MCPServerConfig.NamePrefix
 	toolDef    MCPToolDef
 	timeout    time.Duration
 }
 
 func (w *MCPToolWrapper) Name() string {
-	return fmt.Sprintf("mcp_%s_%s", w.serverName, w.toolDef.Name)
+	return mcpToolPrefix(w.serverName, w.namePrefix) + w.toolDef.Name
 }
 
 func (w *MCPToolWrapper) Description() string {
@@ -351,7 +890,70 @@ func (w *MCPToolWrapper) Execute(ctx context.Context, params json.RawMessage) (s
 	return w.client.CallTool(execCtx, w.toolDef.Name, params)
 }
 
+// MCPResourceTool exposes an MCP server's resources to the agent as a single
+// read tool. The description lists the URIs known at registration time.
+type MCPResourceTool struct {
+	client     *MCPClient
+	serverName string
+	namePrefix string // see MCPServerConfig.NamePrefix
+	resources  []MCPResourceDef
+	timeout    time.Duration
+}
+
+func (t *MCPResourceTool) Name() string {
+	return mcpToolPrefix(t.serverName, t.namePrefix) + "read_resource"
+}
+
+func (t *MCPResourceTool) Description() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Read a resource exposed by the %s MCP server.", t.serverName)
+	if len(t.resources) > 0 {
+		sb.WriteString(" Available resources:")
+		for _, r := range t.resources {
+			fmt.Fprintf(&sb, "\n- %s", r.URI)
+			if r.Name != "" {
+				fmt.Fprintf(&sb, " (%s)", r.Name)
+			}
+			if r.Description != "" {
+				fmt.Fprintf(&sb, ": %s", r.Description)
+			}
+		}
+	}
+	return sb.String()
+}
+
+func (t *MCPResourceTool) Parameters() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"uri": {"type": "string", "description": "URI of the resource to read"}
+		},
+		"required": ["uri"]
+	}`)
+}
+
+func (t *MCPResourceTool) Execute(ctx context.Context, params json.RawMessage) (string, error) {
+	var p struct {
+		URI string `json:"uri"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return "", fmt.Errorf("invalid parameters: %w", err)
+	}
+	if p.URI == "" {
+		return "", fmt.Errorf("uri is required")
+	}
+
+	execCtx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
+	return t.client.ReadResourceText(execCtx, p.URI)
+}
+
 // ConnectMCPServers connects to all configured MCP servers and registers their tools.
+// Servers are connected independently: one that fails does not affect the
+// others. The clients that connected are always returned, along with an error
+// listing the servers that failed, if any. The caller owns the returned clients
+// in both cases.
 func ConnectMCPServers(ctx context.Context, configs map[string]MCPServerConfig, registry *Registry) ([]*MCPClient, error) {
 	if len(configs) == 0 {
 		return []*MCPClient{}, nil
@@ -367,34 +969,24 @@ func ConnectMCPServers(ctx context.Context, configs map[string]MCPServerConfig,
 		go func(name string, cfg MCPServerConfig) {
 			defer wg.Done()
 
-			client, err := NewMCPClient(ctx, name, cfg)
+			client, err := newMCPClient(ctx, name, cfg, registry)
 			if err != nil {
 				errCh <- fmt.Errorf("failed to connect to MCP server %s: %w", name, err)
 				return
 			}
 
-			tools, err := client.ListTools(ctx)
-			if err != nil {
+			if err := client.syncTools(ctx); err != nil {
+				client.removeTools()
 				client.Close()
-				errCh <- fmt.Errorf("failed to list tools from MCP server %s: %w", name, err)
+				if errors.Is(err, ErrToolExists) {
+					errCh <- fmt.Errorf("MCP server %s has conflicting tool names: %w", name, err)
+				} else {
+					errCh <- fmt.Errorf("failed to list tools from MCP server %s: %w", name, err)
+				}
 				return
 			}
 
-			timeout := time.Duration(cfg.ToolTimeout) * time.Second
-			if timeout == 0 {
-				timeout = 30 * time.Second
-			}
-
-			for _, toolDef := range tools {
-				wrapper := &MCPToolWrapper{
-					client:     client,
-					serverName: name,
-					toolDef:    toolDef,
-					timeout:    timeout,
-				}
-				registry.Register(wrapper)
-				slog.Info("Registered MCP tool", "server", name, "tool", toolDef.Name, "as", wrapper.Name())
-			}
+			client.syncResources(ctx)
 
 			mu.Lock()
 			clients = append(clients, client)
@@ -412,11 +1004,7 @@ func ConnectMCPServers(ctx context.Context, configs map[string]MCPServerConfig,
 	}
 
 	if len(errs) > 0 {
-		// Close all successfully connected clients
-		for _, client := range clients {
-			client.Close()
-		}
-		return nil, fmt.Errorf("failed to connect to MCP servers: %v", errs)
+		return clients, fmt.Errorf("failed to connect to MCP servers: %w", errors.Join(errs...))
 	}
 
 	return clients, nil
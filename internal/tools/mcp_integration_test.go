@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -60,6 +62,176 @@ while IFS= read -r line; do
 done
 `
 
+// mockMCPServerScriptMixedContent returns a text block and an image block from tools/call.
+const mockMCPServerScriptMixedContent = `
+while IFS= read -r line; do
+  id=$(echo "$line" | python3 -c "import sys,json; d=json.load(sys.stdin); print(d.get('id',''))" 2>/dev/null)
+  method=$(echo "$line" | python3 -c "import sys,json; d=json.load(sys.stdin); print(d.get('method',''))" 2>/dev/null)
+  case "$method" in
+    initialize)
+      echo "{\"jsonrpc\":\"2.0\",\"id\":$id,\"result\":{\"protocolVersion\":\"2024-11-05\",\"capabilities\":{}}}"
+      ;;
+    notifications/initialized)
+      ;;
+    tools/list)
+      echo "{\"jsonrpc\":\"2.0\",\"id\":$id,\"result\":{\"tools\":[{\"name\":\"screenshot\",\"description\":\"Takes a screenshot\",\"inputSchema\":{\"type\":\"object\"}}]}}"
+      ;;
+    tools/call)
+      echo "{\"jsonrpc\":\"2.0\",\"id\":$id,\"result\":{\"content\":[{\"type\":\"text\",\"text\":\"captured \"},{\"type\":\"image\",\"data\":\"aGVsbG8=\",\"mimeType\":\"image/png\"}]}}"
+      ;;
+    *)
+      echo "{\"jsonrpc\":\"2.0\",\"id\":$id,\"error\":{\"code\":-32601,\"message\":\"method not found\"}}"
+      ;;
+  esac
+done
+`
+
+// mockMCPServerScriptResources advertises the resources capability and serves
+// a single text resource.
+const mockMCPServerScriptResources = `
+while IFS= read -r line; do
+  id=$(echo "$line" | python3 -c "import sys,json; d=json.load(sys.stdin); print(d.get('id',''))" 2>/dev/null)
+  method=$(echo "$line" | python3 -c "import sys,json; d=json.load(sys.stdin); print(d.get('method',''))" 2>/dev/null)
+  case "$method" in
+    initialize)
+      echo "{\"jsonrpc\":\"2.0\",\"id\":$id,\"result\":{\"protocolVersion\":\"2024-11-05\",\"capabilities\":{\"tools\":{},\"resources\":{}}}}"
+      ;;
+    notifications/initialized)
+      ;;
+    tools/list)
+      echo "{\"jsonrpc\":\"2.0\",\"id\":$id,\"result\":{\"tools\":[]}}"
+      ;;
+    resources/list)
+      echo "{\"jsonrpc\":\"2.0\",\"id\":$id,\"result\":{\"resources\":[{\"uri\":\"file:///docs/readme.md\",\"name\":\"readme\",\"mimeType\":\"text/markdown\"}]}}"
+      ;;
+    resources/read)
+      echo "{\"jsonrpc\":\"2.0\",\"id\":$id,\"result\":{\"contents\":[{\"uri\":\"file:///docs/readme.md\",\"mimeType\":\"text/markdown\",\"text\":\"# Project docs\"}]}}"
+      ;;
+    *)
+      echo "{\"jsonrpc\":\"2.0\",\"id\":$id,\"error\":{\"code\":-32601,\"message\":\"method not found\"}}"
+      ;;
+  esac
+done
+`
+
+// mockMCPServerScriptListChanged advertises one tool, then emits a log message
+// and a tools/list_changed notification; subsequent tools/list calls return two tools.
+const mockMCPServerScriptListChanged = `
+calls=0
+while IFS= read -r line; do
+  id=$(echo "$line" | python3 -c "import sys,json; d=json.load(sys.stdin); print(d.get('id',''))" 2>/dev/null)
+  method=$(echo "$line" | python3 -c "import sys,json; d=json.load(sys.stdin); print(d.get('method',''))" 2>/dev/null)
+  case "$method" in
+    initialize)
+      echo "{\"jsonrpc\":\"2.0\",\"id\":$id,\"result\":{\"protocolVersion\":\"2024-11-05\",\"capabilities\":{\"tools\":{\"listChanged\":true}}}}"
+      ;;
+    notifications/initialized)
+      ;;
+    tools/list)
+      calls=$((calls+1))
+      if [ "$calls" -eq 1 ]; then
+        echo "{\"jsonrpc\":\"2.0\",\"id\":$id,\"result\":{\"tools\":[{\"name\":\"first_tool\",\"description\":\"First\",\"inputSchema\":{\"type\":\"object\"}}]}}"
+        ( sleep 0.3
+          echo "{\"jsonrpc\":\"2.0\",\"method\":\"notifications/message\",\"params\":{\"level\":\"info\",\"data\":\"tools changing\"}}"
+          echo "{\"jsonrpc\":\"2.0\",\"method\":\"notifications/tools/list_changed\"}" ) &
+      else
+        echo "{\"jsonrpc\":\"2.0\",\"id\":$id,\"result\":{\"tools\":[{\"name\":\"second_tool\",\"description\":\"Second\",\"inputSchema\":{\"type\":\"object\"}}]}}"
+      fi
+      ;;
+    *)
+      echo "{\"jsonrpc\":\"2.0\",\"id\":$id,\"error\":{\"code\":-32601,\"message\":\"method not found\"}}"
+      ;;
+  esac
+done
+`
+
+// mockMCPServerScriptEarlyListChanged sends tools/list_changed as soon as it
+// is initialized, before the client has listed its tools.
+const mockMCPServerScriptEarlyListChanged = `
+while IFS= read -r line; do
+  id=$(echo "$line" | python3 -c "import sys,json; d=json.load(sys.stdin); print(d.get('id',''))" 2>/dev/null)
+  method=$(echo "$line" | python3 -c "import sys,json; d=json.load(sys.stdin); print(d.get('method',''))" 2>/dev/null)
+  case "$method" in
+    initialize)
+      echo "{\"jsonrpc\":\"2.0\",\"id\":$id,\"result\":{\"protocolVersion\":\"2024-11-05\",\"capabilities\":{\"tools\":{\"listChanged\":true}}}}"
+      ;;
+    notifications/initialized)
+      echo "{\"jsonrpc\":\"2.0\",\"method\":\"notifications/tools/list_changed\"}"
+      ;;
+    tools/list)
+      echo "{\"jsonrpc\":\"2.0\",\"id\":$id,\"result\":{\"tools\":[{\"name\":\"echo_tool\",\"description\":\"Echoes input\",\"inputSchema\":{\"type\":\"object\"}}]}}"
+      ;;
+    *)
+      echo "{\"jsonrpc\":\"2.0\",\"id\":$id,\"error\":{\"code\":-32601,\"message\":\"method not found\"}}"
+      ;;
+  esac
+done
+`
+
+// mockMCPServerScriptListChangedBurst sends five tools/list_changed
+// notifications after the first tools/list. Later listings are slow and name
+// their tool after the call count, so the test can see how many were made.
+const mockMCPServerScriptListChangedBurst = `
+calls=0
+while IFS= read -r line; do
+  id=$(echo "$line" | python3 -c "import sys,json; d=json.load(sys.stdin); print(d.get('id',''))" 2>/dev/null)
+  method=$(echo "$line" | python3 -c "import sys,json; d=json.load(sys.stdin); print(d.get('method',''))" 2>/dev/null)
+  case "$method" in
+    initialize)
+      echo "{\"jsonrpc\":\"2.0\",\"id\":$id,\"result\":{\"protocolVersion\":\"2024-11-05\",\"capabilities\":{\"tools\":{\"listChanged\":true}}}}"
+      ;;
+    notifications/initialized)
+      ;;
+    tools/list)
+      calls=$((calls+1))
+      if [ "$calls" -eq 1 ]; then
+        ( sleep 0.1
+          for i in 1 2 3 4 5; do
+            echo "{\"jsonrpc\":\"2.0\",\"method\":\"notifications/tools/list_changed\"}"
+          done ) &
+      else
+        sleep 0.3
+      fi
+      echo "{\"jsonrpc\":\"2.0\",\"id\":$id,\"result\":{\"tools\":[{\"name\":\"tool_$calls\",\"inputSchema\":{\"type\":\"object\"}}]}}"
+      ;;
+    *)
+      echo "{\"jsonrpc\":\"2.0\",\"id\":$id,\"error\":{\"code\":-32601,\"message\":\"method not found\"}}"
+      ;;
+  esac
+done
+`
+
+// mockMCPServerScriptServerRequests answers tools/list only after sending the
+// client a ping and a roots/list request and reading the replies. The listed
+// tool is named after what came back.
+const mockMCPServerScriptServerRequests = `
+while IFS= read -r line; do
+  id=$(echo "$line" | python3 -c "import sys,json; d=json.load(sys.stdin); print(d.get('id',''))" 2>/dev/null)
+  method=$(echo "$line" | python3 -c "import sys,json; d=json.load(sys.stdin); print(d.get('method',''))" 2>/dev/null)
+  case "$method" in
+    initialize)
+      echo "{\"jsonrpc\":\"2.0\",\"id\":$id,\"result\":{\"protocolVersion\":\"2024-11-05\",\"capabilities\":{\"tools\":{}}}}"
+      ;;
+    notifications/initialized)
+      ;;
+    tools/list)
+      echo "{\"jsonrpc\":\"2.0\",\"id\":\"srv-1\",\"method\":\"ping\"}"
+      IFS= read -r pong
+      echo "{\"jsonrpc\":\"2.0\",\"id\":9,\"method\":\"roots/list\"}"
+      IFS= read -r roots
+      name=unanswered
+      case "$pong" in *'"id":"srv-1"'*'"result":{}'*)
+        case "$roots" in *'"id":9'*'"code":-32601'*) name=answered ;; esac ;;
+      esac
+      echo "{\"jsonrpc\":\"2.0\",\"id\":$id,\"result\":{\"tools\":[{\"name\":\"$name\",\"inputSchema\":{\"type\":\"object\"}}]}}"
+      ;;
+    *)
+      echo "{\"jsonrpc\":\"2.0\",\"id\":$id,\"error\":{\"code\":-32601,\"message\":\"method not found\"}}"
+      ;;
+  esac
+done
+`
+
 // checkPython3 returns true if python3 is available (needed for mock server scripts).
 func checkPython3() bool {
 	cfg := MCPServerConfig{Command: "python3", Args: []string{"--version"}}
@@ -272,6 +444,43 @@ func TestConnectMCPServersWithMockServer(t *testing.T) {
 	}
 }
 
+func TestConnectMCPServersPartialFailure(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	registry := NewRegistry()
+	configs := map[string]MCPServerConfig{
+		"good": {
+			Command: "sh",
+			Args:    []string{"-c", mockMCPServerScript},
+		},
+		"bad": {Command: "/nonexistent/binary/xyz"},
+	}
+
+	clients, err := ConnectMCPServers(ctx, configs, registry)
+	defer func() {
+		for _, c := range clients {
+			c.Close()
+		}
+	}()
+
+	if err == nil {
+		t.Fatal("expected error for the bad server")
+	}
+	if !strings.Contains(err.Error(), "bad") {
+		t.Errorf("error should name the failed server: %v", err)
+	}
+	if strings.Contains(err.Error(), "good") {
+		t.Errorf("error should not mention the working server: %v", err)
+	}
+	if len(clients) != 1 {
+		t.Fatalf("expected 1 client, got %d", len(clients))
+	}
+	if _, ok := registry.Get("mcp_good_echo_tool"); !ok {
+		t.Error("expected mcp_good_echo_tool to be registered")
+	}
+}
+
 func TestConnectMCPServersToolTimeout(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -464,5 +673,447 @@ done
 	}
 }
 
+func TestMCPClientResources(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client, err := NewMCPClient(ctx, "mock", MCPServerConfig{
+		Command: "sh",
+		Args:    []string{"-c", mockMCPServerScriptResources},
+	})
+	if err != nil {
+		t.Skipf("mock MCP server unavailable: %v", err)
+	}
+	defer client.Close()
+
+	if !client.SupportsResources() {
+		t.Fatal("expected server to advertise resources capability")
+	}
+
+	resources, err := client.ListResources(ctx)
+	if err != nil {
+		t.Fatalf("ListResources failed: %v", err)
+	}
+	if len(resources) != 1 || resources[0].URI != "file:///docs/readme.md" {
+		t.Fatalf("unexpected resources: %+v", resources)
+	}
+
+	contents, err := client.ReadResource(ctx, resources[0].URI)
+	if err != nil {
+		t.Fatalf("ReadResource failed: %v", err)
+	}
+	if len(contents) != 1 || contents[0].Text != "# Project docs" {
+		t.Errorf("unexpected contents: %+v", contents)
+	}
+}
+
+func TestMCPClientNoResourcesCapability(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client, err := NewMCPClient(ctx, "mock", MCPServerConfig{
+		Command: "sh",
+		Args:    []string{"-c", mockMCPServerScript},
+	})
+	if err != nil {
+		t.Skipf("mock MCP server unavailable: %v", err)
+	}
+	defer client.Close()
+
+	if client.SupportsResources() {
+		t.Error("expected no resources capability")
+	}
+}
+
+func TestConnectMCPServersRegistersResourceTool(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	registry := NewRegistry()
+	configs := map[string]MCPServerConfig{
+		"docs": {
+			Command: "sh",
+			Args:    []string{"-c", mockMCPServerScriptResources},
+		},
+	}
+
+	clients, err := ConnectMCPServers(ctx, configs, registry)
+	if err != nil {
+		t.Skipf("mock MCP server unavailable: %v", err)
+	}
+	defer func() {
+		for _, c := range clients {
+			c.Close()
+		}
+	}()
+
+	tool, ok := registry.Get("mcp_docs_read_resource")
+	if !ok {
+		t.Fatal("expected mcp_docs_read_resource to be registered")
+	}
+	if !strings.Contains(tool.Description(), "file:///docs/readme.md") {
+		t.Errorf("expected resource URI in description: %s", tool.Description())
+	}
+
+	result, err := tool.Execute(ctx, json.RawMessage(`{"uri":"file:///docs/readme.md"}`))
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if result != "# Project docs" {
+		t.Errorf("unexpected result: %q", result)
+	}
+}
+
+func TestMCPToolsListChangedNotification(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	registry := NewRegistry()
+	configs := map[string]MCPServerConfig{
+		"mock": {
+			Command: "sh",
+			Args:    []string{"-c", mockMCPServerScriptListChanged},
+		},
+	}
+
+	clients, err := ConnectMCPServers(ctx, configs, registry)
+	if err != nil {
+		t.Skipf("mock MCP server unavailable: %v", err)
+	}
+	defer func() {
+		for _, c := range clients {
+			c.Close()
+		}
+	}()
+
+	if _, ok := registry.Get("mcp_mock_first_tool"); !ok {
+		t.Fatal("expected mcp_mock_first_tool to be registered initially")
+	}
+
+	deadline := time.After(5 * time.Second)
+	for {
+		_, hasSecond := registry.Get("mcp_mock_second_tool")
+		_, hasFirst := registry.Get("mcp_mock_first_tool")
+		if hasSecond && !hasFirst {
+			break
+		}
+		select {
+		case <-deadline:
+			t.Fatalf("registry not updated after list_changed: second=%v first=%v", hasSecond, hasFirst)
+		default:
+			time.Sleep(50 * time.Millisecond)
+		}
+	}
+}
+
+func TestMCPListChangedDuringConnect(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	registry := NewRegistry()
+	configs := map[string]MCPServerConfig{
+		"mock": {
+			Command:     "sh",
+			Args:        []string{"-c", mockMCPServerScriptEarlyListChanged},
+			ToolTimeout: 5,
+		},
+	}
+
+	clients, err := ConnectMCPServers(ctx, configs, registry)
+	if err != nil {
+		t.Skipf("mock MCP server unavailable: %v", err)
+	}
+	defer func() {
+		for _, c := range clients {
+			c.Close()
+		}
+	}()
+
+	waitForTool(t, registry, "mcp_mock_echo_tool", true)
+	tool, _ := registry.Get("mcp_mock_echo_tool")
+	if got := tool.(*MCPToolWrapper).timeout; got != 5*time.Second {
+		t.Errorf("tool timeout = %v, want 5s", got)
+	}
+}
+
+func TestMCPListChangedBurstIsCoalesced(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	registry := NewRegistry()
+	configs := map[string]MCPServerConfig{
+		"burst": {Command: "sh", Args: []string{"-c", mockMCPServerScriptListChangedBurst}},
+	}
+	clients, err := ConnectMCPServers(ctx, configs, registry)
+	if err != nil {
+		t.Skipf("mock MCP server unavailable: %v", err)
+	}
+	defer func() {
+		for _, c := range clients {
+			c.Close()
+		}
+	}()
+
+	waitForTool(t, registry, "mcp_burst_tool_1", false)
+	// Five separate refreshes would take 1.5s; give them time to show up.
+	time.Sleep(1500 * time.Millisecond)
+
+	defs := registry.Definitions()
+	if len(defs) != 1 {
+		t.Fatalf("registered %d tools, want 1", len(defs))
+	}
+	switch name := defs[0].Function.Name; name {
+	case "mcp_burst_tool_2", "mcp_burst_tool_3":
+	default:
+		t.Errorf("registered %s; the burst should cost at most two more listings", name)
+	}
+}
+
+func TestMCPServerRequestsAreAnswered(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	registry := NewRegistry()
+	configs := map[string]MCPServerConfig{
+		"mock": {Command: "sh", Args: []string{"-c", mockMCPServerScriptServerRequests}},
+	}
+	clients, err := ConnectMCPServers(ctx, configs, registry)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer func() {
+		for _, c := range clients {
+			c.Close()
+		}
+	}()
+
+	if _, ok := registry.Get("mcp_mock_answered"); !ok {
+		t.Errorf("server requests were not answered as expected; tools: %v", registry.Definitions())
+	}
+}
+
+func TestRegistryUnregister(t *testing.T) {
+	r := NewRegistry()
+	r.Register(&stubTool{name: "gone"})
+	if !r.Unregister("gone") {
+		t.Fatal("expected Unregister to report removal")
+	}
+	if _, ok := r.Get("gone"); ok {
+		t.Error("expected tool to be removed")
+	}
+	if r.Unregister("gone") {
+		t.Error("expected second Unregister to report false")
+	}
+}
+
 // Ensure ConnectMCPServers signature matches — compile-time check via usage.
 var _ = fmt.Sprintf // suppress unused import if needed
+
+// waitForTool polls the registry until the tool's presence matches want.
+func waitForTool(t *testing.T, registry *Registry, name string, want bool) {
+	t.Helper()
+	deadline := time.After(5 * time.Second)
+	for {
+		if _, ok := registry.Get(name); ok == want {
+			return
+		}
+		select {
+		case <-deadline:
+			t.Fatalf("timed out waiting for %s registered=%v", name, want)
+		default:
+			time.Sleep(50 * time.Millisecond)
+		}
+	}
+}
+
+func killMCPServer(c *MCPClient) {
+	c.mu.Lock()
+	cmd := c.cmd
+	c.mu.Unlock()
+	cmd.Process.Kill()
+}
+
+func TestMCPServerCrashUnregistersTools(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	registry := NewRegistry()
+	configs := map[string]MCPServerConfig{
+		"mock": {
+			Command: "sh",
+			Args:    []string{"-c", mockMCPServerScript},
+		},
+	}
+
+	clients, err := ConnectMCPServers(ctx, configs, registry)
+	if err != nil {
+		t.Skipf("mock MCP server unavailable: %v", err)
+	}
+	defer func() {
+		for _, c := range clients {
+			c.Close()
+		}
+	}()
+
+	waitForTool(t, registry, "mcp_mock_echo_tool", true)
+	killMCPServer(clients[0])
+	waitForTool(t, registry, "mcp_mock_echo_tool", false)
+
+	if _, err := clients[0].CallTool(ctx, "echo_tool", nil); err == nil {
+		t.Error("expected error calling a tool on a crashed server")
+	}
+}
+
+func TestMCPServerCrashReconnects(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	registry := NewRegistry()
+	configs := map[string]MCPServerConfig{
+		"mock": {
+			Command:       "sh",
+			Args:          []string{"-c", mockMCPServerScript},
+			MaxReconnects: 3,
+		},
+	}
+
+	clients, err := ConnectMCPServers(ctx, configs, registry)
+	if err != nil {
+		t.Skipf("mock MCP server unavailable: %v", err)
+	}
+	defer func() {
+		for _, c := range clients {
+			c.Close()
+		}
+	}()
+
+	killMCPServer(clients[0])
+	waitForTool(t, registry, "mcp_mock_echo_tool", false)
+	waitForTool(t, registry, "mcp_mock_echo_tool", true)
+
+	if _, err := clients[0].CallTool(ctx, "echo_tool", json.RawMessage(`{}`)); err != nil {
+		t.Fatalf("CallTool after reconnect: %v", err)
+	}
+}
+
+// mockMCPServerScriptFailsRestart serves normally the first time it runs and
+// refuses to initialize on every later start. Each start is recorded as a
+// line in $MCP_STARTS.
+const mockMCPServerScriptFailsRestart = `
+echo started >> "$MCP_STARTS"
+if [ "$(wc -l < "$MCP_STARTS")" -gt 1 ]; then
+  while IFS= read -r line; do
+    id=$(echo "$line" | python3 -c "import sys,json; d=json.load(sys.stdin); print(d.get('id',''))" 2>/dev/null)
+    echo "{\"jsonrpc\":\"2.0\",\"id\":$id,\"error\":{\"code\":-32603,\"message\":\"not ready\"}}"
+  done
+  exit 0
+fi
+` + mockMCPServerScript
+
+func TestMCPServerReconnectStopsAtLimit(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	starts := filepath.Join(t.TempDir(), "starts")
+	countStarts := func() int {
+		data, _ := os.ReadFile(starts)
+		return strings.Count(string(data), "\n")
+	}
+
+	registry := NewRegistry()
+	configs := map[string]MCPServerConfig{
+		"mock": {
+			Command:       "sh",
+			Args:          []string{"-c", mockMCPServerScriptFailsRestart},
+			Env:           map[string]string{"MCP_STARTS": starts},
+			MaxReconnects: 1,
+		},
+	}
+
+	clients, err := ConnectMCPServers(ctx, configs, registry)
+	if err != nil {
+		t.Skipf("mock MCP server unavailable: %v", err)
+	}
+	defer func() {
+		for _, c := range clients {
+			c.Close()
+		}
+	}()
+
+	killMCPServer(clients[0])
+	deadline := time.Now().Add(5 * time.Second)
+	for countStarts() < 2 && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+	}
+	// A second cycle would start the server again a second after the failed
+	// attempt was killed.
+	time.Sleep(2500 * time.Millisecond)
+	if n := countStarts(); n != 2 {
+		t.Errorf("server started %d times, want 2 (initial start and one reconnect)", n)
+	}
+}
+
+func TestMCPClientCallToolMixedContent(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	registry := NewRegistry()
+	configs := map[string]MCPServerConfig{
+		"mock": {
+			Command: "sh",
+			Args:    []string{"-c", mockMCPServerScriptMixedContent},
+		},
+	}
+
+	clients, err := ConnectMCPServers(ctx, configs, registry)
+	if err != nil {
+		t.Skipf("mock MCP server unavailable: %v", err)
+	}
+	defer func() {
+		for _, c := range clients {
+			c.Close()
+		}
+	}()
+
+	contents, err := clients[0].CallToolContent(ctx, "screenshot", json.RawMessage(`{}`))
+	if err != nil {
+		t.Fatalf("CallToolContent failed: %v", err)
+	}
+	if len(contents) != 2 {
+		t.Fatalf("expected 2 content blocks, got %d", len(contents))
+	}
+	if contents[1].Type != "image" || contents[1].MimeType != "image/png" || contents[1].Data != "aGVsbG8=" {
+		t.Errorf("unexpected image block: %+v", contents[1])
+	}
+
+	result := registry.Execute(ctx, "mcp_mock_screenshot", json.RawMessage(`{}`))
+	if !strings.HasPrefix(result, "captured ") {
+		t.Errorf("expected text content in result, got %q", result)
+	}
+	if !strings.Contains(result, "[image content (image/png)") {
+		t.Errorf("expected image placeholder in result, got %q", result)
+	}
+}
+
+func TestFormatMCPContent(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents []MCPContent
+		want     string
+	}{
+		{"empty", nil, ""},
+		{"text", []MCPContent{{Type: "text", Text: "a"}, {Type: "text", Text: "b"}}, "ab"},
+		{"image", []MCPContent{{Type: "image", Data: "AAAA", MimeType: "image/jpeg"}}, "[image content (image/jpeg), 4 bytes base64]"},
+		{"audio", []MCPContent{{Type: "audio", Data: "AA", MimeType: "audio/wav"}}, "[audio content (audio/wav), 2 bytes base64]"},
+		{"text resource", []MCPContent{{Type: "resource", Resource: &MCPResourceContent{URI: "file:///a", Text: "body"}}}, "body"},
+		{"blob resource", []MCPContent{{Type: "resource", Resource: &MCPResourceContent{URI: "file:///b", MimeType: "application/pdf", Blob: "AAA"}}}, "[binary resource file:///b (application/pdf), 3 bytes base64]"},
+		{"unknown", []MCPContent{{Type: "widget"}}, "[unsupported widget content]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FormatMCPContent(tt.contents); got != tt.want {
+				t.Errorf("FormatMCPContent() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package tools
 import (
 	"context"
 	"encoding/json"
+	"slices"
 	"testing"
 	"time"
 )
@@ -21,6 +22,21 @@ func TestMCPToolWrapperName(t *testing.T) {
 	}
 }
 
+func TestMCPToolNamePrefix(t *testing.T) {
+	w := &MCPToolWrapper{serverName: "github", namePrefix: "gh_", toolDef: MCPToolDef{Name: "list_issues"}}
+	if got := w.Name(); got != "gh_list_issues" {
+		t.Errorf("tool name = %q, want gh_list_issues", got)
+	}
+	r := &MCPResourceTool{serverName: "github", namePrefix: "gh_"}
+	if got := r.Name(); got != "gh_read_resource" {
+		t.Errorf("resource tool name = %q, want gh_read_resource", got)
+	}
+	r.namePrefix = ""
+	if got := r.Name(); got != "mcp_github_read_resource" {
+		t.Errorf("default resource tool name = %q", got)
+	}
+}
+
 func TestMCPToolWrapperParameters(t *testing.T) {
 	schema := json.RawMessage(`{"type":"object","properties":{"path":{"type":"string"}}}`)
 	w := &MCPToolWrapper{
@@ -44,3 +60,31 @@ func TestConnectMCPServersEmpty(t *testing.T) {
 		t.Errorf("expected 0 clients, got %d", len(clients))
 	}
 }
+
+func TestServerEnvInheritsHost(t *testing.T) {
+	host := []string{"HOME=/home/u", "TOKEN=host"}
+	got := serverEnv(host, MCPServerConfig{Env: map[string]string{"TOKEN": "cfg", "DEBUG": "1"}})
+	want := []string{"HOME=/home/u", "DEBUG=1", "TOKEN=cfg"}
+	if !slices.Equal(got, want) {
+		t.Errorf("serverEnv = %v, want %v", got, want)
+	}
+}
+
+func TestServerEnvPassthrough(t *testing.T) {
+	host := []string{
+		"PATH=/usr/bin",
+		"GITHUB_TOKEN=gh",
+		"GITHUB_HOST=github.com",
+		"AWS_SECRET_ACCESS_KEY=secret",
+		"GITHUBX=no",
+	}
+	cfg := MCPServerConfig{
+		EnvPassthrough: []string{"PATH", "GITHUB_*"},
+		Env:            map[string]string{"GITHUB_HOST": "ghe.example.com"},
+	}
+	got := serverEnv(host, cfg)
+	want := []string{"PATH=/usr/bin", "GITHUB_TOKEN=gh", "GITHUB_HOST=ghe.example.com"}
+	if !slices.Equal(got, want) {
+		t.Errorf("serverEnv = %v, want %v", got, want)
+	}
+}
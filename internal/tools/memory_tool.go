@@ -0,0 +1,99 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// MemoryEditor reads and updates the agent's long-term memory, which is kept
+// as named sections and loaded into the system prompt.
+type MemoryEditor interface {
+	ReadMemory() string
+	GetMemory(key string) (string, bool)
+	SetMemory(key, value string) error
+	AppendMemory(key, value string) error
+	DeleteMemory(key string) (bool, error)
+}
+
+type MemoryTool struct {
+	memory MemoryEditor
+}
+
+func NewMemoryTool(memory MemoryEditor) *MemoryTool {
+	return &MemoryTool{memory: memory}
+}
+
+func (t *MemoryTool) Name() string { return "memory" }
+func (t *MemoryTool) Description() string {
+	return "Read or update long-term memory that persists across conversations. " +
+		"Memory is organised into named sections and is included in your instructions at the start of every conversation"
+}
+func (t *MemoryTool) Parameters() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"action": {"type": "string", "enum": ["get", "set", "append", "delete"], "description": "get reads a section (or all memory when key is omitted), set replaces a section, append adds to it, delete removes it"},
+			"key": {"type": "string", "description": "Section name, e.g. user_preferences"},
+			"value": {"type": "string", "description": "Text to store for set and append"}
+		},
+		"required": ["action"]
+	}`)
+}
+
+func (t *MemoryTool) Execute(_ context.Context, params json.RawMessage) (string, error) {
+	var p struct {
+		Action string `json:"action"`
+		Key    string `json:"key"`
+		Value  string `json:"value"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return "", fmt.Errorf("invalid parameters: %w", err)
+	}
+	key := strings.TrimSpace(p.Key)
+	if strings.ContainsAny(key, "\r\n") {
+		return "", fmt.Errorf("key must be a single line")
+	}
+	if key == "" && p.Action != "get" {
+		return "", fmt.Errorf("key is required for %s", p.Action)
+	}
+
+	switch p.Action {
+	case "get":
+		if key == "" {
+			if all := t.memory.ReadMemory(); all != "" {
+				return all, nil
+			}
+			return "Memory is empty.", nil
+		}
+		value, ok := t.memory.GetMemory(key)
+		if !ok {
+			return fmt.Sprintf("No memory stored under %q.", key), nil
+		}
+		return value, nil
+	case "set":
+		if err := t.memory.SetMemory(key, p.Value); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Saved memory %q.", key), nil
+	case "append":
+		if strings.TrimSpace(p.Value) == "" {
+			return "", fmt.Errorf("value is required for append")
+		}
+		if err := t.memory.AppendMemory(key, p.Value); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Appended to memory %q.", key), nil
+	case "delete":
+		found, err := t.memory.DeleteMemory(key)
+		if err != nil {
+			return "", err
+		}
+		if !found {
+			return fmt.Sprintf("No memory stored under %q.", key), nil
+		}
+		return fmt.Sprintf("Deleted memory %q.", key), nil
+	}
+	return "", fmt.Errorf("unknown action %q (want get, set, append or delete)", p.Action)
+}
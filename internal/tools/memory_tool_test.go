@@ -0,0 +1,76 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// mapMemory is an in-memory MemoryEditor.
+type mapMemory map[string]string
+
+func (m mapMemory) ReadMemory() string {
+	var parts []string
+	for k, v := range m {
+		parts = append(parts, "## "+k+"\n\n"+v)
+	}
+	return strings.Join(parts, "\n\n")
+}
+func (m mapMemory) GetMemory(key string) (string, bool) { v, ok := m[key]; return v, ok }
+func (m mapMemory) SetMemory(key, value string) error   { m[key] = value; return nil }
+func (m mapMemory) AppendMemory(key, value string) error {
+	if m[key] != "" {
+		value = m[key] + "\n\n" + value
+	}
+	m[key] = value
+	return nil
+}
+func (m mapMemory) DeleteMemory(key string) (bool, error) {
+	_, ok := m[key]
+	delete(m, key)
+	return ok, nil
+}
+
+func TestMemoryTool(t *testing.T) {
+	mem := mapMemory{}
+	tool := NewMemoryTool(mem)
+	run := func(params string) string {
+		t.Helper()
+		out, err := tool.Execute(context.Background(), json.RawMessage(params))
+		if err != nil {
+			t.Fatalf("%s: %v", params, err)
+		}
+		return out
+	}
+
+	if got := run(`{"action":"get"}`); got != "Memory is empty." {
+		t.Errorf("empty get = %q", got)
+	}
+	run(`{"action":"set","key":"user","value":"Name is Ada"}`)
+	run(`{"action":"append","key":"user","value":"Prefers metric units"}`)
+	if got := run(`{"action":"get","key":"user"}`); got != "Name is Ada\n\nPrefers metric units" {
+		t.Errorf("get = %q", got)
+	}
+	if got := run(`{"action":"delete","key":"user"}`); !strings.Contains(got, "Deleted") {
+		t.Errorf("delete = %q", got)
+	}
+	if got := run(`{"action":"get","key":"user"}`); !strings.Contains(got, "No memory") {
+		t.Errorf("get after delete = %q", got)
+	}
+}
+
+func TestMemoryToolInvalid(t *testing.T) {
+	tool := NewMemoryTool(mapMemory{})
+	for _, params := range []string{
+		`not-json`,
+		`{"action":"set","value":"x"}`,
+		`{"action":"append","key":"k"}`,
+		`{"action":"set","key":"a\nb","value":"x"}`,
+		`{"action":"forget","key":"k"}`,
+	} {
+		if _, err := tool.Execute(context.Background(), json.RawMessage(params)); err == nil {
+			t.Errorf("%s: expected error", params)
+		}
+	}
+}
@@ -4,11 +4,15 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"os"
 
 	"github.com/coopco/nanobot/internal/bus"
 )
 
+// SendMessageTool sends text and files to a chat. A root set with SetRoot
+// confines the files it may send.
 type SendMessageTool struct {
+	root
 	bus *bus.MessageBus
 }
 
@@ -16,6 +20,8 @@ func NewSendMessageTool(msgBus *bus.MessageBus) *SendMessageTool {
 	return &SendMessageTool{bus: msgBus}
 }
 
+func (t *SendMessageTool) cloneTool() Tool { c := *t; return &c }
+
 func (t *SendMessageTool) Name() string        { return "send_message" }
 func (t *SendMessageTool) Description() string { return "Send a message to a specific channel and chat" }
 func (t *SendMessageTool) Parameters() json.RawMessage {
@@ -24,24 +30,26 @@ func (t *SendMessageTool) Parameters() json.RawMessage {
 		"properties": {
 			"channel": {"type": "string", "description": "Target channel name"},
 			"chat_id": {"type": "string", "description": "Target chat ID"},
-			"content": {"type": "string", "description": "Message content"}
+			"content": {"type": "string", "description": "Message content"},
+			"files": {"type": "array", "items": {"type": "string"}, "description": "Paths of files to send with the message"}
 		},
-		"required": ["channel", "chat_id", "content"]
+		"required": ["channel", "chat_id"]
 	}`)
 }
 
 func (t *SendMessageTool) Execute(ctx context.Context, params json.RawMessage) (string, error) {
 	var p struct {
-		Channel string `json:"channel"`
-		ChatID  string `json:"chat_id"`
-		Content string `json:"content"`
+		Channel string   `json:"channel"`
+		ChatID  string   `json:"chat_id"`
+		Content string   `json:"content"`
+		Files   []string `json:"files"`
 	}
 	if err := json.Unmarshal(params, &p); err != nil {
 		return "", fmt.Errorf("invalid parameters: %w", err)
 	}
 
-	if p.Channel == "" || p.ChatID == "" || p.Content == "" {
-		return "", fmt.Errorf("channel, chat_id, and content are required")
+	if p.Channel == "" || p.ChatID == "" || (p.Content == "" && len(p.Files) == 0) {
+		return "", fmt.Errorf("channel, chat_id, and content or files are required")
 	}
 
 	msg := bus.OutboundMessage{
@@ -50,7 +58,19 @@ func (t *SendMessageTool) Execute(ctx context.Context, params json.RawMessage) (
 		Content: p.Content,
 		Type:    "text",
 	}
+	for _, f := range p.Files {
+		path, err := t.resolve(f)
+		if err != nil {
+			return "", err
+		}
+		if _, err := os.Stat(path); err != nil {
+			return "", fmt.Errorf("cannot send file: %w", err)
+		}
+		msg.Attachments = append(msg.Attachments, bus.Attachment{Path: path})
+	}
 
-	t.bus.PublishOutbound(msg)
+	if err := t.bus.PublishOutboundContext(ctx, msg); err != nil {
+		return "", fmt.Errorf("failed to queue message: %w", err)
+	}
 	return "Message sent", nil
 }
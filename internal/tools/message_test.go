@@ -3,6 +3,8 @@ package tools
 import (
 	"context"
 	"encoding/json"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -97,3 +99,39 @@ func TestSendMessageTool_Name(t *testing.T) {
 		t.Error("Parameters() is empty")
 	}
 }
+
+func TestSendMessageTool_Files(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "report.csv"), []byte("a,b"), 0o644)
+	outside := filepath.Join(t.TempDir(), "secret.txt")
+	os.WriteFile(outside, []byte("x"), 0o644)
+
+	msgBus := bus.NewMessageBus(10)
+	received := make(chan bus.OutboundMessage, 1)
+	msgBus.Subscribe("telegram", func(msg bus.OutboundMessage) { received <- msg })
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go msgBus.DispatchOutbound(ctx)
+
+	tool := NewSendMessageTool(msgBus)
+	tool.SetRoot(dir)
+	params, _ := json.Marshal(map[string]any{"channel": "telegram", "chat_id": "123", "files": []string{"report.csv"}})
+	if _, err := tool.Execute(context.Background(), params); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case msg := <-received:
+		if len(msg.Attachments) != 1 || filepath.Base(msg.Attachments[0].Path) != "report.csv" || !filepath.IsAbs(msg.Attachments[0].Path) {
+			t.Errorf("Attachments = %+v", msg.Attachments)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for message dispatch")
+	}
+
+	for _, files := range [][]string{{outside}, {"missing.txt"}} {
+		params, _ := json.Marshal(map[string]any{"channel": "telegram", "chat_id": "123", "files": files})
+		if _, err := tool.Execute(context.Background(), params); err == nil {
+			t.Errorf("expected error sending %v", files)
+		}
+	}
+}
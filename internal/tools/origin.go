@@ -0,0 +1,23 @@
+package tools
+
+import "context"
+
+type originKey struct{}
+
+type origin struct {
+	channel string
+	chatID  string
+}
+
+// WithOrigin records the channel and chat a tool call is made on behalf of,
+// so tools that report back later know where to send results.
+func WithOrigin(ctx context.Context, channel, chatID string) context.Context {
+	return context.WithValue(ctx, originKey{}, origin{channel: channel, chatID: chatID})
+}
+
+// OriginFrom returns the channel and chat stored by WithOrigin, or empty
+// strings if none was set.
+func OriginFrom(ctx context.Context) (channel, chatID string) {
+	o, _ := ctx.Value(originKey{}).(origin)
+	return o.channel, o.chatID
+}
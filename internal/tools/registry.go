@@ -1,11 +1,18 @@
 package tools
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
+	"sort"
 	"strings"
 	"sync"
+	"time"
+
+	"github.com/coopco/nanobot/internal/metrics"
 )
 
 type ToolDefinition struct {
@@ -22,18 +29,118 @@ type FunctionDef struct {
 type Registry struct {
 	tools map[string]Tool
 	mu    sync.RWMutex
+
+	// timeout bounds every Execute call; zero disables it. toolTimeouts
+	// overrides it for individual tools.
+	timeout      time.Duration
+	toolTimeouts map[string]time.Duration
 }
 
 func NewRegistry() *Registry {
 	return &Registry{tools: make(map[string]Tool)}
 }
 
+// ErrToolExists is returned by RegisterUnique when a tool with the same name
+// is already registered.
+var ErrToolExists = errors.New("tool already registered")
+
+// Register adds t to the registry, replacing any tool with the same name.
 func (r *Registry) Register(t Tool) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	r.tools[t.Name()] = t
 }
 
+// RegisterUnique adds t to the registry unless a tool with the same name is
+// already registered, in which case it returns an error wrapping ErrToolExists.
+func (r *Registry) RegisterUnique(t Tool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	name := t.Name()
+	if _, ok := r.tools[name]; ok {
+		return fmt.Errorf("%w: %s", ErrToolExists, name)
+	}
+	r.tools[name] = t
+	return nil
+}
+
+// Unregister removes the tool with the given name. It reports whether a tool was removed.
+func (r *Registry) Unregister(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.tools[name]; !ok {
+		return false
+	}
+	delete(r.tools, name)
+	return true
+}
+
+// SetTimeout sets the default execution timeout applied to every tool. Zero disables it.
+func (r *Registry) SetTimeout(d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.timeout = d
+}
+
+// SetToolTimeout overrides the execution timeout for a single tool. Zero
+// disables the timeout for that tool.
+func (r *Registry) SetToolTimeout(name string, d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.toolTimeouts == nil {
+		r.toolTimeouts = make(map[string]time.Duration)
+	}
+	r.toolTimeouts[name] = d
+}
+
+// SetWorkspaceRoot confines every registered tool that works with files,
+// such as read_file and run_shell, to dir. An empty dir lifts the restriction.
+// Tools registered afterwards are not affected.
+func (r *Registry) SetWorkspaceRoot(dir string) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, t := range r.tools {
+		if rt, ok := t.(interface{ SetRoot(string) }); ok {
+			rt.SetRoot(dir)
+		}
+	}
+}
+
+// SetAllowedHosts limits every registered tool that fetches web pages, such
+// as web_get and read_url, to hosts and their subdomains. An empty list
+// allows any host. Tools registered afterwards are not affected.
+func (r *Registry) SetAllowedHosts(hosts []string) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, t := range r.tools {
+		if ht, ok := t.(interface{ SetAllowedHosts([]string) }); ok {
+			ht.SetAllowedHosts(hosts)
+		}
+	}
+}
+
+// SetShellPolicy applies p to every registered tool that runs shell
+// commands, such as run_shell. Tools registered afterwards are not affected.
+func (r *Registry) SetShellPolicy(p ShellPolicy) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, t := range r.tools {
+		if st, ok := t.(interface{ SetShellPolicy(ShellPolicy) }); ok {
+			st.SetShellPolicy(p)
+		}
+	}
+}
+
+// timeoutFor returns the execution timeout that applies to the named tool.
+func (r *Registry) timeoutFor(name string) time.Duration {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if d, ok := r.toolTimeouts[name]; ok {
+		return d
+	}
+	return r.timeout
+}
+
 func (r *Registry) Get(name string) (Tool, bool) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
@@ -41,7 +148,21 @@ func (r *Registry) Get(name string) (Tool, bool) {
 	return t, ok
 }
 
+// ToolResult is the outcome of a tool call. IsError marks results that
+// describe a failure (unknown tool, tool error, timeout) rather than output.
+type ToolResult struct {
+	Content string
+	IsError bool
+}
+
+// Execute runs the named tool and returns its output, or a description of the
+// failure for the model to act on.
 func (r *Registry) Execute(ctx context.Context, name string, args json.RawMessage) string {
+	return r.ExecuteResult(ctx, name, args).Content
+}
+
+// ExecuteResult is like Execute but reports whether the call failed.
+func (r *Registry) ExecuteResult(ctx context.Context, name string, args json.RawMessage) ToolResult {
 	t, ok := r.Get(name)
 	if !ok {
 		r.mu.RLock()
@@ -50,15 +171,95 @@ func (r *Registry) Execute(ctx context.Context, name string, args json.RawMessag
 			names = append(names, n)
 		}
 		r.mu.RUnlock()
-		return fmt.Sprintf("Unknown tool: %s. Available tools: %s", name, strings.Join(names, ", "))
+		return ToolResult{
+			Content: fmt.Sprintf("Unknown tool: %s. Available tools: %s", name, strings.Join(names, ", ")),
+			IsError: true,
+		}
+	}
+	// Models occasionally emit truncated or otherwise broken arguments. Say so
+	// plainly instead of surfacing the tool's own decode error, so the model
+	// can correct itself on the next turn.
+	if len(bytes.TrimSpace(args)) == 0 {
+		args = json.RawMessage(`{}`)
+	} else if !json.Valid(args) {
+		return ToolResult{
+			Content: fmt.Sprintf("The arguments for %s were not valid JSON, please retry with correct JSON matching the tool's parameter schema. Received: %s", name, truncateArgs(args)),
+			IsError: true,
+		}
 	}
-	result, err := t.Execute(ctx, args)
+	start := time.Now()
+	result, err := r.run(ctx, t, args)
+	metrics.ToolExecutions.Inc(name)
+	metrics.ToolDuration.Observe(time.Since(start).Seconds(), name)
 	if err != nil {
-		return fmt.Sprintf("Error executing %s: %v\n\n[Analyze the error above and try a different approach.]", name, err)
+		metrics.Errors.Inc("tool")
+		return ToolResult{
+			Content: fmt.Sprintf("Error executing %s: %v\n\n[Analyze the error above and try a different approach.]", name, err),
+			IsError: true,
+		}
+	}
+	return ToolResult{Content: result}
+}
+
+// truncateArgs shortens raw arguments echoed back to the model.
+func truncateArgs(args json.RawMessage) string {
+	const max = 200
+	if len(args) <= max {
+		return string(args)
+	}
+	return string(args[:max]) + "…"
+}
+
+// run executes the tool, abandoning it once its timeout expires. A tool that
+// ignores context cancellation keeps running in the background, but the caller
+// is released promptly.
+func (r *Registry) run(ctx context.Context, t Tool, args json.RawMessage) (string, error) {
+	timeout := r.timeoutFor(t.Name())
+	if timeout <= 0 {
+		return execute(ctx, t, args)
+	}
+
+	execCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type outcome struct {
+		result string
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		result, err := execute(execCtx, t, args)
+		done <- outcome{result, err}
+	}()
+
+	select {
+	case o := <-done:
+		if o.err != nil && errors.Is(execCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+			return "", fmt.Errorf("tool %s timed out after %s", t.Name(), timeout)
+		}
+		return o.result, o.err
+	case <-execCtx.Done():
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		return "", fmt.Errorf("tool %s timed out after %s", t.Name(), timeout)
 	}
-	return result
 }
 
+// execute calls t.Execute, turning a panic into an error so that one broken
+// tool cannot take down the process from a goroutine no caller can recover.
+func execute(ctx context.Context, t Tool, args json.RawMessage) (result string, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			slog.Error("tool panicked", "name", t.Name(), "panic", p)
+			result, err = "", fmt.Errorf("tool %s panicked: %v", t.Name(), p)
+		}
+	}()
+	return t.Execute(ctx, args)
+}
+
+// Definitions returns the definition of every registered tool, sorted by
+// name so the tools sent to the model are the same from call to call.
 func (r *Registry) Definitions() []ToolDefinition {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
@@ -73,15 +274,58 @@ func (r *Registry) Definitions() []ToolDefinition {
 			},
 		})
 	}
+	sort.Slice(defs, func(i, j int) bool { return defs[i].Function.Name < defs[j].Function.Name })
+	return defs
+}
+
+// Catalog returns the name, description and parameter schema of every
+// registered tool, sorted by name so the output is stable.
+func (r *Registry) Catalog() []FunctionDef {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	defs := make([]FunctionDef, 0, len(r.tools))
+	for _, t := range r.tools {
+		defs = append(defs, FunctionDef{
+			Name:        t.Name(),
+			Description: t.Description(),
+			Parameters:  t.Parameters(),
+		})
+	}
+	sort.Slice(defs, func(i, j int) bool { return defs[i].Name < defs[j].Name })
 	return defs
 }
 
+// CatalogJSON marshals Catalog as a JSON array for external clients.
+func (r *Registry) CatalogJSON() ([]byte, error) {
+	data, err := json.Marshal(r.Catalog())
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal tool catalog: %w", err)
+	}
+	return data, nil
+}
+
+// toolCloner is implemented by tools with settings that SetWorkspaceRoot,
+// SetAllowedHosts or SetShellPolicy change, so that Clone can give the clone
+// its own copy.
+type toolCloner interface {
+	cloneTool() Tool
+}
+
+// Clone returns a registry with the same tools and timeouts. Tools with
+// settings are copied, so configuring the clone leaves r's tools unchanged.
 func (r *Registry) Clone() *Registry {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 	clone := NewRegistry()
 	for k, v := range r.tools {
+		if c, ok := v.(toolCloner); ok {
+			v = c.cloneTool()
+		}
 		clone.tools[k] = v
 	}
+	clone.timeout = r.timeout
+	for k, v := range r.toolTimeouts {
+		clone.SetToolTimeout(k, v)
+	}
 	return clone
 }
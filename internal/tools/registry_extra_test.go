@@ -3,8 +3,10 @@ package tools
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"strings"
 	"testing"
+	"time"
 )
 
 // dummyTool is a simple tool for testing the registry.
@@ -57,6 +59,78 @@ func TestRegistryExecute_Error(t *testing.T) {
 	}
 }
 
+// blockingTool sleeps for delay, ignoring context cancellation.
+type blockingTool struct {
+	name  string
+	delay time.Duration
+}
+
+func (b *blockingTool) Name() string              { return b.name }
+func (b *blockingTool) Description() string       { return "blocks" }
+func (b *blockingTool) Parameters() json.RawMessage { return json.RawMessage(`{"type":"object"}`) }
+func (b *blockingTool) Execute(_ context.Context, _ json.RawMessage) (string, error) {
+	time.Sleep(b.delay)
+	return "finished", nil
+}
+
+func TestRegistryExecute_Timeout(t *testing.T) {
+	r := NewRegistry()
+	r.SetTimeout(100 * time.Millisecond)
+	r.Register(&blockingTool{name: "slow", delay: 2 * time.Second})
+
+	start := time.Now()
+	result := r.Execute(context.Background(), "slow", json.RawMessage(`{}`))
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Execute took %v, expected to return promptly", elapsed)
+	}
+	if !strings.Contains(result, "tool slow timed out after 100ms") {
+		t.Errorf("expected timeout message, got %q", result)
+	}
+}
+
+// panicTool panics whenever it is run.
+type panicTool struct{}
+
+func (panicTool) Name() string                { return "panics" }
+func (panicTool) Description() string         { return "panics" }
+func (panicTool) Parameters() json.RawMessage { return json.RawMessage(`{"type":"object"}`) }
+func (panicTool) Execute(context.Context, json.RawMessage) (string, error) {
+	panic("boom")
+}
+
+func TestRegistryExecute_PanicBecomesError(t *testing.T) {
+	for _, timeout := range []time.Duration{0, time.Second} {
+		r := NewRegistry()
+		r.SetTimeout(timeout)
+		r.Register(panicTool{})
+
+		res := r.ExecuteResult(context.Background(), "panics", json.RawMessage(`{}`))
+		if !res.IsError || !strings.Contains(res.Content, "tool panics panicked: boom") {
+			t.Errorf("timeout %v: result = %+v, want the panic as an error", timeout, res)
+		}
+	}
+}
+
+func TestRegistryExecute_PerToolTimeout(t *testing.T) {
+	r := NewRegistry()
+	r.SetTimeout(50 * time.Millisecond)
+	r.SetToolTimeout("slow", time.Second)
+	r.Register(&blockingTool{name: "slow", delay: 100 * time.Millisecond})
+
+	result := r.Execute(context.Background(), "slow", json.RawMessage(`{}`))
+	if result != "finished" {
+		t.Errorf("expected per-tool override to allow completion, got %q", result)
+	}
+
+	clone := r.Clone()
+	if got := clone.timeoutFor("slow"); got != time.Second {
+		t.Errorf("clone timeout for slow = %v, want 1s", got)
+	}
+	if got := clone.timeoutFor("other"); got != 50*time.Millisecond {
+		t.Errorf("clone default timeout = %v, want 50ms", got)
+	}
+}
+
 func TestRegistryDefinitions(t *testing.T) {
 	r := NewRegistry()
 	r.Register(&dummyTool{name: "a"})
@@ -78,6 +152,24 @@ func TestRegistryDefinitions(t *testing.T) {
 	}
 }
 
+func TestRegistryDefinitionsStableOrder(t *testing.T) {
+	r := NewRegistry()
+	for _, name := range []string{"web_get", "exec", "read_file", "spawn", "cron", "list_dir", "message"} {
+		r.Register(&dummyTool{name: name})
+	}
+	want := []string{"cron", "exec", "list_dir", "message", "read_file", "spawn", "web_get"}
+	for i := 0; i < 20; i++ {
+		defs := r.Definitions()
+		got := make([]string, len(defs))
+		for j, d := range defs {
+			got[j] = d.Function.Name
+		}
+		if strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Fatalf("call %d: Definitions order = %v, want %v", i, got, want)
+		}
+	}
+}
+
 func TestRegistryClone(t *testing.T) {
 	r := NewRegistry()
 	r.Register(&dummyTool{name: "original"})
@@ -118,6 +210,94 @@ func TestRegistryGet(t *testing.T) {
 
 // --- MCP types tests ---
 
+func TestRegistryRegisterUnique(t *testing.T) {
+	r := NewRegistry()
+	if err := r.RegisterUnique(&dummyTool{name: "dup", result: "first"}); err != nil {
+		t.Fatalf("first RegisterUnique: %v", err)
+	}
+
+	err := r.RegisterUnique(&dummyTool{name: "dup", result: "second"})
+	if !errors.Is(err, ErrToolExists) {
+		t.Fatalf("err = %v, want ErrToolExists", err)
+	}
+	if !strings.Contains(err.Error(), "dup") {
+		t.Errorf("error should name the tool: %v", err)
+	}
+	if got := r.Execute(context.Background(), "dup", json.RawMessage(`{}`)); got != "first" {
+		t.Errorf("original tool was replaced, got %q", got)
+	}
+}
+
+func TestConnectMCPServers_ToolNameConflict(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	r := NewRegistry()
+	r.Register(&dummyTool{name: "mcp_mock_echo_tool", result: "builtin"})
+	configs := map[string]MCPServerConfig{
+		"mock": {Command: "sh", Args: []string{"-c", mockMCPServerScript}},
+	}
+
+	clients, err := ConnectMCPServers(ctx, configs, r)
+	for _, c := range clients {
+		c.Close()
+	}
+	if !errors.Is(err, ErrToolExists) {
+		t.Fatalf("err = %v, want ErrToolExists", err)
+	}
+	if got := r.Execute(ctx, "mcp_mock_echo_tool", json.RawMessage(`{}`)); got != "builtin" {
+		t.Errorf("existing tool was replaced, got %q", got)
+	}
+}
+
+func TestConnectMCPServers_NamePrefix(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	r := NewRegistry()
+	configs := map[string]MCPServerConfig{
+		"mock": {Command: "sh", Args: []string{"-c", mockMCPServerScript}, NamePrefix: "m_"},
+	}
+
+	clients, err := ConnectMCPServers(ctx, configs, r)
+	defer func() {
+		for _, c := range clients {
+			c.Close()
+		}
+	}()
+	if err != nil {
+		t.Skipf("mock MCP server unavailable: %v", err)
+	}
+	if _, ok := r.Get("m_echo_tool"); !ok {
+		t.Error("expected m_echo_tool to be registered")
+	}
+	if _, ok := r.Get("mcp_mock_echo_tool"); ok {
+		t.Error("default name should not be registered when a prefix is set")
+	}
+}
+
+func TestConnectMCPServers_NamePrefixConflict(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	r := NewRegistry()
+	r.Register(&dummyTool{name: "echo_echo_tool", result: "builtin"})
+	configs := map[string]MCPServerConfig{
+		"mock": {Command: "sh", Args: []string{"-c", mockMCPServerScript}, NamePrefix: "echo_"},
+	}
+
+	clients, err := ConnectMCPServers(ctx, configs, r)
+	for _, c := range clients {
+		c.Close()
+	}
+	if !errors.Is(err, ErrToolExists) {
+		t.Fatalf("err = %v, want ErrToolExists", err)
+	}
+	if got := r.Execute(ctx, "echo_echo_tool", json.RawMessage(`{}`)); got != "builtin" {
+		t.Errorf("existing tool was replaced, got %q", got)
+	}
+}
+
 func TestMCPToolWrapper_Accessors(t *testing.T) {
 	wrapper := &MCPToolWrapper{
 		serverName: "myserver",
@@ -169,3 +349,60 @@ func TestNewMCPClient_EmptyCommand(t *testing.T) {
 		t.Fatal("expected error for empty command")
 	}
 }
+
+func TestRegistryExecuteResult_InvalidJSON(t *testing.T) {
+	r := NewRegistry()
+	r.Register(&dummyTool{name: "ok", result: "fine"})
+
+	res := r.ExecuteResult(context.Background(), "ok", json.RawMessage(`not-json`))
+	if !res.IsError || !strings.Contains(res.Content, "not valid JSON") || !strings.Contains(res.Content, "not-json") {
+		t.Errorf("got %+v, want a corrective error echoing the arguments", res)
+	}
+
+	// Empty arguments are treated as an empty object.
+	if res := r.ExecuteResult(context.Background(), "ok", nil); res.IsError || res.Content != "fine" {
+		t.Errorf("empty arguments: got %+v, want the tool result", res)
+	}
+}
+
+func TestRegistryExecuteResult_FlagsErrors(t *testing.T) {
+	r := NewRegistry()
+	r.Register(&dummyTool{name: "ok", result: "fine"})
+	r.Register(&dummyTool{name: "fail", err: errors.New("disk full")})
+
+	tests := []struct {
+		name    string
+		wantErr bool
+	}{
+		{"ok", false},
+		{"fail", true},
+		{"missing", true},
+	}
+	for _, tt := range tests {
+		res := r.ExecuteResult(context.Background(), tt.name, json.RawMessage(`{}`))
+		if res.IsError != tt.wantErr {
+			t.Errorf("%s: IsError = %v, want %v (content %q)", tt.name, res.IsError, tt.wantErr, res.Content)
+		}
+	}
+}
+
+func TestRegistryCatalogJSON(t *testing.T) {
+	r := NewRegistry()
+	r.Register(&dummyTool{name: "zeta"})
+	r.Register(&dummyTool{name: "alpha"})
+
+	data, err := r.CatalogJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []FunctionDef
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("catalog is not a JSON array: %v\n%s", err, data)
+	}
+	if len(got) != 2 || got[0].Name != "alpha" || got[1].Name != "zeta" {
+		t.Fatalf("catalog = %+v, want alpha and zeta in order", got)
+	}
+	if got[0].Description != "dummy alpha" || string(got[0].Parameters) != `{"type":"object"}` {
+		t.Errorf("catalog entry = %+v, want the tool's description and schema", got[0])
+	}
+}
@@ -71,6 +71,32 @@ func TestClone(t *testing.T) {
 	}
 }
 
+func TestCloneSettingsDoNotReachOriginal(t *testing.T) {
+	r := NewRegistry()
+	r.Register(NewReadFileTool())
+	r.Register(NewRunShellTool())
+	r.Register(NewWebGetTool())
+	r.SetWorkspaceRoot("/workspace")
+
+	clone := r.Clone()
+	clone.SetWorkspaceRoot("")
+	clone.SetShellPolicy(ShellPolicy{Disabled: true})
+	clone.SetAllowedHosts([]string{"example.com"})
+
+	read, _ := r.Get("read_file")
+	if dir := read.(*ReadFileTool).dir; dir != "/workspace" {
+		t.Errorf("original read_file root = %q, want /workspace", dir)
+	}
+	shell, _ := r.Get("run_shell")
+	if shell.(*RunShellTool).policy.Disabled {
+		t.Error("disabling run_shell on the clone disabled the original")
+	}
+	web, _ := r.Get("web_get")
+	if hosts := web.(*WebGetTool).hosts; len(hosts) != 0 {
+		t.Errorf("original web_get hosts = %v, want none", hosts)
+	}
+}
+
 func TestReadFile(t *testing.T) {
 	f, err := os.CreateTemp("", "readtest*.txt")
 	if err != nil {
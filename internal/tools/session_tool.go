@@ -0,0 +1,67 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// SessionExporter renders a stored conversation as a transcript.
+type SessionExporter interface {
+	Export(key, format string) (string, error)
+}
+
+// ExportSessionTool exports the conversation of the chat it is called from.
+// Other sessions can be exported only after SetAllowAnySession, since any
+// chat user could otherwise read another user's conversation.
+type ExportSessionTool struct {
+	exporter SessionExporter
+	anyKey   bool
+}
+
+func NewExportSessionTool(exporter SessionExporter) *ExportSessionTool {
+	return &ExportSessionTool{exporter: exporter}
+}
+
+// SetAllowAnySession lets the tool export any session, not only the calling
+// chat's.
+func (t *ExportSessionTool) SetAllowAnySession(allow bool) { t.anyKey = allow }
+
+func (t *ExportSessionTool) Name() string { return "export_session" }
+func (t *ExportSessionTool) Description() string {
+	return "Export a conversation session as a readable Markdown or plain-text transcript"
+}
+func (t *ExportSessionTool) Parameters() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"session_key": {"type": "string", "description": "Session to export, e.g. telegram:12345 (default: the current chat)"},
+			"format": {"type": "string", "enum": ["markdown", "text"], "description": "Output format (default markdown)"}
+		}
+	}`)
+}
+
+func (t *ExportSessionTool) Execute(ctx context.Context, params json.RawMessage) (string, error) {
+	var p struct {
+		SessionKey string `json:"session_key"`
+		Format     string `json:"format"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return "", fmt.Errorf("invalid parameters: %w", err)
+	}
+	var own string
+	if channel, chatID := OriginFrom(ctx); channel != "" {
+		own = channel + ":" + chatID
+	}
+	key := p.SessionKey
+	if key == "" {
+		key = own
+	}
+	if key == "" {
+		return "", fmt.Errorf("session_key is required")
+	}
+	if key != own && !t.anyKey {
+		return "", fmt.Errorf("only the current chat's session can be exported")
+	}
+	return t.exporter.Export(key, p.Format)
+}
@@ -0,0 +1,72 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+type mockExporter struct {
+	gotKey, gotFormat string
+}
+
+func (m *mockExporter) Export(key, format string) (string, error) {
+	if key == "missing" {
+		return "", fmt.Errorf("session %q not found", key)
+	}
+	m.gotKey, m.gotFormat = key, format
+	return "transcript", nil
+}
+
+func TestExportSessionTool(t *testing.T) {
+	exp := &mockExporter{}
+	tool := NewExportSessionTool(exp)
+
+	ctx := WithOrigin(context.Background(), "tg", "1")
+
+	params, _ := json.Marshal(map[string]any{"session_key": "tg:1", "format": "text"})
+	result, err := tool.Execute(ctx, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != "transcript" || exp.gotKey != "tg:1" || exp.gotFormat != "text" {
+		t.Errorf("unexpected call: result=%q key=%q format=%q", result, exp.gotKey, exp.gotFormat)
+	}
+
+	params, _ = json.Marshal(map[string]any{})
+	if _, err := tool.Execute(ctx, params); err != nil || exp.gotKey != "tg:1" {
+		t.Errorf("without session_key: err=%v key=%q, want the current chat", err, exp.gotKey)
+	}
+	if _, err := tool.Execute(context.Background(), params); err == nil {
+		t.Error("expected error without session_key or origin")
+	}
+
+	tool.SetAllowAnySession(true)
+	params, _ = json.Marshal(map[string]any{"session_key": "missing"})
+	if _, err := tool.Execute(ctx, params); err == nil {
+		t.Error("expected error for unknown session")
+	}
+}
+
+func TestExportSessionToolRefusesOtherChats(t *testing.T) {
+	exp := &mockExporter{}
+	tool := NewExportSessionTool(exp)
+	ctx := WithOrigin(context.Background(), "telegram", "111")
+
+	params, _ := json.Marshal(map[string]any{"session_key": "telegram:222"})
+	if _, err := tool.Execute(ctx, params); err == nil {
+		t.Fatal("exported another chat's session")
+	}
+	if _, err := tool.Execute(context.Background(), params); err == nil {
+		t.Fatal("exported a session without a calling chat")
+	}
+	if exp.gotKey != "" {
+		t.Errorf("exporter called with %q", exp.gotKey)
+	}
+
+	tool.SetAllowAnySession(true)
+	if _, err := tool.Execute(ctx, params); err != nil || exp.gotKey != "telegram:222" {
+		t.Errorf("with any session allowed: err=%v key=%q", err, exp.gotKey)
+	}
+}
@@ -4,17 +4,104 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
 	"time"
 )
 
 const maxOutputLen = 10000
 
-type RunShellTool struct{}
+// ShellPolicy restricts the commands run_shell will start. The zero value
+// allows everything.
+//
+// Allow and Deny match the program name, without its directory, of every
+// command in the line, so "ls | rm x" needs both ls and rm to pass. With an
+// Allow list set, command and process substitution are refused as well,
+// since they would run programs that cannot be checked. Deny alone only guards against
+// mistakes: a denied program can still be reached through an allowed one
+// such as env or xargs.
+type ShellPolicy struct {
+	Disabled bool     // refuse every command
+	Allow    []string // programs that may run; empty allows any not denied
+	Deny     []string // programs that may never run
+}
+
+// shellSeparators splits a command line into the simple commands it runs.
+var shellSeparators = regexp.MustCompile(`&&|\|\||[;&|\n]`)
+
+// shellRedirect matches redirections such as 2>&1 and &>, whose & does not
+// separate commands.
+var shellRedirect = regexp.MustCompile(`[<>]&|&>`)
+
+// shellSubstitution matches the start of a command substitution, $( or a
+// backquote, or a process substitution, <( or >(.
+var shellSubstitution = regexp.MustCompile("\\$\\(|`|[<>]\\(")
+
+// envAssignment matches a leading VAR=value word.
+var envAssignment = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*=`)
+
+// check returns an error naming the first program in command that the
+// policy does not permit.
+func (p ShellPolicy) check(command string) error {
+	if p.Disabled {
+		return errors.New("run_shell is disabled in this deployment")
+	}
+	if len(p.Allow) == 0 && len(p.Deny) == 0 {
+		return nil
+	}
+	if len(p.Allow) > 0 && shellSubstitution.MatchString(command) {
+		return fmt.Errorf("command and process substitution are not allowed; permitted commands: %s", strings.Join(p.Allow, ", "))
+	}
+	command = shellRedirect.ReplaceAllString(command, ">")
+	for _, segment := range shellSeparators.Split(command, -1) {
+		prog := shellProgram(segment)
+		if prog == "" {
+			continue
+		}
+		if slices.Contains(p.Deny, prog) {
+			return fmt.Errorf("command %q is not allowed", prog)
+		}
+		if len(p.Allow) > 0 && !slices.Contains(p.Allow, prog) {
+			return fmt.Errorf("command %q is not allowed; permitted commands: %s", prog, strings.Join(p.Allow, ", "))
+		}
+	}
+	return nil
+}
+
+// shellProgram returns the name of the program a simple command runs,
+// skipping variable assignments and grouping characters. It returns "" for
+// an empty command.
+func shellProgram(segment string) string {
+	for _, word := range strings.Fields(segment) {
+		word = strings.TrimLeft(word, "({!")
+		if word == "" || envAssignment.MatchString(word) {
+			continue
+		}
+		return filepath.Base(strings.Trim(word, `"'`))
+	}
+	return ""
+}
+
+// RunShellTool runs commands with sh. A root set with SetRoot becomes the
+// working directory; the command itself is not confined to it. Commands are
+// checked against the policy set with SetShellPolicy before they run.
+type RunShellTool struct {
+	root
+	policy ShellPolicy
+}
 
 func NewRunShellTool() *RunShellTool { return &RunShellTool{} }
 
+func (t *RunShellTool) cloneTool() Tool { c := *t; return &c }
+
+// SetShellPolicy restricts the commands the tool will run.
+func (t *RunShellTool) SetShellPolicy(p ShellPolicy) { t.policy = p }
+
 func (t *RunShellTool) Name() string        { return "run_shell" }
 func (t *RunShellTool) Description() string { return "Execute a shell command and return its output" }
 func (t *RunShellTool) Parameters() json.RawMessage {
@@ -36,6 +123,9 @@ func (t *RunShellTool) Execute(ctx context.Context, params json.RawMessage) (str
 	if err := json.Unmarshal(params, &p); err != nil {
 		return "", fmt.Errorf("invalid parameters: %w", err)
 	}
+	if err := t.policy.check(p.Command); err != nil {
+		return "", err
+	}
 	timeout := 30
 	if p.Timeout > 0 {
 		timeout = p.Timeout
@@ -44,6 +134,9 @@ func (t *RunShellTool) Execute(ctx context.Context, params json.RawMessage) (str
 	defer cancel()
 
 	cmd := exec.CommandContext(ctx, "sh", "-c", p.Command)
+	cmd.Dir = t.dir
+	// Children of sh may hold the output pipe open after sh is killed.
+	cmd.WaitDelay = time.Second
 	var buf bytes.Buffer
 	cmd.Stdout = &buf
 	cmd.Stderr = &buf
@@ -3,6 +3,8 @@ package tools
 import (
 	"context"
 	"encoding/json"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -43,6 +45,18 @@ func TestRunShellTool_Timeout(t *testing.T) {
 	}
 }
 
+func TestRunShellTool_TimeoutWithChildHoldingOutput(t *testing.T) {
+	tool := NewRunShellTool()
+	params, _ := json.Marshal(map[string]any{"command": "sleep 10 & wait", "timeout": 1})
+	start := time.Now()
+	if _, err := tool.Execute(context.Background(), params); err == nil {
+		t.Fatal("expected timeout error")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("timeout took too long: %v", elapsed)
+	}
+}
+
 func TestRunShellTool_CustomTimeout(t *testing.T) {
 	tool := NewRunShellTool()
 	params, _ := json.Marshal(map[string]any{"command": "echo ok", "timeout": 5})
@@ -87,3 +101,73 @@ func TestRunShellTool_Name(t *testing.T) {
 		t.Error("Parameters() is empty")
 	}
 }
+
+func TestRunShellTool_RunsInRoot(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "marker.txt"), nil, 0644)
+	tool := NewRunShellTool()
+	tool.SetRoot(dir)
+
+	result, err := tool.Execute(context.Background(), json.RawMessage(`{"command":"ls"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(result, "marker.txt") {
+		t.Errorf("command did not run in the root: %q", result)
+	}
+}
+
+func TestRunShellTool_PolicyAllowsListedCommand(t *testing.T) {
+	tool := NewRunShellTool()
+	tool.SetShellPolicy(ShellPolicy{Allow: []string{"echo", "tr"}})
+
+	result, err := tool.Execute(context.Background(), json.RawMessage(`{"command":"LANG=C /bin/echo hello 2>&1 | tr a-z A-Z"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(result, "HELLO") {
+		t.Errorf("unexpected result: %q", result)
+	}
+}
+
+func TestRunShellTool_PolicyRejectsCommand(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "ran")
+	tests := []struct {
+		name    string
+		policy  ShellPolicy
+		command string
+		want    string
+	}{
+		{"not allowed", ShellPolicy{Allow: []string{"echo"}}, "touch " + marker, `"touch" is not allowed; permitted commands: echo`},
+		{"chained", ShellPolicy{Allow: []string{"echo"}}, "echo ok && touch " + marker, `"touch" is not allowed`},
+		{"substitution", ShellPolicy{Allow: []string{"echo"}}, "echo $(touch " + marker + ")", "substitution are not allowed"},
+		{"process substitution", ShellPolicy{Allow: []string{"cat"}}, "cat <(touch " + marker + ")", "substitution are not allowed"},
+		{"output process substitution", ShellPolicy{Allow: []string{"echo"}}, "echo hi > >(touch " + marker + ")", "substitution are not allowed"},
+		{"denied", ShellPolicy{Deny: []string{"touch"}}, "(touch " + marker + ")", `"touch" is not allowed`},
+		{"disabled", ShellPolicy{Disabled: true}, "echo hi; touch " + marker, "disabled"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tool := NewRunShellTool()
+			tool.SetShellPolicy(tt.policy)
+			params, _ := json.Marshal(map[string]any{"command": tt.command})
+			_, err := tool.Execute(context.Background(), params)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("err = %v, want it to contain %q", err, tt.want)
+			}
+			if _, err := os.Stat(marker); err == nil {
+				t.Fatal("rejected command was run")
+			}
+		})
+	}
+}
+
+func TestRegistrySetShellPolicy(t *testing.T) {
+	r := NewRegistry()
+	r.Register(NewRunShellTool())
+	r.SetShellPolicy(ShellPolicy{Disabled: true})
+
+	if got := r.Execute(context.Background(), "run_shell", json.RawMessage(`{"command":"echo hi"}`)); !strings.Contains(got, "disabled") {
+		t.Errorf("Execute = %q, want the disabled error", got)
+	}
+}
@@ -0,0 +1,53 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// SkillRenderer expands a named skill with the given arguments.
+type SkillRenderer interface {
+	RenderSkill(name string, args map[string]string) (string, error)
+}
+
+type UseSkillTool struct {
+	skills SkillRenderer
+}
+
+func NewUseSkillTool(skills SkillRenderer) *UseSkillTool {
+	return &UseSkillTool{skills: skills}
+}
+
+func (t *UseSkillTool) Name() string { return "use_skill" }
+func (t *UseSkillTool) Description() string {
+	return "Load the instructions for a skill listed in available_skills, filling in its args"
+}
+func (t *UseSkillTool) Parameters() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"name": {"type": "string", "description": "Skill name"},
+			"args": {
+				"type": "object",
+				"description": "Values for the skill's args, keyed by name",
+				"additionalProperties": {"type": "string"}
+			}
+		},
+		"required": ["name"]
+	}`)
+}
+
+func (t *UseSkillTool) Execute(_ context.Context, params json.RawMessage) (string, error) {
+	var p struct {
+		Name string            `json:"name"`
+		Args map[string]string `json:"args"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return "", fmt.Errorf("invalid parameters: %w", err)
+	}
+	if p.Name == "" {
+		return "", fmt.Errorf("name is required")
+	}
+	return t.skills.RenderSkill(p.Name, p.Args)
+}
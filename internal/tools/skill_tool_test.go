@@ -0,0 +1,35 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+type mockSkillRenderer struct{}
+
+func (mockSkillRenderer) RenderSkill(name string, args map[string]string) (string, error) {
+	if name != "greet" {
+		return "", fmt.Errorf("skill %q not found", name)
+	}
+	return "Say hello to " + args["who"], nil
+}
+
+func TestUseSkillTool(t *testing.T) {
+	tool := NewUseSkillTool(mockSkillRenderer{})
+
+	result, err := tool.Execute(context.Background(), json.RawMessage(`{"name":"greet","args":{"who":"Ada"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != "Say hello to Ada" {
+		t.Errorf("result = %q", result)
+	}
+
+	for _, params := range []string{`{}`, `{"name":"missing"}`, `not-json`} {
+		if _, err := tool.Execute(context.Background(), json.RawMessage(params)); err == nil {
+			t.Errorf("%s: expected error", params)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SubagentRunner starts and tracks background task agents. Listing and
+// cancelling are scoped to the conversation that started the task.
+type SubagentRunner interface {
+	Spawn(ctx context.Context, task, label, originChannel, originChatID string) string
+	ListRunningFor(originChannel, originChatID string) []string
+	CancelFor(taskID, originChannel, originChatID string) bool
+}
+
+type SpawnSubagentTool struct {
+	runner SubagentRunner
+}
+
+func NewSpawnSubagentTool(runner SubagentRunner) *SpawnSubagentTool {
+	return &SpawnSubagentTool{runner: runner}
+}
+
+func (t *SpawnSubagentTool) Name() string { return "spawn_subagent" }
+func (t *SpawnSubagentTool) Description() string {
+	return "Start a background subagent on a self-contained task. Returns a task ID immediately; the result is reported back to this conversation when the task finishes."
+}
+func (t *SpawnSubagentTool) Parameters() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"task": {"type": "string", "description": "Complete description of the task for the subagent"},
+			"label": {"type": "string", "description": "Short label used when reporting the result"}
+		},
+		"required": ["task"]
+	}`)
+}
+
+func (t *SpawnSubagentTool) Execute(ctx context.Context, params json.RawMessage) (string, error) {
+	var p struct {
+		Task  string `json:"task"`
+		Label string `json:"label"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return "", fmt.Errorf("invalid parameters: %w", err)
+	}
+	if p.Task == "" {
+		return "", fmt.Errorf("task is required")
+	}
+	if p.Label == "" {
+		p.Label = p.Task
+		if r := []rune(p.Label); len(r) > 40 {
+			p.Label = string(r[:40]) + "…"
+		}
+	}
+
+	channel, chatID := OriginFrom(ctx)
+	// The subagent outlives this tool call, so it must not inherit the call's
+	// cancellation or timeout.
+	taskID := t.runner.Spawn(context.WithoutCancel(ctx), p.Task, p.Label, channel, chatID)
+	return fmt.Sprintf("Subagent started: %s", taskID), nil
+}
+
+type ListSubagentsTool struct {
+	runner SubagentRunner
+}
+
+func NewListSubagentsTool(runner SubagentRunner) *ListSubagentsTool {
+	return &ListSubagentsTool{runner: runner}
+}
+
+func (t *ListSubagentsTool) Name() string { return "list_subagents" }
+func (t *ListSubagentsTool) Description() string {
+	return "List the IDs of running background subagents"
+}
+func (t *ListSubagentsTool) Parameters() json.RawMessage {
+	return json.RawMessage(`{"type": "object", "properties": {}}`)
+}
+
+func (t *ListSubagentsTool) Execute(ctx context.Context, _ json.RawMessage) (string, error) {
+	ids := t.runner.ListRunningFor(OriginFrom(ctx))
+	if len(ids) == 0 {
+		return "No subagents running", nil
+	}
+	sort.Strings(ids)
+	return "Running subagents: " + strings.Join(ids, ", "), nil
+}
+
+type CancelSubagentTool struct {
+	runner SubagentRunner
+}
+
+func NewCancelSubagentTool(runner SubagentRunner) *CancelSubagentTool {
+	return &CancelSubagentTool{runner: runner}
+}
+
+func (t *CancelSubagentTool) Name() string        { return "cancel_subagent" }
+func (t *CancelSubagentTool) Description() string { return "Cancel a running background subagent" }
+func (t *CancelSubagentTool) Parameters() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"task_id": {"type": "string", "description": "Task ID returned by spawn_subagent"}
+		},
+		"required": ["task_id"]
+	}`)
+}
+
+func (t *CancelSubagentTool) Execute(ctx context.Context, params json.RawMessage) (string, error) {
+	var p struct {
+		TaskID string `json:"task_id"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return "", fmt.Errorf("invalid parameters: %w", err)
+	}
+	if p.TaskID == "" {
+		return "", fmt.Errorf("task_id is required")
+	}
+	channel, chatID := OriginFrom(ctx)
+	if !t.runner.CancelFor(p.TaskID, channel, chatID) {
+		return "", fmt.Errorf("no running subagent with ID %s", p.TaskID)
+	}
+	return fmt.Sprintf("Subagent cancelled: %s", p.TaskID), nil
+}
@@ -0,0 +1,88 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+type mockSubagentRunner struct {
+	spawned map[string]string // id -> origin
+	running map[string]bool
+}
+
+func (m *mockSubagentRunner) Spawn(ctx context.Context, task, label, channel, chatID string) string {
+	id := "task_" + label
+	m.spawned[id] = channel + ":" + chatID
+	m.running[id] = true
+	return id
+}
+
+func (m *mockSubagentRunner) ListRunningFor(channel, chatID string) []string {
+	var ids []string
+	for id := range m.running {
+		if m.spawned[id] == channel+":"+chatID {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func (m *mockSubagentRunner) CancelFor(id, channel, chatID string) bool {
+	if !m.running[id] || m.spawned[id] != channel+":"+chatID {
+		return false
+	}
+	delete(m.running, id)
+	return true
+}
+
+func TestSubagentTools(t *testing.T) {
+	runner := &mockSubagentRunner{spawned: map[string]string{}, running: map[string]bool{}}
+	spawn := NewSpawnSubagentTool(runner)
+	list := NewListSubagentsTool(runner)
+	cancel := NewCancelSubagentTool(runner)
+
+	ctx := WithOrigin(context.Background(), "telegram", "42")
+	params, _ := json.Marshal(map[string]any{"task": "summarise the logs", "label": "logs"})
+	result, err := spawn.Execute(ctx, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(result, "task_logs") {
+		t.Errorf("spawn result %q does not contain the task ID", result)
+	}
+	if runner.spawned["task_logs"] != "telegram:42" {
+		t.Errorf("origin = %q, want telegram:42", runner.spawned["task_logs"])
+	}
+
+	if _, err := spawn.Execute(ctx, json.RawMessage(`{}`)); err == nil {
+		t.Error("expected error without task")
+	}
+
+	result, _ = list.Execute(ctx, json.RawMessage(`{}`))
+	if !strings.Contains(result, "task_logs") {
+		t.Errorf("list result %q missing task", result)
+	}
+
+	// Another chat neither sees nor cancels the task.
+	other := WithOrigin(context.Background(), "telegram", "99")
+	if result, _ = list.Execute(other, json.RawMessage(`{}`)); result != "No subagents running" {
+		t.Errorf("list from another chat = %q", result)
+	}
+	params, _ = json.Marshal(map[string]any{"task_id": "task_logs"})
+	if _, err := cancel.Execute(other, params); err == nil {
+		t.Error("expected error cancelling another chat's task")
+	}
+	if _, err := cancel.Execute(ctx, params); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cancel.Execute(ctx, params); err == nil {
+		t.Error("expected error cancelling an already cancelled task")
+	}
+
+	result, _ = list.Execute(ctx, json.RawMessage(`{}`))
+	if result != "No subagents running" {
+		t.Errorf("list after cancel = %q", result)
+	}
+}
@@ -3,6 +3,7 @@ package tools
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -13,10 +14,82 @@ import (
 
 const maxWebContentLen = 100 * 1024 // 100KB
 
-type WebGetTool struct{}
+// webFetchTimeout bounds one page fetch by web_get and read_url.
+const webFetchTimeout = 30 * time.Second
+
+// hostAllowlist limits the hosts a web tool may fetch from.
+type hostAllowlist struct {
+	hosts []string
+}
+
+// SetAllowedHosts restricts fetches, redirects included, to these hosts and
+// their subdomains. An empty list allows any host.
+func (h *hostAllowlist) SetAllowedHosts(hosts []string) {
+	h.hosts = hosts
+}
+
+func (h *hostAllowlist) allows(host string) bool {
+	if len(h.hosts) == 0 {
+		return true
+	}
+	host = strings.ToLower(host)
+	for _, allowed := range h.hosts {
+		allowed = strings.ToLower(strings.TrimPrefix(allowed, "."))
+		if host == allowed || strings.HasSuffix(host, "."+allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// fetch GETs rawURL and returns at most limit bytes of a 200 response.
+func (h *hostAllowlist) fetch(ctx context.Context, rawURL string, limit int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if !h.allows(req.URL.Hostname()) {
+		return nil, fmt.Errorf("host %s is not in the allowed hosts", req.URL.Hostname())
+	}
+	req.Header.Set("User-Agent", "nanobot/0.1")
+
+	client := &http.Client{
+		Timeout: webFetchTimeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			if !h.allows(req.URL.Hostname()) {
+				return fmt.Errorf("redirect to host %s is not in the allowed hosts", req.URL.Hostname())
+			}
+			return nil
+		},
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch URL: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limit))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	return body, nil
+}
+
+type WebGetTool struct {
+	hostAllowlist
+}
 
 func NewWebGetTool() *WebGetTool { return &WebGetTool{} }
 
+func (t *WebGetTool) cloneTool() Tool { c := *t; return &c }
+
 func (t *WebGetTool) Name() string        { return "web_get" }
 func (t *WebGetTool) Description() string { return "Fetch a URL and return its text content" }
 func (t *WebGetTool) Parameters() json.RawMessage {
@@ -41,32 +114,9 @@ func (t *WebGetTool) Execute(ctx context.Context, params json.RawMessage) (strin
 		return "", fmt.Errorf("url is required")
 	}
 
-	// Create HTTP client with timeout
-	client := &http.Client{
-		Timeout: 30 * time.Second,
-	}
-
-	req, err := http.NewRequestWithContext(ctx, "GET", p.URL, nil)
-	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
-	}
-	req.Header.Set("User-Agent", "nanobot/0.1")
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("failed to fetch URL: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
-	}
-
-	// Read body with size limit
-	limitedReader := io.LimitReader(resp.Body, maxWebContentLen)
-	body, err := io.ReadAll(limitedReader)
+	body, err := t.fetch(ctx, p.URL, maxWebContentLen)
 	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
+		return "", err
 	}
 
 	// Strip HTML tags
@@ -0,0 +1,204 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultSearchResults = 5
+	maxSearchResults     = 20
+)
+
+// SearchResult is one hit returned by a SearchBackend.
+type SearchResult struct {
+	Title   string
+	URL     string
+	Snippet string
+}
+
+// SearchBackend runs web searches for WebSearchTool. Implementations return at
+// most count results.
+type SearchBackend interface {
+	Search(ctx context.Context, query string, count int) ([]SearchResult, error)
+}
+
+// NewSearchBackend returns the built-in backend for provider, "brave" or
+// "tavily". An empty provider means brave. A missing API key is reported when
+// a search is made, so the tool can be registered before one is configured.
+func NewSearchBackend(provider, apiKey string) (SearchBackend, error) {
+	switch strings.ToLower(provider) {
+	case "", "brave":
+		return &BraveSearch{apiKey: apiKey, baseURL: "https://api.search.brave.com/res/v1/web/search"}, nil
+	case "tavily":
+		return &TavilySearch{apiKey: apiKey, baseURL: "https://api.tavily.com/search"}, nil
+	}
+	return nil, fmt.Errorf("unknown search provider %q (want brave or tavily)", provider)
+}
+
+type WebSearchTool struct {
+	backend SearchBackend
+}
+
+func NewWebSearchTool(backend SearchBackend) *WebSearchTool {
+	return &WebSearchTool{backend: backend}
+}
+
+func (t *WebSearchTool) Name() string { return "web_search" }
+func (t *WebSearchTool) Description() string {
+	return "Search the web and return the title, URL and a snippet of each result. Use web_get to read a result in full"
+}
+func (t *WebSearchTool) Parameters() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"query": {"type": "string", "description": "Search query"},
+			"count": {"type": "integer", "description": "Number of results, 1 to 20 (default 5)"}
+		},
+		"required": ["query"]
+	}`)
+}
+
+func (t *WebSearchTool) Execute(ctx context.Context, params json.RawMessage) (string, error) {
+	var p struct {
+		Query string `json:"query"`
+		Count int    `json:"count"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return "", fmt.Errorf("invalid parameters: %w", err)
+	}
+	query := strings.TrimSpace(p.Query)
+	if query == "" {
+		return "", fmt.Errorf("query is required")
+	}
+	count := p.Count
+	switch {
+	case count <= 0:
+		count = defaultSearchResults
+	case count > maxSearchResults:
+		count = maxSearchResults
+	}
+
+	results, err := t.backend.Search(ctx, query, count)
+	if err != nil {
+		return "", fmt.Errorf("web search failed: %w", err)
+	}
+	if len(results) == 0 {
+		return fmt.Sprintf("No results for %q.", query), nil
+	}
+	if len(results) > count {
+		results = results[:count]
+	}
+
+	var sb strings.Builder
+	for i, r := range results {
+		fmt.Fprintf(&sb, "%d. %s\n   %s\n", i+1, cleanWhitespace(stripHTML(r.Title)), r.URL)
+		if snippet := cleanWhitespace(stripHTML(r.Snippet)); snippet != "" {
+			fmt.Fprintf(&sb, "   %s\n", snippet)
+		}
+	}
+	return strings.TrimRight(sb.String(), "\n"), nil
+}
+
+// searchClient performs requests for the built-in search backends.
+var searchClient = &http.Client{Timeout: 30 * time.Second}
+
+// BraveSearch queries the Brave Search API.
+type BraveSearch struct {
+	apiKey  string
+	baseURL string
+}
+
+func (b *BraveSearch) Search(ctx context.Context, query string, count int) ([]SearchResult, error) {
+	if b.apiKey == "" {
+		return nil, fmt.Errorf("brave search API key is not configured (set tools.webSearch.apiKey)")
+	}
+	q := url.Values{"q": {query}, "count": {strconv.Itoa(count)}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.baseURL+"?"+q.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-Subscription-Token", b.apiKey)
+
+	var body struct {
+		Web struct {
+			Results []struct {
+				Title       string `json:"title"`
+				URL         string `json:"url"`
+				Description string `json:"description"`
+			} `json:"results"`
+		} `json:"web"`
+	}
+	if err := doSearch(req, &body); err != nil {
+		return nil, fmt.Errorf("brave: %w", err)
+	}
+	results := make([]SearchResult, 0, len(body.Web.Results))
+	for _, r := range body.Web.Results {
+		results = append(results, SearchResult{Title: r.Title, URL: r.URL, Snippet: r.Description})
+	}
+	return results, nil
+}
+
+// TavilySearch queries the Tavily search API.
+type TavilySearch struct {
+	apiKey  string
+	baseURL string
+}
+
+func (s *TavilySearch) Search(ctx context.Context, query string, count int) ([]SearchResult, error) {
+	if s.apiKey == "" {
+		return nil, fmt.Errorf("tavily search API key is not configured (set tools.webSearch.apiKey)")
+	}
+	payload, err := json.Marshal(map[string]any{"query": query, "max_results": count})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.baseURL, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+s.apiKey)
+
+	var body struct {
+		Results []struct {
+			Title   string `json:"title"`
+			URL     string `json:"url"`
+			Content string `json:"content"`
+		} `json:"results"`
+	}
+	if err := doSearch(req, &body); err != nil {
+		return nil, fmt.Errorf("tavily: %w", err)
+	}
+	results := make([]SearchResult, 0, len(body.Results))
+	for _, r := range body.Results {
+		results = append(results, SearchResult{Title: r.Title, URL: r.URL, Snippet: r.Content})
+	}
+	return results, nil
+}
+
+// doSearch sends req and decodes a successful JSON response into out.
+func doSearch(req *http.Request, out any) error {
+	resp, err := searchClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type mockSearchBackend struct {
+	results   []SearchResult
+	err       error
+	lastQuery string
+	lastCount int
+}
+
+func (m *mockSearchBackend) Search(_ context.Context, query string, count int) ([]SearchResult, error) {
+	m.lastQuery, m.lastCount = query, count
+	return m.results, m.err
+}
+
+func TestWebSearchTool_FormatsResults(t *testing.T) {
+	backend := &mockSearchBackend{results: []SearchResult{
+		{Title: "Go 1.25 release notes", URL: "https://go.dev/doc/go1.25", Snippet: "What's new in <strong>Go 1.25</strong>"},
+		{Title: "Go blog", URL: "https://go.dev/blog"},
+	}}
+	tool := NewWebSearchTool(backend)
+
+	params, _ := json.Marshal(map[string]any{"query": " go 1.25 ", "count": 2})
+	result, err := tool.Execute(context.Background(), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "1. Go 1.25 release notes\n   https://go.dev/doc/go1.25\n   What's new in Go 1.25\n" +
+		"2. Go blog\n   https://go.dev/blog"
+	if result != want {
+		t.Errorf("result =\n%s\nwant\n%s", result, want)
+	}
+	if backend.lastQuery != "go 1.25" || backend.lastCount != 2 {
+		t.Errorf("backend got query %q count %d", backend.lastQuery, backend.lastCount)
+	}
+}
+
+func TestWebSearchTool_Count(t *testing.T) {
+	for _, tc := range []struct{ count, want int }{{0, 5}, {-3, 5}, {8, 8}, {100, 20}} {
+		backend := &mockSearchBackend{}
+		params, _ := json.Marshal(map[string]any{"query": "q", "count": tc.count})
+		result, err := NewWebSearchTool(backend).Execute(context.Background(), params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if backend.lastCount != tc.want {
+			t.Errorf("count %d: backend asked for %d, want %d", tc.count, backend.lastCount, tc.want)
+		}
+		if result != `No results for "q".` {
+			t.Errorf("result = %q", result)
+		}
+	}
+}
+
+func TestWebSearchTool_Errors(t *testing.T) {
+	tool := NewWebSearchTool(&mockSearchBackend{err: errors.New("rate limited")})
+	if _, err := tool.Execute(context.Background(), json.RawMessage(`{"query":""}`)); err == nil {
+		t.Error("expected error for empty query")
+	}
+	_, err := tool.Execute(context.Background(), json.RawMessage(`{"query":"x"}`))
+	if err == nil || !strings.Contains(err.Error(), "rate limited") {
+		t.Errorf("error = %v, want the backend error", err)
+	}
+}
+
+func TestSearchBackends_MissingAPIKey(t *testing.T) {
+	for _, provider := range []string{"brave", "tavily"} {
+		backend, err := NewSearchBackend(provider, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = NewWebSearchTool(backend).Execute(context.Background(), json.RawMessage(`{"query":"x"}`))
+		if err == nil || !strings.Contains(err.Error(), "API key is not configured") {
+			t.Errorf("%s: error = %v, want a missing API key error", provider, err)
+		}
+	}
+	if _, err := NewSearchBackend("altavista", "key"); err == nil {
+		t.Error("expected error for unknown provider")
+	}
+}
+
+func TestBraveSearch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Subscription-Token") != "brave-key" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Query().Get("q") != "golang" || r.URL.Query().Get("count") != "3" {
+			http.Error(w, "bad query "+r.URL.RawQuery, http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"web":{"results":[{"title":"The Go Programming Language","url":"https://go.dev","description":"Build simple, secure software"}]}}`))
+	}))
+	defer srv.Close()
+
+	results, err := (&BraveSearch{apiKey: "brave-key", baseURL: srv.URL}).Search(context.Background(), "golang", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].URL != "https://go.dev" || results[0].Snippet != "Build simple, secure software" {
+		t.Errorf("results = %+v", results)
+	}
+
+	_, err = (&BraveSearch{apiKey: "wrong", baseURL: srv.URL}).Search(context.Background(), "golang", 3)
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("error = %v, want HTTP 401", err)
+	}
+}
+
+func TestTavilySearch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Query      string `json:"query"`
+			MaxResults int    `json:"max_results"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if r.Header.Get("Authorization") != "Bearer tvly-key" || body.Query != "golang" || body.MaxResults != 2 {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"results":[{"title":"Go","url":"https://go.dev","content":"Go is an open source language"}]}`))
+	}))
+	defer srv.Close()
+
+	results, err := (&TavilySearch{apiKey: "tvly-key", baseURL: srv.URL}).Search(context.Background(), "golang", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Title != "Go" || results[0].Snippet != "Go is an open source language" {
+		t.Errorf("results = %+v", results)
+	}
+}